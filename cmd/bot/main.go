@@ -29,7 +29,7 @@ func main() {
 	bot.Debug = false
 	log.Info("Authorized", zap.String("bot_username", bot.Self.UserName))
 
-	h := handler.NewHandler(bot, cfg.GatewayURL, log)
+	h := handler.NewHandler(bot, cfg.GatewayURL, cfg.APISecret, log)
 
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
@@ -42,6 +42,11 @@ func main() {
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	stopNotifications := make(chan struct{})
+	if cfg.NotificationInterval > 0 {
+		go h.RunNotifications(stopNotifications, cfg.NotificationInterval)
+	}
+
 	for {
 		select {
 		case update := <-updates:
@@ -49,6 +54,7 @@ func main() {
 		case <-quit:
 			log.Info("Shutting down Bot Service")
 			bot.StopReceivingUpdates()
+			close(stopNotifications)
 			h.Cleanup()
 			log.Info("Bot Service stopped")
 			return
@@ -35,7 +35,7 @@ func main() {
 	defer clients.Close()
 
 	// Initialize handler
-	h := handler.NewHandler(clients, log)
+	h := handler.NewHandler(clients, cfg.BotSecret, log)
 
 	// Setup router
 	r := chi.NewRouter()
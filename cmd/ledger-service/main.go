@@ -7,8 +7,10 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	_ "time/tzdata" // Часовые пояса для тихих часов, если в образе нет zoneinfo
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kiribu/financial-tracker/internal/pkg/blobstore"
 	"github.com/kiribu/financial-tracker/internal/pkg/config"
 	"github.com/kiribu/financial-tracker/internal/pkg/logger"
 	"github.com/kiribu/financial-tracker/internal/ledger/handler"
@@ -20,7 +22,7 @@ import (
 )
 
 func main() {
-	cfg := &config.ServiceConfig{}
+	cfg := &config.LedgerConfig{}
 	config.MustLoadConfig(cfg)
 
 	log := logger.MustNew("info")
@@ -41,9 +43,18 @@ func main() {
 	}
 	log.Info("Connected to PostgreSQL")
 
+	blobs, err := blobstore.New(cfg.Storage)
+	if err != nil {
+		log.Fatal("Failed to initialize attachment storage", zap.Error(err))
+	}
+	log.Info("Attachment storage ready", zap.String("backend", cfg.Storage.Backend))
+
 	// Initialize repository, service, and handler
 	repo := repository.NewRepository(db, log)
-	svc := service.NewService(repo, log)
+	svc := service.NewService(repo, blobs, service.AttachmentLimits{
+		MaxFileSize: cfg.Storage.MaxFileSize,
+		UserQuota:   cfg.Storage.UserQuota,
+	}, log)
 	h := handler.NewHandler(svc, log)
 
 	// Start gRPC server
@@ -52,7 +63,9 @@ func main() {
 		log.Fatal("Failed to listen", zap.Error(err))
 	}
 
-	s := grpc.NewServer()
+	// Вложения передаются одним сообщением, поэтому лимит на размер
+	// сообщения поднимается до размера файла с запасом
+	s := grpc.NewServer(grpc.MaxRecvMsgSize(int(cfg.Storage.MaxFileSize) + 1<<20))
 	pb.RegisterLedgerServiceServer(s, h)
 
 	log.Info("Ledger Service is running", zap.String("address", lis.Addr().String()))
@@ -64,11 +77,23 @@ func main() {
 		}
 	}()
 
+	stopJobs := make(chan struct{})
+	if cfg.AnalysisInterval > 0 {
+		go svc.RunSpendingAnalysis(stopJobs, cfg.AnalysisInterval)
+	}
+	if cfg.DigestInterval > 0 {
+		go svc.RunDigests(stopJobs, cfg.DigestInterval)
+	}
+	if cfg.ReminderInterval > 0 {
+		go svc.RunDebtReminders(stopJobs, cfg.ReminderInterval, cfg.DebtReminderDays)
+	}
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	log.Info("Shutting down Ledger Service")
+	close(stopJobs)
 	s.GracefulStop()
 }
 
@@ -3,25 +3,65 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"regexp"
+	"strconv"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
+
+	"github.com/kiribu/financial-tracker/internal/pkg/chart"
 )
 
+// GatewayError — ответ gateway с кодом ошибки
+type GatewayError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *GatewayError) Error() string {
+	return fmt.Sprintf("gateway returned %d: %s", e.StatusCode, e.Message)
+}
+
+var quickAmountPattern = regexp.MustCompile(`^\d+([.,]\d{1,2})?$`)
+
+// createdTransactionPattern находит номер операции в сообщении бота о
+// записанной операции
+var createdTransactionPattern = regexp.MustCompile(`операция #(\d+)`)
+
+// receiptQRPattern узнает расшифрованный QR-код кассового чека
+var receiptQRPattern = regexp.MustCompile(`(^|[?&])t=\d{8}T\d{4}`)
+
+// maxTelegramFileSize — файлы больше этого Bot API не отдает
+const maxTelegramFileSize = 20 << 20
+
 type Handler struct {
 	bot        *tgbotapi.BotAPI
 	gatewayURL string
+	apiSecret  string // Передается gateway в X-Bot-Secret
 	logger     *zap.Logger
+
+	chartsMu sync.Mutex
+	charts   map[string]cachedChart // Ключ — пользователь, вид отчета и период
 }
 
-func NewHandler(bot *tgbotapi.BotAPI, gatewayURL string, logger *zap.Logger) *Handler {
+func NewHandler(bot *tgbotapi.BotAPI, gatewayURL, apiSecret string, logger *zap.Logger) *Handler {
 	return &Handler{
 		bot:        bot,
 		gatewayURL: gatewayURL,
+		apiSecret:  apiSecret,
 		logger:     logger,
+		charts:     make(map[string]cachedChart),
 	}
 }
 
@@ -43,6 +83,44 @@ func (h *Handler) HandleUpdate(update tgbotapi.Update) {
 		return
 	}
 
+	if msg.IsCommand() && msg.Command() == "balance" {
+		h.handleBalance(msg)
+		return
+	}
+
+	if msg.IsCommand() && msg.Command() == "digest" {
+		h.handleDigest(msg)
+		return
+	}
+
+	if msg.IsCommand() && (msg.Command() == "report" || msg.Command() == "trend") {
+		h.handleReport(msg)
+		return
+	}
+
+	// Фото или документ в ответ на сообщение о записанной операции —
+	// вложение к этой операции
+	if msg.ReplyToMessage != nil && (len(msg.Photo) > 0 || msg.Document != nil) {
+		if transactionID, ok := h.repliedTransactionID(msg.ReplyToMessage); ok {
+			h.handleAttachment(msg, transactionID)
+			return
+		}
+	}
+
+	// Текст QR-кода с чека — операция по чеку
+	if !msg.IsCommand() && receiptQRPattern.MatchString(msg.Text) && strings.Contains(msg.Text, "fn=") {
+		h.handleReceipt(msg)
+		return
+	}
+
+	// "350 кофе" — быстрый ввод расхода
+	if !msg.IsCommand() {
+		if amount, description, tags, ok := parseQuickExpense(msg.Text); ok {
+			h.handleQuickExpense(msg, amount, description, tags)
+			return
+		}
+	}
+
 	// For any other message, just show the Web App button
 	h.showWebAppButton(userID)
 }
@@ -83,6 +161,808 @@ func (h *Handler) handleStart(msg *tgbotapi.Message) {
 	h.showWebAppButton(userID)
 }
 
+// handleBalance показывает остатки по счетам, итоги по валютам и прогресс
+// целей накопления
+func (h *Handler) handleBalance(msg *tgbotapi.Message) {
+	userID := msg.From.ID
+
+	balance, err := h.callGateway("GET", fmt.Sprintf("/api/balance?telegram_id=%d", userID), nil)
+	if err != nil {
+		h.logger.Error("failed to get balance", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Не удалось получить баланс. Попробуйте позже."))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("💰 Баланс\n")
+	accounts, _ := balance["accounts"].([]interface{})
+	for _, item := range accounts {
+		account, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&b, "\n%s: %v %v", account["name"], account["balance"], account["currency"])
+	}
+	if len(accounts) == 0 {
+		b.WriteString("\nСчетов пока нет.")
+	}
+
+	totals, _ := balance["totals"].([]interface{})
+	if len(totals) > 0 {
+		b.WriteString("\n\nИтого:")
+		for _, item := range totals {
+			if total, ok := item.(map[string]interface{}); ok {
+				fmt.Fprintf(&b, "\n%v %v", total["total"], total["currency"])
+			}
+		}
+	}
+
+	// Без целей баланс все равно показываем
+	goals, err := h.callGateway("GET", fmt.Sprintf("/api/goals?telegram_id=%d", userID), nil)
+	if err != nil {
+		h.logger.Error("failed to list goals", zap.Error(err))
+	}
+	goalItems, _ := goals["goals"].([]interface{})
+	if len(goalItems) > 0 {
+		b.WriteString("\n\n🎯 Цели:")
+		for _, item := range goalItems {
+			goal, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			mark := "⚠️"
+			if onTrack, _ := goal["on_track"].(bool); onTrack {
+				mark = "✅"
+			}
+			fmt.Fprintf(&b, "\n%s %v: %v из %v %v (%v%%)", mark, goal["name"], goal["saved"], goal["target_amount"], goal["currency"], goal["progress_percent"])
+			if remaining, _ := goal["remaining"].(string); remaining != "" && remaining != "0.00" {
+				fmt.Fprintf(&b, ", нужно %v в месяц до %v", goal["required_monthly"], goal["deadline"])
+			}
+		}
+	}
+
+	h.sendMessage(userID, b.String())
+}
+
+// digestWeekdays — дни недели для команды /digest, индекс как у time.Weekday
+var digestWeekdays = [][]string{
+	{"вс", "sun"}, {"пн", "mon"}, {"вт", "tue"}, {"ср", "wed"},
+	{"чт", "thu"}, {"пт", "fri"}, {"сб", "sat"},
+}
+
+var digestTimePattern = regexp.MustCompile(`^\d{1,2}:\d{2}$`)
+
+const digestUsage = "Использование:\n" +
+	"/digest — текущие настройки\n" +
+	"/digest on | off — включить или выключить сводку\n" +
+	"/digest weekly [пн..вс] [ЧЧ:ММ] — еженедельная сводка\n" +
+	"/digest monthly [ЧЧ:ММ] — ежемесячная сводка первого числа"
+
+// handleDigest показывает и меняет расписание сводки:
+// "/digest weekly пт 18:00", "/digest monthly 09:00", "/digest off".
+func (h *Handler) handleDigest(msg *tgbotapi.Message) {
+	userID := msg.From.ID
+
+	req := map[string]interface{}{"telegram_id": userID}
+	args := strings.Fields(strings.ToLower(msg.CommandArguments()))
+	if len(args) > 0 {
+		switch args[0] {
+		case "on":
+			req["enabled"] = true
+		case "off":
+			req["enabled"] = false
+		case "weekly", "monthly":
+			req["enabled"] = true
+			req["frequency"] = args[0]
+			for _, arg := range args[1:] {
+				if digestTimePattern.MatchString(arg) {
+					if len(arg) == 4 {
+						arg = "0" + arg
+					}
+					req["time"] = arg
+					continue
+				}
+				weekday := -1
+				for i, names := range digestWeekdays {
+					if containsName(names, arg) {
+						weekday = i
+					}
+				}
+				if weekday < 0 || args[0] != "weekly" {
+					h.sendMessage(userID, digestUsage)
+					return
+				}
+				req["weekday"] = weekday
+			}
+		default:
+			h.sendMessage(userID, digestUsage)
+			return
+		}
+	}
+
+	var settings map[string]interface{}
+	var err error
+	if len(args) == 0 {
+		settings, err = h.callGateway("GET", fmt.Sprintf("/api/digest?telegram_id=%d", userID), nil)
+	} else {
+		settings, err = h.callGateway("PUT", "/api/digest", req)
+	}
+	if err != nil {
+		h.logger.Error("failed to update digest settings", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Не удалось изменить настройки сводки. Попробуйте позже."))
+		return
+	}
+
+	h.sendMessage(userID, describeDigestSettings(settings))
+}
+
+func describeDigestSettings(settings map[string]interface{}) string {
+	if enabled, _ := settings["enabled"].(bool); !enabled {
+		return "📊 Сводка выключена.\n\n" + digestUsage
+	}
+
+	text := fmt.Sprintf("📊 Ежемесячная сводка: первого числа в %v", settings["time"])
+	if settings["frequency"] == "weekly" {
+		weekday, _ := settings["weekday"].(float64)
+		name := "?"
+		if int(weekday) >= 0 && int(weekday) < len(digestWeekdays) {
+			name = digestWeekdays[int(weekday)][0]
+		}
+		text = fmt.Sprintf("📊 Еженедельная сводка: %s в %v", name, settings["time"])
+	}
+	return fmt.Sprintf("%s (%v)", text, settings["timezone"])
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reportPeriods — периоды команд /report и /trend и их названия в тексте
+var reportPeriods = map[string]string{
+	"week": "week", "неделя": "week",
+	"month": "month", "месяц": "month",
+	"year": "year", "год": "year",
+}
+
+var reportPeriodNames = map[string]string{
+	"week":  "неделю",
+	"month": "месяц",
+	"year":  "год",
+}
+
+// legendMarks — эмодзи цветов chart.Palette для легенды в подписи
+var legendMarks = []string{"🟥", "🟧", "🟨", "🟩", "🟦", "🟪", "🟫"}
+
+const reportUsage = "Использование:\n" +
+	"/report [неделя|месяц|год] — расходы по категориям, доходы и расходы по дням\n" +
+	"/trend [неделя|месяц|год] — как менялся остаток"
+
+// cachedChart — уже отправленная картинка отчета. Telegram хранит ее
+// сам, поэтому повторно достаточно отправить file_id.
+type cachedChart struct {
+	version string // Версия данных и дата: отчеты за скользящий период устаревают каждый день
+	fileID  string
+	caption string
+	savedAt time.Time
+}
+
+// maxCachedCharts — сколько картинок хранится в кеше; при переполнении
+// вытесняются сохраненные раньше всех
+const maxCachedCharts = 1000
+
+// chartRender рисует картинку отчета и подпись к ней
+type chartRender func(telegramID int64, period string) ([]byte, string, error)
+
+// handleReport отправляет отчеты картинками: "/report месяц" — структуру
+// расходов и движение денег, "/trend год" — изменение остатка.
+func (h *Handler) handleReport(msg *tgbotapi.Message) {
+	userID := msg.From.ID
+
+	period := "month"
+	if arg := strings.ToLower(strings.TrimSpace(msg.CommandArguments())); arg != "" {
+		var ok bool
+		if period, ok = reportPeriods[arg]; !ok {
+			h.sendMessage(userID, reportUsage)
+			return
+		}
+	}
+
+	renders := map[string]chartRender{"categories": h.renderCategoryChart, "cashflow": h.renderCashflowChart}
+	kinds := []string{"categories", "cashflow"}
+	if msg.Command() == "trend" {
+		renders = map[string]chartRender{"trend": h.renderTrendChart}
+		kinds = []string{"trend"}
+	}
+
+	versionResp, err := h.callGateway("GET", fmt.Sprintf("/api/stats/version?telegram_id=%d", userID), nil)
+	if err != nil {
+		h.logger.Error("failed to get stats version", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Не удалось построить отчет. Попробуйте позже."))
+		return
+	}
+	version := fmt.Sprintf("%v:%s", versionResp["version"], time.Now().UTC().Format("2006-01-02"))
+
+	sent := false
+	for _, kind := range kinds {
+		err := h.sendChart(userID, kind, period, version, renders[kind])
+		if errors.Is(err, chart.ErrNoData) {
+			continue
+		}
+		if err != nil {
+			h.logger.Error("failed to send chart", zap.String("chart", kind), zap.Error(err))
+			h.sendMessage(userID, describeGatewayError(err, "Не удалось построить отчет. Попробуйте позже."))
+			return
+		}
+		sent = true
+	}
+	if !sent {
+		h.sendMessage(userID, fmt.Sprintf("За %s операций нет.", reportPeriodNames[period]))
+	}
+}
+
+// sendChart отправляет картинку отчета. Если такая картинка уже
+// отправлялась и данные с тех пор не менялись, повторно отправляется
+// сохраненный file_id без обращения к статистике.
+func (h *Handler) sendChart(telegramID int64, kind, period, version string, render chartRender) error {
+	key := fmt.Sprintf("%d:%s:%s", telegramID, kind, period)
+
+	h.chartsMu.Lock()
+	cached, ok := h.charts[key]
+	h.chartsMu.Unlock()
+	if ok && cached.version == version {
+		photo := tgbotapi.NewPhoto(telegramID, tgbotapi.FileID(cached.fileID))
+		photo.Caption = cached.caption
+		_, err := h.bot.Send(photo)
+		if err == nil {
+			return nil
+		}
+		// file_id мог стать недействительным — рисуем заново
+		h.logger.Warn("failed to resend cached chart", zap.String("chart", kind), zap.Error(err))
+	}
+
+	data, caption, err := render(telegramID, period)
+	if err != nil {
+		return err
+	}
+	photo := tgbotapi.NewPhoto(telegramID, tgbotapi.FileBytes{Name: kind + ".png", Bytes: data})
+	photo.Caption = caption
+	sent, err := h.bot.Send(photo)
+	if err != nil {
+		return err
+	}
+
+	if len(sent.Photo) > 0 {
+		h.storeChart(key, cachedChart{
+			version: version,
+			fileID:  sent.Photo[len(sent.Photo)-1].FileID,
+			caption: caption,
+			savedAt: time.Now(),
+		})
+	}
+	return nil
+}
+
+// storeChart сохраняет картинку в кеш. Картинки, сохраненные до начала
+// текущих суток UTC, уже не совпадут по версии и удаляются; если кеш все
+// равно полон, вытесняется самая старая.
+func (h *Handler) storeChart(key string, cached cachedChart) {
+	h.chartsMu.Lock()
+	defer h.chartsMu.Unlock()
+
+	today := cached.savedAt.UTC().Truncate(24 * time.Hour)
+	for k, c := range h.charts {
+		if c.savedAt.Before(today) {
+			delete(h.charts, k)
+		}
+	}
+	if _, ok := h.charts[key]; !ok && len(h.charts) >= maxCachedCharts {
+		oldest := ""
+		for k, c := range h.charts {
+			if oldest == "" || c.savedAt.Before(h.charts[oldest].savedAt) {
+				oldest = k
+			}
+		}
+		delete(h.charts, oldest)
+	}
+	h.charts[key] = cached
+}
+
+// renderCategoryChart рисует круговую диаграмму расходов по категориям
+// верхнего уровня. Категории сверх числа цветов объединяются в "Остальное".
+func (h *Handler) renderCategoryChart(telegramID int64, period string) ([]byte, string, error) {
+	stats, err := h.callGateway("GET", fmt.Sprintf("/api/stats/by-category?telegram_id=%d&period=%s", telegramID, period), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	items, _ := stats["categories"].([]interface{})
+	present := make(map[float64]bool)
+	for _, item := range items {
+		if category, ok := item.(map[string]interface{}); ok {
+			id, _ := category["id"].(float64)
+			present[id] = true
+		}
+	}
+
+	type slice struct {
+		name   string
+		amount float64
+	}
+	var slices []slice
+	var total float64
+	for _, item := range items {
+		category, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		// Подкатегории уже входят в сумму родителя
+		if parentID, _ := category["parent_id"].(float64); parentID != 0 && present[parentID] {
+			continue
+		}
+		value, _ := category["total_expense"].(string)
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount <= 0 {
+			continue
+		}
+		name, _ := category["name"].(string)
+		slices = append(slices, slice{name: name, amount: amount})
+		total += amount
+	}
+	if len(slices) == 0 {
+		return nil, "", chart.ErrNoData
+	}
+
+	sort.SliceStable(slices, func(i, j int) bool { return slices[i].amount > slices[j].amount })
+	if len(slices) > len(chart.Palette) {
+		rest := slice{name: "Остальное"}
+		for _, s := range slices[len(chart.Palette)-1:] {
+			rest.amount += s.amount
+		}
+		slices = append(slices[:len(chart.Palette)-1], rest)
+	}
+
+	values := make([]float64, len(slices))
+	var caption strings.Builder
+	fmt.Fprintf(&caption, "Расходы за %s: %.2f\n", reportPeriodNames[period], total)
+	for i, s := range slices {
+		values[i] = s.amount
+		fmt.Fprintf(&caption, "\n%s %s — %.2f (%.0f%%)", legendMarks[i], s.name, s.amount, s.amount/total*100)
+	}
+
+	data, err := chart.Pie(values, chart.Palette[:len(values)])
+	return data, caption.String(), err
+}
+
+// renderCashflowChart рисует столбцы доходов и расходов по дням или месяцам
+func (h *Handler) renderCashflowChart(telegramID int64, period string) ([]byte, string, error) {
+	labels, income, expense, err := h.cashflow(telegramID, period)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var totalIncome, totalExpense float64
+	for i := range labels {
+		totalIncome += income[i]
+		totalExpense += expense[i]
+	}
+	caption := fmt.Sprintf("Доходы и расходы за %s\n\n%s Доходы: %.2f\n%s Расходы: %.2f\nИтого: %+.2f",
+		reportPeriodNames[period], legendMarks[3], totalIncome, legendMarks[0], totalExpense, totalIncome-totalExpense)
+
+	data, err := chart.Bar(labels, []chart.Series{
+		{Values: income, Color: chart.Green},
+		{Values: expense, Color: chart.Red},
+	})
+	return data, caption, err
+}
+
+// renderTrendChart рисует линию изменения остатка с начала периода
+func (h *Handler) renderTrendChart(telegramID int64, period string) ([]byte, string, error) {
+	labels, income, expense, err := h.cashflow(telegramID, period)
+	if err != nil {
+		return nil, "", err
+	}
+
+	balance := make([]float64, len(labels))
+	var sum float64
+	for i := range labels {
+		sum += income[i] - expense[i]
+		balance[i] = sum
+	}
+	caption := fmt.Sprintf("%s Изменение остатка за %s: %+.2f", legendMarks[4], reportPeriodNames[period], sum)
+
+	data, err := chart.Line(labels, []chart.Series{{Values: balance, Color: chart.Blue}})
+	return data, caption, err
+}
+
+// cashflow забирает доходы и расходы по дням или месяцам и подписи к
+// ним: "05" — день месяца, "10.26" — месяц и год
+func (h *Handler) cashflow(telegramID int64, period string) (labels []string, income, expense []float64, err error) {
+	flow, err := h.callGateway("GET", fmt.Sprintf("/api/stats/cashflow?telegram_id=%d&period=%s", telegramID, period), nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	points, _ := flow["points"].([]interface{})
+	for _, item := range points {
+		point, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		date, _ := point["date"].(string)
+		label := date
+		if len(date) == len("2006-01-02") {
+			label = date[8:]
+		} else if len(date) == len("2006-01") {
+			label = date[5:] + "." + date[2:4]
+		}
+		in, _ := point["income"].(string)
+		out, _ := point["expense"].(string)
+		inValue, _ := strconv.ParseFloat(in, 64)
+		outValue, _ := strconv.ParseFloat(out, 64)
+		labels = append(labels, label)
+		income = append(income, inValue)
+		expense = append(expense, outValue)
+	}
+	return labels, income, expense, nil
+}
+
+// notificationTemplates — шаблоны текста уведомлений по типу. Поля
+// подставляются из payload уведомления, отсутствующие поля пусты.
+var notificationTemplates = map[string]*template.Template{
+	"budget_exceeded":   notificationTemplate("💸 Бюджет «{{.budget}}» превышен: потрачено {{.spent}} из {{.limit}} {{.currency}}"),
+	"recurring_payment": notificationTemplate("🔁 Проведен регулярный платеж «{{.name}}»: {{.amount}} {{.currency}}"),
+	"large_expense": notificationTemplate("⚠️ Крупный расход в «{{.category}}»: {{.amount}} {{.currency}}, обычно — около {{.baseline}} {{.currency}}" +
+		"{{with .description}}\n{{.}}{{end}}"),
+	"spending_trend": notificationTemplate("📈 «{{.category}}»: с начала месяца потрачено {{.amount}} {{.currency}}, " +
+		"обычно к этому дню — около {{.baseline}} {{.currency}}"),
+	"weekly_digest":  notificationTemplate("📊 {{.title}}\n\n{{.text}}"),
+	"monthly_digest": notificationTemplate("🗓 {{.title}}\n\n{{.text}}"),
+	"debt_reminder": notificationTemplate("🔔 {{.counterparty}}: " +
+		"{{if eq .direction \"borrowed\"}}нужно вернуть{{else}}вам должны вернуть{{end}} {{.amount}} {{.currency}}" +
+		"{{if .overdue}} — срок прошел {{.due_date}}{{else}} до {{.due_date}}{{end}}" +
+		"{{with .description}}\n{{.}}{{end}}"),
+}
+
+func notificationTemplate(text string) *template.Template {
+	return template.Must(template.New("").Option("missingkey=zero").Parse(text))
+}
+
+const (
+	notificationBatch = 50
+	// Bot API пропускает около 30 сообщений в секунду на бота
+	notificationSendInterval = 40 * time.Millisecond
+)
+
+// RunNotifications периодически забирает из очереди уведомления, которые
+// пора отправить, и отправляет их, пока не закрыт stop
+func (h *Handler) RunNotifications(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		h.dispatchNotifications(stop)
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// dispatchNotifications отправляет уведомления пачками, пока очередь не
+// опустеет. Результат каждой отправки сообщается gateway: повтор с паузой
+// назначает ledger-сервис. При ответе 429 остаток пачки откладывается на
+// время, которое назвал Telegram.
+func (h *Handler) dispatchNotifications(stop <-chan struct{}) {
+	for {
+		resp, err := h.callGateway("POST", "/api/notifications/claim", map[string]interface{}{
+			"limit": notificationBatch,
+		})
+		if err != nil {
+			h.logger.Error("failed to claim notifications", zap.Error(err))
+			return
+		}
+
+		notifications, _ := resp["notifications"].([]interface{})
+		for i, item := range notifications {
+			notification, ok := item.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			id := int64Field(notification, "id")
+
+			text, err := renderNotification(notification)
+			if err != nil {
+				h.completeNotification(id, map[string]interface{}{"error": err.Error(), "permanent": true})
+				continue
+			}
+
+			retryAfter, permanent, err := h.deliverNotification(int64Field(notification, "telegram_id"), text)
+			switch {
+			case err == nil:
+				h.completeNotification(id, map[string]interface{}{"delivered": true})
+			case retryAfter > 0:
+				h.logger.Warn("telegram rate limit reached", zap.Int("retry_after", retryAfter))
+				for _, rest := range notifications[i:] {
+					if restNotification, ok := rest.(map[string]interface{}); ok {
+						h.completeNotification(int64Field(restNotification, "id"), map[string]interface{}{
+							"error":       err.Error(),
+							"retry_after": retryAfter,
+						})
+					}
+				}
+				return
+			default:
+				h.completeNotification(id, map[string]interface{}{"error": err.Error(), "permanent": permanent})
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(notificationSendInterval):
+			}
+		}
+
+		if len(notifications) < notificationBatch {
+			return
+		}
+	}
+}
+
+// renderNotification составляет текст уведомления по шаблону его типа
+func renderNotification(notification map[string]interface{}) (string, error) {
+	notificationType, _ := notification["type"].(string)
+	tmpl, ok := notificationTemplates[notificationType]
+	if !ok {
+		return "", fmt.Errorf("unknown notification type: %s", notificationType)
+	}
+
+	payload := map[string]string{}
+	if fields, ok := notification["payload"].(map[string]interface{}); ok {
+		for key, value := range fields {
+			payload[key] = fmt.Sprint(value)
+		}
+	}
+
+	var text strings.Builder
+	if err := tmpl.Execute(&text, payload); err != nil {
+		return "", err
+	}
+	return text.String(), nil
+}
+
+// deliverNotification отправляет сообщение. Для ответа 429 возвращает, через
+// сколько секунд можно повторить; permanent — повторять бессмысленно
+// (бот заблокирован или чат не найден).
+func (h *Handler) deliverNotification(telegramID int64, text string) (retryAfter int, permanent bool, err error) {
+	_, err = h.bot.Send(tgbotapi.NewMessage(telegramID, text))
+	if err == nil {
+		return 0, false, nil
+	}
+
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests:
+			retryAfter = apiErr.RetryAfter
+			if retryAfter <= 0 {
+				retryAfter = 1
+			}
+			return retryAfter, false, err
+		case http.StatusBadRequest, http.StatusForbidden:
+			return 0, true, err
+		}
+	}
+	return 0, false, err
+}
+
+func (h *Handler) completeNotification(id int64, result map[string]interface{}) {
+	if _, err := h.callGateway("POST", fmt.Sprintf("/api/notifications/%d/complete", id), result); err != nil {
+		h.logger.Error("failed to complete notification", zap.Int64("notification_id", id), zap.Error(err))
+	}
+}
+
+// handleQuickExpense записывает расход на основной счет в категорию по умолчанию
+// (глобальную "Прочее", если пользователь не выбрал другую)
+func (h *Handler) handleQuickExpense(msg *tgbotapi.Message, amount, description string, tags []string) {
+	userID := msg.From.ID
+
+	accountID, err := h.defaultAccountID(userID)
+	if err != nil {
+		h.logger.Error("failed to find default account", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Не удалось найти счет для записи расхода."))
+		return
+	}
+
+	// Категорию подбирает ledger: по правилам пользователя, затем по
+	// известному получателю из описания, иначе категория по умолчанию
+	resp, err := h.callGateway("POST", "/api/transactions/expense", map[string]interface{}{
+		"telegram_id": userID,
+		"account_id":  accountID,
+		"amount":      amount,
+		"description": description,
+		"tags":        tags,
+		"apply_rules": true,
+	})
+	if err != nil {
+		h.logger.Error("failed to create quick expense", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Ошибка при записи расхода. Попробуйте позже."))
+		return
+	}
+
+	text := fmt.Sprintf("✅ Расход %s записан (операция #%d).", amount, int64Field(resp, "transaction_id"))
+	if balance, ok := resp["account_balance"].(string); ok {
+		text += fmt.Sprintf("\nБаланс счета: %s", balance)
+	}
+	if warning, ok := resp["warning"].(string); ok && warning != "" {
+		text += fmt.Sprintf("\n⚠️ %s", warning)
+	}
+	text += "\n📎 Ответьте на это сообщение фото чека, чтобы прикрепить его."
+	h.sendMessage(userID, text)
+}
+
+// handleReceipt записывает операцию по QR-коду чека на основной счет:
+// покупку — расходом, возврат — доходом. Повторно тот же чек не записывается.
+func (h *Handler) handleReceipt(msg *tgbotapi.Message) {
+	userID := msg.From.ID
+	qr := strings.TrimSpace(msg.Text)
+
+	draft, err := h.callGateway("POST", "/api/receipts/parse", map[string]interface{}{
+		"telegram_id": userID,
+		"qr":          qr,
+	})
+	if err != nil {
+		h.logger.Error("failed to parse receipt", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Не удалось разобрать чек. Попробуйте позже."))
+		return
+	}
+	if duplicateID := int64Field(draft, "duplicate_transaction_id"); duplicateID > 0 {
+		h.sendMessage(userID, fmt.Sprintf("🧾 Этот чек уже записан (операция #%d).", duplicateID))
+		return
+	}
+
+	accountID, err := h.defaultAccountID(userID)
+	if err != nil {
+		h.logger.Error("failed to find default account", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Не удалось найти счет для записи чека."))
+		return
+	}
+
+	txType, _ := draft["type"].(string)
+	amount, _ := draft["amount"].(string)
+	resp, err := h.callGateway("POST", "/api/transactions/"+txType, map[string]interface{}{
+		"telegram_id": userID,
+		"account_id":  accountID,
+		"receipt_qr":  qr,
+		"apply_rules": true,
+	})
+	if err != nil {
+		h.logger.Error("failed to create receipt transaction", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Ошибка при записи чека. Попробуйте позже."))
+		return
+	}
+
+	kind := "Расход"
+	if txType == "income" {
+		kind = "Возврат"
+	}
+	text := fmt.Sprintf("🧾 %s %s по чеку записан (операция #%d).", kind, amount, int64Field(resp, "transaction_id"))
+	if balance, ok := resp["account_balance"].(string); ok {
+		text += fmt.Sprintf("\nБаланс счета: %s", balance)
+	}
+	if warning, ok := resp["warning"].(string); ok && warning != "" {
+		text += fmt.Sprintf("\n⚠️ %s", warning)
+	}
+	text += "\n📎 Ответьте на это сообщение фото чека, чтобы прикрепить его."
+	h.sendMessage(userID, text)
+}
+
+// repliedTransactionID достает номер операции из сообщения бота, на которое
+// ответил пользователь.
+func (h *Handler) repliedTransactionID(reply *tgbotapi.Message) (int64, bool) {
+	if reply.From == nil || reply.From.ID != h.bot.Self.ID {
+		return 0, false
+	}
+	match := createdTransactionPattern.FindStringSubmatch(reply.Text)
+	if match == nil {
+		return 0, false
+	}
+	transactionID, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return transactionID, true
+}
+
+// handleAttachment скачивает фото или документ из Telegram и прикрепляет
+// его к операции. Для фото берется самый крупный размер.
+func (h *Handler) handleAttachment(msg *tgbotapi.Message, transactionID int64) {
+	userID := msg.From.ID
+
+	var fileID, fileName string
+	var fileSize int
+	if len(msg.Photo) > 0 {
+		photo := msg.Photo[len(msg.Photo)-1]
+		fileID, fileSize = photo.FileID, photo.FileSize
+		fileName = fmt.Sprintf("receipt_%d.jpg", transactionID)
+	} else {
+		fileID, fileSize, fileName = msg.Document.FileID, msg.Document.FileSize, msg.Document.FileName
+	}
+	if fileSize > maxTelegramFileSize {
+		h.sendMessage(userID, "❌ Файл слишком большой.")
+		return
+	}
+
+	data, err := h.downloadTelegramFile(fileID)
+	if err != nil {
+		h.logger.Error("failed to download telegram file", zap.Error(err))
+		h.sendMessage(userID, "Не удалось получить файл. Попробуйте позже.")
+		return
+	}
+
+	path := fmt.Sprintf("/api/transactions/%d/attachments", transactionID)
+	if _, err := h.uploadToGateway(path, userID, fileName, data); err != nil {
+		h.logger.Error("failed to upload attachment", zap.Error(err))
+		h.sendMessage(userID, describeGatewayError(err, "Не удалось прикрепить файл. Попробуйте позже."))
+		return
+	}
+
+	h.sendMessage(userID, fmt.Sprintf("📎 Файл прикреплен к операции #%d.", transactionID))
+}
+
+func (h *Handler) downloadTelegramFile(fileID string) ([]byte, error) {
+	url, err := h.bot.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("telegram returned %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxTelegramFileSize))
+}
+
+func (h *Handler) defaultAccountID(userID int64) (int64, error) {
+	resp, err := h.callGateway("GET", fmt.Sprintf("/api/accounts?telegram_id=%d", userID), nil)
+	if err != nil {
+		return 0, err
+	}
+
+	accounts, _ := resp["accounts"].([]interface{})
+	var firstID int64
+	for _, item := range accounts {
+		account, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		id := int64Field(account, "id")
+		if isDefault, _ := account["is_default"].(bool); isDefault {
+			return id, nil
+		}
+		if firstID == 0 {
+			firstID = id
+		}
+	}
+	if firstID == 0 {
+		return 0, fmt.Errorf("user has no accounts")
+	}
+
+	return firstID, nil
+}
+
 func (h *Handler) showWebAppButton(userID int64) {
 	webAppURL := fmt.Sprintf("%s/webapp", h.gatewayURL)
 	
@@ -123,6 +1003,9 @@ func (h *Handler) callGateway(method, path string, body interface{}) (map[string
 	}
 
 	req.Header.Set("Content-Type", "application/json")
+	if h.apiSecret != "" {
+		req.Header.Set("X-Bot-Secret", h.apiSecret)
+	}
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
@@ -131,10 +1014,108 @@ func (h *Handler) callGateway(method, path string, body interface{}) (map[string
 	}
 	defer resp.Body.Close()
 
-	var result map[string]interface{}
-	if resp.StatusCode == http.StatusOK {
-		json.NewDecoder(resp.Body).Decode(&result)
+	return decodeGatewayResponse(resp)
+}
+
+// uploadToGateway отправляет файл формой multipart/form-data
+func (h *Handler) uploadToGateway(path string, telegramID int64, fileName string, data []byte) (map[string]interface{}, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("telegram_id", strconv.FormatInt(telegramID, 10)); err != nil {
+		return nil, err
+	}
+	part, err := writer.CreateFormFile("file", fileName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("POST", h.gatewayURL+path, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return decodeGatewayResponse(resp)
+}
+
+func decodeGatewayResponse(resp *http.Response) (map[string]interface{}, error) {
+	if resp.StatusCode != http.StatusOK {
+		var errBody struct {
+			Error string `json:"error"`
+			Code  string `json:"code"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		return nil, &GatewayError{
+			StatusCode: resp.StatusCode,
+			Code:       errBody.Code,
+			Message:    errBody.Error,
+		}
 	}
 
+	var result map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&result)
+
 	return result, nil
 }
+
+// describeGatewayError превращает ошибку gateway в сообщение для пользователя
+func describeGatewayError(err error, fallback string) string {
+	var gwErr *GatewayError
+	if !errors.As(err, &gwErr) {
+		return fallback
+	}
+
+	if gwErr.Code == "insufficient_funds" {
+		return "❌ Операция отклонена: " + gwErr.Message
+	}
+	if gwErr.StatusCode < http.StatusInternalServerError && gwErr.Message != "" {
+		return "❌ " + gwErr.Message
+	}
+
+	return fallback
+}
+
+// parseQuickExpense разбирает сообщение вида "350 кофе" или "99,90".
+// Слова, начинающиеся с "#", становятся тегами: "1200 отель #отпуск".
+func parseQuickExpense(text string) (amount, description string, tags []string, ok bool) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !quickAmountPattern.MatchString(fields[0]) {
+		return "", "", nil, false
+	}
+
+	amount = strings.ReplaceAll(fields[0], ",", ".")
+	if value, err := strconv.ParseFloat(amount, 64); err != nil || value <= 0 {
+		return "", "", nil, false
+	}
+
+	var words []string
+	for _, field := range fields[1:] {
+		if strings.HasPrefix(field, "#") && len(field) > 1 {
+			tags = append(tags, field[1:])
+			continue
+		}
+		words = append(words, field)
+	}
+
+	return amount, strings.Join(words, " "), tags, true
+}
+
+func int64Field(m map[string]interface{}, key string) int64 {
+	if value, ok := m[key].(float64); ok {
+		return int64(value)
+	}
+	return 0
+}
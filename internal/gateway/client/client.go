@@ -11,6 +11,10 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 )
 
+// maxMessageSize ограничивает размер сообщений ledger-сервиса: через них
+// передаются вложения
+const maxMessageSize = 32 << 20
+
 type Clients struct {
 	User   pbUser.UserServiceClient
 	Ledger pbLedger.LedgerServiceClient
@@ -32,7 +36,10 @@ func NewClients(cfg *config.ServicesConfig, logger *zap.Logger) (*Clients, error
 	clients.User = pbUser.NewUserServiceClient(userConn)
 
 	// Ledger Service
-	ledgerConn, err := grpc.NewClient(cfg.LedgerService, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	ledgerConn, err := grpc.NewClient(cfg.LedgerService,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(maxMessageSize), grpc.MaxCallSendMsgSize(maxMessageSize)),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -58,4 +65,14 @@ func (c *Clients) GetUserIDByTelegramID(ctx context.Context, telegramID int64) (
 		return 0, err
 	}
 	return resp.UserId, nil
-}
\ No newline at end of file
+}
+
+func (c *Clients) GetTelegramIDByUserID(ctx context.Context, userID int64) (int64, error) {
+	resp, err := c.User.GetUserById(ctx, &pbUser.GetUserByIdRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return resp.TelegramId, nil
+}
@@ -2,10 +2,14 @@ package handler
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -13,22 +17,44 @@ import (
 	pbLedger "github.com/kiribu/financial-tracker/proto/ledger"
 	pbUser "github.com/kiribu/financial-tracker/proto/user"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type Handler struct {
-	clients *client.Clients
-	logger  *zap.Logger
+	clients   *client.Clients
+	botSecret string
+	logger    *zap.Logger
 }
 
-func NewHandler(clients *client.Clients, logger *zap.Logger) *Handler {
+func NewHandler(clients *client.Clients, botSecret string, logger *zap.Logger) *Handler {
 	return &Handler{
-		clients: clients,
-		logger:  logger,
+		clients:   clients,
+		botSecret: botSecret,
+		logger:    logger,
 	}
 }
 
+// botSecretHeader — заголовок с секретом бота для служебных эндпоинтов
+const botSecretHeader = "X-Bot-Secret"
+
+// requireBotSecret пропускает только запросы с секретом бота. Без
+// настроенного секрета служебные эндпоинты закрыты.
+func (h *Handler) requireBotSecret(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.botSecret == "" {
+			h.respondError(w, http.StatusForbidden, "bot endpoints are disabled")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(botSecretHeader)), []byte(h.botSecret)) != 1 {
+			h.respondError(w, http.StatusUnauthorized, "invalid bot secret")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (h *Handler) RegisterRoutes(r chi.Router) {
 	// Web App static files
 	r.Handle("/webapp/*", http.StripPrefix("/webapp/", http.FileServer(http.Dir("./webapp"))))
@@ -42,18 +68,86 @@ func (h *Handler) RegisterRoutes(r chi.Router) {
 		r.Post("/accounts", h.CreateAccount)
 		r.Put("/accounts/{id}", h.UpdateAccount)
 		r.Delete("/accounts/{id}", h.DeleteAccount)
+		r.Post("/accounts/{id}/unarchive", h.UnarchiveAccount)
+		r.Post("/accounts/{id}/purge", h.PurgeAccount)
+		r.Get("/accounts/{id}/loan", h.GetLoanSchedule)
+		r.Put("/accounts/{id}/loan", h.SetLoan)
+		r.Delete("/accounts/{id}/loan", h.DeleteLoan)
+		r.Post("/accounts/{id}/loan/payments", h.PostLoanPayment)
+		r.Post("/accounts/{id}/loan/prepayment", h.CalculateLoanPrepayment)
+		r.Put("/accounts/order", h.ReorderAccounts)
 		r.Get("/categories", h.ListCategories)
 		r.Post("/categories", h.CreateCategory)
+		r.Put("/categories/{id}", h.UpdateCategory)
 		r.Delete("/categories/{id}", h.DeleteCategory)
+		r.Post("/categories/{id}/archive", h.ArchiveCategory)
+		r.Post("/categories/{id}/unarchive", h.UnarchiveCategory)
+		r.Post("/categories/merge", h.MergeCategories)
+		r.Put("/categories/order", h.ReorderCategories)
+		r.Put("/categories/fallback", h.SetFallbackCategory)
 		r.Post("/transactions/expense", h.CreateExpense)
 		r.Post("/transactions/income", h.CreateIncome)
 		r.Post("/transactions/transfer", h.CreateTransfer)
+		r.Get("/transactions/{id}", h.GetTransaction)
 		r.Put("/transactions/{id}", h.UpdateTransaction)
 		r.Delete("/transactions/{id}", h.DeleteTransaction)
+		r.Get("/transactions/{id}/attachments", h.ListAttachments)
+		r.Post("/transactions/{id}/attachments", h.UploadAttachment)
+		r.Get("/attachments/{id}", h.DownloadAttachment)
+		r.Delete("/attachments/{id}", h.DeleteAttachment)
 		r.Get("/balance", h.GetBalance)
 		r.Get("/transactions", h.ListTransactions)
 		r.Get("/stats/overview", h.GetStatsOverview)
 		r.Get("/stats/by-category", h.GetStatsByCategory)
+		r.Get("/stats/cashflow", h.GetStatsCashflow)
+		r.Get("/stats/version", h.GetStatsVersion)
+		r.Get("/stats/by-tag", h.GetStatsByTag)
+		r.Get("/stats/by-payee", h.GetStatsByPayee)
+		r.Get("/stats/subscriptions", h.GetSubscriptions)
+		r.Get("/stats/forecast", h.GetForecast)
+		r.Get("/insights", h.ListInsights)
+		r.Get("/notifications/preferences", h.GetNotificationPreferences)
+		r.Put("/notifications/preferences", h.UpdateNotificationPreferences)
+		r.With(h.requireBotSecret).Post("/notifications/claim", h.ClaimNotifications)
+		r.With(h.requireBotSecret).Post("/notifications/{id}/complete", h.CompleteNotification)
+		r.Get("/digest", h.GetDigestSettings)
+		r.Put("/digest", h.UpdateDigestSettings)
+		r.Post("/receipts/parse", h.ParseReceipt)
+		r.Get("/payees", h.ListPayees)
+		r.Post("/payees", h.CreatePayee)
+		r.Put("/payees/{id}", h.UpdatePayee)
+		r.Delete("/payees/{id}", h.DeletePayee)
+		r.Get("/rules", h.ListRules)
+		r.Post("/rules", h.CreateRule)
+		r.Put("/rules/order", h.ReorderRules)
+		r.Post("/rules/test", h.TestRule)
+		r.Post("/rules/apply", h.ApplyRules)
+		r.Put("/rules/{id}", h.UpdateRule)
+		r.Delete("/rules/{id}", h.DeleteRule)
+		r.Get("/goals", h.ListGoals)
+		r.Post("/goals", h.CreateGoal)
+		r.Get("/goals/{id}", h.GetGoal)
+		r.Put("/goals/{id}", h.UpdateGoal)
+		r.Delete("/goals/{id}", h.DeleteGoal)
+		r.Get("/goals/{id}/contributions", h.ListGoalContributions)
+		r.Post("/goals/{id}/contributions", h.AddGoalContribution)
+		r.Delete("/goals/{id}/contributions/{contributionID}", h.DeleteGoalContribution)
+		r.Get("/budgets", h.ListBudgets)
+		r.Post("/budgets", h.CreateBudget)
+		r.Post("/budgets/from-subscription", h.CreateBudgetFromSubscription)
+		r.Put("/budgets/{id}", h.UpdateBudget)
+		r.Delete("/budgets/{id}", h.DeleteBudget)
+		r.Get("/counterparties", h.ListCounterparties)
+		r.Post("/counterparties", h.CreateCounterparty)
+		r.Put("/counterparties/{id}", h.UpdateCounterparty)
+		r.Delete("/counterparties/{id}", h.DeleteCounterparty)
+		r.Get("/debts", h.ListDebts)
+		r.Post("/debts", h.CreateDebt)
+		r.Get("/debts/{id}", h.GetDebt)
+		r.Put("/debts/{id}", h.UpdateDebt)
+		r.Delete("/debts/{id}", h.DeleteDebt)
+		r.Post("/debts/{id}/repayments", h.AddDebtRepayment)
+		r.Delete("/debts/{id}/repayments/{repaymentID}", h.DeleteDebtRepayment)
 	})
 }
 
@@ -130,9 +224,12 @@ func (h *Handler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+
 	ctx := r.Context()
 	resp, err := h.clients.Ledger.ListAccounts(ctx, &pbLedger.ListAccountsRequest{
-		UserId: userID,
+		UserId:          userID,
+		IncludeArchived: includeArchived,
 	})
 	if err != nil {
 		h.logger.Error("failed to list accounts", zap.Error(err))
@@ -142,14 +239,7 @@ func (h *Handler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 
 	var accounts []map[string]interface{}
 	for _, acc := range resp.Accounts {
-		accounts = append(accounts, map[string]interface{}{
-			"id":         acc.Id,
-			"name":       acc.Name,
-			"currency":   acc.Currency,
-			"balance":    acc.Balance,
-			"is_archived": acc.IsArchived,
-			"is_default": acc.IsDefault,
-		})
+		accounts = append(accounts, accountToMap(acc))
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
@@ -159,10 +249,19 @@ func (h *Handler) ListAccounts(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		TelegramID int64  `json:"telegram_id"`
-		Name        string `json:"name"`
-		Currency    string `json:"currency"`
-		Balance     string `json:"balance"`
+		TelegramID        int64  `json:"telegram_id"`
+		Name              string `json:"name"`
+		Currency          string `json:"currency"`
+		Balance           string `json:"balance"`
+		Type              string `json:"type"`
+		CreditLimit       string `json:"credit_limit"`
+		StatementDay      int32  `json:"statement_day"`
+		DueDay            int32  `json:"due_day"`
+		OverdraftPolicy   string `json:"overdraft_policy"`
+		Group             string `json:"group"`
+		Icon              string `json:"icon"`
+		Color             string `json:"color"`
+		ExcludeFromTotals bool   `json:"exclude_from_totals"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -182,9 +281,18 @@ func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	createReq := &pbLedger.CreateAccountRequest{
-		UserId:   userID,
-		Name:     req.Name,
-		Currency: req.Currency,
+		UserId:            userID,
+		Name:              req.Name,
+		Currency:          req.Currency,
+		Type:              req.Type,
+		CreditLimit:       req.CreditLimit,
+		StatementDay:      req.StatementDay,
+		DueDay:            req.DueDay,
+		OverdraftPolicy:   req.OverdraftPolicy,
+		Group:             req.Group,
+		Icon:              req.Icon,
+		Color:             req.Color,
+		ExcludeFromTotals: req.ExcludeFromTotals,
 	}
 	
 	// Set balance if provided
@@ -204,18 +312,28 @@ func (h *Handler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"account_id": resp.AccountId,
-		"name":       resp.Name,
-		"currency":   resp.Currency,
-		"balance":    resp.Balance,
+		"account_id":   resp.AccountId,
+		"name":         resp.Name,
+		"currency":     resp.Currency,
+		"balance":      resp.Balance,
+		"type":         resp.Type,
+		"credit_limit": resp.CreditLimit,
 	})
 }
 
 func (h *Handler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		TelegramID int64  `json:"telegram_id"`
-		Name       string `json:"name"`
-		Balance    string `json:"balance"`
+		TelegramID        int64   `json:"telegram_id"`
+		Name              string  `json:"name"`
+		Balance           string  `json:"balance"`
+		CreditLimit       string  `json:"credit_limit"`
+		StatementDay      int32   `json:"statement_day"`
+		DueDay            int32   `json:"due_day"`
+		OverdraftPolicy   string  `json:"overdraft_policy"`
+		Group             *string `json:"group"`
+		Icon              *string `json:"icon"`
+		Color             *string `json:"color"`
+		ExcludeFromTotals *bool   `json:"exclude_from_totals"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -238,10 +356,18 @@ func (h *Handler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	resp, err := h.clients.Ledger.UpdateAccount(ctx, &pbLedger.UpdateAccountRequest{
-		UserId:    userID,
-		AccountId: accountID,
-		Name:      req.Name,
-		Balance:   req.Balance,
+		UserId:            userID,
+		AccountId:         accountID,
+		Name:              req.Name,
+		Balance:           req.Balance,
+		CreditLimit:       req.CreditLimit,
+		StatementDay:      req.StatementDay,
+		DueDay:            req.DueDay,
+		OverdraftPolicy:   req.OverdraftPolicy,
+		Group:             req.Group,
+		Icon:              req.Icon,
+		Color:             req.Color,
+		ExcludeFromTotals: req.ExcludeFromTotals,
 	})
 	if err != nil {
 		h.logger.Error("failed to update account", zap.Error(err))
@@ -249,6 +375,8 @@ func (h *Handler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
 			statusCode := http.StatusInternalServerError
 			if st.Code() == codes.NotFound {
 				statusCode = http.StatusNotFound
+			} else if st.Code() == codes.InvalidArgument {
+				statusCode = http.StatusBadRequest
 			}
 			h.respondError(w, statusCode, st.Message())
 			return
@@ -258,10 +386,12 @@ func (h *Handler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"account_id": resp.AccountId,
-		"name":       resp.Name,
-		"currency":   resp.Currency,
-		"balance":    resp.Balance,
+		"account_id":   resp.AccountId,
+		"name":         resp.Name,
+		"currency":     resp.Currency,
+		"balance":      resp.Balance,
+		"type":         resp.Type,
+		"credit_limit": resp.CreditLimit,
 	})
 }
 
@@ -311,6 +441,126 @@ func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handler) UnarchiveAccount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64 `json:"telegram_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UnarchiveAccount(ctx, &pbLedger.UnarchiveAccountRequest{
+		UserId:    userID,
+		AccountId: accountID,
+	})
+	if err != nil {
+		h.logger.Error("failed to unarchive account", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to unarchive account")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"account_id":   resp.AccountId,
+		"name":         resp.Name,
+		"currency":     resp.Currency,
+		"balance":      resp.Balance,
+		"type":         resp.Type,
+		"credit_limit": resp.CreditLimit,
+	})
+}
+
+func (h *Handler) PurgeAccount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID          int64  `json:"telegram_id"`
+		ReassignToAccountID int64  `json:"reassign_to_account_id"`
+		ConfirmName         string `json:"confirm_name"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.PurgeAccount(ctx, &pbLedger.PurgeAccountRequest{
+		UserId:              userID,
+		AccountId:           accountID,
+		ReassignToAccountId: req.ReassignToAccountID,
+		ConfirmName:         req.ConfirmName,
+	})
+	if err != nil {
+		h.logger.Error("failed to purge account", zap.Error(err))
+		h.respondLedgerError(w, err, "failed to purge account")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status":                  resp.Status,
+		"deleted_transactions":    resp.DeletedTransactions,
+		"reassigned_transactions": resp.ReassignedTransactions,
+	})
+}
+
+func (h *Handler) ReorderAccounts(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64   `json:"telegram_id"`
+		AccountIDs []int64 `json:"account_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ReorderAccounts(ctx, &pbLedger.ReorderAccountsRequest{
+		UserId:     userID,
+		AccountIds: req.AccountIDs,
+	})
+	if err != nil {
+		h.logger.Error("failed to reorder accounts", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to reorder accounts")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
 func (h *Handler) DeleteCategory(w http.ResponseWriter, r *http.Request) {
 	telegramID, err := h.getTelegramID(r)
 	if err != nil {
@@ -377,8 +627,9 @@ func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	resp, err := h.clients.Ledger.ListCategories(ctx, &pbLedger.ListCategoriesRequest{
-		UserId: userID,
-		Type:   categoryType,
+		UserId:          userID,
+		Type:            categoryType,
+		IncludeArchived: r.URL.Query().Get("include_archived") == "true",
 	})
 	if err != nil {
 		h.logger.Error("failed to list categories", zap.Error(err))
@@ -386,25 +637,36 @@ func (h *Handler) ListCategories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var categories []map[string]interface{}
-	for _, cat := range resp.Categories {
-		categories = append(categories, map[string]interface{}{
-			"id":   cat.Id,
-			"name": cat.Name,
-			"type": cat.Type,
-		})
-	}
-
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"categories": categories,
+		"categories":           categoriesToMaps(resp.Categories),
+		"fallback_category_id": resp.FallbackCategoryId,
 	})
 }
 
+func categoriesToMaps(categories []*pbLedger.Category) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, cat := range categories {
+		result = append(result, map[string]interface{}{
+			"id":          cat.Id,
+			"name":        cat.Name,
+			"type":        cat.Type,
+			"parent_id":   cat.ParentId,
+			"icon":        cat.Icon,
+			"color":       cat.Color,
+			"is_archived": cat.IsArchived,
+			"is_system":   cat.IsSystem,
+			"children":    categoriesToMaps(cat.Children),
+		})
+	}
+	return result
+}
+
 func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		TelegramID int64  `json:"telegram_id"`
 		Name       string `json:"name"`
 		Type       string `json:"type"`
+		ParentID   int64  `json:"parent_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -420,9 +682,10 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 
 	ctx := r.Context()
 	resp, err := h.clients.Ledger.CreateCategory(ctx, &pbLedger.CreateCategoryRequest{
-		UserId: userID,
-		Name:   req.Name,
-		Type:   req.Type,
+		UserId:   userID,
+		Name:     req.Name,
+		Type:     req.Type,
+		ParentId: req.ParentID,
 	})
 	if err != nil {
 		h.logger.Error("failed to create category", zap.Error(err))
@@ -434,21 +697,15 @@ func (h *Handler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"category_id": resp.CategoryId,
-		"name":        resp.Name,
-		"type":        resp.Type,
-	})
+	h.respondJSON(w, http.StatusOK, categoryResponseToMap(resp))
 }
 
-func (h *Handler) CreateExpense(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateCategory(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		TelegramID   int64  `json:"telegram_id"`
-		AccountID    int64  `json:"account_id"`
-		Amount       string `json:"amount"`
-		CategoryID   int64  `json:"category_id"`
-		Description  string `json:"description"`
-		OperationDate string `json:"operation_date"`
+		TelegramID int64   `json:"telegram_id"`
+		Name       string  `json:"name"`
+		Icon       *string `json:"icon"`
+		Color      *string `json:"color"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -456,52 +713,46 @@ func (h *Handler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := h.getUserID(req.TelegramID)
+	categoryID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		h.respondError(w, http.StatusNotFound, "user not found")
+		h.respondError(w, http.StatusBadRequest, "invalid category id")
 		return
 	}
 
-	// Использовать переданную дату или текущую дату
-	operationDate := req.OperationDate
-	if operationDate == "" {
-		operationDate = time.Now().Format(time.RFC3339)
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
 	}
 
 	ctx := r.Context()
-	resp, err := h.clients.Ledger.CreateExpense(ctx, &pbLedger.CreateExpenseRequest{
-		UserId:       userID,
-		AccountId:    req.AccountID,
-		Amount:       req.Amount,
-		CategoryId:   req.CategoryID,
-		Description: req.Description,
-		OperationDate: operationDate,
+	resp, err := h.clients.Ledger.UpdateCategory(ctx, &pbLedger.UpdateCategoryRequest{
+		UserId:     userID,
+		CategoryId: categoryID,
+		Name:       req.Name,
+		Icon:       req.Icon,
+		Color:      req.Color,
 	})
 	if err != nil {
-		h.logger.Error("failed to create expense", zap.Error(err))
-		if st, ok := status.FromError(err); ok {
-			h.respondError(w, http.StatusBadRequest, st.Message())
-			return
-		}
-		h.respondError(w, http.StatusInternalServerError, "failed to create expense")
+		h.logger.Error("failed to update category", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update category")
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"status":         resp.Status,
-		"transaction_id": resp.TransactionId,
-		"account_balance": resp.AccountBalance,
-	})
+	h.respondJSON(w, http.StatusOK, categoryResponseToMap(resp))
 }
 
-func (h *Handler) CreateIncome(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ArchiveCategory(w http.ResponseWriter, r *http.Request) {
+	h.setCategoryArchived(w, r, true)
+}
+
+func (h *Handler) UnarchiveCategory(w http.ResponseWriter, r *http.Request) {
+	h.setCategoryArchived(w, r, false)
+}
+
+func (h *Handler) setCategoryArchived(w http.ResponseWriter, r *http.Request, archived bool) {
 	var req struct {
-		TelegramID   int64  `json:"telegram_id"`
-		AccountID    int64  `json:"account_id"`
-		Amount       string `json:"amount"`
-		CategoryID   int64  `json:"category_id"`
-		Description  string `json:"description"`
-		OperationDate string `json:"operation_date"`
+		TelegramID int64 `json:"telegram_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -509,52 +760,45 @@ func (h *Handler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := h.getUserID(req.TelegramID)
+	categoryID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
 	if err != nil {
-		h.respondError(w, http.StatusNotFound, "user not found")
+		h.respondError(w, http.StatusBadRequest, "invalid category id")
 		return
 	}
 
-	// Использовать переданную дату или текущую дату
-	operationDate := req.OperationDate
-	if operationDate == "" {
-		operationDate = time.Now().Format(time.RFC3339)
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
 	}
 
 	ctx := r.Context()
-	resp, err := h.clients.Ledger.CreateIncome(ctx, &pbLedger.CreateIncomeRequest{
-		UserId:       userID,
-		AccountId:    req.AccountID,
-		Amount:       req.Amount,
-		CategoryId:   req.CategoryID,
-		Description: req.Description,
-		OperationDate: operationDate,
-	})
+	var resp *pbLedger.CategoryResponse
+	if archived {
+		resp, err = h.clients.Ledger.ArchiveCategory(ctx, &pbLedger.ArchiveCategoryRequest{
+			UserId:     userID,
+			CategoryId: categoryID,
+		})
+	} else {
+		resp, err = h.clients.Ledger.UnarchiveCategory(ctx, &pbLedger.UnarchiveCategoryRequest{
+			UserId:     userID,
+			CategoryId: categoryID,
+		})
+	}
 	if err != nil {
-		h.logger.Error("failed to create income", zap.Error(err))
-		if st, ok := status.FromError(err); ok {
-			h.respondError(w, http.StatusBadRequest, st.Message())
-			return
-		}
-		h.respondError(w, http.StatusInternalServerError, "failed to create income")
+		h.logger.Error("failed to change category archive state", zap.Error(err), zap.Bool("archived", archived))
+		h.respondGRPCError(w, err, "failed to update category")
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"status":         resp.Status,
-		"transaction_id": resp.TransactionId,
-		"account_balance": resp.AccountBalance,
-	})
+	h.respondJSON(w, http.StatusOK, categoryResponseToMap(resp))
 }
 
-func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) MergeCategories(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		TelegramID    int64  `json:"telegram_id"`
-		FromAccountID int64  `json:"from_account_id"`
-		ToAccountID   int64  `json:"to_account_id"`
-		Amount        string `json:"amount"`
-		Description   string `json:"description"`
-		OperationDate string `json:"operation_date"`
+		TelegramID        int64   `json:"telegram_id"`
+		SourceCategoryIDs []int64 `json:"source_category_ids"`
+		TargetCategoryID  int64   `json:"target_category_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -562,180 +806,225 @@ func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.FromAccountID == req.ToAccountID {
-		h.respondError(w, http.StatusBadRequest, "нельзя переводить с одного и того же счета на этот же")
-		return
-	}
-
 	userID, err := h.getUserID(req.TelegramID)
 	if err != nil {
 		h.respondError(w, http.StatusNotFound, "user not found")
 		return
 	}
 
-	// Использовать переданную дату или текущую дату
-	operationDate := req.OperationDate
-	if operationDate == "" {
-		operationDate = time.Now().Format(time.RFC3339)
-	}
-
 	ctx := r.Context()
-	resp, err := h.clients.Ledger.CreateTransfer(ctx, &pbLedger.CreateTransferRequest{
-		UserId:        userID,
-		FromAccountId: req.FromAccountID,
-		ToAccountId:   req.ToAccountID,
-		Amount:        req.Amount,
-		Description:   req.Description,
-		OperationDate: operationDate,
+	resp, err := h.clients.Ledger.MergeCategories(ctx, &pbLedger.MergeCategoriesRequest{
+		UserId:            userID,
+		SourceCategoryIds: req.SourceCategoryIDs,
+		TargetCategoryId:  req.TargetCategoryID,
 	})
 	if err != nil {
-		h.logger.Error("failed to create transfer", zap.Error(err))
-		if st, ok := status.FromError(err); ok {
-			h.respondError(w, http.StatusBadRequest, st.Message())
-			return
-		}
-		h.respondError(w, http.StatusInternalServerError, "failed to create transfer")
+		h.logger.Error("failed to merge categories", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to merge categories")
 		return
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"status":             resp.Status,
-		"from_account_balance": resp.FromAccountBalance,
-		"to_account_balance":   resp.ToAccountBalance,
+		"moved_transactions": resp.MovedTransactions,
 	})
 }
 
-func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
-	telegramID, err := h.getTelegramID(r)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+func categoryResponseToMap(resp *pbLedger.CategoryResponse) map[string]interface{} {
+	return map[string]interface{}{
+		"category_id": resp.CategoryId,
+		"name":        resp.Name,
+		"type":        resp.Type,
+		"parent_id":   resp.ParentId,
+		"icon":        resp.Icon,
+		"color":       resp.Color,
+		"is_archived": resp.IsArchived,
+		"is_system":   resp.IsSystem,
+	}
+}
+
+func (h *Handler) ReorderCategories(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID  int64   `json:"telegram_id"`
+		CategoryIDs []int64 `json:"category_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	userID, err := h.getUserID(telegramID)
+	userID, err := h.getUserID(req.TelegramID)
 	if err != nil {
 		h.respondError(w, http.StatusNotFound, "user not found")
 		return
 	}
 
 	ctx := r.Context()
-	resp, err := h.clients.Ledger.GetBalance(ctx, &pbLedger.GetBalanceRequest{
-		UserId: userID,
+	resp, err := h.clients.Ledger.ReorderCategories(ctx, &pbLedger.ReorderCategoriesRequest{
+		UserId:      userID,
+		CategoryIds: req.CategoryIDs,
 	})
 	if err != nil {
-		h.logger.Error("failed to get balance", zap.Error(err))
-		h.respondError(w, http.StatusInternalServerError, "failed to get balance")
+		h.logger.Error("failed to reorder categories", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to reorder categories")
 		return
 	}
 
-	var accounts []map[string]interface{}
-	for _, acc := range resp.Accounts {
-		accounts = append(accounts, map[string]interface{}{
-			"id":         acc.Id,
-			"name":       acc.Name,
-			"currency":   acc.Currency,
-			"balance":    acc.Balance,
-			"is_archived": acc.IsArchived,
-			"is_default": acc.IsDefault,
-		})
-	}
-
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"accounts": accounts,
+		"status": resp.Status,
 	})
 }
 
-func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
-	telegramID, err := h.getTelegramID(r)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+func (h *Handler) SetFallbackCategory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64  `json:"telegram_id"`
+		Type       string `json:"type"`
+		CategoryID int64  `json:"category_id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	userID, err := h.getUserID(telegramID)
+	userID, err := h.getUserID(req.TelegramID)
 	if err != nil {
 		h.respondError(w, http.StatusNotFound, "user not found")
 		return
 	}
 
-	period := r.URL.Query().Get("period")
-	if period == "" {
-		period = "week"
-	}
-
-	limitStr := r.URL.Query().Get("limit")
-	limit := int32(100)
-	if limitStr != "" {
-		if l, err := strconv.ParseInt(limitStr, 10, 32); err == nil {
-			limit = int32(l)
-		}
+	if req.Type == "" {
+		req.Type = "expense"
 	}
 
-	startDate := r.URL.Query().Get("start_date")
-	endDate := r.URL.Query().Get("end_date")
-
 	ctx := r.Context()
-	resp, err := h.clients.Ledger.ListTransactions(ctx, &pbLedger.ListTransactionsRequest{
-		UserId:    userID,
-		Period:    period,
-		Limit:     limit,
-		StartDate: startDate,
-		EndDate:   endDate,
+	resp, err := h.clients.Ledger.SetFallbackCategory(ctx, &pbLedger.SetFallbackCategoryRequest{
+		UserId:     userID,
+		Type:       req.Type,
+		CategoryId: req.CategoryID,
 	})
 	if err != nil {
-		h.logger.Error("failed to list transactions", zap.Error(err))
-		h.respondError(w, http.StatusInternalServerError, "failed to list transactions")
+		h.logger.Error("failed to set fallback category", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to set fallback category")
 		return
 	}
 
-	var transactions []map[string]interface{}
-	for _, tx := range resp.Transactions {
-		txMap := map[string]interface{}{
-			"id":            tx.Id,
-			"type":          tx.Type,
-			"amount":        tx.Amount,
-			"currency":      tx.Currency,
-			"category_name": tx.CategoryName,
-			"account_name":  tx.AccountName,
-			"operation_date": tx.OperationDate,
-			"description":   tx.Description,
-			"account_id":    tx.AccountId,
-		}
-		if tx.RelatedAccountId > 0 {
-			txMap["related_account_id"] = tx.RelatedAccountId
-		}
-		if tx.CategoryId > 0 {
-			txMap["category_id"] = tx.CategoryId
-		}
-		transactions = append(transactions, txMap)
+	h.respondJSON(w, http.StatusOK, categoryResponseToMap(resp))
+}
+
+// splitRequest — строка разбивки операции в теле запроса
+type splitRequest struct {
+	CategoryID int64  `json:"category_id"`
+	Amount     string `json:"amount"`
+	Note       string `json:"note"`
+}
+
+func toPbSplits(splits []splitRequest) []*pbLedger.Split {
+	var result []*pbLedger.Split
+	for _, split := range splits {
+		result = append(result, &pbLedger.Split{
+			CategoryId: split.CategoryID,
+			Amount:     split.Amount,
+			Note:       split.Note,
+		})
 	}
+	return result
+}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"transactions": transactions,
-	})
+func splitsToMaps(splits []*pbLedger.Split) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(splits))
+	for _, split := range splits {
+		result = append(result, map[string]interface{}{
+			"category_id":   split.CategoryId,
+			"category_name": split.CategoryName,
+			"amount":        split.Amount,
+			"note":          split.Note,
+		})
+	}
+	return result
 }
 
-func (h *Handler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
-	telegramID, err := h.getTelegramID(r)
+func (h *Handler) CreateExpense(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID   int64  `json:"telegram_id"`
+		AccountID    int64  `json:"account_id"`
+		Amount       string `json:"amount"`
+		CategoryID   int64  `json:"category_id"`
+		Description  string `json:"description"`
+		OperationDate string `json:"operation_date"`
+		Tags          []string `json:"tags"`
+		Splits        []splitRequest `json:"splits"`
+		PayeeID       int64  `json:"payee_id"`
+		Payee         string `json:"payee"` // Имя или псевдоним получателя
+		ApplyRules    bool   `json:"apply_rules"`
+		ReceiptQR     string `json:"receipt_qr"` // QR-код чека: сумма и дата берутся из него
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		h.respondError(w, http.StatusNotFound, "user not found")
 		return
 	}
 
-	transactionIDStr := chi.URLParam(r, "id")
-	transactionID, err := strconv.ParseInt(transactionIDStr, 10, 64)
+	// Использовать переданную дату, дату из чека или текущую дату
+	operationDate := req.OperationDate
+	if operationDate == "" && req.ReceiptQR == "" {
+		operationDate = time.Now().Format(time.RFC3339)
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreateExpense(ctx, &pbLedger.CreateExpenseRequest{
+		UserId:       userID,
+		AccountId:    req.AccountID,
+		Amount:       req.Amount,
+		CategoryId:   req.CategoryID,
+		Description: req.Description,
+		OperationDate: operationDate,
+		Tags:          req.Tags,
+		Splits:        toPbSplits(req.Splits),
+		PayeeId:       req.PayeeID,
+		Payee:         req.Payee,
+		ApplyRules:    req.ApplyRules,
+		ReceiptQr:     req.ReceiptQR,
+	})
 	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid transaction id")
+		h.logger.Error("failed to create expense", zap.Error(err))
+		h.respondLedgerError(w, err, "failed to create expense")
 		return
 	}
 
+	result := map[string]interface{}{
+		"status":         resp.Status,
+		"transaction_id": resp.TransactionId,
+		"account_balance": resp.AccountBalance,
+	}
+	if resp.Warning != "" {
+		result["warning"] = resp.Warning
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) CreateIncome(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		AccountID      int64  `json:"account_id"`
-		Amount         string `json:"amount"`
-		CategoryID     int64  `json:"category_id"`
-		Description    string `json:"description"`
-		OperationDate  string `json:"operation_date"`
-		RelatedAccountID int64 `json:"related_account_id"`
+		TelegramID   int64  `json:"telegram_id"`
+		AccountID    int64  `json:"account_id"`
+		Amount       string `json:"amount"`
+		CategoryID   int64  `json:"category_id"`
+		Description  string `json:"description"`
+		OperationDate string `json:"operation_date"`
+		Tags          []string `json:"tags"`
+		Splits        []splitRequest `json:"splits"`
+		PayeeID       int64  `json:"payee_id"`
+		Payee         string `json:"payee"` // Имя или псевдоним получателя
+		ApplyRules    bool   `json:"apply_rules"`
+		ReceiptQR     string `json:"receipt_qr"` // QR-код чека: сумма и дата берутся из него
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -743,38 +1032,36 @@ func (h *Handler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := h.getUserID(telegramID)
+	userID, err := h.getUserID(req.TelegramID)
 	if err != nil {
 		h.respondError(w, http.StatusNotFound, "user not found")
 		return
 	}
 
+	// Использовать переданную дату, дату из чека или текущую дату
 	operationDate := req.OperationDate
-	if operationDate == "" {
+	if operationDate == "" && req.ReceiptQR == "" {
 		operationDate = time.Now().Format(time.RFC3339)
 	}
 
 	ctx := r.Context()
-	updateReq := &pbLedger.UpdateTransactionRequest{
-		UserId:        userID,
-		TransactionId: transactionID,
-		AccountId:     req.AccountID,
-		Amount:        req.Amount,
-		CategoryId:    req.CategoryID,
-		Description:   req.Description,
+	resp, err := h.clients.Ledger.CreateIncome(ctx, &pbLedger.CreateIncomeRequest{
+		UserId:       userID,
+		AccountId:    req.AccountID,
+		Amount:       req.Amount,
+		CategoryId:   req.CategoryID,
+		Description: req.Description,
 		OperationDate: operationDate,
-	}
-	if req.RelatedAccountID > 0 {
-		updateReq.RelatedAccountId = req.RelatedAccountID
-	}
-	resp, err := h.clients.Ledger.UpdateTransaction(ctx, updateReq)
+		Tags:          req.Tags,
+		Splits:        toPbSplits(req.Splits),
+		PayeeId:       req.PayeeID,
+		Payee:         req.Payee,
+		ApplyRules:    req.ApplyRules,
+		ReceiptQr:     req.ReceiptQR,
+	})
 	if err != nil {
-		h.logger.Error("failed to update transaction", zap.Error(err))
-		if st, ok := status.FromError(err); ok {
-			h.respondError(w, http.StatusBadRequest, st.Message())
-			return
-		}
-		h.respondError(w, http.StatusInternalServerError, "failed to update transaction")
+		h.logger.Error("failed to create income", zap.Error(err))
+		h.respondLedgerError(w, err, "failed to create income")
 		return
 	}
 
@@ -785,47 +1072,68 @@ func (h *Handler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (h *Handler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
-	telegramID, err := h.getTelegramID(r)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+func (h *Handler) CreateTransfer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID    int64  `json:"telegram_id"`
+		FromAccountID int64  `json:"from_account_id"`
+		ToAccountID   int64  `json:"to_account_id"`
+		Amount        string `json:"amount"`
+		Description   string `json:"description"`
+		OperationDate string `json:"operation_date"`
+		Tags          []string `json:"tags"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	transactionIDStr := chi.URLParam(r, "id")
-	transactionID, err := strconv.ParseInt(transactionIDStr, 10, 64)
-	if err != nil {
-		h.respondError(w, http.StatusBadRequest, "invalid transaction id")
+	if req.FromAccountID == req.ToAccountID {
+		h.respondError(w, http.StatusBadRequest, "нельзя переводить с одного и того же счета на этот же")
 		return
 	}
 
-	userID, err := h.getUserID(telegramID)
+	userID, err := h.getUserID(req.TelegramID)
 	if err != nil {
 		h.respondError(w, http.StatusNotFound, "user not found")
 		return
 	}
 
+	// Использовать переданную дату или текущую дату
+	operationDate := req.OperationDate
+	if operationDate == "" {
+		operationDate = time.Now().Format(time.RFC3339)
+	}
+
 	ctx := r.Context()
-	resp, err := h.clients.Ledger.DeleteTransaction(ctx, &pbLedger.DeleteTransactionRequest{
+	resp, err := h.clients.Ledger.CreateTransfer(ctx, &pbLedger.CreateTransferRequest{
 		UserId:        userID,
-		TransactionId: transactionID,
+		FromAccountId: req.FromAccountID,
+		ToAccountId:   req.ToAccountID,
+		Amount:        req.Amount,
+		Description:   req.Description,
+		OperationDate: operationDate,
+		Tags:          req.Tags,
 	})
 	if err != nil {
-		h.logger.Error("failed to delete transaction", zap.Error(err))
-		if st, ok := status.FromError(err); ok {
-			h.respondError(w, http.StatusBadRequest, st.Message())
-			return
-		}
-		h.respondError(w, http.StatusInternalServerError, "failed to delete transaction")
+		h.logger.Error("failed to create transfer", zap.Error(err))
+		h.respondLedgerError(w, err, "failed to create transfer")
 		return
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"status": resp.Status,
-	})
+	result := map[string]interface{}{
+		"status":             resp.Status,
+		"from_account_balance": resp.FromAccountBalance,
+		"to_account_balance":   resp.ToAccountBalance,
+	}
+	if resp.Warning != "" {
+		result["warning"] = resp.Warning
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
 }
 
-func (h *Handler) GetStatsOverview(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetBalance(w http.ResponseWriter, r *http.Request) {
 	telegramID, err := h.getTelegramID(r)
 	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
@@ -838,51 +1146,29 @@ func (h *Handler) GetStatsOverview(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	period := r.URL.Query().Get("period")
-	if period == "" {
-		period = "week"
-	}
-
-	startDate := r.URL.Query().Get("start_date")
-	endDate := r.URL.Query().Get("end_date")
-
 	ctx := r.Context()
-	// Get all transactions for the period
-	resp, err := h.clients.Ledger.ListTransactions(ctx, &pbLedger.ListTransactionsRequest{
-		UserId:    userID,
-		Period:    period,
-		StartDate: startDate,
-		EndDate:   endDate,
-		Limit:     10000, // Get all transactions
+	resp, err := h.clients.Ledger.GetBalance(ctx, &pbLedger.GetBalanceRequest{
+		UserId: userID,
 	})
 	if err != nil {
-		h.logger.Error("failed to get transactions for stats", zap.Error(err))
-		h.respondError(w, http.StatusInternalServerError, "failed to get stats")
+		h.logger.Error("failed to get balance", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "failed to get balance")
 		return
 	}
 
-	// Calculate totals
-	var totalExpense, totalIncome float64
-	for _, tx := range resp.Transactions {
-		amount, err := strconv.ParseFloat(tx.Amount, 64)
-		if err != nil {
-			continue
-		}
-		if tx.Type == "expense" {
-			totalExpense += amount
-		} else if tx.Type == "income" {
-			totalIncome += amount
-		}
+	var accounts []map[string]interface{}
+	for _, acc := range resp.Accounts {
+		accounts = append(accounts, accountToMap(acc))
 	}
 
 	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"period":       period,
-		"total_expense": fmt.Sprintf("%.2f", totalExpense),
-		"total_income":  fmt.Sprintf("%.2f", totalIncome),
+		"accounts": accounts,
+		"groups":   subtotalsToMaps(resp.Groups),
+		"totals":   subtotalsToMaps(resp.Totals),
 	})
 }
 
-func (h *Handler) GetStatsByCategory(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) ListTransactions(w http.ResponseWriter, r *http.Request) {
 	telegramID, err := h.getTelegramID(r)
 	if err != nil {
 		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
@@ -900,49 +1186,3407 @@ func (h *Handler) GetStatsByCategory(w http.ResponseWriter, r *http.Request) {
 		period = "week"
 	}
 
+	limitStr := r.URL.Query().Get("limit")
+	limit := int32(100)
+	if limitStr != "" {
+		if l, err := strconv.ParseInt(limitStr, 10, 32); err == nil {
+			limit = int32(l)
+		}
+	}
+
 	startDate := r.URL.Query().Get("start_date")
 	endDate := r.URL.Query().Get("end_date")
 
+	query := r.URL.Query()
+	accountIDs, err := parseIDList(query.Get("account_ids"))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account_ids")
+		return
+	}
+	categoryIDs, err := parseIDList(query.Get("category_ids"))
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid category_ids")
+		return
+	}
+
 	ctx := r.Context()
-	// Get all transactions for the period
 	resp, err := h.clients.Ledger.ListTransactions(ctx, &pbLedger.ListTransactionsRequest{
+		UserId:       userID,
+		Period:       period,
+		Limit:        limit,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		Tags:         splitList(query.Get("tags")),
+		AccountIds:   accountIDs,
+		CategoryIds:  categoryIDs,
+		Types:        splitList(query.Get("types")),
+		MinAmount:    query.Get("min_amount"),
+		MaxAmount:    query.Get("max_amount"),
+		Query:        query.Get("q"),
+		Counterparty: query.Get("counterparty"),
+		Sort:         query.Get("sort"),
+		PageToken:    query.Get("page_token"),
+	})
+	if err != nil {
+		h.logger.Error("failed to list transactions", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list transactions")
+		return
+	}
+
+	var transactions []map[string]interface{}
+	for _, tx := range resp.Transactions {
+		transactions = append(transactions, transactionToMap(tx))
+	}
+
+	result := map[string]interface{}{
+		"transactions": transactions,
+	}
+	if resp.NextPageToken != "" {
+		result["next_page_token"] = resp.NextPageToken
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	// Чужая операция не находится так же, как несуществующая
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetTransaction(ctx, &pbLedger.GetTransactionRequest{
+		UserId:        userID,
+		TransactionId: transactionID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get transaction", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get transaction")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, transactionToMap(resp.Transaction))
+}
+
+// ParseReceipt разбирает QR-код кассового чека в черновик операции.
+// Операция не создается: черновик передается в /transactions/expense или
+// /transactions/income вместе с receipt_qr.
+func (h *Handler) ParseReceipt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64  `json:"telegram_id"`
+		QR         string `json:"qr"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ParseReceipt(ctx, &pbLedger.ParseReceiptRequest{
+		UserId: userID,
+		Qr:     req.QR,
+	})
+	if err != nil {
+		h.logger.Error("failed to parse receipt", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to parse receipt")
+		return
+	}
+
+	draft := resp.Draft
+	result := map[string]interface{}{
+		"type":           draft.Type,
+		"amount":         draft.Amount,
+		"operation_date": draft.OperationDate,
+		"fn":             draft.Fn,
+		"fd":             draft.Fd,
+		"fp":             draft.Fp,
+		"operation":      draft.Operation,
+	}
+	if draft.DuplicateTransactionId > 0 {
+		result["duplicate_transaction_id"] = draft.DuplicateTransactionId
+	}
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// maxUploadSize ограничивает тело запроса с вложением. Точный лимит на
+// размер файла проверяет ledger-сервис.
+const maxUploadSize = 32 << 20
+
+// UploadAttachment принимает файл в поле "file" формы multipart/form-data
+// и прикрепляет его к операции.
+func (h *Handler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		h.respondError(w, http.StatusRequestEntityTooLarge, "invalid or too large upload")
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	telegramID, err := strconv.ParseInt(r.FormValue("telegram_id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "file is required")
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "failed to read file")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UploadAttachment(ctx, &pbLedger.UploadAttachmentRequest{
+		UserId:        userID,
+		TransactionId: transactionID,
+		FileName:      header.Filename,
+		Data:          data,
+	})
+	if err != nil {
+		h.logger.Error("failed to upload attachment", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to upload attachment")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, attachmentToMap(resp.Attachment))
+}
+
+func (h *Handler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	transactionID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListAttachments(ctx, &pbLedger.ListAttachmentsRequest{
+		UserId:        userID,
+		TransactionId: transactionID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list attachments", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list attachments")
+		return
+	}
+
+	attachments := []map[string]interface{}{}
+	for _, attachment := range resp.Attachments {
+		attachments = append(attachments, attachmentToMap(attachment))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"attachments": attachments,
+	})
+}
+
+// DownloadAttachment отдает содержимое вложения. Чужое вложение не
+// находится так же, как несуществующее.
+func (h *Handler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	attachmentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid attachment id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetAttachment(ctx, &pbLedger.GetAttachmentRequest{
+		UserId:       userID,
+		AttachmentId: attachmentID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get attachment", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get attachment")
+		return
+	}
+
+	w.Header().Set("Content-Type", resp.Attachment.ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(resp.Data)))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{
+		"filename": resp.Attachment.FileName,
+	}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp.Data)
+}
+
+func (h *Handler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	attachmentID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid attachment id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteAttachment(ctx, &pbLedger.DeleteAttachmentRequest{
+		UserId:       userID,
+		AttachmentId: attachmentID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete attachment", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete attachment")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func attachmentToMap(attachment *pbLedger.Attachment) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             attachment.Id,
+		"transaction_id": attachment.TransactionId,
+		"file_name":      attachment.FileName,
+		"content_type":   attachment.ContentType,
+		"size_bytes":     attachment.SizeBytes,
+		"created_at":     attachment.CreatedAt,
+	}
+}
+
+func transactionToMap(tx *pbLedger.Transaction) map[string]interface{} {
+	txMap := map[string]interface{}{
+		"id":             tx.Id,
+		"type":           tx.Type,
+		"amount":         tx.Amount,
+		"currency":       tx.Currency,
+		"category_name":  tx.CategoryName,
+		"account_name":   tx.AccountName,
+		"operation_date": tx.OperationDate,
+		"description":    tx.Description,
+		"account_id":     tx.AccountId,
+		"created_at":     tx.CreatedAt,
+		"updated_at":     tx.UpdatedAt,
+	}
+	if tx.RelatedAccountId > 0 {
+		txMap["related_account_id"] = tx.RelatedAccountId
+		txMap["related_account_name"] = tx.RelatedAccountName
+	}
+	if tx.CategoryId > 0 {
+		txMap["category_id"] = tx.CategoryId
+	}
+	if tx.PayeeId > 0 {
+		txMap["payee_id"] = tx.PayeeId
+		txMap["payee_name"] = tx.PayeeName
+	}
+	if len(tx.Tags) > 0 {
+		txMap["tags"] = tx.Tags
+	}
+	if len(tx.Splits) > 0 {
+		txMap["splits"] = splitsToMaps(tx.Splits)
+	}
+	return txMap
+}
+
+func (h *Handler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := strconv.ParseInt(transactionIDStr, 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	var req struct {
+		AccountID      int64  `json:"account_id"`
+		Amount         string `json:"amount"`
+		CategoryID     int64  `json:"category_id"`
+		Description    string `json:"description"`
+		OperationDate  string `json:"operation_date"`
+		RelatedAccountID int64 `json:"related_account_id"`
+		Tags           *[]string `json:"tags"` // Не передано — теги не меняются
+		Splits         *[]splitRequest `json:"splits"` // Не передано — разбивка не меняется
+		PayeeID        int64  `json:"payee_id"`
+		Payee          string `json:"payee"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	operationDate := req.OperationDate
+	if operationDate == "" {
+		operationDate = time.Now().Format(time.RFC3339)
+	}
+
+	ctx := r.Context()
+	updateReq := &pbLedger.UpdateTransactionRequest{
+		UserId:        userID,
+		TransactionId: transactionID,
+		AccountId:     req.AccountID,
+		Amount:        req.Amount,
+		CategoryId:    req.CategoryID,
+		Description:   req.Description,
+		OperationDate: operationDate,
+		PayeeId:       req.PayeeID,
+		Payee:         req.Payee,
+	}
+	if req.RelatedAccountID > 0 {
+		updateReq.RelatedAccountId = req.RelatedAccountID
+	}
+	if req.Tags != nil {
+		updateReq.Tags = &pbLedger.TagList{Tags: *req.Tags}
+	}
+	if req.Splits != nil {
+		updateReq.Splits = &pbLedger.SplitList{Splits: toPbSplits(*req.Splits)}
+	}
+	resp, err := h.clients.Ledger.UpdateTransaction(ctx, updateReq)
+	if err != nil {
+		h.logger.Error("failed to update transaction", zap.Error(err))
+		h.respondLedgerError(w, err, "failed to update transaction")
+		return
+	}
+
+	result := map[string]interface{}{
+		"status":         resp.Status,
+		"transaction_id": resp.TransactionId,
+		"account_balance": resp.AccountBalance,
+	}
+	if resp.Warning != "" {
+		result["warning"] = resp.Warning
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	transactionIDStr := chi.URLParam(r, "id")
+	transactionID, err := strconv.ParseInt(transactionIDStr, 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid transaction id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteTransaction(ctx, &pbLedger.DeleteTransactionRequest{
+		UserId:        userID,
+		TransactionId: transactionID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete transaction", zap.Error(err))
+		h.respondLedgerError(w, err, "failed to delete transaction")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func (h *Handler) GetStatsOverview(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	ctx := r.Context()
+	// Get all transactions for the period
+	resp, err := h.clients.Ledger.ListTransactions(ctx, &pbLedger.ListTransactionsRequest{
+		UserId:    userID,
+		Period:    period,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Limit:     10000, // Get all transactions
+	})
+	if err != nil {
+		h.logger.Error("failed to get transactions for stats", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "failed to get stats")
+		return
+	}
+
+	// Calculate totals
+	var totalExpense, totalIncome float64
+	for _, tx := range resp.Transactions {
+		amount, err := strconv.ParseFloat(tx.Amount, 64)
+		if err != nil {
+			continue
+		}
+		if tx.Type == "expense" {
+			totalExpense += amount
+		} else if tx.Type == "income" {
+			totalIncome += amount
+		}
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period":       period,
+		"total_expense": fmt.Sprintf("%.2f", totalExpense),
+		"total_income":  fmt.Sprintf("%.2f", totalIncome),
+	})
+}
+
+// GetStatsCashflow возвращает доходы и расходы за период по дням, а для
+// периодов длиннее двух месяцев — по месяцам. Дни и месяцы без операций
+// тоже попадают в ответ.
+func (h *Handler) GetStatsCashflow(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListTransactions(ctx, &pbLedger.ListTransactionsRequest{
+		UserId:    userID,
+		Period:    period,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Limit:     10000, // Get all transactions
+	})
+	if err != nil {
+		h.logger.Error("failed to get transactions for cashflow", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "failed to get cashflow")
+		return
+	}
+
+	to := time.Now().UTC()
+	from := to
+	switch period {
+	case "today":
+	case "week":
+		from = to.AddDate(0, 0, -6)
+	case "month":
+		from = to.AddDate(0, 0, -29)
+	case "year":
+		from = to.AddDate(0, -11, 0)
+	case "period":
+		if start, err := time.Parse("2006-01-02", prefix(startDate, 10)); err == nil {
+			from = start
+		}
+		if end, err := time.Parse("2006-01-02", prefix(endDate, 10)); err == nil {
+			to = end
+		}
+	default:
+		for _, tx := range resp.Transactions {
+			if date, err := time.Parse(time.RFC3339, tx.OperationDate); err == nil && date.Before(from) {
+				from = date
+			}
+		}
+	}
+	if from.After(to) {
+		from = to
+	}
+
+	granularity, layout := "day", "2006-01-02"
+	if to.Sub(from) > 62*24*time.Hour {
+		granularity, layout = "month", "2006-01"
+	}
+
+	var keys []string
+	for date := from; ; {
+		key := date.Format(layout)
+		if len(keys) > 0 && keys[len(keys)-1] == key {
+			break
+		}
+		keys = append(keys, key)
+		if key == to.Format(layout) {
+			break
+		}
+		if granularity == "month" {
+			date = time.Date(date.Year(), date.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		} else {
+			date = date.AddDate(0, 0, 1)
+		}
+	}
+
+	income := make(map[string]float64)
+	expense := make(map[string]float64)
+	for _, tx := range resp.Transactions {
+		date, err := time.Parse(time.RFC3339, tx.OperationDate)
+		if err != nil {
+			continue
+		}
+		amount, err := strconv.ParseFloat(tx.Amount, 64)
+		if err != nil {
+			continue
+		}
+		key := date.UTC().Format(layout)
+		if tx.Type == "expense" {
+			expense[key] += amount
+		} else if tx.Type == "income" {
+			income[key] += amount
+		}
+	}
+
+	points := []map[string]interface{}{}
+	for _, key := range keys {
+		points = append(points, map[string]interface{}{
+			"date":    key,
+			"income":  fmt.Sprintf("%.2f", income[key]),
+			"expense": fmt.Sprintf("%.2f", expense[key]),
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period":      period,
+		"granularity": granularity,
+		"points":      points,
+	})
+}
+
+// GetStatsVersion возвращает версию данных статистики: пока она не
+// изменилась, отчеты за тот же период можно брать из кеша.
+func (h *Handler) GetStatsVersion(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	resp, err := h.clients.Ledger.GetDataVersion(r.Context(), &pbLedger.GetDataVersionRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get stats version", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get stats version")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"version": resp.Version,
+	})
+}
+
+func prefix(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
+}
+
+func (h *Handler) GetStatsByCategory(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+
+	startDate := r.URL.Query().Get("start_date")
+	endDate := r.URL.Query().Get("end_date")
+
+	ctx := r.Context()
+	// Get all transactions for the period
+	resp, err := h.clients.Ledger.ListTransactions(ctx, &pbLedger.ListTransactionsRequest{
+		UserId:    userID,
+		Period:    period,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Limit:     10000, // Get all transactions
+	})
+	if err != nil {
+		h.logger.Error("failed to get transactions for category stats", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "failed to get category stats")
+		return
+	}
+
+	// Архивные категории тоже нужны: по ним могут быть операции за период
+	categoriesResp, err := h.clients.Ledger.ListCategories(ctx, &pbLedger.ListCategoriesRequest{
+		UserId:          userID,
+		Type:            "expense",
+		IncludeArchived: true,
+	})
+	if err != nil {
+		h.logger.Error("failed to get categories for category stats", zap.Error(err))
+		h.respondError(w, http.StatusInternalServerError, "failed to get category stats")
+		return
+	}
+
+	// Индексируем дерево категорий, чтобы поднимать расходы к родителям
+	categoryByID := make(map[int64]*pbLedger.Category)
+	var index func(categories []*pbLedger.Category)
+	index = func(categories []*pbLedger.Category) {
+		for _, cat := range categories {
+			categoryByID[cat.Id] = cat
+			index(cat.Children)
+		}
+	}
+	index(categoriesResp.Categories)
+
+	// Aggregate by category: сумма категории включает расходы подкатегорий
+	totals := make(map[int64]float64)
+	own := make(map[int64]float64)
+	names := make(map[int64]string)
+	var order []int64
+	add := func(categoryID int64, name, value string) {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || categoryID == 0 {
+			return
+		}
+		own[categoryID] += amount
+		names[categoryID] = name
+		for id := categoryID; id != 0; {
+			if _, seen := totals[id]; !seen {
+				order = append(order, id)
+			}
+			totals[id] += amount
+			cat, ok := categoryByID[id]
+			if !ok {
+				break
+			}
+			id = cat.ParentId
+		}
+	}
+	for _, tx := range resp.Transactions {
+		if tx.Type != "expense" {
+			continue
+		}
+		// Разбитая операция учитывается по категориям своих строк
+		if len(tx.Splits) > 0 {
+			for _, split := range tx.Splits {
+				add(split.CategoryId, split.CategoryName, split.Amount)
+			}
+			continue
+		}
+		add(tx.CategoryId, tx.CategoryName, tx.Amount)
+	}
+
+	// Convert to response format
+	categories := []map[string]interface{}{}
+	for _, id := range order {
+		name := names[id]
+		var parentID int64
+		if cat, ok := categoryByID[id]; ok {
+			name = cat.Name
+			parentID = cat.ParentId
+		}
+		categories = append(categories, map[string]interface{}{
+			"id":            id,
+			"name":          name,
+			"parent_id":     parentID,
+			"total_expense": fmt.Sprintf("%.2f", totals[id]),
+			"own_expense":   fmt.Sprintf("%.2f", own[id]),
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period":     period,
+		"categories": categories,
+	})
+}
+
+func (h *Handler) GetStatsByTag(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetTagBreakdown(ctx, &pbLedger.GetTagBreakdownRequest{
+		UserId:    userID,
+		Type:      r.URL.Query().Get("type"),
+		Period:    period,
+		StartDate: r.URL.Query().Get("start_date"),
+		EndDate:   r.URL.Query().Get("end_date"),
+	})
+	if err != nil {
+		h.logger.Error("failed to get tag stats", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get tag stats")
+		return
+	}
+
+	tags := []map[string]interface{}{}
+	for _, total := range resp.Tags {
+		tags = append(tags, map[string]interface{}{
+			"tag":      total.Tag,
+			"currency": total.Currency,
+			"total":    total.Total,
+			"count":    total.Count,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period": period,
+		"tags":   tags,
+	})
+}
+
+func (h *Handler) GetStatsByPayee(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+
+	limit := int32(10)
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.ParseInt(limitStr, 10, 32); err == nil {
+			limit = int32(l)
+		}
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetPayeeBreakdown(ctx, &pbLedger.GetPayeeBreakdownRequest{
+		UserId:    userID,
+		Type:      r.URL.Query().Get("type"),
+		Period:    period,
+		StartDate: r.URL.Query().Get("start_date"),
+		EndDate:   r.URL.Query().Get("end_date"),
+		Limit:     limit,
+	})
+	if err != nil {
+		h.logger.Error("failed to get payee stats", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get payee stats")
+		return
+	}
+
+	payees := []map[string]interface{}{}
+	for _, total := range resp.Payees {
+		payees = append(payees, map[string]interface{}{
+			"payee_id": total.PayeeId,
+			"name":     total.Name,
+			"currency": total.Currency,
+			"total":    total.Total,
+			"count":    total.Count,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"period": period,
+		"payees": payees,
+	})
+}
+
+func (h *Handler) GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	var months int32
+	if monthsStr := r.URL.Query().Get("months"); monthsStr != "" {
+		m, err := strconv.ParseInt(monthsStr, 10, 32)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid months")
+			return
+		}
+		months = int32(m)
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DetectSubscriptions(ctx, &pbLedger.DetectSubscriptionsRequest{
+		UserId:          userID,
+		Months:          months,
+		IncludeInactive: r.URL.Query().Get("include_inactive") == "true",
+	})
+	if err != nil {
+		h.logger.Error("failed to detect subscriptions", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to detect subscriptions")
+		return
+	}
+
+	subscriptions := []map[string]interface{}{}
+	for _, subscription := range resp.Subscriptions {
+		item := map[string]interface{}{
+			"name":           subscription.Name,
+			"cadence":        subscription.Cadence,
+			"currency":       subscription.Currency,
+			"average_amount": subscription.AverageAmount,
+			"last_amount":    subscription.LastAmount,
+			"charge_count":   subscription.ChargeCount,
+			"first_charge":   subscription.FirstCharge,
+			"last_charge":    subscription.LastCharge,
+			"next_expected":  subscription.NextExpected,
+			"active":         subscription.Active,
+			"account_id":     subscription.AccountId,
+			"account_name":   subscription.AccountName,
+			"key":            subscription.Key,
+		}
+		if subscription.PayeeId > 0 {
+			item["payee_id"] = subscription.PayeeId
+		}
+		if subscription.CategoryId > 0 {
+			item["category_id"] = subscription.CategoryId
+			item["category_name"] = subscription.CategoryName
+		}
+		subscriptions = append(subscriptions, item)
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"subscriptions": subscriptions,
+	})
+}
+
+func (h *Handler) GetForecast(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	var days int32
+	if daysStr := r.URL.Query().Get("days"); daysStr != "" {
+		d, err := strconv.ParseInt(daysStr, 10, 32)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid days")
+			return
+		}
+		days = int32(d)
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetForecast(ctx, &pbLedger.GetForecastRequest{
+		UserId: userID,
+		Days:   days,
+	})
+	if err != nil {
+		h.logger.Error("failed to get forecast", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get forecast")
+		return
+	}
+
+	accounts := []map[string]interface{}{}
+	for _, account := range resp.Accounts {
+		categories := []map[string]interface{}{}
+		for _, category := range account.Categories {
+			categories = append(categories, map[string]interface{}{
+				"category_id":   category.CategoryId,
+				"category_name": category.CategoryName,
+				"daily_amount":  category.DailyAmount,
+			})
+		}
+		points := []map[string]interface{}{}
+		for _, point := range account.Points {
+			points = append(points, map[string]interface{}{
+				"date":    point.Date,
+				"balance": point.Balance,
+			})
+		}
+
+		item := map[string]interface{}{
+			"account_id":     account.AccountId,
+			"account_name":   account.AccountName,
+			"account_type":   account.AccountType,
+			"currency":       account.Currency,
+			"balance":        account.Balance,
+			"daily_spending": account.DailySpending,
+			"categories":     categories,
+			"points":         points,
+			"min_balance":    account.MinBalance,
+		}
+		if account.FirstNegativeDate != "" {
+			item["first_negative_date"] = account.FirstNegativeDate
+		}
+		accounts = append(accounts, item)
+	}
+
+	events := []map[string]interface{}{}
+	for _, event := range resp.Events {
+		events = append(events, map[string]interface{}{
+			"date":       event.Date,
+			"account_id": event.AccountId,
+			"kind":       event.Kind,
+			"name":       event.Name,
+			"amount":     event.Amount,
+		})
+	}
+
+	negativeDates := resp.NegativeDates
+	if negativeDates == nil {
+		negativeDates = []string{}
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"days":           resp.Days,
+		"accounts":       accounts,
+		"events":         events,
+		"negative_dates": negativeDates,
+	})
+}
+
+func (h *Handler) ListInsights(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	var limit int32
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.ParseInt(limitStr, 10, 32); err == nil {
+			limit = int32(l)
+		}
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListInsights(ctx, &pbLedger.ListInsightsRequest{
+		UserId: userID,
+		Limit:  limit,
+	})
+	if err != nil {
+		h.logger.Error("failed to list insights", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list insights")
+		return
+	}
+
+	insights := []map[string]interface{}{}
+	for _, insight := range resp.Insights {
+		insights = append(insights, insightToMap(insight))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"insights": insights,
+	})
+}
+
+func (h *Handler) GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetNotificationPreferences(ctx, &pbLedger.GetNotificationPreferencesRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get notification preferences", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get notification preferences")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, notificationPreferencesToMap(resp))
+}
+
+func (h *Handler) UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64    `json:"telegram_id"`
+		MutedTypes []string `json:"muted_types"`
+		QuietFrom  string   `json:"quiet_from"`
+		QuietTo    string   `json:"quiet_to"`
+		Timezone   string   `json:"timezone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UpdateNotificationPreferences(ctx, &pbLedger.UpdateNotificationPreferencesRequest{
+		UserId: userID,
+		Preferences: &pbLedger.NotificationPreferences{
+			MutedTypes: req.MutedTypes,
+			QuietFrom:  req.QuietFrom,
+			QuietTo:    req.QuietTo,
+			Timezone:   req.Timezone,
+		},
+	})
+	if err != nil {
+		h.logger.Error("failed to update notification preferences", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update notification preferences")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, notificationPreferencesToMap(resp))
+}
+
+func (h *Handler) GetDigestSettings(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetDigestSettings(ctx, &pbLedger.GetDigestSettingsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get digest settings", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get digest settings")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, digestSettingsToMap(resp))
+}
+
+// UpdateDigestSettings меняет только переданные поля расписания сводки
+func (h *Handler) UpdateDigestSettings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64   `json:"telegram_id"`
+		Enabled    *bool   `json:"enabled"`
+		Frequency  *string `json:"frequency"`
+		Weekday    *int32  `json:"weekday"`
+		Time       *string `json:"time"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	settings, err := h.clients.Ledger.GetDigestSettings(ctx, &pbLedger.GetDigestSettingsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get digest settings", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update digest settings")
+		return
+	}
+	if req.Enabled != nil {
+		settings.Enabled = *req.Enabled
+	}
+	if req.Frequency != nil {
+		settings.Frequency = *req.Frequency
+	}
+	if req.Weekday != nil {
+		settings.Weekday = *req.Weekday
+	}
+	if req.Time != nil {
+		settings.Time = *req.Time
+	}
+
+	resp, err := h.clients.Ledger.UpdateDigestSettings(ctx, &pbLedger.UpdateDigestSettingsRequest{
+		UserId:   userID,
+		Settings: settings,
+	})
+	if err != nil {
+		h.logger.Error("failed to update digest settings", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update digest settings")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, digestSettingsToMap(resp))
+}
+
+// ClaimNotifications вызывается ботом: выдает уведомления, которые пора
+// отправить, с telegram_id получателей. Уведомления удаленных
+// пользователей сразу помечаются неотправленными.
+func (h *Handler) ClaimNotifications(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Limit int32 `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ClaimNotifications(ctx, &pbLedger.ClaimNotificationsRequest{
+		Limit: req.Limit,
+	})
+	if err != nil {
+		h.logger.Error("failed to claim notifications", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to claim notifications")
+		return
+	}
+
+	telegramIDs := map[int64]int64{}
+	notifications := []map[string]interface{}{}
+	for _, n := range resp.Notifications {
+		telegramID, ok := telegramIDs[n.UserId]
+		if !ok {
+			telegramID, err = h.clients.GetTelegramIDByUserID(ctx, n.UserId)
+			if err != nil && status.Code(err) != codes.NotFound {
+				// Уведомление вернется в очередь, когда истечет срок выдачи
+				h.logger.Error("failed to resolve telegram id", zap.Int64("user_id", n.UserId), zap.Error(err))
+				continue
+			}
+			telegramIDs[n.UserId] = telegramID
+		}
+		if telegramID == 0 {
+			h.clients.Ledger.CompleteNotification(ctx, &pbLedger.CompleteNotificationRequest{
+				Id:        n.Id,
+				Error:     "user has no telegram id",
+				Permanent: true,
+			})
+			continue
+		}
+
+		payload := n.Payload
+		if payload == nil {
+			payload = map[string]string{}
+		}
+		notifications = append(notifications, map[string]interface{}{
+			"id":          n.Id,
+			"telegram_id": telegramID,
+			"type":        n.Type,
+			"payload":     payload,
+			"attempts":    n.Attempts,
+			"created_at":  n.CreatedAt,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"notifications": notifications,
+	})
+}
+
+// CompleteNotification вызывается ботом с результатом отправки
+func (h *Handler) CompleteNotification(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Delivered  bool   `json:"delivered"`
+		Error      string `json:"error"`
+		RetryAfter int32  `json:"retry_after"`
+		Permanent  bool   `json:"permanent"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid notification id")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CompleteNotification(ctx, &pbLedger.CompleteNotificationRequest{
+		Id:         notificationID,
+		Delivered:  req.Delivered,
+		Error:      req.Error,
+		RetryAfter: req.RetryAfter,
+		Permanent:  req.Permanent,
+	})
+	if err != nil {
+		h.logger.Error("failed to complete notification", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to complete notification")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func digestSettingsToMap(settings *pbLedger.DigestSettings) map[string]interface{} {
+	return map[string]interface{}{
+		"enabled":   settings.Enabled,
+		"frequency": settings.Frequency,
+		"weekday":   settings.Weekday,
+		"time":      settings.Time,
+		"timezone":  settings.Timezone,
+	}
+}
+
+func notificationPreferencesToMap(prefs *pbLedger.NotificationPreferences) map[string]interface{} {
+	muted := prefs.MutedTypes
+	if muted == nil {
+		muted = []string{}
+	}
+	result := map[string]interface{}{
+		"muted_types": muted,
+		"timezone":    prefs.Timezone,
+	}
+	if prefs.QuietFrom != "" {
+		result["quiet_from"] = prefs.QuietFrom
+		result["quiet_to"] = prefs.QuietTo
+	}
+	return result
+}
+
+func insightToMap(insight *pbLedger.Insight) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":            insight.Id,
+		"kind":          insight.Kind,
+		"category_id":   insight.CategoryId,
+		"category_name": insight.CategoryName,
+		"amount":        insight.Amount,
+		"baseline":      insight.Baseline,
+		"threshold":     insight.Threshold,
+		"currency":      insight.Currency,
+		"created_at":    insight.CreatedAt,
+	}
+	if insight.TransactionId > 0 {
+		result["transaction_id"] = insight.TransactionId
+		result["operation_date"] = insight.OperationDate
+		if insight.Description != "" {
+			result["description"] = insight.Description
+		}
+	}
+	if insight.PeriodStart != "" {
+		result["period_start"] = insight.PeriodStart
+	}
+	if insight.Weekday >= 0 {
+		result["weekday"] = insight.Weekday
+	}
+	return result
+}
+
+func (h *Handler) ListPayees(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListPayees(ctx, &pbLedger.ListPayeesRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list payees", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list payees")
+		return
+	}
+
+	payees := []map[string]interface{}{}
+	for _, payee := range resp.Payees {
+		payees = append(payees, payeeToMap(payee))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"payees": payees,
+	})
+}
+
+// payeeRequest — тело запроса на создание и изменение получателя
+type payeeRequest struct {
+	TelegramID        int64    `json:"telegram_id"`
+	Name              string   `json:"name"`
+	Aliases           []string `json:"aliases"`
+	DefaultCategoryID int64    `json:"default_category_id"`
+	DefaultAccountID  int64    `json:"default_account_id"`
+}
+
+func (h *Handler) CreatePayee(w http.ResponseWriter, r *http.Request) {
+	var req payeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreatePayee(ctx, &pbLedger.CreatePayeeRequest{
+		UserId:            userID,
+		Name:              req.Name,
+		Aliases:           req.Aliases,
+		DefaultCategoryId: req.DefaultCategoryID,
+		DefaultAccountId:  req.DefaultAccountID,
+	})
+	if err != nil {
+		h.logger.Error("failed to create payee", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to create payee")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, payeeToMap(resp.Payee))
+}
+
+func (h *Handler) UpdatePayee(w http.ResponseWriter, r *http.Request) {
+	var req payeeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	payeeID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid payee id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UpdatePayee(ctx, &pbLedger.UpdatePayeeRequest{
+		UserId:            userID,
+		PayeeId:           payeeID,
+		Name:              req.Name,
+		Aliases:           req.Aliases,
+		DefaultCategoryId: req.DefaultCategoryID,
+		DefaultAccountId:  req.DefaultAccountID,
+	})
+	if err != nil {
+		h.logger.Error("failed to update payee", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update payee")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, payeeToMap(resp.Payee))
+}
+
+func (h *Handler) DeletePayee(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	payeeID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid payee id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeletePayee(ctx, &pbLedger.DeletePayeeRequest{
+		UserId:  userID,
+		PayeeId: payeeID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete payee", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete payee")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func payeeToMap(payee *pbLedger.Payee) map[string]interface{} {
+	aliases := payee.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+	result := map[string]interface{}{
+		"id":      payee.Id,
+		"name":    payee.Name,
+		"aliases": aliases,
+	}
+	if payee.DefaultCategoryId > 0 {
+		result["default_category_id"] = payee.DefaultCategoryId
+	}
+	if payee.DefaultAccountId > 0 {
+		result["default_account_id"] = payee.DefaultAccountId
+	}
+	return result
+}
+
+func (h *Handler) ListRules(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListRules(ctx, &pbLedger.ListRulesRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list rules", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list rules")
+		return
+	}
+
+	rules := []map[string]interface{}{}
+	for _, rule := range resp.Rules {
+		rules = append(rules, ruleToMap(rule))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"rules": rules,
+	})
+}
+
+// ruleRequest — условия и действия правила. Новое правило по умолчанию
+// активно.
+type ruleRequest struct {
+	TelegramID          int64    `json:"telegram_id"`
+	Name                string   `json:"name"`
+	IsActive            *bool    `json:"is_active"`
+	StopProcessing      bool     `json:"stop_processing"`
+	TransactionType     string   `json:"transaction_type"`
+	DescriptionContains string   `json:"description_contains"`
+	DescriptionRegex    string   `json:"description_regex"`
+	MinAmount           string   `json:"min_amount"`
+	MaxAmount           string   `json:"max_amount"`
+	AccountID           int64    `json:"account_id"`
+	PayeeID             int64    `json:"payee_id"`
+	SetCategoryID       int64    `json:"set_category_id"`
+	AddTags             []string `json:"add_tags"`
+	SetPayeeID          int64    `json:"set_payee_id"`
+	SetDescription      string   `json:"set_description"`
+}
+
+func (req ruleRequest) toPb() *pbLedger.Rule {
+	return &pbLedger.Rule{
+		Name:                req.Name,
+		IsActive:            req.IsActive == nil || *req.IsActive,
+		StopProcessing:      req.StopProcessing,
+		TransactionType:     req.TransactionType,
+		DescriptionContains: req.DescriptionContains,
+		DescriptionRegex:    req.DescriptionRegex,
+		MinAmount:           req.MinAmount,
+		MaxAmount:           req.MaxAmount,
+		AccountId:           req.AccountID,
+		PayeeId:             req.PayeeID,
+		SetCategoryId:       req.SetCategoryID,
+		AddTags:             req.AddTags,
+		SetPayeeId:          req.SetPayeeID,
+		SetDescription:      req.SetDescription,
+	}
+}
+
+func (h *Handler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var req ruleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreateRule(ctx, &pbLedger.CreateRuleRequest{
+		UserId: userID,
+		Rule:   req.toPb(),
+	})
+	if err != nil {
+		h.logger.Error("failed to create rule", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to create rule")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, ruleToMap(resp.Rule))
+}
+
+func (h *Handler) UpdateRule(w http.ResponseWriter, r *http.Request) {
+	var req ruleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid rule id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UpdateRule(ctx, &pbLedger.UpdateRuleRequest{
+		UserId: userID,
+		RuleId: ruleID,
+		Rule:   req.toPb(),
+	})
+	if err != nil {
+		h.logger.Error("failed to update rule", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update rule")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, ruleToMap(resp.Rule))
+}
+
+func (h *Handler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	ruleID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid rule id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteRule(ctx, &pbLedger.DeleteRuleRequest{
+		UserId: userID,
+		RuleId: ruleID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete rule", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete rule")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func (h *Handler) ReorderRules(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64   `json:"telegram_id"`
+		RuleIDs    []int64 `json:"rule_ids"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ReorderRules(ctx, &pbLedger.ReorderRulesRequest{
+		UserId:  userID,
+		RuleIds: req.RuleIDs,
+	})
+	if err != nil {
+		h.logger.Error("failed to reorder rules", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to reorder rules")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+// TestRule показывает, какие операции за период изменит правило, ничего
+// не сохраняя.
+func (h *Handler) TestRule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ruleRequest
+		Period    string `json:"period"`
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+		Limit     int32  `json:"limit"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	period := req.Period
+	if period == "" {
+		period = "month"
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.TestRule(ctx, &pbLedger.TestRuleRequest{
+		UserId:    userID,
+		Rule:      req.toPb(),
+		Period:    period,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+		Limit:     req.Limit,
+	})
+	if err != nil {
+		h.logger.Error("failed to test rule", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to test rule")
+		return
+	}
+
+	matches := []map[string]interface{}{}
+	for _, match := range resp.Matches {
+		tags := match.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		matches = append(matches, map[string]interface{}{
+			"transaction": transactionToMap(match.Transaction),
+			"category_id": match.CategoryId,
+			"payee_id":    match.PayeeId,
+			"description": match.Description,
+			"tags":        tags,
+		})
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"matched": resp.Matched,
+		"matches": matches,
+	})
+}
+
+// ApplyRules заново применяет активные правила к операциям за период.
+func (h *Handler) ApplyRules(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64  `json:"telegram_id"`
+		Period     string `json:"period"`
+		StartDate  string `json:"start_date"`
+		EndDate    string `json:"end_date"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	period := req.Period
+	if period == "" {
+		period = "month"
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ApplyRules(ctx, &pbLedger.ApplyRulesRequest{
+		UserId:    userID,
+		Period:    period,
+		StartDate: req.StartDate,
+		EndDate:   req.EndDate,
+	})
+	if err != nil {
+		h.logger.Error("failed to apply rules", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to apply rules")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"matched": resp.Matched,
+		"updated": resp.Updated,
+	})
+}
+
+func ruleToMap(rule *pbLedger.Rule) map[string]interface{} {
+	addTags := rule.AddTags
+	if addTags == nil {
+		addTags = []string{}
+	}
+	result := map[string]interface{}{
+		"id":              rule.Id,
+		"name":            rule.Name,
+		"position":        rule.Position,
+		"is_active":       rule.IsActive,
+		"stop_processing": rule.StopProcessing,
+		"add_tags":        addTags,
+	}
+	for key, value := range map[string]string{
+		"transaction_type":     rule.TransactionType,
+		"description_contains": rule.DescriptionContains,
+		"description_regex":    rule.DescriptionRegex,
+		"min_amount":           rule.MinAmount,
+		"max_amount":           rule.MaxAmount,
+		"set_description":      rule.SetDescription,
+	} {
+		if value != "" {
+			result[key] = value
+		}
+	}
+	for key, value := range map[string]int64{
+		"account_id":      rule.AccountId,
+		"payee_id":        rule.PayeeId,
+		"set_category_id": rule.SetCategoryId,
+		"set_payee_id":    rule.SetPayeeId,
+	} {
+		if value > 0 {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// splitList разбирает список через запятую из query-параметра.
+func (h *Handler) ListGoals(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListGoals(ctx, &pbLedger.ListGoalsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list goals", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list goals")
+		return
+	}
+
+	goals := []map[string]interface{}{}
+	for _, goal := range resp.Goals {
+		goals = append(goals, goalStatusToMap(goal))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"goals": goals,
+	})
+}
+
+func (h *Handler) GetGoal(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	goalID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid goal id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetGoalStatus(ctx, &pbLedger.GetGoalStatusRequest{
+		UserId: userID,
+		GoalId: goalID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get goal status", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get goal status")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, goalStatusToMap(resp.Status))
+}
+
+// goalRequest — тело запроса на создание и изменение цели накопления
+type goalRequest struct {
+	TelegramID   int64  `json:"telegram_id"`
+	Name         string `json:"name"`
+	TargetAmount string `json:"target_amount"`
+	Currency     string `json:"currency"`
+	Deadline     string `json:"deadline"` // YYYY-MM-DD
+	AccountID    int64  `json:"account_id"`
+}
+
+func (h *Handler) CreateGoal(w http.ResponseWriter, r *http.Request) {
+	var req goalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreateGoal(ctx, &pbLedger.CreateGoalRequest{
+		UserId:       userID,
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		Currency:     req.Currency,
+		Deadline:     req.Deadline,
+		AccountId:    req.AccountID,
+	})
+	if err != nil {
+		h.logger.Error("failed to create goal", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to create goal")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, goalStatusToMap(resp.Status))
+}
+
+func (h *Handler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
+	var req goalRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	goalID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid goal id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UpdateGoal(ctx, &pbLedger.UpdateGoalRequest{
+		UserId:       userID,
+		GoalId:       goalID,
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		Currency:     req.Currency,
+		Deadline:     req.Deadline,
+		AccountId:    req.AccountID,
+	})
+	if err != nil {
+		h.logger.Error("failed to update goal", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update goal")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, goalStatusToMap(resp.Status))
+}
+
+func (h *Handler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	goalID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid goal id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteGoal(ctx, &pbLedger.DeleteGoalRequest{
+		UserId: userID,
+		GoalId: goalID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete goal", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete goal")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func (h *Handler) ListGoalContributions(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	goalID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid goal id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListGoalContributions(ctx, &pbLedger.ListGoalContributionsRequest{
+		UserId: userID,
+		GoalId: goalID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list goal contributions", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list goal contributions")
+		return
+	}
+
+	contributions := []map[string]interface{}{}
+	for _, contribution := range resp.Contributions {
+		contributions = append(contributions, goalContributionToMap(contribution))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"contributions": contributions,
+	})
+}
+
+func (h *Handler) AddGoalContribution(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64  `json:"telegram_id"`
+		Amount     string `json:"amount"`
+		Date       string `json:"date"`
+		Note       string `json:"note"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	goalID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid goal id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.AddGoalContribution(ctx, &pbLedger.AddGoalContributionRequest{
+		UserId: userID,
+		GoalId: goalID,
+		Amount: req.Amount,
+		Date:   req.Date,
+		Note:   req.Note,
+	})
+	if err != nil {
+		h.logger.Error("failed to add goal contribution", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to add goal contribution")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"contribution": goalContributionToMap(resp.Contribution),
+		"goal":         goalStatusToMap(resp.Status),
+	})
+}
+
+func (h *Handler) DeleteGoalContribution(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	goalID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid goal id")
+		return
+	}
+
+	contributionID, err := strconv.ParseInt(chi.URLParam(r, "contributionID"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid contribution id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteGoalContribution(ctx, &pbLedger.DeleteGoalContributionRequest{
+		UserId:         userID,
+		GoalId:         goalID,
+		ContributionId: contributionID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete goal contribution", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete goal contribution")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func goalStatusToMap(st *pbLedger.GoalStatus) map[string]interface{} {
+	goal := st.Goal
+	result := map[string]interface{}{
+		"id":               goal.Id,
+		"name":             goal.Name,
+		"target_amount":    goal.TargetAmount,
+		"currency":         goal.Currency,
+		"deadline":         goal.Deadline,
+		"created_at":       goal.CreatedAt,
+		"saved":            st.Saved,
+		"remaining":        st.Remaining,
+		"progress_percent": st.ProgressPercent,
+		"required_monthly": st.RequiredMonthly,
+		"months_left":      st.MonthsLeft,
+		"on_track":         st.OnTrack,
+	}
+	if goal.AccountId > 0 {
+		result["account_id"] = goal.AccountId
+		result["account_name"] = goal.AccountName
+	}
+	return result
+}
+
+func goalContributionToMap(contribution *pbLedger.GoalContribution) map[string]interface{} {
+	result := map[string]interface{}{
+		"amount": contribution.Amount,
+		"date":   contribution.Date,
+	}
+	if contribution.Id > 0 {
+		result["id"] = contribution.Id
+	}
+	if contribution.Note != "" {
+		result["note"] = contribution.Note
+	}
+	if contribution.TransactionId > 0 {
+		result["transaction_id"] = contribution.TransactionId
+	}
+	return result
+}
+
+func (h *Handler) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListBudgets(ctx, &pbLedger.ListBudgetsRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list budgets", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list budgets")
+		return
+	}
+
+	budgets := []map[string]interface{}{}
+	for _, budget := range resp.Budgets {
+		budgets = append(budgets, budgetStatusToMap(budget))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"budgets": budgets,
+	})
+}
+
+// budgetRequest — тело запроса на создание и изменение бюджета
+type budgetRequest struct {
+	TelegramID int64  `json:"telegram_id"`
+	Name       string `json:"name"`
+	CategoryID int64  `json:"category_id"`
+	PayeeID    int64  `json:"payee_id"`
+	Amount     string `json:"amount"`
+	Currency   string `json:"currency"`
+	Period     string `json:"period"` // weekly, monthly или yearly
+}
+
+func (h *Handler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var req budgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreateBudget(ctx, &pbLedger.CreateBudgetRequest{
+		UserId:     userID,
+		Name:       req.Name,
+		CategoryId: req.CategoryID,
+		PayeeId:    req.PayeeID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Period:     req.Period,
+	})
+	if err != nil {
+		h.logger.Error("failed to create budget", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to create budget")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, budgetStatusToMap(resp.Status))
+}
+
+func (h *Handler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	var req budgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	budgetID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid budget id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UpdateBudget(ctx, &pbLedger.UpdateBudgetRequest{
+		UserId:     userID,
+		BudgetId:   budgetID,
+		Name:       req.Name,
+		CategoryId: req.CategoryID,
+		PayeeId:    req.PayeeID,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Period:     req.Period,
+	})
+	if err != nil {
+		h.logger.Error("failed to update budget", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update budget")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, budgetStatusToMap(resp.Status))
+}
+
+func (h *Handler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	budgetID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid budget id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteBudget(ctx, &pbLedger.DeleteBudgetRequest{
+		UserId:   userID,
+		BudgetId: budgetID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete budget", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete budget")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+// CreateBudgetFromSubscription создает бюджет по подписке с ключом key из
+// GET /api/stats/subscriptions
+func (h *Handler) CreateBudgetFromSubscription(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64  `json:"telegram_id"`
+		Key        string `json:"key"`
+		Months     int32  `json:"months"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Key == "" {
+		h.respondError(w, http.StatusBadRequest, "key is required")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreateBudgetFromSubscription(ctx, &pbLedger.CreateBudgetFromSubscriptionRequest{
+		UserId: userID,
+		Key:    req.Key,
+		Months: req.Months,
+	})
+	if err != nil {
+		h.logger.Error("failed to create budget from subscription", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to create budget from subscription")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, budgetStatusToMap(resp.Status))
+}
+
+func budgetStatusToMap(st *pbLedger.BudgetStatus) map[string]interface{} {
+	budget := st.Budget
+	result := map[string]interface{}{
+		"id":               budget.Id,
+		"name":             budget.Name,
+		"amount":           budget.Amount,
+		"currency":         budget.Currency,
+		"period":           budget.Period,
+		"created_at":       budget.CreatedAt,
+		"period_start":     st.PeriodStart,
+		"period_end":       st.PeriodEnd,
+		"spent":            st.Spent,
+		"remaining":        st.Remaining,
+		"progress_percent": st.ProgressPercent,
+		"exceeded":         st.Exceeded,
+	}
+	if budget.CategoryId > 0 {
+		result["category_id"] = budget.CategoryId
+		result["category_name"] = budget.CategoryName
+	}
+	if budget.PayeeId > 0 {
+		result["payee_id"] = budget.PayeeId
+		result["payee_name"] = budget.PayeeName
+	}
+	return result
+}
+
+func (h *Handler) ListCounterparties(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListCounterparties(ctx, &pbLedger.ListCounterpartiesRequest{
+		UserId: userID,
+	})
+	if err != nil {
+		h.logger.Error("failed to list counterparties", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list counterparties")
+		return
+	}
+
+	counterparties := []map[string]interface{}{}
+	for _, counterparty := range resp.Counterparties {
+		counterparties = append(counterparties, counterpartyToMap(counterparty))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"counterparties": counterparties,
+	})
+}
+
+// counterpartyRequest — тело запроса на создание и изменение контрагента
+type counterpartyRequest struct {
+	TelegramID int64  `json:"telegram_id"`
+	Name       string `json:"name"`
+	Note       string `json:"note"`
+}
+
+func (h *Handler) CreateCounterparty(w http.ResponseWriter, r *http.Request) {
+	var req counterpartyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreateCounterparty(ctx, &pbLedger.CreateCounterpartyRequest{
+		UserId: userID,
+		Name:   req.Name,
+		Note:   req.Note,
+	})
+	if err != nil {
+		h.logger.Error("failed to create counterparty", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to create counterparty")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, counterpartyToMap(resp.Counterparty))
+}
+
+func (h *Handler) UpdateCounterparty(w http.ResponseWriter, r *http.Request) {
+	var req counterpartyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	counterpartyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid counterparty id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UpdateCounterparty(ctx, &pbLedger.UpdateCounterpartyRequest{
+		UserId:         userID,
+		CounterpartyId: counterpartyID,
+		Name:           req.Name,
+		Note:           req.Note,
+	})
+	if err != nil {
+		h.logger.Error("failed to update counterparty", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update counterparty")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, counterpartyToMap(resp.Counterparty))
+}
+
+func (h *Handler) DeleteCounterparty(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	counterpartyID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid counterparty id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteCounterparty(ctx, &pbLedger.DeleteCounterpartyRequest{
+		UserId:         userID,
+		CounterpartyId: counterpartyID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete counterparty", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete counterparty")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func (h *Handler) ListDebts(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	query := r.URL.Query()
+	var counterpartyID int64
+	if value := query.Get("counterparty_id"); value != "" {
+		counterpartyID, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			h.respondError(w, http.StatusBadRequest, "invalid counterparty_id")
+			return
+		}
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.ListDebts(ctx, &pbLedger.ListDebtsRequest{
+		UserId:         userID,
+		CounterpartyId: counterpartyID,
+		IncludeClosed:  query.Get("include_closed") == "true",
+	})
+	if err != nil {
+		h.logger.Error("failed to list debts", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to list debts")
+		return
+	}
+
+	summaries := []map[string]interface{}{}
+	for _, summary := range resp.Summaries {
+		item := map[string]interface{}{
+			"counterparty_id":   summary.CounterpartyId,
+			"counterparty_name": summary.CounterpartyName,
+			"currency":          summary.Currency,
+			"lent":              summary.Lent,
+			"borrowed":          summary.Borrowed,
+			"net":               summary.Net,
+			"open_debts":        summary.OpenDebts,
+		}
+		if summary.NextDueDate != "" {
+			item["next_due_date"] = summary.NextDueDate
+		}
+		summaries = append(summaries, item)
+	}
+
+	debts := []map[string]interface{}{}
+	for _, debt := range resp.Debts {
+		debts = append(debts, debtToMap(debt))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"summaries": summaries,
+		"debts":     debts,
+	})
+}
+
+func (h *Handler) GetDebt(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	debtID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid debt id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetDebt(ctx, &pbLedger.GetDebtRequest{
+		UserId: userID,
+		DebtId: debtID,
+	})
+	if err != nil {
+		h.logger.Error("failed to get debt", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get debt")
+		return
+	}
+
+	repayments := []map[string]interface{}{}
+	for _, repayment := range resp.Repayments {
+		repayments = append(repayments, debtRepaymentToMap(repayment))
+	}
+
+	result := debtToMap(resp.Debt)
+	result["repayments"] = repayments
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+// debtRequest — тело запроса на создание и изменение долга. Направление,
+// валюта и операция при изменении не учитываются.
+type debtRequest struct {
+	TelegramID     int64  `json:"telegram_id"`
+	CounterpartyID int64  `json:"counterparty_id"`
+	Counterparty   string `json:"counterparty"`
+	Direction      string `json:"direction"`
+	Amount         string `json:"amount"`
+	Currency       string `json:"currency"`
+	Description    string `json:"description"`
+	DueDate        string `json:"due_date"` // YYYY-MM-DD
+	TransactionID  int64  `json:"transaction_id"`
+}
+
+func (h *Handler) CreateDebt(w http.ResponseWriter, r *http.Request) {
+	var req debtRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CreateDebt(ctx, &pbLedger.CreateDebtRequest{
+		UserId:         userID,
+		CounterpartyId: req.CounterpartyID,
+		Counterparty:   req.Counterparty,
+		Direction:      req.Direction,
+		Amount:         req.Amount,
+		Currency:       req.Currency,
+		Description:    req.Description,
+		DueDate:        req.DueDate,
+		TransactionId:  req.TransactionID,
+	})
+	if err != nil {
+		h.logger.Error("failed to create debt", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to create debt")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, debtToMap(resp.Debt))
+}
+
+func (h *Handler) UpdateDebt(w http.ResponseWriter, r *http.Request) {
+	var req debtRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	debtID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid debt id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.UpdateDebt(ctx, &pbLedger.UpdateDebtRequest{
+		UserId:         userID,
+		DebtId:         debtID,
+		CounterpartyId: req.CounterpartyID,
+		Counterparty:   req.Counterparty,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		DueDate:        req.DueDate,
+	})
+	if err != nil {
+		h.logger.Error("failed to update debt", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to update debt")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, debtToMap(resp.Debt))
+}
+
+func (h *Handler) DeleteDebt(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	debtID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid debt id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteDebt(ctx, &pbLedger.DeleteDebtRequest{
+		UserId: userID,
+		DebtId: debtID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete debt", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete debt")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func (h *Handler) AddDebtRepayment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID    int64  `json:"telegram_id"`
+		Amount        string `json:"amount"`
+		Date          string `json:"date"`
+		Note          string `json:"note"`
+		TransactionID int64  `json:"transaction_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	debtID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid debt id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.AddDebtRepayment(ctx, &pbLedger.AddDebtRepaymentRequest{
+		UserId:        userID,
+		DebtId:        debtID,
+		Amount:        req.Amount,
+		Date:          req.Date,
+		Note:          req.Note,
+		TransactionId: req.TransactionID,
+	})
+	if err != nil {
+		h.logger.Error("failed to add debt repayment", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to add debt repayment")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"repayment": debtRepaymentToMap(resp.Repayment),
+		"debt":      debtToMap(resp.Debt),
+	})
+}
+
+func (h *Handler) DeleteDebtRepayment(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	debtID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid debt id")
+		return
+	}
+
+	repaymentID, err := strconv.ParseInt(chi.URLParam(r, "repaymentID"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid repayment id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteDebtRepayment(ctx, &pbLedger.DeleteDebtRepaymentRequest{
+		UserId:      userID,
+		DebtId:      debtID,
+		RepaymentId: repaymentID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete debt repayment", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete debt repayment")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func counterpartyToMap(counterparty *pbLedger.Counterparty) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":   counterparty.Id,
+		"name": counterparty.Name,
+	}
+	if counterparty.Note != "" {
+		result["note"] = counterparty.Note
+	}
+	return result
+}
+
+func debtToMap(debt *pbLedger.Debt) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":                debt.Id,
+		"counterparty_id":   debt.CounterpartyId,
+		"counterparty_name": debt.CounterpartyName,
+		"direction":         debt.Direction,
+		"amount":            debt.Amount,
+		"currency":          debt.Currency,
+		"repaid":            debt.Repaid,
+		"outstanding":       debt.Outstanding,
+		"closed":            debt.Closed,
+		"overdue":           debt.Overdue,
+		"created_at":        debt.CreatedAt,
+	}
+	if debt.Description != "" {
+		result["description"] = debt.Description
+	}
+	if debt.DueDate != "" {
+		result["due_date"] = debt.DueDate
+	}
+	if debt.TransactionId > 0 {
+		result["transaction_id"] = debt.TransactionId
+	}
+	return result
+}
+
+func debtRepaymentToMap(repayment *pbLedger.DebtRepayment) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":     repayment.Id,
+		"amount": repayment.Amount,
+		"date":   repayment.Date,
+	}
+	if repayment.Note != "" {
+		result["note"] = repayment.Note
+	}
+	if repayment.TransactionId > 0 {
+		result["transaction_id"] = repayment.TransactionId
+	}
+	return result
+}
+
+func (h *Handler) GetLoanSchedule(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.GetLoanSchedule(ctx, &pbLedger.GetLoanScheduleRequest{
 		UserId:    userID,
-		Period:    period,
-		StartDate: startDate,
-		EndDate:   endDate,
-		Limit:     10000, // Get all transactions
+		AccountId: accountID,
 	})
 	if err != nil {
-		h.logger.Error("failed to get transactions for category stats", zap.Error(err))
-		h.respondError(w, http.StatusInternalServerError, "failed to get category stats")
+		h.logger.Error("failed to get loan schedule", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to get loan schedule")
 		return
 	}
 
-	// Aggregate by category
-	categoryStats := make(map[string]float64) // category_name -> total_expense
-	for _, tx := range resp.Transactions {
-		if tx.Type == "expense" && tx.CategoryName != "" {
-			amount, err := strconv.ParseFloat(tx.Amount, 64)
-			if err != nil {
+	h.respondJSON(w, http.StatusOK, loanScheduleToMap(resp))
+}
+
+func (h *Handler) SetLoan(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID         int64  `json:"telegram_id"`
+		Principal          string `json:"principal"`
+		AnnualRate         string `json:"annual_rate"`
+		TermMonths         int32  `json:"term_months"`
+		PaymentType        string `json:"payment_type"`
+		FirstPaymentDate   string `json:"first_payment_date"`
+		PaymentAccountID   int64  `json:"payment_account_id"`
+		InterestCategoryID int64  `json:"interest_category_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.SetLoan(ctx, &pbLedger.SetLoanRequest{
+		UserId:             userID,
+		AccountId:          accountID,
+		Principal:          req.Principal,
+		AnnualRate:         req.AnnualRate,
+		TermMonths:         req.TermMonths,
+		PaymentType:        req.PaymentType,
+		FirstPaymentDate:   req.FirstPaymentDate,
+		PaymentAccountId:   req.PaymentAccountID,
+		InterestCategoryId: req.InterestCategoryID,
+	})
+	if err != nil {
+		h.logger.Error("failed to set loan", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to set loan")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, loanScheduleToMap(resp))
+}
+
+func (h *Handler) DeleteLoan(w http.ResponseWriter, r *http.Request) {
+	telegramID, err := h.getTelegramID(r)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "telegram_id is required")
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
+	userID, err := h.getUserID(telegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.DeleteLoan(ctx, &pbLedger.DeleteLoanRequest{
+		UserId:    userID,
+		AccountId: accountID,
+	})
+	if err != nil {
+		h.logger.Error("failed to delete loan", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to delete loan")
+		return
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"status": resp.Status,
+	})
+}
+
+func (h *Handler) PostLoanPayment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID    int64  `json:"telegram_id"`
+		Number        int32  `json:"number"`
+		OperationDate string `json:"operation_date"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.PostLoanPayment(ctx, &pbLedger.PostLoanPaymentRequest{
+		UserId:        userID,
+		AccountId:     accountID,
+		Number:        req.Number,
+		OperationDate: req.OperationDate,
+	})
+	if err != nil {
+		h.logger.Error("failed to post loan payment", zap.Error(err))
+		h.respondLedgerError(w, err, "failed to post loan payment")
+		return
+	}
+
+	result := map[string]interface{}{
+		"payment":                 loanPaymentToMap(resp.Payment),
+		"payment_account_balance": resp.PaymentAccountBalance,
+		"loan_account_balance":    resp.LoanAccountBalance,
+	}
+	if resp.Warning != "" {
+		result["warning"] = resp.Warning
+	}
+
+	h.respondJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) CalculateLoanPrepayment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TelegramID int64  `json:"telegram_id"`
+		Amount     string `json:"amount"`
+		Date       string `json:"date"`
+		Mode       string `json:"mode"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	accountID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		h.respondError(w, http.StatusBadRequest, "invalid account id")
+		return
+	}
+
+	userID, err := h.getUserID(req.TelegramID)
+	if err != nil {
+		h.respondError(w, http.StatusNotFound, "user not found")
+		return
+	}
+
+	ctx := r.Context()
+	resp, err := h.clients.Ledger.CalculateLoanPrepayment(ctx, &pbLedger.CalculateLoanPrepaymentRequest{
+		UserId:    userID,
+		AccountId: accountID,
+		Amount:    req.Amount,
+		Date:      req.Date,
+		Mode:      req.Mode,
+	})
+	if err != nil {
+		h.logger.Error("failed to calculate loan prepayment", zap.Error(err))
+		h.respondGRPCError(w, err, "failed to calculate loan prepayment")
+		return
+	}
+
+	payments := []map[string]interface{}{}
+	for _, payment := range resp.Payments {
+		payments = append(payments, loanPaymentToMap(payment))
+	}
+
+	h.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"payments":       payments,
+		"balance_after":  resp.BalanceAfter,
+		"new_payment":    resp.NewPayment,
+		"total_interest": resp.TotalInterest,
+		"interest_saved": resp.InterestSaved,
+		"months_saved":   resp.MonthsSaved,
+	})
+}
+
+func loanScheduleToMap(resp *pbLedger.LoanScheduleResponse) map[string]interface{} {
+	loan := map[string]interface{}{
+		"account_id":         resp.Loan.AccountId,
+		"account_name":       resp.Loan.AccountName,
+		"currency":           resp.Loan.Currency,
+		"principal":          resp.Loan.Principal,
+		"annual_rate":        resp.Loan.AnnualRate,
+		"term_months":        resp.Loan.TermMonths,
+		"payment_type":       resp.Loan.PaymentType,
+		"first_payment_date": resp.Loan.FirstPaymentDate,
+	}
+	if resp.Loan.PaymentAccountId > 0 {
+		loan["payment_account_id"] = resp.Loan.PaymentAccountId
+	}
+	if resp.Loan.InterestCategoryId > 0 {
+		loan["interest_category_id"] = resp.Loan.InterestCategoryId
+	}
+
+	payments := []map[string]interface{}{}
+	for _, payment := range resp.Payments {
+		payments = append(payments, loanPaymentToMap(payment))
+	}
+
+	return map[string]interface{}{
+		"loan":           loan,
+		"payments":       payments,
+		"total_interest": resp.TotalInterest,
+		"total_paid":     resp.TotalPaid,
+	}
+}
+
+func loanPaymentToMap(payment *pbLedger.LoanPayment) map[string]interface{} {
+	result := map[string]interface{}{
+		"number":    payment.Number,
+		"date":      payment.Date,
+		"payment":   payment.Payment,
+		"principal": payment.Principal,
+		"interest":  payment.Interest,
+		"balance":   payment.Balance,
+		"posted":    payment.Posted,
+	}
+	if payment.PrincipalTransactionId > 0 {
+		result["principal_transaction_id"] = payment.PrincipalTransactionId
+	}
+	if payment.InterestTransactionId > 0 {
+		result["interest_transaction_id"] = payment.InterestTransactionId
+	}
+	return result
+}
+
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// parseIDList разбирает список ID через запятую
+func parseIDList(value string) ([]int64, error) {
+	var ids []int64
+	for _, item := range splitList(value) {
+		id, err := strconv.ParseInt(item, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func accountToMap(acc *pbLedger.Account) map[string]interface{} {
+	account := map[string]interface{}{
+		"id":                  acc.Id,
+		"name":                acc.Name,
+		"currency":            acc.Currency,
+		"balance":             acc.Balance,
+		"is_archived":         acc.IsArchived,
+		"is_default":          acc.IsDefault,
+		"type":                acc.Type,
+		"sort_order":          acc.SortOrder,
+		"group":               acc.Group,
+		"icon":                acc.Icon,
+		"color":               acc.Color,
+		"exclude_from_totals": acc.ExcludeFromTotals,
+	}
+	if acc.OverdraftPolicy != "" {
+		account["overdraft_policy"] = acc.OverdraftPolicy
+	}
+	if acc.CreditLimit != "" {
+		account["credit_limit"] = acc.CreditLimit
+		account["available_credit"] = acc.AvailableCredit
+	}
+	if acc.AmountOwed != "" {
+		account["amount_owed"] = acc.AmountOwed
+	}
+	if acc.StatementDay > 0 {
+		account["statement_day"] = acc.StatementDay
+	}
+	if acc.DueDay > 0 {
+		account["due_day"] = acc.DueDay
+	}
+	return account
+}
+
+func subtotalsToMaps(subtotals []*pbLedger.BalanceSubtotal) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for _, subtotal := range subtotals {
+		result = append(result, map[string]interface{}{
+			"group":    subtotal.Group,
+			"currency": subtotal.Currency,
+			"total":    subtotal.Total,
+		})
+	}
+	return result
+}
+
+// respondLedgerError отвечает на ошибку ledger-сервиса. Нехватка средств
+// отдается как 422 с суммой недостачи, NotFound — как 404, Internal — как
+// 500 с текстом fallback, прочие gRPC-ошибки — как 400.
+func (h *Handler) respondLedgerError(w http.ResponseWriter, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		h.respondError(w, http.StatusInternalServerError, fallback)
+		return
+	}
+
+	if st.Code() == codes.FailedPrecondition {
+		for _, detail := range st.Details() {
+			info, ok := detail.(*errdetails.ErrorInfo)
+			if !ok || info.Reason != "INSUFFICIENT_FUNDS" {
 				continue
 			}
-			categoryStats[tx.CategoryName] += amount
+			accountID, _ := strconv.ParseInt(info.Metadata["account_id"], 10, 64)
+			h.respondJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+				"error":      st.Message(),
+				"code":       "insufficient_funds",
+				"account_id": accountID,
+				"shortfall":  info.Metadata["shortfall"],
+				"currency":   info.Metadata["currency"],
+			})
+			return
 		}
 	}
 
-	// Convert to response format
-	var categories []map[string]interface{}
-	for categoryName, totalExpense := range categoryStats {
-		categories = append(categories, map[string]interface{}{
-			"name":          categoryName,
-			"total_expense": fmt.Sprintf("%.2f", totalExpense),
-		})
+	switch st.Code() {
+	case codes.NotFound:
+		h.respondError(w, http.StatusNotFound, st.Message())
+	case codes.AlreadyExists:
+		h.respondError(w, http.StatusConflict, st.Message())
+	case codes.Internal:
+		h.respondError(w, http.StatusInternalServerError, fallback)
+	default:
+		h.respondError(w, http.StatusBadRequest, st.Message())
+	}
+}
+
+// respondGRPCError переводит код gRPC-ошибки в HTTP-статус
+func (h *Handler) respondGRPCError(w http.ResponseWriter, err error, fallback string) {
+	st, ok := status.FromError(err)
+	if !ok {
+		h.respondError(w, http.StatusInternalServerError, fallback)
+		return
 	}
 
-	h.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"period":     period,
-		"categories": categories,
-	})
+	switch st.Code() {
+	case codes.NotFound:
+		h.respondError(w, http.StatusNotFound, st.Message())
+	case codes.InvalidArgument, codes.FailedPrecondition:
+		h.respondError(w, http.StatusBadRequest, st.Message())
+	case codes.AlreadyExists:
+		h.respondError(w, http.StatusConflict, st.Message())
+	case codes.PermissionDenied:
+		h.respondError(w, http.StatusForbidden, st.Message())
+	case codes.ResourceExhausted:
+		h.respondError(w, http.StatusRequestEntityTooLarge, st.Message())
+	default:
+		h.respondError(w, http.StatusInternalServerError, fallback)
+	}
 }
 
 func (h *Handler) getTelegramID(r *http.Request) (int64, error) {
@@ -2,11 +2,16 @@ package handler
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"strconv"
 	"time"
 
+	"github.com/kiribu/financial-tracker/internal/ledger/repository"
 	"github.com/kiribu/financial-tracker/internal/ledger/service"
 	pb "github.com/kiribu/financial-tracker/proto/ledger"
 	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -30,9 +35,33 @@ func (h *Handler) CreateExpense(ctx context.Context, req *pb.CreateExpenseReques
 		operationDate = time.Now()
 	}
 
-	tx, balance, err := h.service.CreateExpense(ctx, req.UserId, req.AccountId, req.Amount, req.CategoryId, req.Description, operationDate)
+	tx, balance, warning, err := h.service.CreateExpense(ctx, req.UserId, service.TransactionInput{
+		AccountID:     req.AccountId,
+		Amount:        req.Amount,
+		CategoryID:    req.CategoryId,
+		Description:   req.Description,
+		OperationDate: operationDate,
+		Tags:          req.Tags,
+		Splits:        fromPbSplits(req.Splits),
+		PayeeID:       req.PayeeId,
+		Payee:         req.Payee,
+		ApplyRules:    req.ApplyRules,
+		ReceiptQR:     req.ReceiptQr,
+	})
 	if err != nil {
 		h.logger.Error("failed to create expense", zap.Error(err))
+		if st, ok := debitStatus(err); ok {
+			return nil, st.Err()
+		}
+		if err.Error() == "payee not found" {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		if st, ok := receiptStatus(err); ok {
+			return nil, st.Err()
+		}
+		if isTagError(err) || isSplitError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to create expense: %v", err)
 	}
 
@@ -40,6 +69,7 @@ func (h *Handler) CreateExpense(ctx context.Context, req *pb.CreateExpenseReques
 		TransactionId: tx.ID,
 		AccountBalance: balance,
 		Status:         "ok",
+		Warning:        warning,
 	}, nil
 }
 
@@ -49,9 +79,30 @@ func (h *Handler) CreateIncome(ctx context.Context, req *pb.CreateIncomeRequest)
 		operationDate = time.Now()
 	}
 
-	tx, balance, err := h.service.CreateIncome(ctx, req.UserId, req.AccountId, req.Amount, req.CategoryId, req.Description, operationDate)
+	tx, balance, err := h.service.CreateIncome(ctx, req.UserId, service.TransactionInput{
+		AccountID:     req.AccountId,
+		Amount:        req.Amount,
+		CategoryID:    req.CategoryId,
+		Description:   req.Description,
+		OperationDate: operationDate,
+		Tags:          req.Tags,
+		Splits:        fromPbSplits(req.Splits),
+		PayeeID:       req.PayeeId,
+		Payee:         req.Payee,
+		ApplyRules:    req.ApplyRules,
+		ReceiptQR:     req.ReceiptQr,
+	})
 	if err != nil {
 		h.logger.Error("failed to create income", zap.Error(err))
+		if err.Error() == "payee not found" {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		if st, ok := receiptStatus(err); ok {
+			return nil, st.Err()
+		}
+		if isTagError(err) || isSplitError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to create income: %v", err)
 	}
 
@@ -68,9 +119,15 @@ func (h *Handler) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequ
 		operationDate = time.Now()
 	}
 
-	tx, fromBalance, toBalance, err := h.service.CreateTransfer(ctx, req.UserId, req.FromAccountId, req.ToAccountId, req.Amount, req.Description, operationDate)
+	tx, fromBalance, toBalance, warning, err := h.service.CreateTransfer(ctx, req.UserId, req.FromAccountId, req.ToAccountId, req.Amount, req.Description, operationDate, req.Tags)
 	if err != nil {
 		h.logger.Error("failed to create transfer", zap.Error(err))
+		if st, ok := debitStatus(err); ok {
+			return nil, st.Err()
+		}
+		if isTagError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to create transfer: %v", err)
 	}
 
@@ -79,11 +136,12 @@ func (h *Handler) CreateTransfer(ctx context.Context, req *pb.CreateTransferRequ
 		FromAccountBalance: fromBalance,
 		ToAccountBalance:   toBalance,
 		Status:             "ok",
+		Warning:            warning,
 	}, nil
 }
 
 func (h *Handler) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
-	accounts, err := h.service.ListAccounts(ctx, req.UserId)
+	accounts, err := h.service.ListAccounts(ctx, req.UserId, req.IncludeArchived)
 	if err != nil {
 		h.logger.Error("failed to list accounts", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to list accounts: %v", err)
@@ -91,14 +149,7 @@ func (h *Handler) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest)
 
 	var pbAccounts []*pb.Account
 	for _, acc := range accounts {
-		pbAccounts = append(pbAccounts, &pb.Account{
-			Id:         acc.ID,
-			Name:       acc.Name,
-			Currency:   acc.Currency,
-			Balance:    acc.Balance,
-			IsArchived: acc.IsArchived,
-			IsDefault:  acc.IsDefault,
-		})
+		pbAccounts = append(pbAccounts, toPbAccount(acc))
 	}
 
 	return &pb.ListAccountsResponse{
@@ -107,12 +158,28 @@ func (h *Handler) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest)
 }
 
 func (h *Handler) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.AccountResponse, error) {
-	account, err := h.service.CreateAccount(ctx, req.UserId, req.Name, req.Currency, req.Balance)
+	account, err := h.service.CreateAccount(ctx, req.UserId, service.AccountInput{
+		Name:              req.Name,
+		Currency:          req.Currency,
+		Balance:           req.Balance,
+		Type:              req.Type,
+		CreditLimit:       req.CreditLimit,
+		StatementDay:      req.StatementDay,
+		DueDay:            req.DueDay,
+		OverdraftPolicy:   req.OverdraftPolicy,
+		Group:             req.Group,
+		Icon:              req.Icon,
+		Color:             req.Color,
+		ExcludeFromTotals: req.ExcludeFromTotals,
+	})
 	if err != nil {
 		h.logger.Error("failed to create account", zap.Error(err))
 		if err.Error() == "account name cannot be empty" {
 			return nil, status.Errorf(codes.InvalidArgument, "account name cannot be empty")
 		}
+		if isCreditParamsError(err) {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
 		if err.Error() == "счет с таким названием уже существует" {
 			return nil, status.Errorf(codes.InvalidArgument, "счет с таким названием уже существует")
 		}
@@ -120,28 +187,46 @@ func (h *Handler) CreateAccount(ctx context.Context, req *pb.CreateAccountReques
 	}
 
 	return &pb.AccountResponse{
-		AccountId: account.ID,
-		Name:      account.Name,
-		Currency:  account.Currency,
-		Balance:   account.Balance,
+		AccountId:   account.ID,
+		Name:        account.Name,
+		Currency:    account.Currency,
+		Balance:     account.Balance,
+		Type:        account.Type,
+		CreditLimit: account.CreditLimit.String,
 	}, nil
 }
 
 func (h *Handler) UpdateAccount(ctx context.Context, req *pb.UpdateAccountRequest) (*pb.AccountResponse, error) {
-	account, err := h.service.UpdateAccount(ctx, req.UserId, req.AccountId, req.Name, req.Balance)
+	account, err := h.service.UpdateAccount(ctx, req.UserId, req.AccountId, service.AccountUpdate{
+		Name:              req.Name,
+		Balance:           req.Balance,
+		CreditLimit:       req.CreditLimit,
+		StatementDay:      req.StatementDay,
+		DueDay:            req.DueDay,
+		OverdraftPolicy:   req.OverdraftPolicy,
+		Group:             req.Group,
+		Icon:              req.Icon,
+		Color:             req.Color,
+		ExcludeFromTotals: req.ExcludeFromTotals,
+	})
 	if err != nil {
 		h.logger.Error("failed to update account", zap.Error(err))
 		if err.Error() == "account not found or doesn't belong to user" {
 			return nil, status.Errorf(codes.NotFound, "account not found")
 		}
+		if isCreditParamsError(err) {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update account: %v", err)
 	}
 
 	return &pb.AccountResponse{
-		AccountId: account.ID,
-		Name:      account.Name,
-		Currency:  account.Currency,
-		Balance:   account.Balance,
+		AccountId:   account.ID,
+		Name:        account.Name,
+		Currency:    account.Currency,
+		Balance:     account.Balance,
+		Type:        account.Type,
+		CreditLimit: account.CreditLimit.String,
 	}, nil
 }
 
@@ -166,29 +251,194 @@ func (h *Handler) DeleteAccount(ctx context.Context, req *pb.DeleteAccountReques
 	}, nil
 }
 
+func (h *Handler) UnarchiveAccount(ctx context.Context, req *pb.UnarchiveAccountRequest) (*pb.AccountResponse, error) {
+	account, err := h.service.UnarchiveAccount(ctx, req.UserId, req.AccountId)
+	if err != nil {
+		h.logger.Error("failed to unarchive account", zap.Error(err))
+		if err.Error() == "account not found" {
+			return nil, status.Errorf(codes.NotFound, "account not found")
+		}
+		if err.Error() == "счет с таким названием уже существует" {
+			return nil, status.Errorf(codes.AlreadyExists, "счет с таким названием уже существует")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to unarchive account: %v", err)
+	}
+
+	return &pb.AccountResponse{
+		AccountId:   account.ID,
+		Name:        account.Name,
+		Currency:    account.Currency,
+		Balance:     account.Balance,
+		Type:        account.Type,
+		CreditLimit: account.CreditLimit.String,
+	}, nil
+}
+
+func (h *Handler) PurgeAccount(ctx context.Context, req *pb.PurgeAccountRequest) (*pb.PurgeAccountResponse, error) {
+	deleted, reassigned, err := h.service.PurgeAccount(ctx, req.UserId, req.AccountId, req.ReassignToAccountId, req.ConfirmName)
+	if err != nil {
+		h.logger.Error("failed to purge account", zap.Error(err))
+		switch err.Error() {
+		case "account not found", "target account not found":
+			return nil, status.Errorf(codes.NotFound, "%v", err)
+		case "cannot delete default account",
+			"only archived accounts can be purged",
+			"target account is archived",
+			"target account currency does not match":
+			return nil, status.Errorf(codes.FailedPrecondition, "%v", err)
+		case "confirmation does not match account name":
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+		if st, ok := debitStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to purge account: %v", err)
+	}
+
+	return &pb.PurgeAccountResponse{
+		Status:                 "ok",
+		DeletedTransactions:    deleted,
+		ReassignedTransactions: reassigned,
+	}, nil
+}
+
+func (h *Handler) ReorderAccounts(ctx context.Context, req *pb.ReorderAccountsRequest) (*pb.ReorderAccountsResponse, error) {
+	err := h.service.ReorderAccounts(ctx, req.UserId, req.AccountIds)
+	if err != nil {
+		h.logger.Error("failed to reorder accounts", zap.Error(err))
+		if err.Error() == "account not found" {
+			return nil, status.Errorf(codes.NotFound, "account not found")
+		}
+		if err.Error() == "duplicate account id" {
+			return nil, status.Errorf(codes.InvalidArgument, "duplicate account id")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reorder accounts: %v", err)
+	}
+
+	return &pb.ReorderAccountsResponse{
+		Status: "ok",
+	}, nil
+}
+
 func (h *Handler) CreateCategory(ctx context.Context, req *pb.CreateCategoryRequest) (*pb.CategoryResponse, error) {
-	category, err := h.service.CreateCategory(ctx, req.UserId, req.Name, req.Type)
+	category, err := h.service.CreateCategory(ctx, req.UserId, req.Name, req.Type, req.ParentId)
 	if err != nil {
 		h.logger.Error("failed to create category", zap.Error(err))
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
+		}
 		return nil, status.Errorf(codes.Internal, "failed to create category: %v", err)
 	}
 
+	return toPbCategoryResponse(category), nil
+}
+
+func (h *Handler) UpdateCategory(ctx context.Context, req *pb.UpdateCategoryRequest) (*pb.CategoryResponse, error) {
+	category, err := h.service.UpdateCategory(ctx, req.UserId, req.CategoryId, service.CategoryUpdate{
+		Name:  req.Name,
+		Icon:  req.Icon,
+		Color: req.Color,
+	})
+	if err != nil {
+		h.logger.Error("failed to update category", zap.Error(err))
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update category: %v", err)
+	}
+
+	return toPbCategoryResponse(category), nil
+}
+
+func (h *Handler) MergeCategories(ctx context.Context, req *pb.MergeCategoriesRequest) (*pb.MergeCategoriesResponse, error) {
+	moved, err := h.service.MergeCategories(ctx, req.UserId, req.SourceCategoryIds, req.TargetCategoryId)
+	if err != nil {
+		h.logger.Error("failed to merge categories", zap.Error(err))
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to merge categories: %v", err)
+	}
+
+	return &pb.MergeCategoriesResponse{
+		Status:            "ok",
+		MovedTransactions: moved,
+	}, nil
+}
+
+func (h *Handler) ArchiveCategory(ctx context.Context, req *pb.ArchiveCategoryRequest) (*pb.CategoryResponse, error) {
+	category, err := h.service.ArchiveCategory(ctx, req.UserId, req.CategoryId)
+	if err != nil {
+		h.logger.Error("failed to archive category", zap.Error(err))
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to archive category: %v", err)
+	}
+
+	return toPbCategoryResponse(category), nil
+}
+
+func (h *Handler) UnarchiveCategory(ctx context.Context, req *pb.UnarchiveCategoryRequest) (*pb.CategoryResponse, error) {
+	category, err := h.service.UnarchiveCategory(ctx, req.UserId, req.CategoryId)
+	if err != nil {
+		h.logger.Error("failed to unarchive category", zap.Error(err))
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to unarchive category: %v", err)
+	}
+
+	return toPbCategoryResponse(category), nil
+}
+
+func toPbCategoryResponse(category *repository.Category) *pb.CategoryResponse {
 	return &pb.CategoryResponse{
 		CategoryId: category.ID,
 		Name:       category.Name,
 		Type:       category.Type,
+		ParentId:   category.ParentID.Int64,
+		Icon:       category.Icon,
+		Color:      category.Color,
+		IsArchived: category.IsArchived,
+		IsSystem:   !category.UserID.Valid,
+	}
+}
+
+func (h *Handler) ReorderCategories(ctx context.Context, req *pb.ReorderCategoriesRequest) (*pb.ReorderCategoriesResponse, error) {
+	err := h.service.ReorderCategories(ctx, req.UserId, req.CategoryIds)
+	if err != nil {
+		h.logger.Error("failed to reorder categories", zap.Error(err))
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reorder categories: %v", err)
+	}
+
+	return &pb.ReorderCategoriesResponse{
+		Status: "ok",
 	}, nil
 }
 
+func (h *Handler) SetFallbackCategory(ctx context.Context, req *pb.SetFallbackCategoryRequest) (*pb.CategoryResponse, error) {
+	category, err := h.service.SetFallbackCategory(ctx, req.UserId, req.Type, req.CategoryId)
+	if err != nil {
+		h.logger.Error("failed to set fallback category", zap.Error(err))
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to set fallback category: %v", err)
+	}
+
+	return toPbCategoryResponse(category), nil
+}
+
 func (h *Handler) DeleteCategory(ctx context.Context, req *pb.DeleteCategoryRequest) (*pb.DeleteCategoryResponse, error) {
 	err := h.service.DeleteCategory(ctx, req.UserId, req.CategoryId)
 	if err != nil {
 		h.logger.Error("failed to delete category", zap.Error(err))
-		if err.Error() == "category not found" {
-			return nil, status.Errorf(codes.NotFound, "category not found")
-		}
-		if err.Error() == "cannot delete system category" {
-			return nil, status.Errorf(codes.PermissionDenied, "cannot delete system category")
+		if st, ok := categoryStatus(err); ok {
+			return nil, st.Err()
 		}
 		return nil, status.Errorf(codes.Internal, "failed to delete category: %v", err)
 	}
@@ -199,67 +449,127 @@ func (h *Handler) DeleteCategory(ctx context.Context, req *pb.DeleteCategoryRequ
 }
 
 func (h *Handler) ListCategories(ctx context.Context, req *pb.ListCategoriesRequest) (*pb.ListCategoriesResponse, error) {
-	categories, err := h.service.ListCategories(ctx, req.UserId, req.Type)
+	categories, err := h.service.ListCategoryTree(ctx, req.UserId, req.Type, req.IncludeArchived)
 	if err != nil {
 		h.logger.Error("failed to list categories", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to list categories: %v", err)
 	}
 
-	var pbCategories []*pb.Category
-	for _, cat := range categories {
-		pbCategories = append(pbCategories, &pb.Category{
-			Id:   cat.ID,
-			Name: cat.Name,
-			Type: cat.Type,
-		})
+	fallbackID, err := h.service.GetFallbackCategoryID(ctx, req.UserId, req.Type)
+	if err != nil {
+		// Без категории по умолчанию список все равно полезен
+		h.logger.Warn("failed to get fallback category", zap.Error(err))
 	}
 
 	return &pb.ListCategoriesResponse{
-		Categories: pbCategories,
+		Categories:         toPbCategories(categories),
+		FallbackCategoryId: fallbackID,
 	}, nil
 }
 
+func toPbCategories(nodes []*service.CategoryNode) []*pb.Category {
+	var result []*pb.Category
+	for _, node := range nodes {
+		result = append(result, &pb.Category{
+			Id:         node.ID,
+			Name:       node.Name,
+			Type:       node.Type,
+			ParentId:   node.ParentID.Int64,
+			Children:   toPbCategories(node.Children),
+			Icon:       node.Icon,
+			Color:      node.Color,
+			IsArchived: node.IsArchived,
+			IsSystem:   !node.UserID.Valid,
+		})
+	}
+	return result
+}
+
 func (h *Handler) ListTransactions(ctx context.Context, req *pb.ListTransactionsRequest) (*pb.ListTransactionsResponse, error) {
 	limit := req.Limit
 	if limit <= 0 {
 		limit = 10
 	}
 
-	transactions, err := h.service.ListTransactions(ctx, req.UserId, req.Period, limit, req.StartDate, req.EndDate)
+	transactions, nextPageToken, err := h.service.ListTransactions(ctx, req.UserId, repository.TransactionFilter{
+		Period:       req.Period,
+		StartDate:    req.StartDate,
+		EndDate:      req.EndDate,
+		Limit:        limit,
+		Tags:         req.Tags,
+		AccountIDs:   req.AccountIds,
+		CategoryIDs:  req.CategoryIds,
+		Types:        req.Types,
+		MinAmount:    req.MinAmount,
+		MaxAmount:    req.MaxAmount,
+		Query:        req.Query,
+		Counterparty: req.Counterparty,
+		Sort:         req.Sort,
+	}, req.PageToken)
 	if err != nil {
 		h.logger.Error("failed to list transactions", zap.Error(err))
+		if isTagError(err) || isFilterError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to list transactions: %v", err)
 	}
 
 	var pbTransactions []*pb.Transaction
 	for _, tx := range transactions {
-		pbTx := &pb.Transaction{
-			Id:           tx.ID,
-			Type:         tx.Type,
-			Amount:       tx.Amount,
-			Currency:     tx.Currency,
-			CategoryName: tx.CategoryName,
-			AccountName:  tx.AccountName,
-			OperationDate: tx.OperationDate.Format("2006-01-02T15:04:05Z07:00"),
-			AccountId:    tx.AccountID,
-		}
-		if tx.Description.Valid {
-			pbTx.Description = tx.Description.String
-		}
-		if tx.RelatedAccountID.Valid {
-			pbTx.RelatedAccountId = tx.RelatedAccountID.Int64
-		}
-		if tx.CategoryID.Valid {
-			pbTx.CategoryId = tx.CategoryID.Int64
-		}
-		pbTransactions = append(pbTransactions, pbTx)
+		pbTransactions = append(pbTransactions, toPbTransaction(tx))
 	}
 
 	return &pb.ListTransactionsResponse{
-		Transactions: pbTransactions,
+		Transactions:  pbTransactions,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (h *Handler) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.GetTransactionResponse, error) {
+	tx, err := h.service.GetTransaction(ctx, req.UserId, req.TransactionId)
+	if err != nil {
+		if err.Error() == "transaction not found" {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		h.logger.Error("failed to get transaction", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get transaction: %v", err)
+	}
+
+	return &pb.GetTransactionResponse{
+		Transaction: toPbTransaction(tx),
 	}, nil
 }
 
+func toPbTransaction(tx *repository.TransactionWithDetails) *pb.Transaction {
+	pbTx := &pb.Transaction{
+		Id:                 tx.ID,
+		Type:               tx.Type,
+		Amount:             tx.Amount,
+		Currency:           tx.Currency,
+		CategoryName:       tx.CategoryName,
+		AccountName:        tx.AccountName,
+		OperationDate:      tx.OperationDate.Format("2006-01-02T15:04:05Z07:00"),
+		AccountId:          tx.AccountID,
+		Tags:               tx.Tags,
+		Splits:             toPbSplits(tx.Splits),
+		RelatedAccountName: tx.RelatedAccountName,
+		CreatedAt:          tx.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:          tx.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		PayeeId:            tx.PayeeID.Int64,
+		PayeeName:          tx.PayeeName,
+	}
+	if tx.Description.Valid {
+		pbTx.Description = tx.Description.String
+	}
+	if tx.RelatedAccountID.Valid {
+		pbTx.RelatedAccountId = tx.RelatedAccountID.Int64
+	}
+	if tx.CategoryID.Valid {
+		pbTx.CategoryId = tx.CategoryID.Int64
+	}
+	return pbTx
+}
+
 func (h *Handler) UpdateTransaction(ctx context.Context, req *pb.UpdateTransactionRequest) (*pb.TransactionResponse, error) {
 	operationDate, err := parseTime(req.OperationDate)
 	if err != nil {
@@ -271,9 +581,31 @@ func (h *Handler) UpdateTransaction(ctx context.Context, req *pb.UpdateTransacti
 		relatedAccountID = req.RelatedAccountId
 	}
 
-	tx, balance, err := h.service.UpdateTransaction(ctx, req.UserId, req.TransactionId, req.AccountId, req.Amount, req.CategoryId, req.Description, operationDate, relatedAccountID)
+	tx, balance, warning, err := h.service.UpdateTransaction(ctx, req.UserId, req.TransactionId, service.TransactionInput{
+		AccountID:        req.AccountId,
+		RelatedAccountID: relatedAccountID,
+		Amount:           req.Amount,
+		CategoryID:       req.CategoryId,
+		Description:      req.Description,
+		OperationDate:    operationDate,
+		Tags:             req.Tags.GetTags(),
+		ReplaceTags:      req.Tags != nil,
+		Splits:           fromPbSplits(req.Splits.GetSplits()),
+		ReplaceSplits:    req.Splits != nil,
+		PayeeID:          req.PayeeId,
+		Payee:            req.Payee,
+	})
 	if err != nil {
 		h.logger.Error("failed to update transaction", zap.Error(err))
+		if st, ok := debitStatus(err); ok {
+			return nil, st.Err()
+		}
+		if err.Error() == "payee not found" {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		if isTagError(err) || isSplitError(err) {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update transaction: %v", err)
 	}
 
@@ -281,6 +613,7 @@ func (h *Handler) UpdateTransaction(ctx context.Context, req *pb.UpdateTransacti
 		TransactionId: tx.ID,
 		AccountBalance: balance,
 		Status:         "ok",
+		Warning:        warning,
 	}, nil
 }
 
@@ -288,6 +621,9 @@ func (h *Handler) DeleteTransaction(ctx context.Context, req *pb.DeleteTransacti
 	err := h.service.DeleteTransaction(ctx, req.UserId, req.TransactionId)
 	if err != nil {
 		h.logger.Error("failed to delete transaction", zap.Error(err))
+		if st, ok := debitStatus(err); ok {
+			return nil, st.Err()
+		}
 		return nil, status.Errorf(codes.Internal, "failed to delete transaction: %v", err)
 	}
 
@@ -297,29 +633,210 @@ func (h *Handler) DeleteTransaction(ctx context.Context, req *pb.DeleteTransacti
 }
 
 func (h *Handler) GetBalance(ctx context.Context, req *pb.GetBalanceRequest) (*pb.GetBalanceResponse, error) {
-	accounts, err := h.service.GetBalance(ctx, req.UserId)
+	balance, err := h.service.GetBalance(ctx, req.UserId)
 	if err != nil {
 		h.logger.Error("failed to get balance", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to get balance: %v", err)
 	}
 
 	var pbAccounts []*pb.Account
-	for _, acc := range accounts {
-		pbAccounts = append(pbAccounts, &pb.Account{
-			Id:         acc.ID,
-			Name:       acc.Name,
-			Currency:   acc.Currency,
-			Balance:    acc.Balance,
-			IsArchived: acc.IsArchived,
-			IsDefault:  acc.IsDefault,
-		})
+	for _, acc := range balance.Accounts {
+		pbAccounts = append(pbAccounts, toPbAccount(acc))
 	}
 
 	return &pb.GetBalanceResponse{
 		Accounts: pbAccounts,
+		Groups:   toPbSubtotals(balance.Groups),
+		Totals:   toPbSubtotals(balance.Totals),
+	}, nil
+}
+
+func toPbAccount(acc *repository.Account) *pb.Account {
+	availableCredit, amountOwed := service.AccountCredit(acc)
+	return &pb.Account{
+		Id:                acc.ID,
+		Name:              acc.Name,
+		Currency:          acc.Currency,
+		Balance:           acc.Balance,
+		IsArchived:        acc.IsArchived,
+		IsDefault:         acc.IsDefault,
+		Type:              acc.Type,
+		CreditLimit:       acc.CreditLimit.String,
+		StatementDay:      acc.StatementDay.Int32,
+		DueDay:            acc.DueDay.Int32,
+		AvailableCredit:   availableCredit,
+		AmountOwed:        amountOwed,
+		OverdraftPolicy:   acc.OverdraftPolicy,
+		SortOrder:         acc.SortOrder,
+		Group:             acc.Group,
+		Icon:              acc.Icon,
+		Color:             acc.Color,
+		ExcludeFromTotals: acc.ExcludeFromTotals,
+	}
+}
+
+func toPbSubtotals(subtotals []*service.BalanceSubtotal) []*pb.BalanceSubtotal {
+	var result []*pb.BalanceSubtotal
+	for _, subtotal := range subtotals {
+		result = append(result, &pb.BalanceSubtotal{
+			Group:    subtotal.Group,
+			Currency: subtotal.Currency,
+			Total:    subtotal.Total,
+		})
+	}
+	return result
+}
+
+func isCreditParamsError(err error) bool {
+	switch err.Error() {
+	case "invalid account type",
+		"invalid credit limit",
+		"invalid statement day",
+		"invalid due day",
+		"invalid overdraft policy",
+		"invalid account color",
+		"account group is too long",
+		"account icon is too long",
+		"credit parameters are only allowed for credit accounts":
+		return true
+	}
+	return false
+}
+
+func (h *Handler) GetTagBreakdown(ctx context.Context, req *pb.GetTagBreakdownRequest) (*pb.GetTagBreakdownResponse, error) {
+	totals, err := h.service.GetTagBreakdown(ctx, req.UserId, req.Type, req.Period, req.StartDate, req.EndDate)
+	if err != nil {
+		h.logger.Error("failed to get tag breakdown", zap.Error(err))
+		if err.Error() == "invalid transaction type" {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get tag breakdown: %v", err)
+	}
+
+	var pbTotals []*pb.TagTotal
+	for _, total := range totals {
+		pbTotals = append(pbTotals, &pb.TagTotal{
+			Tag:      total.Tag,
+			Currency: total.Currency,
+			Total:    total.Total,
+			Count:    total.Count,
+		})
+	}
+
+	return &pb.GetTagBreakdownResponse{
+		Tags: pbTotals,
 	}, nil
 }
 
+func isTagError(err error) bool {
+	switch err.Error() {
+	case "invalid tag", "tag is too long", "too many tags":
+		return true
+	}
+	return false
+}
+
+// isFilterError сообщает, что условия поиска операций заданы неверно.
+func isFilterError(err error) bool {
+	switch err.Error() {
+	case "invalid transaction type", "invalid amount range", "invalid sort order", "invalid page token":
+		return true
+	}
+	return false
+}
+
+// isSplitError сообщает, что строки разбивки операции заданы неверно.
+func isSplitError(err error) bool {
+	switch err.Error() {
+	case "splits are allowed only for expenses and income", "split must have at least two lines",
+		"too many split lines", "invalid split amount", "split category is required",
+		"split category not found", "split category type mismatch", "split note is too long",
+		"split lines must sum to transaction amount":
+		return true
+	}
+	return false
+}
+
+func fromPbSplits(splits []*pb.Split) []*repository.Split {
+	var result []*repository.Split
+	for _, split := range splits {
+		result = append(result, &repository.Split{
+			CategoryID: sql.NullInt64{Int64: split.CategoryId, Valid: split.CategoryId > 0},
+			Amount:     split.Amount,
+			Note:       sql.NullString{String: split.Note, Valid: split.Note != ""},
+		})
+	}
+	return result
+}
+
+func toPbSplits(splits []*repository.Split) []*pb.Split {
+	var result []*pb.Split
+	for _, split := range splits {
+		result = append(result, &pb.Split{
+			CategoryId:   split.CategoryID.Int64,
+			Amount:       split.Amount,
+			Note:         split.Note.String,
+			CategoryName: split.CategoryName,
+		})
+	}
+	return result
+}
+
+// categoryStatus переводит ошибки операций с категориями в gRPC-статусы.
+func categoryStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "category not found", "parent category not found", "target category not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "cannot delete system category":
+		return status.New(codes.PermissionDenied, err.Error()), true
+	case "категория с таким названием уже существует":
+		return status.New(codes.AlreadyExists, err.Error()), true
+	case "category name cannot be empty",
+		"invalid category type",
+		"parent category type mismatch",
+		"parent category is archived",
+		"category nesting is too deep",
+		"category icon is too long",
+		"invalid category color",
+		"no source categories",
+		"duplicate category id",
+		"cannot merge category into itself",
+		"cannot merge category into its subcategory",
+		"category type mismatch",
+		"target category is archived",
+		"fallback category is archived":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+// debitStatus переводит ошибки проверки списания в FailedPrecondition.
+// Для нехватки средств к статусу прикладывается ErrorInfo с суммой недостачи,
+// чтобы gateway мог показать ее пользователю.
+func debitStatus(err error) (*status.Status, bool) {
+	var insufficient *service.InsufficientFundsError
+	if errors.As(err, &insufficient) {
+		st := status.New(codes.FailedPrecondition, insufficient.Error())
+		detailed, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason: "INSUFFICIENT_FUNDS",
+			Domain: "ledger",
+			Metadata: map[string]string{
+				"account_id": strconv.FormatInt(insufficient.AccountID, 10),
+				"shortfall":  insufficient.Shortfall,
+				"currency":   insufficient.Currency,
+			},
+		})
+		if detailsErr == nil {
+			st = detailed
+		}
+		return st, true
+	}
+	if errors.Is(err, service.ErrCreditLimitExceeded) {
+		return status.New(codes.FailedPrecondition, err.Error()), true
+	}
+	return nil, false
+}
+
 func parseTime(timeStr string) (time.Time, error) {
 	if timeStr == "" {
 		return time.Time{}, nil
@@ -327,3 +844,1409 @@ func parseTime(timeStr string) (time.Time, error) {
 	return time.Parse("2006-01-02T15:04:05Z07:00", timeStr)
 }
 
+func (h *Handler) ListPayees(ctx context.Context, req *pb.ListPayeesRequest) (*pb.ListPayeesResponse, error) {
+	payees, err := h.service.ListPayees(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to list payees", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list payees: %v", err)
+	}
+
+	var pbPayees []*pb.Payee
+	for _, payee := range payees {
+		pbPayees = append(pbPayees, toPbPayee(payee))
+	}
+
+	return &pb.ListPayeesResponse{
+		Payees: pbPayees,
+	}, nil
+}
+
+func (h *Handler) CreatePayee(ctx context.Context, req *pb.CreatePayeeRequest) (*pb.PayeeResponse, error) {
+	payee, err := h.service.CreatePayee(ctx, req.UserId, service.PayeeInput{
+		Name:              req.Name,
+		Aliases:           req.Aliases,
+		DefaultCategoryID: req.DefaultCategoryId,
+		DefaultAccountID:  req.DefaultAccountId,
+	})
+	if err != nil {
+		h.logger.Error("failed to create payee", zap.Error(err))
+		if st, ok := payeeStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create payee: %v", err)
+	}
+
+	return &pb.PayeeResponse{
+		Payee: toPbPayee(payee),
+	}, nil
+}
+
+func (h *Handler) UpdatePayee(ctx context.Context, req *pb.UpdatePayeeRequest) (*pb.PayeeResponse, error) {
+	payee, err := h.service.UpdatePayee(ctx, req.UserId, req.PayeeId, service.PayeeInput{
+		Name:              req.Name,
+		Aliases:           req.Aliases,
+		DefaultCategoryID: req.DefaultCategoryId,
+		DefaultAccountID:  req.DefaultAccountId,
+	})
+	if err != nil {
+		h.logger.Error("failed to update payee", zap.Error(err))
+		if st, ok := payeeStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update payee: %v", err)
+	}
+
+	return &pb.PayeeResponse{
+		Payee: toPbPayee(payee),
+	}, nil
+}
+
+func (h *Handler) DeletePayee(ctx context.Context, req *pb.DeletePayeeRequest) (*pb.DeletePayeeResponse, error) {
+	if err := h.service.DeletePayee(ctx, req.UserId, req.PayeeId); err != nil {
+		h.logger.Error("failed to delete payee", zap.Error(err))
+		if st, ok := payeeStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete payee: %v", err)
+	}
+
+	return &pb.DeletePayeeResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) GetPayeeBreakdown(ctx context.Context, req *pb.GetPayeeBreakdownRequest) (*pb.GetPayeeBreakdownResponse, error) {
+	totals, err := h.service.GetPayeeBreakdown(ctx, req.UserId, req.Type, req.Period, req.StartDate, req.EndDate, req.Limit)
+	if err != nil {
+		h.logger.Error("failed to get payee breakdown", zap.Error(err))
+		if err.Error() == "invalid transaction type" {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get payee breakdown: %v", err)
+	}
+
+	var pbTotals []*pb.PayeeTotal
+	for _, total := range totals {
+		pbTotals = append(pbTotals, &pb.PayeeTotal{
+			PayeeId:  total.PayeeID,
+			Name:     total.Name,
+			Currency: total.Currency,
+			Total:    total.Total,
+			Count:    total.Count,
+		})
+	}
+
+	return &pb.GetPayeeBreakdownResponse{
+		Payees: pbTotals,
+	}, nil
+}
+
+func toPbPayee(payee *repository.Payee) *pb.Payee {
+	return &pb.Payee{
+		Id:                payee.ID,
+		Name:              payee.Name,
+		Aliases:           payee.Aliases,
+		DefaultCategoryId: payee.DefaultCategoryID.Int64,
+		DefaultAccountId:  payee.DefaultAccountID.Int64,
+	}
+}
+
+// payeeStatus переводит ошибки операций с получателями в gRPC-статусы.
+func payeeStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "payee not found", "default category not found", "default account not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "payee already exists":
+		return status.New(codes.AlreadyExists, err.Error()), true
+	case "payee name cannot be empty", "payee name is too long", "too many payee aliases",
+		"default category is archived", "default account is archived":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+func (h *Handler) ListRules(ctx context.Context, req *pb.ListRulesRequest) (*pb.ListRulesResponse, error) {
+	rules, err := h.service.ListRules(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to list rules", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list rules: %v", err)
+	}
+
+	pbRules := make([]*pb.Rule, 0, len(rules))
+	for _, rule := range rules {
+		pbRules = append(pbRules, toPbRule(rule))
+	}
+
+	return &pb.ListRulesResponse{
+		Rules: pbRules,
+	}, nil
+}
+
+func (h *Handler) CreateRule(ctx context.Context, req *pb.CreateRuleRequest) (*pb.RuleResponse, error) {
+	rule, err := h.service.CreateRule(ctx, req.UserId, fromPbRule(req.Rule))
+	if err != nil {
+		h.logger.Error("failed to create rule", zap.Error(err))
+		if st, ok := ruleStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create rule: %v", err)
+	}
+
+	return &pb.RuleResponse{
+		Rule: toPbRule(rule),
+	}, nil
+}
+
+func (h *Handler) UpdateRule(ctx context.Context, req *pb.UpdateRuleRequest) (*pb.RuleResponse, error) {
+	rule, err := h.service.UpdateRule(ctx, req.UserId, req.RuleId, fromPbRule(req.Rule))
+	if err != nil {
+		h.logger.Error("failed to update rule", zap.Error(err))
+		if st, ok := ruleStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update rule: %v", err)
+	}
+
+	return &pb.RuleResponse{
+		Rule: toPbRule(rule),
+	}, nil
+}
+
+func (h *Handler) DeleteRule(ctx context.Context, req *pb.DeleteRuleRequest) (*pb.DeleteRuleResponse, error) {
+	if err := h.service.DeleteRule(ctx, req.UserId, req.RuleId); err != nil {
+		h.logger.Error("failed to delete rule", zap.Error(err))
+		if st, ok := ruleStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete rule: %v", err)
+	}
+
+	return &pb.DeleteRuleResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) ReorderRules(ctx context.Context, req *pb.ReorderRulesRequest) (*pb.ReorderRulesResponse, error) {
+	if err := h.service.ReorderRules(ctx, req.UserId, req.RuleIds); err != nil {
+		h.logger.Error("failed to reorder rules", zap.Error(err))
+		if st, ok := ruleStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to reorder rules: %v", err)
+	}
+
+	return &pb.ReorderRulesResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) TestRule(ctx context.Context, req *pb.TestRuleRequest) (*pb.TestRuleResponse, error) {
+	matched, matches, err := h.service.TestRule(ctx, req.UserId, fromPbRule(req.Rule), req.Period, req.StartDate, req.EndDate, req.Limit)
+	if err != nil {
+		h.logger.Error("failed to test rule", zap.Error(err))
+		if st, ok := ruleStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to test rule: %v", err)
+	}
+
+	pbMatches := make([]*pb.RuleMatch, 0, len(matches))
+	for _, match := range matches {
+		pbMatches = append(pbMatches, &pb.RuleMatch{
+			Transaction: toPbTransaction(match.Transaction),
+			CategoryId:  match.CategoryID,
+			PayeeId:     match.PayeeID,
+			Description: match.Description,
+			Tags:        match.Tags,
+		})
+	}
+
+	return &pb.TestRuleResponse{
+		Matched: matched,
+		Matches: pbMatches,
+	}, nil
+}
+
+func (h *Handler) ApplyRules(ctx context.Context, req *pb.ApplyRulesRequest) (*pb.ApplyRulesResponse, error) {
+	matched, updated, err := h.service.ApplyRules(ctx, req.UserId, req.Period, req.StartDate, req.EndDate)
+	if err != nil {
+		h.logger.Error("failed to apply rules", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to apply rules: %v", err)
+	}
+
+	return &pb.ApplyRulesResponse{
+		Matched: matched,
+		Updated: updated,
+	}, nil
+}
+
+func fromPbRule(rule *pb.Rule) service.RuleInput {
+	if rule == nil {
+		return service.RuleInput{}
+	}
+	return service.RuleInput{
+		Name:                rule.Name,
+		IsActive:            rule.IsActive,
+		StopProcessing:      rule.StopProcessing,
+		TransactionType:     rule.TransactionType,
+		DescriptionContains: rule.DescriptionContains,
+		DescriptionRegex:    rule.DescriptionRegex,
+		MinAmount:           rule.MinAmount,
+		MaxAmount:           rule.MaxAmount,
+		AccountID:           rule.AccountId,
+		PayeeID:             rule.PayeeId,
+		SetCategoryID:       rule.SetCategoryId,
+		AddTags:             rule.AddTags,
+		SetPayeeID:          rule.SetPayeeId,
+		SetDescription:      rule.SetDescription,
+	}
+}
+
+func toPbRule(rule *repository.Rule) *pb.Rule {
+	return &pb.Rule{
+		Id:                  rule.ID,
+		Name:                rule.Name,
+		Position:            rule.Position,
+		IsActive:            rule.IsActive,
+		StopProcessing:      rule.StopProcessing,
+		TransactionType:     rule.TransactionType.String,
+		DescriptionContains: rule.DescriptionContains.String,
+		DescriptionRegex:    rule.DescriptionRegex.String,
+		MinAmount:           rule.MinAmount.String,
+		MaxAmount:           rule.MaxAmount.String,
+		AccountId:           rule.AccountID.Int64,
+		PayeeId:             rule.PayeeID.Int64,
+		SetCategoryId:       rule.SetCategoryID.Int64,
+		AddTags:             rule.AddTags,
+		SetPayeeId:          rule.SetPayeeID.Int64,
+		SetDescription:      rule.SetDescription.String,
+	}
+}
+
+// ruleStatus переводит ошибки правил в коды gRPC.
+func ruleStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "rule not found", "rule account not found", "rule payee not found", "rule category not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "rule name cannot be empty", "rule name is too long", "rule pattern is too long",
+		"invalid rule regex", "invalid transaction type", "invalid amount range",
+		"rule category type mismatch", "rule description is too long",
+		"rule has no conditions", "rule has no actions", "duplicate rule id":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	if isTagError(err) {
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+func (h *Handler) UploadAttachment(ctx context.Context, req *pb.UploadAttachmentRequest) (*pb.AttachmentResponse, error) {
+	attachment, err := h.service.UploadAttachment(ctx, req.UserId, req.TransactionId, req.FileName, req.Data)
+	if err != nil {
+		h.logger.Error("failed to upload attachment", zap.Error(err))
+		if st, ok := attachmentStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to upload attachment: %v", err)
+	}
+
+	return &pb.AttachmentResponse{
+		Attachment: toPbAttachment(attachment),
+	}, nil
+}
+
+func (h *Handler) ListAttachments(ctx context.Context, req *pb.ListAttachmentsRequest) (*pb.ListAttachmentsResponse, error) {
+	attachments, err := h.service.ListAttachments(ctx, req.UserId, req.TransactionId)
+	if err != nil {
+		h.logger.Error("failed to list attachments", zap.Error(err))
+		if st, ok := attachmentStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to list attachments: %v", err)
+	}
+
+	pbAttachments := make([]*pb.Attachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		pbAttachments = append(pbAttachments, toPbAttachment(attachment))
+	}
+
+	return &pb.ListAttachmentsResponse{
+		Attachments: pbAttachments,
+	}, nil
+}
+
+func (h *Handler) GetAttachment(ctx context.Context, req *pb.GetAttachmentRequest) (*pb.GetAttachmentResponse, error) {
+	attachment, data, err := h.service.GetAttachment(ctx, req.UserId, req.AttachmentId)
+	if err != nil {
+		h.logger.Error("failed to get attachment", zap.Error(err))
+		if st, ok := attachmentStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get attachment: %v", err)
+	}
+
+	return &pb.GetAttachmentResponse{
+		Attachment: toPbAttachment(attachment),
+		Data:       data,
+	}, nil
+}
+
+func (h *Handler) DeleteAttachment(ctx context.Context, req *pb.DeleteAttachmentRequest) (*pb.DeleteAttachmentResponse, error) {
+	if err := h.service.DeleteAttachment(ctx, req.UserId, req.AttachmentId); err != nil {
+		h.logger.Error("failed to delete attachment", zap.Error(err))
+		if st, ok := attachmentStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete attachment: %v", err)
+	}
+
+	return &pb.DeleteAttachmentResponse{
+		Status: "ok",
+	}, nil
+}
+
+func toPbAttachment(attachment *repository.Attachment) *pb.Attachment {
+	return &pb.Attachment{
+		Id:            attachment.ID,
+		TransactionId: attachment.TransactionID,
+		FileName:      attachment.FileName,
+		ContentType:   attachment.ContentType,
+		SizeBytes:     attachment.SizeBytes,
+		CreatedAt:     attachment.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// attachmentStatus переводит ошибки вложений в коды gRPC.
+func attachmentStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "transaction not found", "attachment not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "attachment is empty", "unsupported attachment type":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	case "attachment is too large", "too many attachments", "attachment quota exceeded":
+		return status.New(codes.ResourceExhausted, err.Error()), true
+	}
+	return nil, false
+}
+
+func (h *Handler) ParseReceipt(ctx context.Context, req *pb.ParseReceiptRequest) (*pb.ParseReceiptResponse, error) {
+	receipt, duplicateID, err := h.service.ParseReceipt(ctx, req.UserId, req.Qr)
+	if err != nil {
+		h.logger.Error("failed to parse receipt", zap.Error(err))
+		if st, ok := receiptStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to parse receipt: %v", err)
+	}
+
+	return &pb.ParseReceiptResponse{
+		Draft: &pb.ReceiptDraft{
+			Type:                   receipt.Type,
+			Amount:                 receipt.Amount,
+			OperationDate:          receipt.OperationDate.Format("2006-01-02T15:04:05Z07:00"),
+			Fn:                     receipt.FN,
+			Fd:                     receipt.FD,
+			Fp:                     receipt.FP,
+			Operation:              receipt.Operation,
+			DuplicateTransactionId: duplicateID,
+		},
+	}, nil
+}
+
+// receiptStatus переводит ошибки разбора чека в коды gRPC.
+func receiptStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "invalid receipt code", "receipt type does not match transaction":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	case "receipt already recorded":
+		return status.New(codes.AlreadyExists, err.Error()), true
+	}
+	return nil, false
+}
+
+func (h *Handler) ListGoals(ctx context.Context, req *pb.ListGoalsRequest) (*pb.ListGoalsResponse, error) {
+	statuses, err := h.service.ListGoals(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to list goals", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list goals: %v", err)
+	}
+
+	goals := make([]*pb.GoalStatus, 0, len(statuses))
+	for _, st := range statuses {
+		goals = append(goals, toPbGoalStatus(st))
+	}
+
+	return &pb.ListGoalsResponse{
+		Goals: goals,
+	}, nil
+}
+
+func (h *Handler) GetGoalStatus(ctx context.Context, req *pb.GetGoalStatusRequest) (*pb.GoalStatusResponse, error) {
+	result, err := h.service.GetGoalStatus(ctx, req.UserId, req.GoalId)
+	if err != nil {
+		h.logger.Error("failed to get goal status", zap.Error(err))
+		if st, ok := goalStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get goal status: %v", err)
+	}
+
+	return &pb.GoalStatusResponse{
+		Status: toPbGoalStatus(result),
+	}, nil
+}
+
+func (h *Handler) CreateGoal(ctx context.Context, req *pb.CreateGoalRequest) (*pb.GoalStatusResponse, error) {
+	result, err := h.service.CreateGoal(ctx, req.UserId, service.GoalInput{
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		Currency:     req.Currency,
+		Deadline:     req.Deadline,
+		AccountID:    req.AccountId,
+	})
+	if err != nil {
+		h.logger.Error("failed to create goal", zap.Error(err))
+		if st, ok := goalStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create goal: %v", err)
+	}
+
+	return &pb.GoalStatusResponse{
+		Status: toPbGoalStatus(result),
+	}, nil
+}
+
+func (h *Handler) UpdateGoal(ctx context.Context, req *pb.UpdateGoalRequest) (*pb.GoalStatusResponse, error) {
+	result, err := h.service.UpdateGoal(ctx, req.UserId, req.GoalId, service.GoalInput{
+		Name:         req.Name,
+		TargetAmount: req.TargetAmount,
+		Currency:     req.Currency,
+		Deadline:     req.Deadline,
+		AccountID:    req.AccountId,
+	})
+	if err != nil {
+		h.logger.Error("failed to update goal", zap.Error(err))
+		if st, ok := goalStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update goal: %v", err)
+	}
+
+	return &pb.GoalStatusResponse{
+		Status: toPbGoalStatus(result),
+	}, nil
+}
+
+func (h *Handler) DeleteGoal(ctx context.Context, req *pb.DeleteGoalRequest) (*pb.DeleteGoalResponse, error) {
+	if err := h.service.DeleteGoal(ctx, req.UserId, req.GoalId); err != nil {
+		h.logger.Error("failed to delete goal", zap.Error(err))
+		if st, ok := goalStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete goal: %v", err)
+	}
+
+	return &pb.DeleteGoalResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) AddGoalContribution(ctx context.Context, req *pb.AddGoalContributionRequest) (*pb.GoalContributionResponse, error) {
+	date, err := parseTime(req.Date)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid contribution date")
+	}
+
+	contribution, result, err := h.service.AddGoalContribution(ctx, req.UserId, req.GoalId, req.Amount, date, req.Note)
+	if err != nil {
+		h.logger.Error("failed to add goal contribution", zap.Error(err))
+		if st, ok := goalStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to add goal contribution: %v", err)
+	}
+
+	return &pb.GoalContributionResponse{
+		Contribution: toPbGoalContribution(contribution),
+		Status:       toPbGoalStatus(result),
+	}, nil
+}
+
+func (h *Handler) ListGoalContributions(ctx context.Context, req *pb.ListGoalContributionsRequest) (*pb.ListGoalContributionsResponse, error) {
+	contributions, err := h.service.ListGoalContributions(ctx, req.UserId, req.GoalId)
+	if err != nil {
+		h.logger.Error("failed to list goal contributions", zap.Error(err))
+		if st, ok := goalStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to list goal contributions: %v", err)
+	}
+
+	pbContributions := make([]*pb.GoalContribution, 0, len(contributions))
+	for _, contribution := range contributions {
+		pbContributions = append(pbContributions, toPbGoalContribution(contribution))
+	}
+
+	return &pb.ListGoalContributionsResponse{
+		Contributions: pbContributions,
+	}, nil
+}
+
+func (h *Handler) DeleteGoalContribution(ctx context.Context, req *pb.DeleteGoalContributionRequest) (*pb.DeleteGoalContributionResponse, error) {
+	if err := h.service.DeleteGoalContribution(ctx, req.UserId, req.GoalId, req.ContributionId); err != nil {
+		h.logger.Error("failed to delete goal contribution", zap.Error(err))
+		if st, ok := goalStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete goal contribution: %v", err)
+	}
+
+	return &pb.DeleteGoalContributionResponse{
+		Status: "ok",
+	}, nil
+}
+
+func toPbGoalStatus(st *service.GoalStatus) *pb.GoalStatus {
+	goal := st.Goal
+	return &pb.GoalStatus{
+		Goal: &pb.Goal{
+			Id:           goal.ID,
+			Name:         goal.Name,
+			TargetAmount: goal.TargetAmount,
+			Currency:     goal.Currency,
+			Deadline:     goal.Deadline.Format("2006-01-02"),
+			AccountId:    goal.AccountID.Int64,
+			AccountName:  goal.AccountName.String,
+			CreatedAt:    goal.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		},
+		Saved:           st.Saved,
+		Remaining:       st.Remaining,
+		ProgressPercent: st.ProgressPercent,
+		RequiredMonthly: st.RequiredMonthly,
+		MonthsLeft:      st.MonthsLeft,
+		OnTrack:         st.OnTrack,
+	}
+}
+
+func toPbGoalContribution(contribution *repository.GoalContribution) *pb.GoalContribution {
+	return &pb.GoalContribution{
+		Id:            contribution.ID,
+		Amount:        contribution.Amount,
+		Date:          contribution.Date.Format("2006-01-02T15:04:05Z07:00"),
+		Note:          contribution.Note.String,
+		TransactionId: contribution.TransactionID.Int64,
+	}
+}
+
+// goalStatus переводит ошибки целей накопления в коды gRPC.
+func goalStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "goal not found", "goal account not found", "contribution not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "goal name cannot be empty", "goal name is too long", "invalid target amount",
+		"invalid goal deadline", "goal deadline is in the past", "goal currency does not match account",
+		"invalid contribution amount", "contribution note is too long":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+func (h *Handler) ListCounterparties(ctx context.Context, req *pb.ListCounterpartiesRequest) (*pb.ListCounterpartiesResponse, error) {
+	counterparties, err := h.service.ListCounterparties(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to list counterparties", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list counterparties: %v", err)
+	}
+
+	pbCounterparties := make([]*pb.Counterparty, 0, len(counterparties))
+	for _, counterparty := range counterparties {
+		pbCounterparties = append(pbCounterparties, toPbCounterparty(counterparty))
+	}
+
+	return &pb.ListCounterpartiesResponse{
+		Counterparties: pbCounterparties,
+	}, nil
+}
+
+func (h *Handler) CreateCounterparty(ctx context.Context, req *pb.CreateCounterpartyRequest) (*pb.CounterpartyResponse, error) {
+	counterparty, err := h.service.CreateCounterparty(ctx, req.UserId, service.CounterpartyInput{
+		Name: req.Name,
+		Note: req.Note,
+	})
+	if err != nil {
+		h.logger.Error("failed to create counterparty", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create counterparty: %v", err)
+	}
+
+	return &pb.CounterpartyResponse{
+		Counterparty: toPbCounterparty(counterparty),
+	}, nil
+}
+
+func (h *Handler) UpdateCounterparty(ctx context.Context, req *pb.UpdateCounterpartyRequest) (*pb.CounterpartyResponse, error) {
+	counterparty, err := h.service.UpdateCounterparty(ctx, req.UserId, req.CounterpartyId, service.CounterpartyInput{
+		Name: req.Name,
+		Note: req.Note,
+	})
+	if err != nil {
+		h.logger.Error("failed to update counterparty", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update counterparty: %v", err)
+	}
+
+	return &pb.CounterpartyResponse{
+		Counterparty: toPbCounterparty(counterparty),
+	}, nil
+}
+
+func (h *Handler) DeleteCounterparty(ctx context.Context, req *pb.DeleteCounterpartyRequest) (*pb.DeleteCounterpartyResponse, error) {
+	if err := h.service.DeleteCounterparty(ctx, req.UserId, req.CounterpartyId); err != nil {
+		h.logger.Error("failed to delete counterparty", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete counterparty: %v", err)
+	}
+
+	return &pb.DeleteCounterpartyResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) ListDebts(ctx context.Context, req *pb.ListDebtsRequest) (*pb.ListDebtsResponse, error) {
+	summaries, debts, err := h.service.ListDebts(ctx, req.UserId, req.CounterpartyId, req.IncludeClosed)
+	if err != nil {
+		h.logger.Error("failed to list debts", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list debts: %v", err)
+	}
+
+	pbSummaries := make([]*pb.DebtSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		pbSummary := &pb.DebtSummary{
+			CounterpartyId:   summary.CounterpartyID,
+			CounterpartyName: summary.CounterpartyName,
+			Currency:         summary.Currency,
+			Lent:             summary.Lent,
+			Borrowed:         summary.Borrowed,
+			Net:              summary.Net,
+			OpenDebts:        summary.OpenDebts,
+		}
+		if !summary.NextDueDate.IsZero() {
+			pbSummary.NextDueDate = summary.NextDueDate.Format("2006-01-02")
+		}
+		pbSummaries = append(pbSummaries, pbSummary)
+	}
+
+	pbDebts := make([]*pb.Debt, 0, len(debts))
+	for _, debt := range debts {
+		pbDebts = append(pbDebts, toPbDebt(debt))
+	}
+
+	return &pb.ListDebtsResponse{
+		Summaries: pbSummaries,
+		Debts:     pbDebts,
+	}, nil
+}
+
+func (h *Handler) GetDebt(ctx context.Context, req *pb.GetDebtRequest) (*pb.GetDebtResponse, error) {
+	debt, repayments, err := h.service.GetDebt(ctx, req.UserId, req.DebtId)
+	if err != nil {
+		h.logger.Error("failed to get debt", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get debt: %v", err)
+	}
+
+	pbRepayments := make([]*pb.DebtRepayment, 0, len(repayments))
+	for _, repayment := range repayments {
+		pbRepayments = append(pbRepayments, toPbDebtRepayment(repayment))
+	}
+
+	return &pb.GetDebtResponse{
+		Debt:       toPbDebt(debt),
+		Repayments: pbRepayments,
+	}, nil
+}
+
+func (h *Handler) CreateDebt(ctx context.Context, req *pb.CreateDebtRequest) (*pb.DebtResponse, error) {
+	debt, err := h.service.CreateDebt(ctx, req.UserId, service.DebtInput{
+		CounterpartyID: req.CounterpartyId,
+		Counterparty:   req.Counterparty,
+		Direction:      req.Direction,
+		Amount:         req.Amount,
+		Currency:       req.Currency,
+		Description:    req.Description,
+		DueDate:        req.DueDate,
+		TransactionID:  req.TransactionId,
+	})
+	if err != nil {
+		h.logger.Error("failed to create debt", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create debt: %v", err)
+	}
+
+	return &pb.DebtResponse{
+		Debt: toPbDebt(debt),
+	}, nil
+}
+
+func (h *Handler) UpdateDebt(ctx context.Context, req *pb.UpdateDebtRequest) (*pb.DebtResponse, error) {
+	debt, err := h.service.UpdateDebt(ctx, req.UserId, req.DebtId, service.DebtInput{
+		CounterpartyID: req.CounterpartyId,
+		Counterparty:   req.Counterparty,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		DueDate:        req.DueDate,
+	})
+	if err != nil {
+		h.logger.Error("failed to update debt", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update debt: %v", err)
+	}
+
+	return &pb.DebtResponse{
+		Debt: toPbDebt(debt),
+	}, nil
+}
+
+func (h *Handler) DeleteDebt(ctx context.Context, req *pb.DeleteDebtRequest) (*pb.DeleteDebtResponse, error) {
+	if err := h.service.DeleteDebt(ctx, req.UserId, req.DebtId); err != nil {
+		h.logger.Error("failed to delete debt", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete debt: %v", err)
+	}
+
+	return &pb.DeleteDebtResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) AddDebtRepayment(ctx context.Context, req *pb.AddDebtRepaymentRequest) (*pb.DebtRepaymentResponse, error) {
+	date, err := parseTime(req.Date)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid repayment date")
+	}
+
+	repayment, debt, err := h.service.AddDebtRepayment(ctx, req.UserId, req.DebtId, service.RepaymentInput{
+		Amount:        req.Amount,
+		Date:          date,
+		Note:          req.Note,
+		TransactionID: req.TransactionId,
+	})
+	if err != nil {
+		h.logger.Error("failed to add debt repayment", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to add debt repayment: %v", err)
+	}
+
+	return &pb.DebtRepaymentResponse{
+		Repayment: toPbDebtRepayment(repayment),
+		Debt:      toPbDebt(debt),
+	}, nil
+}
+
+func (h *Handler) DeleteDebtRepayment(ctx context.Context, req *pb.DeleteDebtRepaymentRequest) (*pb.DeleteDebtRepaymentResponse, error) {
+	if err := h.service.DeleteDebtRepayment(ctx, req.UserId, req.DebtId, req.RepaymentId); err != nil {
+		h.logger.Error("failed to delete debt repayment", zap.Error(err))
+		if st, ok := debtStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete debt repayment: %v", err)
+	}
+
+	return &pb.DeleteDebtRepaymentResponse{
+		Status: "ok",
+	}, nil
+}
+
+func toPbCounterparty(counterparty *repository.Counterparty) *pb.Counterparty {
+	return &pb.Counterparty{
+		Id:   counterparty.ID,
+		Name: counterparty.Name,
+		Note: counterparty.Note.String,
+	}
+}
+
+func toPbDebt(st *service.DebtStatus) *pb.Debt {
+	debt := st.Debt
+	pbDebt := &pb.Debt{
+		Id:               debt.ID,
+		CounterpartyId:   debt.CounterpartyID,
+		CounterpartyName: debt.CounterpartyName,
+		Direction:        debt.Direction,
+		Amount:           debt.Amount,
+		Currency:         debt.Currency,
+		Description:      debt.Description.String,
+		TransactionId:    debt.TransactionID.Int64,
+		Repaid:           debt.Repaid,
+		Outstanding:      st.Outstanding,
+		Closed:           st.Closed,
+		Overdue:          st.Overdue,
+		CreatedAt:        debt.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if debt.DueDate.Valid {
+		pbDebt.DueDate = debt.DueDate.Time.Format("2006-01-02")
+	}
+	return pbDebt
+}
+
+func toPbDebtRepayment(repayment *repository.DebtRepayment) *pb.DebtRepayment {
+	return &pb.DebtRepayment{
+		Id:            repayment.ID,
+		Amount:        repayment.Amount,
+		Date:          repayment.Date.Format("2006-01-02T15:04:05Z07:00"),
+		TransactionId: repayment.TransactionID.Int64,
+		Note:          repayment.Note.String,
+	}
+}
+
+// debtStatus переводит ошибки долгов и контрагентов в коды gRPC.
+func debtStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "debt not found", "counterparty not found", "repayment not found", "transaction not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "counterparty already exists", "transaction already linked to a debt":
+		return status.New(codes.AlreadyExists, err.Error()), true
+	case "counterparty has debts", "repayment exceeds outstanding amount", "debt amount is less than repaid":
+		return status.New(codes.FailedPrecondition, err.Error()), true
+	case "counterparty name cannot be empty", "counterparty name is too long", "counterparty note is too long",
+		"counterparty is required", "invalid debt direction", "invalid debt amount", "invalid debt due date",
+		"debt description is too long", "transaction type does not match debt", "transaction currency does not match debt",
+		"invalid repayment amount", "repayment note is too long":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+func (h *Handler) SetLoan(ctx context.Context, req *pb.SetLoanRequest) (*pb.LoanScheduleResponse, error) {
+	schedule, err := h.service.SetLoan(ctx, req.UserId, req.AccountId, service.LoanInput{
+		Principal:          req.Principal,
+		AnnualRate:         req.AnnualRate,
+		TermMonths:         req.TermMonths,
+		PaymentType:        req.PaymentType,
+		FirstPaymentDate:   req.FirstPaymentDate,
+		PaymentAccountID:   req.PaymentAccountId,
+		InterestCategoryID: req.InterestCategoryId,
+	})
+	if err != nil {
+		h.logger.Error("failed to set loan", zap.Error(err))
+		if st, ok := loanStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to set loan: %v", err)
+	}
+
+	return toPbLoanSchedule(schedule), nil
+}
+
+func (h *Handler) GetLoanSchedule(ctx context.Context, req *pb.GetLoanScheduleRequest) (*pb.LoanScheduleResponse, error) {
+	schedule, err := h.service.GetLoanSchedule(ctx, req.UserId, req.AccountId)
+	if err != nil {
+		h.logger.Error("failed to get loan schedule", zap.Error(err))
+		if st, ok := loanStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get loan schedule: %v", err)
+	}
+
+	return toPbLoanSchedule(schedule), nil
+}
+
+func (h *Handler) DeleteLoan(ctx context.Context, req *pb.DeleteLoanRequest) (*pb.DeleteLoanResponse, error) {
+	if err := h.service.DeleteLoan(ctx, req.UserId, req.AccountId); err != nil {
+		h.logger.Error("failed to delete loan", zap.Error(err))
+		if st, ok := loanStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete loan: %v", err)
+	}
+
+	return &pb.DeleteLoanResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) PostLoanPayment(ctx context.Context, req *pb.PostLoanPaymentRequest) (*pb.PostLoanPaymentResponse, error) {
+	operationDate, err := parseTime(req.OperationDate)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid operation date")
+	}
+
+	result, err := h.service.PostLoanPayment(ctx, req.UserId, req.AccountId, req.Number, operationDate)
+	if err != nil {
+		h.logger.Error("failed to post loan payment", zap.Error(err))
+		if st, ok := debitStatus(err); ok {
+			return nil, st.Err()
+		}
+		if st, ok := loanStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to post loan payment: %v", err)
+	}
+
+	return &pb.PostLoanPaymentResponse{
+		Payment:               toPbLoanPayment(result.Payment),
+		PaymentAccountBalance: result.PaymentAccountBalance,
+		LoanAccountBalance:    result.LoanAccountBalance,
+		Warning:               result.Warning,
+	}, nil
+}
+
+func (h *Handler) CalculateLoanPrepayment(ctx context.Context, req *pb.CalculateLoanPrepaymentRequest) (*pb.CalculateLoanPrepaymentResponse, error) {
+	var date time.Time
+	if req.Date != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid prepayment date")
+		}
+	}
+
+	result, err := h.service.CalculateLoanPrepayment(ctx, req.UserId, req.AccountId, req.Amount, date, req.Mode)
+	if err != nil {
+		h.logger.Error("failed to calculate loan prepayment", zap.Error(err))
+		if st, ok := loanStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to calculate loan prepayment: %v", err)
+	}
+
+	payments := make([]*pb.LoanPayment, 0, len(result.Payments))
+	for _, payment := range result.Payments {
+		payments = append(payments, toPbLoanPayment(payment))
+	}
+
+	return &pb.CalculateLoanPrepaymentResponse{
+		Payments:      payments,
+		BalanceAfter:  result.BalanceAfter,
+		NewPayment:    result.NewPayment,
+		TotalInterest: result.TotalInterest,
+		InterestSaved: result.InterestSaved,
+		MonthsSaved:   result.MonthsSaved,
+	}, nil
+}
+
+func (h *Handler) DetectSubscriptions(ctx context.Context, req *pb.DetectSubscriptionsRequest) (*pb.DetectSubscriptionsResponse, error) {
+	subscriptions, err := h.service.DetectSubscriptions(ctx, req.UserId, req.Months, req.IncludeInactive)
+	if err != nil {
+		h.logger.Error("failed to detect subscriptions", zap.Error(err))
+		if err.Error() == "invalid analysis period" {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		return nil, status.Errorf(codes.Internal, "failed to detect subscriptions: %v", err)
+	}
+
+	result := make([]*pb.Subscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		result = append(result, &pb.Subscription{
+			PayeeId:       subscription.PayeeID,
+			Name:          subscription.Name,
+			Cadence:       subscription.Cadence,
+			Currency:      subscription.Currency,
+			AverageAmount: subscription.AverageAmount,
+			LastAmount:    subscription.LastAmount,
+			ChargeCount:   subscription.ChargeCount,
+			FirstCharge:   subscription.FirstCharge.Format("2006-01-02"),
+			LastCharge:    subscription.LastCharge.Format("2006-01-02"),
+			NextExpected:  subscription.NextExpected.Format("2006-01-02"),
+			Active:        subscription.Active,
+			CategoryId:    subscription.CategoryID,
+			CategoryName:  subscription.CategoryName,
+			AccountId:     subscription.AccountID,
+			AccountName:   subscription.AccountName,
+			Key:           subscription.Key,
+		})
+	}
+
+	return &pb.DetectSubscriptionsResponse{
+		Subscriptions: result,
+	}, nil
+}
+
+func (h *Handler) ListBudgets(ctx context.Context, req *pb.ListBudgetsRequest) (*pb.ListBudgetsResponse, error) {
+	statuses, err := h.service.ListBudgets(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to list budgets", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list budgets: %v", err)
+	}
+
+	budgets := make([]*pb.BudgetStatus, 0, len(statuses))
+	for _, st := range statuses {
+		budgets = append(budgets, toPbBudgetStatus(st))
+	}
+
+	return &pb.ListBudgetsResponse{
+		Budgets: budgets,
+	}, nil
+}
+
+func (h *Handler) CreateBudget(ctx context.Context, req *pb.CreateBudgetRequest) (*pb.BudgetStatusResponse, error) {
+	result, err := h.service.CreateBudget(ctx, req.UserId, service.BudgetInput{
+		Name:       req.Name,
+		CategoryID: req.CategoryId,
+		PayeeID:    req.PayeeId,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Period:     req.Period,
+	})
+	if err != nil {
+		h.logger.Error("failed to create budget", zap.Error(err))
+		if st, ok := budgetStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create budget: %v", err)
+	}
+
+	return &pb.BudgetStatusResponse{
+		Status: toPbBudgetStatus(result),
+	}, nil
+}
+
+func (h *Handler) UpdateBudget(ctx context.Context, req *pb.UpdateBudgetRequest) (*pb.BudgetStatusResponse, error) {
+	result, err := h.service.UpdateBudget(ctx, req.UserId, req.BudgetId, service.BudgetInput{
+		Name:       req.Name,
+		CategoryID: req.CategoryId,
+		PayeeID:    req.PayeeId,
+		Amount:     req.Amount,
+		Currency:   req.Currency,
+		Period:     req.Period,
+	})
+	if err != nil {
+		h.logger.Error("failed to update budget", zap.Error(err))
+		if st, ok := budgetStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update budget: %v", err)
+	}
+
+	return &pb.BudgetStatusResponse{
+		Status: toPbBudgetStatus(result),
+	}, nil
+}
+
+func (h *Handler) DeleteBudget(ctx context.Context, req *pb.DeleteBudgetRequest) (*pb.DeleteBudgetResponse, error) {
+	if err := h.service.DeleteBudget(ctx, req.UserId, req.BudgetId); err != nil {
+		h.logger.Error("failed to delete budget", zap.Error(err))
+		if st, ok := budgetStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to delete budget: %v", err)
+	}
+
+	return &pb.DeleteBudgetResponse{
+		Status: "ok",
+	}, nil
+}
+
+func (h *Handler) CreateBudgetFromSubscription(ctx context.Context, req *pb.CreateBudgetFromSubscriptionRequest) (*pb.BudgetStatusResponse, error) {
+	result, err := h.service.CreateBudgetFromSubscription(ctx, req.UserId, req.Key, req.Months)
+	if err != nil {
+		h.logger.Error("failed to create budget from subscription", zap.Error(err))
+		if st, ok := budgetStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create budget from subscription: %v", err)
+	}
+
+	return &pb.BudgetStatusResponse{
+		Status: toPbBudgetStatus(result),
+	}, nil
+}
+
+func toPbBudgetStatus(st *service.BudgetStatus) *pb.BudgetStatus {
+	budget := st.Budget
+	return &pb.BudgetStatus{
+		Budget: &pb.Budget{
+			Id:           budget.ID,
+			Name:         budget.Name,
+			CategoryId:   budget.CategoryID.Int64,
+			CategoryName: budget.CategoryName.String,
+			PayeeId:      budget.PayeeID.Int64,
+			PayeeName:    budget.PayeeName.String,
+			Amount:       budget.Amount,
+			Currency:     budget.Currency,
+			Period:       budget.Period,
+			CreatedAt:    budget.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		},
+		PeriodStart:     st.PeriodStart.Format("2006-01-02"),
+		PeriodEnd:       st.PeriodEnd.Format("2006-01-02"),
+		Spent:           st.Spent,
+		Remaining:       st.Remaining,
+		ProgressPercent: st.ProgressPercent,
+		Exceeded:        st.Exceeded,
+	}
+}
+
+// budgetStatus переводит ошибки бюджетов в коды gRPC.
+func budgetStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "budget not found", "budget category not found", "budget payee not found", "subscription not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "budget name cannot be empty", "budget name is too long", "invalid budget amount",
+		"invalid budget period", "budget needs a category or payee", "subscription has no payee or category",
+		"invalid analysis period":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+func (h *Handler) ListInsights(ctx context.Context, req *pb.ListInsightsRequest) (*pb.ListInsightsResponse, error) {
+	insights, err := h.service.ListInsights(ctx, req.UserId, req.Limit)
+	if err != nil {
+		h.logger.Error("failed to list insights", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list insights: %v", err)
+	}
+
+	return &pb.ListInsightsResponse{
+		Insights: toPbInsights(insights),
+	}, nil
+}
+
+func (h *Handler) GetDataVersion(ctx context.Context, req *pb.GetDataVersionRequest) (*pb.DataVersion, error) {
+	version, err := h.service.GetDataVersion(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to get data version", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get data version: %v", err)
+	}
+	return &pb.DataVersion{Version: version}, nil
+}
+
+func (h *Handler) ClaimNotifications(ctx context.Context, req *pb.ClaimNotificationsRequest) (*pb.ClaimNotificationsResponse, error) {
+	notifications, err := h.service.ClaimNotifications(ctx, req.Limit)
+	if err != nil {
+		h.logger.Error("failed to claim notifications", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to claim notifications: %v", err)
+	}
+
+	result := make([]*pb.Notification, 0, len(notifications))
+	for _, n := range notifications {
+		result = append(result, &pb.Notification{
+			Id:        n.ID,
+			UserId:    n.UserID,
+			Type:      n.Type,
+			Payload:   n.Payload,
+			Attempts:  n.Attempts,
+			CreatedAt: n.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	return &pb.ClaimNotificationsResponse{
+		Notifications: result,
+	}, nil
+}
+
+func (h *Handler) CompleteNotification(ctx context.Context, req *pb.CompleteNotificationRequest) (*pb.CompleteNotificationResponse, error) {
+	retryAfter := time.Duration(req.RetryAfter) * time.Second
+	if err := h.service.CompleteNotification(ctx, req.Id, req.Delivered, req.Error, retryAfter, req.Permanent); err != nil {
+		h.logger.Error("failed to complete notification", zap.Error(err))
+		if st, ok := notificationStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to complete notification: %v", err)
+	}
+
+	return &pb.CompleteNotificationResponse{
+		Status: "completed",
+	}, nil
+}
+
+func (h *Handler) GetNotificationPreferences(ctx context.Context, req *pb.GetNotificationPreferencesRequest) (*pb.NotificationPreferences, error) {
+	prefs, err := h.service.GetNotificationPreferences(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to get notification preferences", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get notification preferences: %v", err)
+	}
+	return toPbNotificationPreferences(prefs), nil
+}
+
+func (h *Handler) UpdateNotificationPreferences(ctx context.Context, req *pb.UpdateNotificationPreferencesRequest) (*pb.NotificationPreferences, error) {
+	var input service.NotificationPreferences
+	if req.Preferences != nil {
+		input = service.NotificationPreferences{
+			MutedTypes: req.Preferences.MutedTypes,
+			QuietFrom:  req.Preferences.QuietFrom,
+			QuietTo:    req.Preferences.QuietTo,
+			Timezone:   req.Preferences.Timezone,
+		}
+	}
+
+	prefs, err := h.service.UpdateNotificationPreferences(ctx, req.UserId, input)
+	if err != nil {
+		h.logger.Error("failed to update notification preferences", zap.Error(err))
+		if st, ok := notificationStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update notification preferences: %v", err)
+	}
+	return toPbNotificationPreferences(prefs), nil
+}
+
+func toPbNotificationPreferences(prefs *service.NotificationPreferences) *pb.NotificationPreferences {
+	return &pb.NotificationPreferences{
+		MutedTypes: prefs.MutedTypes,
+		QuietFrom:  prefs.QuietFrom,
+		QuietTo:    prefs.QuietTo,
+		Timezone:   prefs.Timezone,
+	}
+}
+
+func (h *Handler) GetDigestSettings(ctx context.Context, req *pb.GetDigestSettingsRequest) (*pb.DigestSettings, error) {
+	settings, err := h.service.GetDigestSettings(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to get digest settings", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get digest settings: %v", err)
+	}
+	return toPbDigestSettings(settings), nil
+}
+
+func (h *Handler) UpdateDigestSettings(ctx context.Context, req *pb.UpdateDigestSettingsRequest) (*pb.DigestSettings, error) {
+	var input service.DigestSettings
+	if req.Settings != nil {
+		input = service.DigestSettings{
+			Enabled:   req.Settings.Enabled,
+			Frequency: req.Settings.Frequency,
+			Weekday:   req.Settings.Weekday,
+			Time:      req.Settings.Time,
+		}
+	}
+
+	settings, err := h.service.UpdateDigestSettings(ctx, req.UserId, input)
+	if err != nil {
+		h.logger.Error("failed to update digest settings", zap.Error(err))
+		if st, ok := notificationStatus(err); ok {
+			return nil, st.Err()
+		}
+		return nil, status.Errorf(codes.Internal, "failed to update digest settings: %v", err)
+	}
+	return toPbDigestSettings(settings), nil
+}
+
+func toPbDigestSettings(settings *service.DigestSettings) *pb.DigestSettings {
+	return &pb.DigestSettings{
+		Enabled:   settings.Enabled,
+		Frequency: settings.Frequency,
+		Weekday:   settings.Weekday,
+		Time:      settings.Time,
+		Timezone:  settings.Timezone,
+	}
+}
+
+// notificationStatus переводит ошибки уведомлений в коды gRPC.
+func notificationStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "notification not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "notification is already completed":
+		return status.New(codes.FailedPrecondition, err.Error()), true
+	case "invalid notification type", "quiet hours require both start and end", "invalid quiet hours", "invalid timezone",
+		"invalid digest frequency", "invalid digest weekday", "invalid digest time":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
+
+func toPbInsights(insights []*repository.Insight) []*pb.Insight {
+	result := make([]*pb.Insight, 0, len(insights))
+	for _, insight := range insights {
+		item := &pb.Insight{
+			Id:            insight.ID,
+			UserId:        insight.UserID,
+			Kind:          insight.Kind,
+			CategoryId:    insight.CategoryID,
+			CategoryName:  insight.CategoryName,
+			TransactionId: insight.TransactionID.Int64,
+			Description:   insight.Description.String,
+			Weekday:       -1,
+			Amount:        insight.Amount,
+			Baseline:      insight.Baseline,
+			Threshold:     insight.Threshold,
+			Currency:      insight.Currency,
+			CreatedAt:     insight.CreatedAt.Format(time.RFC3339),
+		}
+		if insight.OperationDate.Valid {
+			item.OperationDate = insight.OperationDate.Time.Format(time.RFC3339)
+		}
+		if insight.PeriodStart.Valid {
+			item.PeriodStart = insight.PeriodStart.Time.Format("2006-01-02")
+		}
+		if insight.Weekday.Valid {
+			item.Weekday = int32(insight.Weekday.Int16)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func toPbLoanSchedule(schedule *service.LoanSchedule) *pb.LoanScheduleResponse {
+	loan := schedule.Loan
+	payments := make([]*pb.LoanPayment, 0, len(schedule.Payments))
+	for _, payment := range schedule.Payments {
+		payments = append(payments, toPbLoanPayment(payment))
+	}
+
+	return &pb.LoanScheduleResponse{
+		Loan: &pb.Loan{
+			AccountId:          loan.AccountID,
+			AccountName:        loan.AccountName,
+			Currency:           loan.Currency,
+			Principal:          loan.Principal,
+			AnnualRate:         loan.AnnualRate,
+			TermMonths:         loan.TermMonths,
+			PaymentType:        loan.PaymentType,
+			FirstPaymentDate:   loan.FirstPaymentDate.Format("2006-01-02"),
+			PaymentAccountId:   loan.PaymentAccountID.Int64,
+			InterestCategoryId: loan.InterestCategoryID.Int64,
+		},
+		Payments:      payments,
+		TotalInterest: schedule.TotalInterest,
+		TotalPaid:     schedule.TotalPaid,
+	}
+}
+
+func toPbLoanPayment(payment *service.LoanPayment) *pb.LoanPayment {
+	return &pb.LoanPayment{
+		Number:                 payment.Number,
+		Date:                   payment.Date.Format("2006-01-02"),
+		Payment:                payment.Payment,
+		Principal:              payment.Principal,
+		Interest:               payment.Interest,
+		Balance:                payment.Balance,
+		Posted:                 payment.Posted,
+		PrincipalTransactionId: payment.PrincipalTransactionID,
+		InterestTransactionId:  payment.InterestTransactionID,
+	}
+}
+
+// loanStatus переводит ошибки кредитов в коды gRPC.
+func loanStatus(err error) (*status.Status, bool) {
+	switch err.Error() {
+	case "account not found", "loan not found", "loan payment not found", "payment account not found",
+		"interest category not found":
+		return status.New(codes.NotFound, err.Error()), true
+	case "loan payment already posted":
+		return status.New(codes.AlreadyExists, err.Error()), true
+	case "loan payment account is not set", "loan is repaid by this date", "prepayment exceeds loan balance":
+		return status.New(codes.FailedPrecondition, err.Error()), true
+	case "account is not a loan", "invalid loan principal", "invalid loan rate", "invalid loan term",
+		"invalid loan payment type", "invalid first payment date", "payment account must differ from loan account",
+		"payment account currency does not match loan", "interest category must be an expense category",
+		"invalid prepayment mode", "invalid prepayment amount":
+		return status.New(codes.InvalidArgument, err.Error()), true
+	}
+	return nil, false
+}
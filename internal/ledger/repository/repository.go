@@ -3,43 +3,174 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
 
+// querier — общее подмножество pgxpool.Pool и pgx.Tx
+type querier interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 type Repository struct {
-	db     *pgxpool.Pool
+	pool   *pgxpool.Pool
+	db     querier
 	logger *zap.Logger
 }
 
 func NewRepository(db *pgxpool.Pool, logger *zap.Logger) *Repository {
 	return &Repository{
+		pool:   db,
 		db:     db,
 		logger: logger,
 	}
 }
 
+// WithTx выполняет fn в одной транзакции БД. Все запросы репозитория,
+// переданного в fn, идут через эту транзакцию; при ошибке она откатывается.
+func (r *Repository) WithTx(ctx context.Context, fn func(repo *Repository) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	txRepo := &Repository{
+		pool:   r.pool,
+		db:     tx,
+		logger: r.logger,
+	}
+	if err := fn(txRepo); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 type Account struct {
+	ID                int64
+	UserID            int64
+	Name              string
+	Currency          string
+	Balance           string
+	IsArchived        bool
+	IsDefault         bool
+	Type              string
+	CreditLimit       sql.NullString
+	StatementDay      sql.NullInt32
+	DueDay            sql.NullInt32
+	OverdraftPolicy   string
+	SortOrder         int32
+	Group             string
+	Icon              string
+	Color             string
+	ExcludeFromTotals bool
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+}
+
+const accountColumns = `id, user_id, name, currency, balance, is_archived, is_default, type, credit_limit, statement_day, due_day, overdraft_policy,
+	sort_order, group_name, icon, color, exclude_from_totals, created_at, updated_at`
+
+// scanAccount читает строку, выбранную с колонками accountColumns
+func scanAccount(row pgx.Row) (*Account, error) {
+	var account Account
+	err := row.Scan(
+		&account.ID,
+		&account.UserID,
+		&account.Name,
+		&account.Currency,
+		&account.Balance,
+		&account.IsArchived,
+		&account.IsDefault,
+		&account.Type,
+		&account.CreditLimit,
+		&account.StatementDay,
+		&account.DueDay,
+		&account.OverdraftPolicy,
+		&account.SortOrder,
+		&account.Group,
+		&account.Icon,
+		&account.Color,
+		&account.ExcludeFromTotals,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+type Category struct {
 	ID         int64
-	UserID     int64
+	UserID     sql.NullInt64
+	ParentID   sql.NullInt64
 	Name       string
-	Currency   string
-	Balance    string
+	Type       string
+	Icon       string
+	Color      string
 	IsArchived bool
-	IsDefault  bool
 	CreatedAt  time.Time
-	UpdatedAt  time.Time
 }
 
-type Category struct {
-	ID       int64
-	UserID   sql.NullInt64
-	Name     string
-	Type     string
-	CreatedAt time.Time
+const categoryColumns = `id, user_id, parent_id, name, type, icon, color, is_archived, created_at`
+
+// categoryView выбирает категории с учетом пользовательских настроек из
+// category_overrides: название, иконка и цвет переопределяются, скрытая
+// категория считается архивной. $1 — id пользователя.
+const categoryView = `
+	SELECT c.id, c.user_id, c.parent_id, COALESCE(o.name, c.name), c.type, COALESCE(o.icon, c.icon), COALESCE(o.color, c.color),
+		c.is_archived OR COALESCE(o.is_hidden, false), c.created_at
+	FROM categories c
+	LEFT JOIN category_overrides o ON o.category_id = c.id AND o.user_id = $1`
+
+// CategoryOverride — настройки категории для конкретного пользователя.
+// Для глобальных категорий хранит переименование, иконку, цвет и скрытие,
+// для любых категорий — порядок в списке.
+type CategoryOverride struct {
+	UserID     int64
+	CategoryID int64
+	Name       sql.NullString
+	Icon       sql.NullString
+	Color      sql.NullString
+	IsHidden   bool
+	SortOrder  sql.NullInt32
+}
+
+func scanCategory(row pgx.Row) (*Category, error) {
+	var category Category
+	err := row.Scan(
+		&category.ID,
+		&category.UserID,
+		&category.ParentID,
+		&category.Name,
+		&category.Type,
+		&category.Icon,
+		&category.Color,
+		&category.IsArchived,
+		&category.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &category, nil
 }
 
 type Transaction struct {
@@ -54,45 +185,49 @@ type Transaction struct {
 	Description     sql.NullString
 	OperationDate   time.Time
 	CreatedAt       time.Time
+	PayeeID         sql.NullInt64
 }
 
-func (r *Repository) CreateAccount(ctx context.Context, userID int64, name, currency, balance string) (*Account, error) {
-	var account Account
-
+func (r *Repository) CreateAccount(ctx context.Context, account *Account) (*Account, error) {
 	query := `
-		INSERT INTO accounts (user_id, name, currency, balance)
-		VALUES ($1, $2, $3, $4)
-		RETURNING id, user_id, name, currency, balance, is_archived, is_default, created_at, updated_at
-	`
-
-	err := r.db.QueryRow(ctx, query, userID, name, currency, balance).Scan(
-		&account.ID,
-		&account.UserID,
-		&account.Name,
-		&account.Currency,
-		&account.Balance,
-		&account.IsArchived,
-		&account.IsDefault,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-	)
+		INSERT INTO accounts (user_id, name, currency, balance, type, credit_limit, statement_day, due_day, overdraft_policy,
+		                      group_name, icon, color, exclude_from_totals, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13,
+		        (SELECT COALESCE(MAX(sort_order) + 1, 0) FROM accounts WHERE user_id = $1))
+		RETURNING ` + accountColumns
+
+	result, err := scanAccount(r.db.QueryRow(ctx, query,
+		account.UserID,
+		account.Name,
+		account.Currency,
+		account.Balance,
+		account.Type,
+		account.CreditLimit,
+		account.StatementDay,
+		account.DueDay,
+		account.OverdraftPolicy,
+		account.Group,
+		account.Icon,
+		account.Color,
+		account.ExcludeFromTotals,
+	))
 	if err != nil {
 		r.logger.Error("failed to create account", zap.Error(err))
 		return nil, err
 	}
 
-	return &account, nil
+	return result, nil
 }
 
-func (r *Repository) ListAccounts(ctx context.Context, userID int64) ([]*Account, error) {
+func (r *Repository) ListAccounts(ctx context.Context, userID int64, includeArchived bool) ([]*Account, error) {
 	query := `
-		SELECT id, user_id, name, currency, balance, is_archived, is_default, created_at, updated_at
+		SELECT ` + accountColumns + `
 		FROM accounts
-		WHERE user_id = $1 AND is_archived = false
-		ORDER BY is_default DESC, created_at DESC
+		WHERE user_id = $1 AND (is_archived = false OR $2)
+		ORDER BY is_archived, sort_order, created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	rows, err := r.db.Query(ctx, query, userID, includeArchived)
 	if err != nil {
 		r.logger.Error("failed to list accounts", zap.Error(err))
 		return nil, err
@@ -101,55 +236,71 @@ func (r *Repository) ListAccounts(ctx context.Context, userID int64) ([]*Account
 
 	var accounts []*Account
 	for rows.Next() {
-		var account Account
-		if err := rows.Scan(
-			&account.ID,
-			&account.UserID,
-			&account.Name,
-			&account.Currency,
-			&account.Balance,
-			&account.IsArchived,
-			&account.IsDefault,
-			&account.CreatedAt,
-			&account.UpdatedAt,
-		); err != nil {
+		account, err := scanAccount(rows)
+		if err != nil {
 			return nil, err
 		}
-		accounts = append(accounts, &account)
+		accounts = append(accounts, account)
 	}
 
 	return accounts, nil
 }
 
 func (r *Repository) GetAccount(ctx context.Context, accountID, userID int64) (*Account, error) {
-	var account Account
-
 	query := `
-		SELECT id, user_id, name, currency, balance, is_archived, is_default, created_at, updated_at
+		SELECT ` + accountColumns + `
 		FROM accounts
 		WHERE id = $1 AND user_id = $2
 	`
 
-	err := r.db.QueryRow(ctx, query, accountID, userID).Scan(
-		&account.ID,
-		&account.UserID,
-		&account.Name,
-		&account.Currency,
-		&account.Balance,
-		&account.IsArchived,
-		&account.IsDefault,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-	)
+	account, err := scanAccount(r.db.QueryRow(ctx, query, accountID, userID))
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		r.logger.Error("failed to get account", zap.Error(err))
 		return nil, err
 	}
 
-	return &account, nil
+	return account, nil
+}
+
+// SetAccountSortOrder меняет позицию счета. Возвращает false, если счет
+// не найден у пользователя.
+func (r *Repository) SetAccountSortOrder(ctx context.Context, accountID, userID int64, sortOrder int32) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE accounts
+		SET sort_order = $1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3
+	`, sortOrder, accountID, userID)
+	if err != nil {
+		r.logger.Error("failed to set account sort order", zap.Error(err))
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// GetAccountForUpdate читает счет с блокировкой строки до конца транзакции.
+// Вызывать только внутри WithTx.
+func (r *Repository) GetAccountForUpdate(ctx context.Context, accountID, userID int64) (*Account, error) {
+	query := `
+		SELECT ` + accountColumns + `
+		FROM accounts
+		WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`
+
+	account, err := scanAccount(r.db.QueryRow(ctx, query, accountID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to lock account", zap.Error(err))
+		return nil, err
+	}
+
+	return account, nil
 }
 
 func (r *Repository) UpdateAccountBalance(ctx context.Context, accountID int64, delta string) error {
@@ -169,77 +320,77 @@ func (r *Repository) UpdateAccountBalance(ctx context.Context, accountID int64,
 	return nil
 }
 
-func (r *Repository) UpdateAccount(ctx context.Context, accountID, userID int64, name, balance string) (*Account, error) {
+func (r *Repository) UpdateAccount(ctx context.Context, account *Account) (*Account, error) {
 	query := `
 		UPDATE accounts
 		SET name = $1,
 		    balance = $2::numeric,
+		    credit_limit = $3,
+		    statement_day = $4,
+		    due_day = $5,
+		    overdraft_policy = $6,
+		    group_name = $7,
+		    icon = $8,
+		    color = $9,
+		    exclude_from_totals = $10,
 		    updated_at = NOW()
-		WHERE id = $3 AND user_id = $4
-		RETURNING id, user_id, name, currency, balance, is_archived, is_default, created_at, updated_at
-	`
-
-	var account Account
-	err := r.db.QueryRow(ctx, query, name, balance, accountID, userID).Scan(
-		&account.ID,
-		&account.UserID,
-		&account.Name,
-		&account.Currency,
-		&account.Balance,
-		&account.IsArchived,
-		&account.IsDefault,
-		&account.CreatedAt,
-		&account.UpdatedAt,
-	)
+		WHERE id = $11 AND user_id = $12
+		RETURNING ` + accountColumns
+
+	result, err := scanAccount(r.db.QueryRow(ctx, query,
+		account.Name,
+		account.Balance,
+		account.CreditLimit,
+		account.StatementDay,
+		account.DueDay,
+		account.OverdraftPolicy,
+		account.Group,
+		account.Icon,
+		account.Color,
+		account.ExcludeFromTotals,
+		account.ID,
+		account.UserID,
+	))
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		r.logger.Error("failed to update account", zap.Error(err))
 		return nil, err
 	}
 
-	return &account, nil
+	return result, nil
 }
 
-func (r *Repository) CreateCategory(ctx context.Context, userID int64, name, categoryType string) (*Category, error) {
-	var category Category
-
+func (r *Repository) CreateCategory(ctx context.Context, userID int64, name, categoryType string, parentID int64) (*Category, error) {
 	query := `
-		INSERT INTO categories (user_id, name, type)
-		VALUES ($1, $2, $3)
-		RETURNING id, user_id, name, type, created_at
-	`
+		INSERT INTO categories (user_id, parent_id, name, type)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + categoryColumns
 
 	var userIDNull sql.NullInt64
 	if userID > 0 {
 		userIDNull = sql.NullInt64{Int64: userID, Valid: true}
 	}
+	parentIDNull := sql.NullInt64{Int64: parentID, Valid: parentID > 0}
 
-	err := r.db.QueryRow(ctx, query, userIDNull, name, categoryType).Scan(
-		&category.ID,
-		&category.UserID,
-		&category.Name,
-		&category.Type,
-		&category.CreatedAt,
-	)
+	category, err := scanCategory(r.db.QueryRow(ctx, query, userIDNull, parentIDNull, name, categoryType))
 	if err != nil {
 		r.logger.Error("failed to create category", zap.Error(err))
 		return nil, err
 	}
 
-	return &category, nil
+	return category, nil
 }
 
-func (r *Repository) ListCategories(ctx context.Context, userID int64, categoryType string) ([]*Category, error) {
-	query := `
-		SELECT id, user_id, name, type, created_at
-		FROM categories
-		WHERE type = $1 AND (user_id = $2 OR user_id IS NULL)
-		ORDER BY user_id NULLS LAST, name
+func (r *Repository) ListCategories(ctx context.Context, userID int64, categoryType string, includeArchived bool) ([]*Category, error) {
+	query := categoryView + `
+		WHERE c.type = $2 AND (c.user_id = $1 OR c.user_id IS NULL)
+			AND ((c.is_archived = false AND COALESCE(o.is_hidden, false) = false) OR $3)
+		ORDER BY COALESCE(o.sort_order, 2147483647), c.user_id NULLS LAST, COALESCE(o.name, c.name)
 	`
 
-	rows, err := r.db.Query(ctx, query, categoryType, userID)
+	rows, err := r.db.Query(ctx, query, userID, categoryType, includeArchived)
 	if err != nil {
 		r.logger.Error("failed to list categories", zap.Error(err))
 		return nil, err
@@ -248,452 +399,3163 @@ func (r *Repository) ListCategories(ctx context.Context, userID int64, categoryT
 
 	var categories []*Category
 	for rows.Next() {
-		var category Category
-		if err := rows.Scan(
-			&category.ID,
-			&category.UserID,
-			&category.Name,
-			&category.Type,
-			&category.CreatedAt,
-		); err != nil {
+		category, err := scanCategory(rows)
+		if err != nil {
 			return nil, err
 		}
-		categories = append(categories, &category)
+		categories = append(categories, category)
 	}
 
 	return categories, nil
 }
 
-func (r *Repository) CreateTransaction(ctx context.Context, tx *Transaction) (*Transaction, error) {
-	query := `
-		INSERT INTO transactions (user_id, account_id, related_account_id, category_id, type, amount, currency, description, operation_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		RETURNING id, user_id, account_id, related_account_id, category_id, type, amount, currency, description, operation_date, created_at
+// GetCategory возвращает категорию пользователя или глобальную категорию.
+func (r *Repository) GetCategory(ctx context.Context, categoryID, userID int64) (*Category, error) {
+	query := categoryView + `
+		WHERE c.id = $2 AND (c.user_id = $1 OR c.user_id IS NULL)
 	`
 
-	var relatedAccountID sql.NullInt64
-	if tx.RelatedAccountID.Valid {
-		relatedAccountID = tx.RelatedAccountID
-	}
-
-	var categoryID sql.NullInt64
-	if tx.CategoryID.Valid {
-		categoryID = tx.CategoryID
+	category, err := scanCategory(r.db.QueryRow(ctx, query, userID, categoryID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("category not found")
+		}
+		r.logger.Error("failed to get category", zap.Error(err))
+		return nil, err
 	}
 
-	var description sql.NullString
-	if tx.Description.Valid {
-		description = tx.Description
-	}
+	return category, nil
+}
 
-	var operationDate time.Time
-	if tx.OperationDate.IsZero() {
-		operationDate = time.Now()
-	} else {
-		operationDate = tx.OperationDate
-	}
+func (r *Repository) UpdateCategory(ctx context.Context, category *Category) (*Category, error) {
+	query := `
+		UPDATE categories
+		SET name = $1, icon = $2, color = $3
+		WHERE id = $4 AND user_id = $5
+		RETURNING ` + categoryColumns
 
-	var result Transaction
-	err := r.db.QueryRow(ctx, query,
-		tx.UserID,
-		tx.AccountID,
-		relatedAccountID,
-		categoryID,
-		tx.Type,
-		tx.Amount,
-		tx.Currency,
-		description,
-		operationDate,
-	).Scan(
-		&result.ID,
-		&result.UserID,
-		&result.AccountID,
-		&result.RelatedAccountID,
-		&result.CategoryID,
-		&result.Type,
-		&result.Amount,
-		&result.Currency,
-		&result.Description,
-		&result.OperationDate,
-		&result.CreatedAt,
-	)
+	updated, err := scanCategory(r.db.QueryRow(ctx, query, category.Name, category.Icon, category.Color, category.ID, category.UserID))
 	if err != nil {
-		r.logger.Error("failed to create transaction", zap.Error(err))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("category not found")
+		}
+		r.logger.Error("failed to update category", zap.Error(err))
 		return nil, err
 	}
 
-	return &result, nil
+	return updated, nil
 }
 
-func (r *Repository) ListTransactions(ctx context.Context, userID int64, period string, limit int32, startDate, endDate string) ([]*Transaction, error) {
-	var query string
-	var args []interface{}
-
-	switch period {
-	case "today":
-		query = `
-			SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-			FROM transactions t
-			WHERE t.user_id = $1 AND DATE(t.operation_date) = CURRENT_DATE
-			ORDER BY t.operation_date DESC
-			LIMIT $2
-		`
-		args = []interface{}{userID, limit}
-	case "week":
-		query = `
-			SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-			FROM transactions t
-			WHERE t.user_id = $1 AND t.operation_date >= NOW() - INTERVAL '7 days'
-			ORDER BY t.operation_date DESC
-			LIMIT $2
-		`
-		args = []interface{}{userID, limit}
-	case "month":
-		query = `
-			SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-			FROM transactions t
-			WHERE t.user_id = $1 AND t.operation_date >= NOW() - INTERVAL '30 days'
-			ORDER BY t.operation_date DESC
-			LIMIT $2
-		`
-		args = []interface{}{userID, limit}
-	case "year":
-		query = `
-			SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-			FROM transactions t
-			WHERE t.user_id = $1 AND t.operation_date >= NOW() - INTERVAL '365 days'
-			ORDER BY t.operation_date DESC
-			LIMIT $2
-		`
-		args = []interface{}{userID, limit}
-	case "period":
-		if startDate != "" && endDate != "" {
-			query = `
-				SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-				FROM transactions t
-				WHERE t.user_id = $1 AND t.operation_date >= $2::timestamp AND t.operation_date <= $3::timestamp
-				ORDER BY t.operation_date DESC
-				LIMIT $4
-			`
-			args = []interface{}{userID, startDate, endDate, limit}
-		} else {
-			query = `
-				SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-				FROM transactions t
-				WHERE t.user_id = $1
-				ORDER BY t.operation_date DESC
-				LIMIT $2
-			`
-			args = []interface{}{userID, limit}
-		}
-	case "all":
-		query = `
-			SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-			FROM transactions t
-			WHERE t.user_id = $1
-			ORDER BY t.operation_date DESC
-			LIMIT $2
-		`
-		args = []interface{}{userID, limit}
-	default:
-		query = `
-			SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at
-			FROM transactions t
-			WHERE t.user_id = $1
-			ORDER BY t.operation_date DESC
-			LIMIT $2
-		`
-		args = []interface{}{userID, limit}
-	}
+func (r *Repository) GetCategoryOverride(ctx context.Context, userID, categoryID int64) (*CategoryOverride, error) {
+	override := CategoryOverride{UserID: userID, CategoryID: categoryID}
+	query := `
+		SELECT name, icon, color, is_hidden, sort_order
+		FROM category_overrides
+		WHERE user_id = $1 AND category_id = $2
+	`
 
-	rows, err := r.db.Query(ctx, query, args...)
-	if err != nil {
-		r.logger.Error("failed to list transactions", zap.Error(err))
+	err := r.db.QueryRow(ctx, query, userID, categoryID).Scan(
+		&override.Name,
+		&override.Icon,
+		&override.Color,
+		&override.IsHidden,
+		&override.SortOrder,
+	)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		r.logger.Error("failed to get category override", zap.Error(err))
 		return nil, err
 	}
-	defer rows.Close()
-
-	var transactions []*Transaction
-	for rows.Next() {
-		var tx Transaction
-		if err := rows.Scan(
-			&tx.ID,
-			&tx.UserID,
-			&tx.AccountID,
-			&tx.RelatedAccountID,
-			&tx.CategoryID,
-			&tx.Type,
-			&tx.Amount,
-			&tx.Currency,
-			&tx.Description,
-			&tx.OperationDate,
-			&tx.CreatedAt,
-		); err != nil {
-			return nil, err
-		}
-		transactions = append(transactions, &tx)
-	}
 
-	return transactions, nil
+	return &override, nil
 }
 
-func (r *Repository) GetTransactionWithDetails(ctx context.Context, transactionID int64) (*TransactionWithDetails, error) {
+func (r *Repository) SaveCategoryOverride(ctx context.Context, override *CategoryOverride) error {
 	query := `
-		SELECT 
-			t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency, t.description, t.operation_date, t.created_at,
-			c.name as category_name,
-			a.name as account_name
-		FROM transactions t
-		LEFT JOIN categories c ON t.category_id = c.id
-		LEFT JOIN accounts a ON t.account_id = a.id
-		WHERE t.id = $1
+		INSERT INTO category_overrides (user_id, category_id, name, icon, color, is_hidden, sort_order)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, category_id) DO UPDATE
+		SET name = EXCLUDED.name, icon = EXCLUDED.icon, color = EXCLUDED.color,
+			is_hidden = EXCLUDED.is_hidden, sort_order = EXCLUDED.sort_order, updated_at = NOW()
 	`
 
-	var result TransactionWithDetails
-	var categoryName, accountName sql.NullString
-
-	err := r.db.QueryRow(ctx, query, transactionID).Scan(
-		&result.ID,
-		&result.UserID,
-		&result.AccountID,
-		&result.RelatedAccountID,
-		&result.CategoryID,
-		&result.Type,
-		&result.Amount,
-		&result.Currency,
-		&result.Description,
-		&result.OperationDate,
-		&result.CreatedAt,
-		&categoryName,
-		&accountName,
+	_, err := r.db.Exec(ctx, query,
+		override.UserID,
+		override.CategoryID,
+		override.Name,
+		override.Icon,
+		override.Color,
+		override.IsHidden,
+		override.SortOrder,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		r.logger.Error("failed to get transaction with details", zap.Error(err))
-		return nil, err
-	}
-
-	if categoryName.Valid {
-		result.CategoryName = categoryName.String
-	}
-	if accountName.Valid {
-		result.AccountName = accountName.String
+		r.logger.Error("failed to save category override", zap.Error(err))
+		return err
 	}
 
-	return &result, nil
+	return nil
 }
 
-type TransactionWithDetails struct {
-	Transaction
-	CategoryName string
-	AccountName  string
-}
+func (r *Repository) SetCategorySortOrder(ctx context.Context, userID, categoryID int64, sortOrder int32) error {
+	query := `
+		INSERT INTO category_overrides (user_id, category_id, sort_order)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, category_id) DO UPDATE
+		SET sort_order = EXCLUDED.sort_order, updated_at = NOW()
+	`
 
-func (r *Repository) GetTransaction(ctx context.Context, transactionID, userID int64) (*Transaction, error) {
-	var tx Transaction
+	_, err := r.db.Exec(ctx, query, userID, categoryID, sortOrder)
+	if err != nil {
+		r.logger.Error("failed to set category sort order", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
 
+// GetFallbackCategoryID возвращает категорию по умолчанию, выбранную
+// пользователем, или глобальную категорию "Прочее" того же типа.
+func (r *Repository) GetFallbackCategoryID(ctx context.Context, userID int64, categoryType string) (int64, error) {
 	query := `
-		SELECT id, user_id, account_id, related_account_id, category_id, type, amount, currency, description, operation_date, created_at
-		FROM transactions
-		WHERE id = $1 AND user_id = $2
+		SELECT COALESCE(
+			(SELECT category_id FROM category_fallbacks WHERE user_id = $1 AND type = $2),
+			(SELECT id FROM categories WHERE name = 'Прочее' AND type = $2 AND user_id IS NULL LIMIT 1)
+		)
 	`
 
-	err := r.db.QueryRow(ctx, query, transactionID, userID).Scan(
-		&tx.ID,
-		&tx.UserID,
-		&tx.AccountID,
-		&tx.RelatedAccountID,
-		&tx.CategoryID,
-		&tx.Type,
-		&tx.Amount,
-		&tx.Currency,
-		&tx.Description,
-		&tx.OperationDate,
-		&tx.CreatedAt,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		r.logger.Error("failed to get transaction", zap.Error(err))
-		return nil, err
+	var categoryID sql.NullInt64
+	if err := r.db.QueryRow(ctx, query, userID, categoryType).Scan(&categoryID); err != nil {
+		r.logger.Error("failed to get fallback category", zap.Error(err))
+		return 0, err
+	}
+	if !categoryID.Valid {
+		return 0, fmt.Errorf("fallback category not found")
 	}
 
-	return &tx, nil
+	return categoryID.Int64, nil
 }
 
-func (r *Repository) UpdateTransaction(ctx context.Context, tx *Transaction) error {
+func (r *Repository) SetFallbackCategory(ctx context.Context, userID int64, categoryType string, categoryID int64) error {
 	query := `
-		UPDATE transactions
-		SET account_id = $1, related_account_id = $2, category_id = $3, amount = $4, currency = $5, description = $6, operation_date = $7
-		WHERE id = $8 AND user_id = $9
+		INSERT INTO category_fallbacks (user_id, type, category_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, type) DO UPDATE
+		SET category_id = EXCLUDED.category_id
 	`
 
-	var categoryID sql.NullInt64
-	if tx.CategoryID.Valid {
-		categoryID = tx.CategoryID
+	_, err := r.db.Exec(ctx, query, userID, categoryType, categoryID)
+	if err != nil {
+		r.logger.Error("failed to set fallback category", zap.Error(err))
+		return err
 	}
 
-	var relatedAccountID sql.NullInt64
-	if tx.RelatedAccountID.Valid {
-		relatedAccountID = tx.RelatedAccountID
-	}
+	return nil
+}
 
-	var description sql.NullString
-	if tx.Description.Valid {
-		description = tx.Description
+// ReplaceFallbackCategory переназначает категорию по умолчанию пользователя
+// с fromCategoryID на toCategoryID (0 — сбросить на "Прочее").
+func (r *Repository) ReplaceFallbackCategory(ctx context.Context, userID, fromCategoryID, toCategoryID int64) error {
+	var err error
+	if toCategoryID > 0 {
+		_, err = r.db.Exec(ctx, `
+			UPDATE category_fallbacks SET category_id = $1
+			WHERE user_id = $2 AND category_id = $3
+		`, toCategoryID, userID, fromCategoryID)
+	} else {
+		_, err = r.db.Exec(ctx, `
+			DELETE FROM category_fallbacks
+			WHERE user_id = $1 AND category_id = $2
+		`, userID, fromCategoryID)
 	}
-
-	_, err := r.db.Exec(ctx, query,
-		tx.AccountID,
-		relatedAccountID,
-		categoryID,
-		tx.Amount,
-		tx.Currency,
-		description,
-		tx.OperationDate,
-		tx.ID,
-		tx.UserID,
-	)
 	if err != nil {
-		r.logger.Error("failed to update transaction", zap.Error(err))
+		r.logger.Error("failed to replace fallback category", zap.Error(err))
 		return err
 	}
 
 	return nil
 }
 
-func (r *Repository) DeleteTransaction(ctx context.Context, transactionID, userID int64) (*Transaction, error) {
-	// Get transaction first
-	tx, err := r.GetTransaction(ctx, transactionID, userID)
+// SetCategoryArchived архивирует или возвращает из архива категорию
+// пользователя вместе со всеми подкатегориями.
+func (r *Repository) SetCategoryArchived(ctx context.Context, categoryID, userID int64, archived bool) error {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		UPDATE categories
+		SET is_archived = $3
+		WHERE id IN (SELECT id FROM subtree) AND user_id = $2
+	`
+
+	_, err := r.db.Exec(ctx, query, categoryID, userID, archived)
 	if err != nil {
-		return nil, err
+		r.logger.Error("failed to set category archived", zap.Error(err))
+		return err
 	}
-	if tx == nil {
-		return nil, fmt.Errorf("transaction not found")
+
+	return nil
+}
+
+// CategorySubtreeDepth возвращает высоту поддерева категории: 1 для категории
+// без подкатегорий.
+func (r *Repository) CategorySubtreeDepth(ctx context.Context, categoryID int64) (int, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id, 1 AS depth FROM categories WHERE id = $1
+			UNION ALL
+			SELECT c.id, s.depth + 1 FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT COALESCE(MAX(depth), 0) FROM subtree
+	`
+
+	var depth int
+	if err := r.db.QueryRow(ctx, query, categoryID).Scan(&depth); err != nil {
+		r.logger.Error("failed to get category subtree depth", zap.Error(err))
+		return 0, err
 	}
 
-	// Delete transaction
+	return depth, nil
+}
+
+// MoveChildCategories переносит подкатегории под другого родителя
+// (NULL — на верхний уровень).
+func (r *Repository) MoveChildCategories(ctx context.Context, fromCategoryID int64, toParentID sql.NullInt64, userID int64) error {
 	query := `
-		DELETE FROM transactions
-		WHERE id = $1 AND user_id = $2
+		UPDATE categories
+		SET parent_id = $1
+		WHERE parent_id = $2 AND user_id = $3
 	`
 
-	_, err = r.db.Exec(ctx, query, transactionID, userID)
+	_, err := r.db.Exec(ctx, query, toParentID, fromCategoryID, userID)
 	if err != nil {
-		r.logger.Error("failed to delete transaction", zap.Error(err))
-		return nil, err
+		r.logger.Error("failed to update child categories", zap.Error(err))
+		return fmt.Errorf("failed to update child categories: %w", err)
 	}
 
-	return tx, nil
+	return nil
 }
 
-func (r *Repository) DeleteAccount(ctx context.Context, accountID, userID int64) error {
-	// Проверяем, что счет принадлежит пользователю
-	account, err := r.GetAccount(ctx, accountID, userID)
+// CategorySubtreeIDs возвращает указанные категории вместе со всеми их
+// подкатегориями.
+func (r *Repository) CategorySubtreeIDs(ctx context.Context, categoryIDs []int64) ([]int64, error) {
+	query := `
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ANY($1)
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM subtree
+	`
+
+	rows, err := r.db.Query(ctx, query, categoryIDs)
 	if err != nil {
-		return err
+		r.logger.Error("failed to get category subtree", zap.Error(err))
+		return nil, err
 	}
-	if account == nil {
-		return fmt.Errorf("account not found")
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
 	}
 
-	// Запрещаем удаление базового счета
-	if account.IsDefault {
-		return fmt.Errorf("cannot delete default account")
+	return ids, rows.Err()
+}
+
+// MoveCategoryTransactions переносит операции пользователя в другую категорию
+// и возвращает их количество.
+func (r *Repository) MoveCategoryTransactions(ctx context.Context, fromCategoryID, toCategoryID, userID int64) (int64, error) {
+	query := `
+		UPDATE transactions
+		SET category_id = $1
+		WHERE category_id = $2 AND user_id = $3
+	`
+
+	tag, err := r.db.Exec(ctx, query, toCategoryID, fromCategoryID, userID)
+	if err != nil {
+		r.logger.Error("failed to update transactions", zap.Error(err))
+		return 0, fmt.Errorf("failed to update transactions: %w", err)
 	}
 
-	// Проверяем, что баланс равен нулю
-	if account.Balance != "0" && account.Balance != "0.00" {
-		return fmt.Errorf("cannot delete account with non-zero balance")
+	// Строки разбивки переносятся вместе с операциями
+	_, err = r.db.Exec(ctx, `
+		UPDATE transaction_splits s
+		SET category_id = $1
+		FROM transactions t
+		WHERE s.transaction_id = t.id AND s.category_id = $2 AND t.user_id = $3
+	`, toCategoryID, fromCategoryID, userID)
+	if err != nil {
+		r.logger.Error("failed to update transaction splits", zap.Error(err))
+		return 0, fmt.Errorf("failed to update transaction splits: %w", err)
 	}
 
-	// Помечаем счет как архивный вместо удаления
+	return tag.RowsAffected(), nil
+}
+
+// RemoveCategory удаляет категорию пользователя без переноса операций.
+func (r *Repository) RemoveCategory(ctx context.Context, categoryID, userID int64) error {
 	query := `
-		UPDATE accounts
-		SET is_archived = true, updated_at = NOW()
+		DELETE FROM categories
 		WHERE id = $1 AND user_id = $2
 	`
 
-	_, err = r.db.Exec(ctx, query, accountID, userID)
+	_, err := r.db.Exec(ctx, query, categoryID, userID)
 	if err != nil {
-		r.logger.Error("failed to delete account", zap.Error(err))
+		r.logger.Error("failed to delete category", zap.Error(err))
 		return err
 	}
 
 	return nil
 }
 
-func (r *Repository) DeleteCategory(ctx context.Context, categoryID, userID int64) error {
-	// Проверяем, что категория принадлежит пользователю
-	var category Category
+func (r *Repository) CreateTransaction(ctx context.Context, tx *Transaction) (*Transaction, error) {
 	query := `
-		SELECT id, user_id, name, type, created_at
-		FROM categories
-		WHERE id = $1 AND (user_id = $2 OR user_id IS NULL)
+		INSERT INTO transactions (user_id, account_id, related_account_id, category_id, type, amount, currency, description, operation_date, payee_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id, user_id, account_id, related_account_id, category_id, type, amount, currency, description, operation_date, created_at, payee_id
 	`
 
-	err := r.db.QueryRow(ctx, query, categoryID, userID).Scan(
-		&category.ID,
-		&category.UserID,
-		&category.Name,
-		&category.Type,
-		&category.CreatedAt,
+	var relatedAccountID sql.NullInt64
+	if tx.RelatedAccountID.Valid {
+		relatedAccountID = tx.RelatedAccountID
+	}
+
+	var categoryID sql.NullInt64
+	if tx.CategoryID.Valid {
+		categoryID = tx.CategoryID
+	}
+
+	var description sql.NullString
+	if tx.Description.Valid {
+		description = tx.Description
+	}
+
+	var operationDate time.Time
+	if tx.OperationDate.IsZero() {
+		operationDate = time.Now()
+	} else {
+		operationDate = tx.OperationDate
+	}
+
+	var result Transaction
+	err := r.db.QueryRow(ctx, query,
+		tx.UserID,
+		tx.AccountID,
+		relatedAccountID,
+		categoryID,
+		tx.Type,
+		tx.Amount,
+		tx.Currency,
+		description,
+		operationDate,
+		tx.PayeeID,
+	).Scan(
+		&result.ID,
+		&result.UserID,
+		&result.AccountID,
+		&result.RelatedAccountID,
+		&result.CategoryID,
+		&result.Type,
+		&result.Amount,
+		&result.Currency,
+		&result.Description,
+		&result.OperationDate,
+		&result.CreatedAt,
+		&result.PayeeID,
 	)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return fmt.Errorf("category not found")
+		r.logger.Error("failed to create transaction", zap.Error(err))
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// TransactionFilter — параметры выборки операций.
+// Порядок сортировки операций
+const (
+	SortDateDesc   = "date_desc"
+	SortDateAsc    = "date_asc"
+	SortAmountDesc = "amount_desc"
+	SortAmountAsc  = "amount_asc"
+)
+
+type TransactionFilter struct {
+	Period       string // "today", "week", "month", "year", "period", "all"
+	StartDate    string // для периода "period"
+	EndDate      string // для периода "period"
+	Limit        int32
+	Tags         []string // Операции хотя бы с одним из тегов
+	AccountIDs   []int64  // Операции по любому из счетов, включая входящие переводы
+	CategoryIDs  []int64  // Категория операции или одной из ее строк разбивки
+	Types        []string
+	MinAmount    string
+	MaxAmount    string
+	Query        string // Полнотекстовый поиск по описанию
+	Counterparty string // Нормализованная подстрока описания или имени получателя
+	Sort         string // По умолчанию SortDateDesc
+	After        *TransactionCursor
+}
+
+// TransactionCursor — позиция последней полученной операции: значение
+// поля сортировки и ID для операций с одинаковым значением.
+type TransactionCursor struct {
+	Value string // operation_date в RFC3339Nano или amount
+	ID    int64
+}
+
+// periodCondition возвращает условие на t.operation_date для периода и
+// добавляет его параметры в args.
+func periodCondition(period, startDate, endDate string, args *[]interface{}) string {
+	switch period {
+	case "today":
+		return "DATE(t.operation_date) = CURRENT_DATE"
+	case "week":
+		return "t.operation_date >= NOW() - INTERVAL '7 days'"
+	case "month":
+		return "t.operation_date >= NOW() - INTERVAL '30 days'"
+	case "year":
+		return "t.operation_date >= NOW() - INTERVAL '365 days'"
+	case "period":
+		if startDate != "" && endDate != "" {
+			*args = append(*args, startDate, endDate)
+			return fmt.Sprintf("t.operation_date >= $%d::timestamp AND t.operation_date <= $%d::timestamp", len(*args)-1, len(*args))
 		}
-		r.logger.Error("failed to get category", zap.Error(err))
-		return err
 	}
+	return "TRUE"
+}
 
-	// Нельзя удалять системные категории (user_id IS NULL)
-	if !category.UserID.Valid {
-		return fmt.Errorf("cannot delete system category")
+// ListTransactions возвращает операции пользователя по фильтру вместе
+// с названиями счетов и категорий одним запросом.
+func (r *Repository) ListTransactions(ctx context.Context, userID int64, filter TransactionFilter) ([]*TransactionWithDetails, error) {
+	args := []interface{}{userID}
+	conditions := []string{
+		"t.user_id = $1",
+		periodCondition(filter.Period, filter.StartDate, filter.EndDate, &args),
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, filter.Tags)
+		conditions = append(conditions, fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM transaction_tags tt JOIN tags g ON g.id = tt.tag_id
+				WHERE tt.transaction_id = t.id AND g.name = ANY($%d)
+			)`, len(args)))
+	}
+	if len(filter.AccountIDs) > 0 {
+		args = append(args, filter.AccountIDs)
+		conditions = append(conditions, fmt.Sprintf("(t.account_id = ANY($%d) OR t.related_account_id = ANY($%d))", len(args), len(args)))
+	}
+	if len(filter.CategoryIDs) > 0 {
+		args = append(args, filter.CategoryIDs)
+		conditions = append(conditions, fmt.Sprintf(`(t.category_id = ANY($%d) OR EXISTS (
+				SELECT 1 FROM transaction_splits s
+				WHERE s.transaction_id = t.id AND s.category_id = ANY($%d)
+			))`, len(args), len(args)))
+	}
+	if len(filter.Types) > 0 {
+		args = append(args, filter.Types)
+		conditions = append(conditions, fmt.Sprintf("t.type = ANY($%d)", len(args)))
+	}
+	if filter.MinAmount != "" {
+		args = append(args, filter.MinAmount)
+		conditions = append(conditions, fmt.Sprintf("t.amount >= $%d::numeric", len(args)))
+	}
+	if filter.MaxAmount != "" {
+		args = append(args, filter.MaxAmount)
+		conditions = append(conditions, fmt.Sprintf("t.amount <= $%d::numeric", len(args)))
+	}
+	if filter.Query != "" {
+		args = append(args, filter.Query)
+		conditions = append(conditions, fmt.Sprintf("t.search_vector @@ plainto_tsquery('russian', $%d)", len(args)))
+	}
+	if filter.Counterparty != "" {
+		args = append(args, filter.Counterparty)
+		conditions = append(conditions, fmt.Sprintf(`(strpos(replace(LOWER(COALESCE(t.description, '')), 'ё', 'е'), $%d) > 0 OR EXISTS (
+				SELECT 1 FROM payees p
+				WHERE p.id = t.payee_id AND (strpos(p.normalized_name, $%d) > 0 OR EXISTS (
+					SELECT 1 FROM payee_aliases a WHERE a.payee_id = p.id AND strpos(a.normalized_alias, $%d) > 0
+				))
+			))`, len(args), len(args), len(args)))
 	}
 
-	// Находим категорию "Прочее" того же типа
-	var otherCategoryID int64
-	otherQuery := `
-		SELECT id FROM categories
-		WHERE name = 'Прочее' AND type = $1 AND user_id IS NULL
-		LIMIT 1
-	`
+	// Keyset-пагинация: следующая страница начинается после курсора
+	column, cast, direction, compare := "t.operation_date", "timestamp", "DESC", "<"
+	switch filter.Sort {
+	case SortDateAsc:
+		direction, compare = "ASC", ">"
+	case SortAmountDesc:
+		column, cast = "t.amount", "numeric"
+	case SortAmountAsc:
+		column, cast, direction, compare = "t.amount", "numeric", "ASC", ">"
+	}
+	if filter.After != nil {
+		args = append(args, filter.After.Value, filter.After.ID)
+		conditions = append(conditions, fmt.Sprintf("(%s, t.id) %s ($%d::%s, $%d)", column, compare, len(args)-1, cast, len(args)))
+	}
+	args = append(args, filter.Limit)
 
-	err = r.db.QueryRow(ctx, otherQuery, category.Type).Scan(&otherCategoryID)
+	query := transactionDetailsView + `
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY ` + column + ` ` + direction + `, t.id ` + direction + `
+		LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		r.logger.Error("failed to find 'Прочее' category", zap.Error(err))
-		return fmt.Errorf("failed to find 'Прочее' category")
+		r.logger.Error("failed to list transactions", zap.Error(err))
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Переносим все транзакции в категорию "Прочее"
-	updateTxQuery := `
-		UPDATE transactions
-		SET category_id = $1
-		WHERE category_id = $2 AND user_id = $3
-	`
+	var transactions []*TransactionWithDetails
+	for rows.Next() {
+		tx, err := scanTransactionDetails(rows)
+		if err != nil {
+			r.logger.Error("failed to scan transaction", zap.Error(err))
+			return nil, err
+		}
+		transactions = append(transactions, tx)
+	}
+
+	return transactions, rows.Err()
+}
 
-	_, err = r.db.Exec(ctx, updateTxQuery, otherCategoryID, categoryID, userID)
+// SetTransactionTags заменяет теги операции. Новые теги создаются
+// в справочнике пользователя.
+func (r *Repository) SetTransactionTags(ctx context.Context, userID, transactionID int64, tags []string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM transaction_tags WHERE transaction_id = $1`, transactionID)
 	if err != nil {
-		r.logger.Error("failed to update transactions", zap.Error(err))
-		return fmt.Errorf("failed to update transactions: %w", err)
+		r.logger.Error("failed to clear transaction tags", zap.Error(err))
+		return err
+	}
+	if len(tags) == 0 {
+		return nil
 	}
 
-	// Удаляем категорию
-	deleteQuery := `
-		DELETE FROM categories
-		WHERE id = $1 AND user_id = $2
-	`
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO tags (user_id, name)
+		SELECT $1, unnest($2::text[])
+		ON CONFLICT (user_id, name) DO NOTHING
+	`, userID, tags)
+	if err != nil {
+		r.logger.Error("failed to create tags", zap.Error(err))
+		return err
+	}
 
-	_, err = r.db.Exec(ctx, deleteQuery, categoryID, userID)
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO transaction_tags (transaction_id, tag_id)
+		SELECT $1, id FROM tags WHERE user_id = $2 AND name = ANY($3)
+	`, transactionID, userID, tags)
 	if err != nil {
-		r.logger.Error("failed to delete category", zap.Error(err))
+		r.logger.Error("failed to link transaction tags", zap.Error(err))
 		return err
 	}
 
 	return nil
 }
 
+// ListTransactionTags возвращает теги операций, отсортированные по имени.
+func (r *Repository) ListTransactionTags(ctx context.Context, transactionIDs []int64) (map[int64][]string, error) {
+	result := make(map[int64][]string)
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT tt.transaction_id, g.name
+		FROM transaction_tags tt
+		JOIN tags g ON g.id = tt.tag_id
+		WHERE tt.transaction_id = ANY($1)
+		ORDER BY g.name
+	`, transactionIDs)
+	if err != nil {
+		r.logger.Error("failed to list transaction tags", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var transactionID int64
+		var name string
+		if err := rows.Scan(&transactionID, &name); err != nil {
+			return nil, err
+		}
+		result[transactionID] = append(result[transactionID], name)
+	}
+
+	return result, rows.Err()
+}
+
+// Split — строка разбивки операции со своей суммой и категорией.
+type Split struct {
+	ID            int64
+	TransactionID int64
+	CategoryID    sql.NullInt64
+	CategoryName  string
+	Amount        string
+	Note          sql.NullString
+}
+
+// SetTransactionSplits заменяет строки разбивки операции. Пустой список
+// снимает разбивку.
+func (r *Repository) SetTransactionSplits(ctx context.Context, transactionID int64, splits []*Split) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM transaction_splits WHERE transaction_id = $1`, transactionID)
+	if err != nil {
+		r.logger.Error("failed to clear transaction splits", zap.Error(err))
+		return err
+	}
+
+	for i, split := range splits {
+		_, err := r.db.Exec(ctx, `
+			INSERT INTO transaction_splits (transaction_id, category_id, amount, note, position)
+			VALUES ($1, $2, $3, $4, $5)
+		`, transactionID, split.CategoryID, split.Amount, split.Note, i)
+		if err != nil {
+			r.logger.Error("failed to create transaction split", zap.Error(err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListTransactionSplits возвращает строки разбивки операций в порядке ввода.
+// Названия категорий учитывают настройки пользователя.
+func (r *Repository) ListTransactionSplits(ctx context.Context, userID int64, transactionIDs []int64) (map[int64][]*Split, error) {
+	result := make(map[int64][]*Split)
+	if len(transactionIDs) == 0 {
+		return result, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT s.id, s.transaction_id, s.category_id, COALESCE(o.name, c.name), s.amount, s.note
+		FROM transaction_splits s
+		LEFT JOIN categories c ON c.id = s.category_id
+		LEFT JOIN category_overrides o ON o.category_id = c.id AND o.user_id = $1
+		WHERE s.transaction_id = ANY($2)
+		ORDER BY s.transaction_id, s.position
+	`, userID, transactionIDs)
+	if err != nil {
+		r.logger.Error("failed to list transaction splits", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var split Split
+		var categoryName sql.NullString
+		if err := rows.Scan(&split.ID, &split.TransactionID, &split.CategoryID, &categoryName, &split.Amount, &split.Note); err != nil {
+			return nil, err
+		}
+		split.CategoryName = categoryName.String
+		result[split.TransactionID] = append(result[split.TransactionID], &split)
+	}
+
+	return result, rows.Err()
+}
+
+type TagTotal struct {
+	Tag      string
+	Currency string
+	Total    string
+	Count    int64
+}
+
+// GetTagBreakdown суммирует операции указанного типа по тегам. Операция
+// с несколькими тегами учитывается в каждом из них.
+func (r *Repository) GetTagBreakdown(ctx context.Context, userID int64, txType, period, startDate, endDate string) ([]*TagTotal, error) {
+	args := []interface{}{userID, txType}
+	query := `
+		SELECT g.name, t.currency, SUM(t.amount), COUNT(*)
+		FROM transactions t
+		JOIN transaction_tags tt ON tt.transaction_id = t.id
+		JOIN tags g ON g.id = tt.tag_id
+		WHERE t.user_id = $1 AND t.type = $2 AND ` + periodCondition(period, startDate, endDate, &args) + `
+		GROUP BY g.name, t.currency
+		ORDER BY SUM(t.amount) DESC, g.name
+	`
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("failed to get tag breakdown", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*TagTotal
+	for rows.Next() {
+		var total TagTotal
+		if err := rows.Scan(&total.Tag, &total.Currency, &total.Total, &total.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &total)
+	}
+
+	return totals, rows.Err()
+}
+
+// transactionDetailsView выбирает операции вместе с названиями счетов
+// и категории. Названия категорий учитывают настройки пользователя $1.
+const transactionDetailsView = `
+	SELECT t.id, t.user_id, t.account_id, t.related_account_id, t.category_id, t.type, t.amount, t.currency,
+		t.description, t.operation_date, t.created_at, t.updated_at, t.payee_id,
+		COALESCE(o.name, c.name), a.name, ra.name, p.name
+	FROM transactions t
+	LEFT JOIN categories c ON c.id = t.category_id
+	LEFT JOIN category_overrides o ON o.category_id = c.id AND o.user_id = $1
+	LEFT JOIN accounts a ON a.id = t.account_id
+	LEFT JOIN accounts ra ON ra.id = t.related_account_id
+	LEFT JOIN payees p ON p.id = t.payee_id`
+
+func scanTransactionDetails(row pgx.Row) (*TransactionWithDetails, error) {
+	var result TransactionWithDetails
+	var categoryName, accountName, relatedAccountName, payeeName sql.NullString
+	err := row.Scan(
+		&result.ID,
+		&result.UserID,
+		&result.AccountID,
+		&result.RelatedAccountID,
+		&result.CategoryID,
+		&result.Type,
+		&result.Amount,
+		&result.Currency,
+		&result.Description,
+		&result.OperationDate,
+		&result.CreatedAt,
+		&result.UpdatedAt,
+		&result.PayeeID,
+		&categoryName,
+		&accountName,
+		&relatedAccountName,
+		&payeeName,
+	)
+	if err != nil {
+		return nil, err
+	}
+	result.CategoryName = categoryName.String
+	result.AccountName = accountName.String
+	result.RelatedAccountName = relatedAccountName.String
+	result.PayeeName = payeeName.String
+	return &result, nil
+}
+
+// GetTransactionWithDetails возвращает операцию пользователя с названиями
+// счетов и категории или nil, если такой операции у пользователя нет.
+func (r *Repository) GetTransactionWithDetails(ctx context.Context, transactionID, userID int64) (*TransactionWithDetails, error) {
+	query := transactionDetailsView + `
+		WHERE t.user_id = $1 AND t.id = $2
+	`
+
+	result, err := scanTransactionDetails(r.db.QueryRow(ctx, query, userID, transactionID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to get transaction with details", zap.Error(err))
+		return nil, err
+	}
+
+	return result, nil
+}
+
+type TransactionWithDetails struct {
+	Transaction
+	UpdatedAt          time.Time
+	CategoryName       string
+	AccountName        string
+	RelatedAccountName string
+	PayeeName          string
+	Tags               []string
+	Splits             []*Split
+}
+
+func (r *Repository) GetTransaction(ctx context.Context, transactionID, userID int64) (*Transaction, error) {
+	var tx Transaction
+
+	query := `
+		SELECT id, user_id, account_id, related_account_id, category_id, type, amount, currency, description, operation_date, created_at, payee_id
+		FROM transactions
+		WHERE id = $1 AND user_id = $2
+	`
+
+	err := r.db.QueryRow(ctx, query, transactionID, userID).Scan(
+		&tx.ID,
+		&tx.UserID,
+		&tx.AccountID,
+		&tx.RelatedAccountID,
+		&tx.CategoryID,
+		&tx.Type,
+		&tx.Amount,
+		&tx.Currency,
+		&tx.Description,
+		&tx.OperationDate,
+		&tx.CreatedAt,
+		&tx.PayeeID,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to get transaction", zap.Error(err))
+		return nil, err
+	}
+
+	return &tx, nil
+}
+
+func (r *Repository) UpdateTransaction(ctx context.Context, tx *Transaction) error {
+	query := `
+		UPDATE transactions
+		SET account_id = $1, related_account_id = $2, category_id = $3, amount = $4, currency = $5, description = $6, operation_date = $7,
+		    payee_id = $10, updated_at = NOW()
+		WHERE id = $8 AND user_id = $9
+	`
+
+	var categoryID sql.NullInt64
+	if tx.CategoryID.Valid {
+		categoryID = tx.CategoryID
+	}
+
+	var relatedAccountID sql.NullInt64
+	if tx.RelatedAccountID.Valid {
+		relatedAccountID = tx.RelatedAccountID
+	}
+
+	var description sql.NullString
+	if tx.Description.Valid {
+		description = tx.Description
+	}
+
+	_, err := r.db.Exec(ctx, query,
+		tx.AccountID,
+		relatedAccountID,
+		categoryID,
+		tx.Amount,
+		tx.Currency,
+		description,
+		tx.OperationDate,
+		tx.ID,
+		tx.UserID,
+		tx.PayeeID,
+	)
+	if err != nil {
+		r.logger.Error("failed to update transaction", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteTransaction(ctx context.Context, transactionID, userID int64) (*Transaction, error) {
+	// Get transaction first
+	tx, err := r.GetTransaction(ctx, transactionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction not found")
+	}
+
+	// Delete transaction
+	query := `
+		DELETE FROM transactions
+		WHERE id = $1 AND user_id = $2
+	`
+
+	_, err = r.db.Exec(ctx, query, transactionID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete transaction", zap.Error(err))
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (r *Repository) DeleteAccount(ctx context.Context, accountID, userID int64) error {
+	// Проверяем, что счет принадлежит пользователю
+	account, err := r.GetAccount(ctx, accountID, userID)
+	if err != nil {
+		return err
+	}
+	if account == nil {
+		return fmt.Errorf("account not found")
+	}
+
+	// Запрещаем удаление базового счета
+	if account.IsDefault {
+		return fmt.Errorf("cannot delete default account")
+	}
+
+	// Проверяем, что баланс равен нулю
+	if account.Balance != "0" && account.Balance != "0.00" {
+		return fmt.Errorf("cannot delete account with non-zero balance")
+	}
+
+	// Помечаем счет как архивный вместо удаления
+	query := `
+		UPDATE accounts
+		SET is_archived = true, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`
+
+	_, err = r.db.Exec(ctx, query, accountID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete account", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *Repository) UnarchiveAccount(ctx context.Context, accountID, userID int64) (*Account, error) {
+	query := `
+		UPDATE accounts
+		SET is_archived = false, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + accountColumns
+
+	account, err := scanAccount(r.db.QueryRow(ctx, query, accountID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to unarchive account", zap.Error(err))
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// DeleteAccountTransactions удаляет историю операций счета. Переводы с другими
+// счетами не удаляются, а становятся доходом или расходом второго счета,
+// чтобы его история оставалась согласованной с балансом.
+func (r *Repository) DeleteAccountTransactions(ctx context.Context, accountID, userID int64) (int64, error) {
+	// Перевод с удаляемого счета — доход счета-получателя
+	_, err := r.db.Exec(ctx, `
+		UPDATE transactions
+		SET type = 'income', account_id = related_account_id, related_account_id = NULL
+		WHERE user_id = $2 AND type = 'transfer' AND account_id = $1 AND related_account_id <> $1
+	`, accountID, userID)
+	if err != nil {
+		r.logger.Error("failed to detach outgoing transfers", zap.Error(err))
+		return 0, err
+	}
+
+	// Перевод на удаляемый счет — расход счета-отправителя
+	_, err = r.db.Exec(ctx, `
+		UPDATE transactions
+		SET type = 'expense', related_account_id = NULL
+		WHERE user_id = $2 AND type = 'transfer' AND related_account_id = $1 AND account_id <> $1
+	`, accountID, userID)
+	if err != nil {
+		r.logger.Error("failed to detach incoming transfers", zap.Error(err))
+		return 0, err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM transactions
+		WHERE user_id = $2 AND (account_id = $1 OR related_account_id = $1)
+	`, accountID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete account transactions", zap.Error(err))
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// ReassignAccountTransactions переносит историю операций на другой счет.
+// Переводы между этими двумя счетами после переноса теряют смысл и удаляются:
+// на сумму остатков двух счетов они не влияли. Остаток переносимого счета
+// добавляет к целевому вызывающий.
+func (r *Repository) ReassignAccountTransactions(ctx context.Context, fromAccountID, toAccountID, userID int64) (int64, error) {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM transactions
+		WHERE user_id = $3 AND type = 'transfer'
+		  AND ((account_id = $1 AND related_account_id = $2) OR (account_id = $2 AND related_account_id = $1))
+	`, fromAccountID, toAccountID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete transfers between accounts", zap.Error(err))
+		return 0, err
+	}
+
+	tag, err := r.db.Exec(ctx, `
+		UPDATE transactions
+		SET account_id = $2
+		WHERE user_id = $3 AND account_id = $1
+	`, fromAccountID, toAccountID, userID)
+	if err != nil {
+		r.logger.Error("failed to reassign transactions", zap.Error(err))
+		return 0, err
+	}
+	reassigned := tag.RowsAffected()
+
+	tag, err = r.db.Exec(ctx, `
+		UPDATE transactions
+		SET related_account_id = $2
+		WHERE user_id = $3 AND related_account_id = $1
+	`, fromAccountID, toAccountID, userID)
+	if err != nil {
+		r.logger.Error("failed to reassign related transactions", zap.Error(err))
+		return 0, err
+	}
+
+	return reassigned + tag.RowsAffected(), nil
+}
+
+// DeleteArchivedAccount удаляет строку архивного счета. История операций
+// должна быть удалена или перенесена заранее.
+func (r *Repository) DeleteArchivedAccount(ctx context.Context, accountID, userID int64) error {
+	_, err := r.db.Exec(ctx, `
+		DELETE FROM accounts
+		WHERE id = $1 AND user_id = $2 AND is_archived = true
+	`, accountID, userID)
+	if err != nil {
+		r.logger.Error("failed to purge account", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCategory удаляет категорию пользователя. Подкатегории и операции
+// переходят к родительской категории, а у категорий верхнего уровня —
+// в категорию по умолчанию того же типа.
+func (r *Repository) DeleteCategory(ctx context.Context, categoryID, userID int64) error {
+	category, err := r.GetCategory(ctx, categoryID, userID)
+	if err != nil {
+		return err
+	}
+
+	// Нельзя удалять системные категории (user_id IS NULL)
+	if !category.UserID.Valid {
+		return fmt.Errorf("cannot delete system category")
+	}
+
+	// Удаляемая категория перестает быть категорией по умолчанию
+	if err := r.ReplaceFallbackCategory(ctx, userID, categoryID, 0); err != nil {
+		return err
+	}
+
+	targetCategoryID := category.ParentID.Int64
+	if !category.ParentID.Valid {
+		targetCategoryID, err = r.GetFallbackCategoryID(ctx, userID, category.Type)
+		if err != nil {
+			return fmt.Errorf("failed to find fallback category: %w", err)
+		}
+	}
+
+	// Подкатегории поднимаются на уровень удаляемой категории
+	if err := r.MoveChildCategories(ctx, categoryID, category.ParentID, userID); err != nil {
+		return err
+	}
+
+	// Переносим все транзакции в родительскую категорию
+	if _, err := r.MoveCategoryTransactions(ctx, categoryID, targetCategoryID, userID); err != nil {
+		return err
+	}
+
+	// Удаляем категорию
+	return r.RemoveCategory(ctx, categoryID, userID)
+}
+
+
+// Payee — получатель или источник платежа. Имя и псевдонимы хранятся
+// также в нормализованном виде для поиска.
+type Payee struct {
+	ID                int64
+	UserID            int64
+	Name              string
+	NormalizedName    string
+	DefaultCategoryID sql.NullInt64
+	DefaultAccountID  sql.NullInt64
+	Aliases           []string
+	CreatedAt         time.Time
+}
+
+const payeeView = `
+	SELECT p.id, p.user_id, p.name, p.normalized_name, p.default_category_id, p.default_account_id, p.created_at,
+		COALESCE(ARRAY(SELECT a.alias FROM payee_aliases a WHERE a.payee_id = p.id ORDER BY a.alias), '{}')
+	FROM payees p`
+
+func scanPayee(row pgx.Row) (*Payee, error) {
+	var payee Payee
+	err := row.Scan(
+		&payee.ID,
+		&payee.UserID,
+		&payee.Name,
+		&payee.NormalizedName,
+		&payee.DefaultCategoryID,
+		&payee.DefaultAccountID,
+		&payee.CreatedAt,
+		&payee.Aliases,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &payee, nil
+}
+
+func (r *Repository) CreatePayee(ctx context.Context, payee *Payee) (*Payee, error) {
+	query := `
+		INSERT INTO payees (user_id, name, normalized_name, default_category_id, default_account_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	var id int64
+	err := r.db.QueryRow(ctx, query, payee.UserID, payee.Name, payee.NormalizedName, payee.DefaultCategoryID, payee.DefaultAccountID).Scan(&id)
+	if err != nil {
+		r.logger.Error("failed to create payee", zap.Error(err))
+		return nil, err
+	}
+
+	return r.GetPayee(ctx, id, payee.UserID)
+}
+
+func (r *Repository) UpdatePayee(ctx context.Context, payee *Payee) (*Payee, error) {
+	query := `
+		UPDATE payees
+		SET name = $1, normalized_name = $2, default_category_id = $3, default_account_id = $4, updated_at = NOW()
+		WHERE id = $5 AND user_id = $6
+	`
+
+	_, err := r.db.Exec(ctx, query, payee.Name, payee.NormalizedName, payee.DefaultCategoryID, payee.DefaultAccountID, payee.ID, payee.UserID)
+	if err != nil {
+		r.logger.Error("failed to update payee", zap.Error(err))
+		return nil, err
+	}
+
+	return r.GetPayee(ctx, payee.ID, payee.UserID)
+}
+
+// GetPayee возвращает получателя пользователя или nil, если его нет.
+func (r *Repository) GetPayee(ctx context.Context, payeeID, userID int64) (*Payee, error) {
+	query := payeeView + `
+		WHERE p.id = $1 AND p.user_id = $2
+	`
+
+	payee, err := scanPayee(r.db.QueryRow(ctx, query, payeeID, userID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to get payee", zap.Error(err))
+		return nil, err
+	}
+
+	return payee, nil
+}
+
+// FindPayee ищет получателя по нормализованному имени или псевдониму.
+// Возвращает nil, если совпадений нет.
+func (r *Repository) FindPayee(ctx context.Context, userID int64, normalizedName string) (*Payee, error) {
+	query := payeeView + `
+		WHERE p.user_id = $1 AND (p.normalized_name = $2 OR EXISTS (
+			SELECT 1 FROM payee_aliases a WHERE a.payee_id = p.id AND a.normalized_alias = $2
+		))
+		LIMIT 1
+	`
+
+	payee, err := scanPayee(r.db.QueryRow(ctx, query, userID, normalizedName))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to find payee", zap.Error(err))
+		return nil, err
+	}
+
+	return payee, nil
+}
+
+func (r *Repository) ListPayees(ctx context.Context, userID int64) ([]*Payee, error) {
+	query := payeeView + `
+		WHERE p.user_id = $1
+		ORDER BY p.name
+	`
+
+	rows, err := r.db.Query(ctx, query, userID)
+	if err != nil {
+		r.logger.Error("failed to list payees", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payees []*Payee
+	for rows.Next() {
+		payee, err := scanPayee(rows)
+		if err != nil {
+			return nil, err
+		}
+		payees = append(payees, payee)
+	}
+
+	return payees, rows.Err()
+}
+
+// SetPayeeAliases заменяет псевдонимы получателя. aliases и normalized
+// идут парами в одном порядке.
+func (r *Repository) SetPayeeAliases(ctx context.Context, payeeID, userID int64, aliases, normalized []string) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM payee_aliases WHERE payee_id = $1`, payeeID)
+	if err != nil {
+		r.logger.Error("failed to clear payee aliases", zap.Error(err))
+		return err
+	}
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	_, err = r.db.Exec(ctx, `
+		INSERT INTO payee_aliases (payee_id, user_id, alias, normalized_alias)
+		SELECT $1, $2, a.alias, a.normalized_alias
+		FROM unnest($3::text[], $4::text[]) AS a(alias, normalized_alias)
+	`, payeeID, userID, aliases, normalized)
+	if err != nil {
+		r.logger.Error("failed to create payee aliases", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// PayeeNameTaken сообщает, занято ли нормализованное имя другим получателем
+// пользователя — как имя или как псевдоним.
+func (r *Repository) PayeeNameTaken(ctx context.Context, userID int64, normalizedName string, exceptPayeeID int64) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM payees WHERE user_id = $1 AND normalized_name = $2 AND id <> $3
+			UNION ALL
+			SELECT 1 FROM payee_aliases WHERE user_id = $1 AND normalized_alias = $2 AND payee_id <> $3
+		)
+	`
+
+	var taken bool
+	if err := r.db.QueryRow(ctx, query, userID, normalizedName, exceptPayeeID).Scan(&taken); err != nil {
+		r.logger.Error("failed to check payee name", zap.Error(err))
+		return false, err
+	}
+
+	return taken, nil
+}
+
+func (r *Repository) DeletePayee(ctx context.Context, payeeID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM payees WHERE id = $1 AND user_id = $2`, payeeID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete payee", zap.Error(err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+type PayeeTotal struct {
+	PayeeID  int64
+	Name     string
+	Currency string
+	Total    string
+	Count    int64
+}
+
+// GetPayeeBreakdown суммирует операции указанного типа по получателям
+// и возвращает limit крупнейших.
+func (r *Repository) GetPayeeBreakdown(ctx context.Context, userID int64, txType, period, startDate, endDate string, limit int32) ([]*PayeeTotal, error) {
+	args := []interface{}{userID, txType}
+	query := `
+		SELECT p.id, p.name, t.currency, SUM(t.amount), COUNT(*)
+		FROM transactions t
+		JOIN payees p ON p.id = t.payee_id
+		WHERE t.user_id = $1 AND t.type = $2 AND ` + periodCondition(period, startDate, endDate, &args) + `
+		GROUP BY p.id, p.name, t.currency
+		ORDER BY SUM(t.amount) DESC, p.name
+	`
+	args = append(args, limit)
+	query += `LIMIT $` + strconv.Itoa(len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("failed to get payee breakdown", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*PayeeTotal
+	for rows.Next() {
+		var total PayeeTotal
+		if err := rows.Scan(&total.PayeeID, &total.Name, &total.Currency, &total.Total, &total.Count); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &total)
+	}
+
+	return totals, rows.Err()
+}
+
+// Rule — правило автоматической обработки операций. Пустые условия
+// не проверяются, пустые действия ничего не меняют.
+type Rule struct {
+	ID                  int64
+	UserID              int64
+	Name                string
+	Position            int32
+	IsActive            bool
+	StopProcessing      bool
+	TransactionType     sql.NullString
+	DescriptionContains sql.NullString
+	DescriptionRegex    sql.NullString
+	MinAmount           sql.NullString
+	MaxAmount           sql.NullString
+	AccountID           sql.NullInt64
+	PayeeID             sql.NullInt64
+	SetCategoryID       sql.NullInt64
+	AddTags             []string
+	SetPayeeID          sql.NullInt64
+	SetDescription      sql.NullString
+	CreatedAt           time.Time
+}
+
+const ruleColumns = `id, user_id, name, position, is_active, stop_processing, transaction_type, description_contains,
+	description_regex, min_amount::text, max_amount::text, account_id, payee_id, set_category_id, add_tags, set_payee_id,
+	set_description, created_at`
+
+func scanRule(row pgx.Row) (*Rule, error) {
+	var rule Rule
+	err := row.Scan(
+		&rule.ID,
+		&rule.UserID,
+		&rule.Name,
+		&rule.Position,
+		&rule.IsActive,
+		&rule.StopProcessing,
+		&rule.TransactionType,
+		&rule.DescriptionContains,
+		&rule.DescriptionRegex,
+		&rule.MinAmount,
+		&rule.MaxAmount,
+		&rule.AccountID,
+		&rule.PayeeID,
+		&rule.SetCategoryID,
+		&rule.AddTags,
+		&rule.SetPayeeID,
+		&rule.SetDescription,
+		&rule.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// CreateRule добавляет правило в конец списка правил пользователя.
+func (r *Repository) CreateRule(ctx context.Context, rule *Rule) (*Rule, error) {
+	query := `
+		INSERT INTO rules (user_id, name, position, is_active, stop_processing, transaction_type, description_contains,
+			description_regex, min_amount, max_amount, account_id, payee_id, set_category_id, add_tags, set_payee_id, set_description)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(position), 0) + 1 FROM rules WHERE user_id = $1), $3, $4, $5, $6,
+			$7, $8::numeric, $9::numeric, $10, $11, $12, $13, $14, $15)
+		RETURNING ` + ruleColumns
+
+	created, err := scanRule(r.db.QueryRow(ctx, query,
+		rule.UserID,
+		rule.Name,
+		rule.IsActive,
+		rule.StopProcessing,
+		rule.TransactionType,
+		rule.DescriptionContains,
+		rule.DescriptionRegex,
+		rule.MinAmount,
+		rule.MaxAmount,
+		rule.AccountID,
+		rule.PayeeID,
+		rule.SetCategoryID,
+		rule.AddTags,
+		rule.SetPayeeID,
+		rule.SetDescription,
+	))
+	if err != nil {
+		r.logger.Error("failed to create rule", zap.Error(err))
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// UpdateRule заменяет условия и действия правила, не меняя его позицию.
+func (r *Repository) UpdateRule(ctx context.Context, rule *Rule) (*Rule, error) {
+	query := `
+		UPDATE rules
+		SET name = $3, is_active = $4, stop_processing = $5, transaction_type = $6, description_contains = $7,
+			description_regex = $8, min_amount = $9::numeric, max_amount = $10::numeric, account_id = $11, payee_id = $12,
+			set_category_id = $13, add_tags = $14, set_payee_id = $15, set_description = $16, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING ` + ruleColumns
+
+	updated, err := scanRule(r.db.QueryRow(ctx, query,
+		rule.ID,
+		rule.UserID,
+		rule.Name,
+		rule.IsActive,
+		rule.StopProcessing,
+		rule.TransactionType,
+		rule.DescriptionContains,
+		rule.DescriptionRegex,
+		rule.MinAmount,
+		rule.MaxAmount,
+		rule.AccountID,
+		rule.PayeeID,
+		rule.SetCategoryID,
+		rule.AddTags,
+		rule.SetPayeeID,
+		rule.SetDescription,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to update rule", zap.Error(err))
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// ListRules возвращает правила пользователя в порядке применения.
+func (r *Repository) ListRules(ctx context.Context, userID int64, activeOnly bool) ([]*Rule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM rules
+		WHERE user_id = $1 AND (is_active OR NOT $2)
+		ORDER BY position, id
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, activeOnly)
+	if err != nil {
+		r.logger.Error("failed to list rules", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*Rule
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+func (r *Repository) DeleteRule(ctx context.Context, ruleID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM rules WHERE id = $1 AND user_id = $2`, ruleID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete rule", zap.Error(err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *Repository) SetRulePosition(ctx context.Context, ruleID, userID int64, position int32) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE rules
+		SET position = $1, updated_at = NOW()
+		WHERE id = $2 AND user_id = $3
+	`, position, ruleID, userID)
+	if err != nil {
+		r.logger.Error("failed to set rule position", zap.Error(err))
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// UpdateTransactionLabels меняет категорию, получателя и описание операции,
+// не затрагивая суммы и балансы.
+func (r *Repository) UpdateTransactionLabels(ctx context.Context, tx *Transaction) error {
+	query := `
+		UPDATE transactions
+		SET category_id = $1, payee_id = $2, description = $3, updated_at = NOW()
+		WHERE id = $4 AND user_id = $5
+	`
+
+	_, err := r.db.Exec(ctx, query, tx.CategoryID, tx.PayeeID, tx.Description, tx.ID, tx.UserID)
+	if err != nil {
+		r.logger.Error("failed to update transaction labels", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+// Attachment — файл, приложенный к операции. Содержимое лежит в хранилище
+// вложений под ключом StorageKey.
+type Attachment struct {
+	ID            int64
+	UserID        int64
+	TransactionID int64
+	StorageKey    string
+	FileName      string
+	ContentType   string
+	SizeBytes     int64
+	CreatedAt     time.Time
+}
+
+const attachmentColumns = `id, user_id, transaction_id, storage_key, file_name, content_type, size_bytes, created_at`
+
+func scanAttachment(row pgx.Row) (*Attachment, error) {
+	var attachment Attachment
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.UserID,
+		&attachment.TransactionID,
+		&attachment.StorageKey,
+		&attachment.FileName,
+		&attachment.ContentType,
+		&attachment.SizeBytes,
+		&attachment.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
+
+func (r *Repository) CreateAttachment(ctx context.Context, attachment *Attachment) (*Attachment, error) {
+	query := `
+		INSERT INTO attachments (user_id, transaction_id, storage_key, file_name, content_type, size_bytes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + attachmentColumns
+
+	created, err := scanAttachment(r.db.QueryRow(ctx, query,
+		attachment.UserID,
+		attachment.TransactionID,
+		attachment.StorageKey,
+		attachment.FileName,
+		attachment.ContentType,
+		attachment.SizeBytes,
+	))
+	if err != nil {
+		r.logger.Error("failed to create attachment", zap.Error(err))
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// GetAttachment возвращает вложение пользователя или nil, если его нет.
+func (r *Repository) GetAttachment(ctx context.Context, attachmentID, userID int64) (*Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1 AND user_id = $2`
+
+	attachment, err := scanAttachment(r.db.QueryRow(ctx, query, attachmentID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get attachment", zap.Error(err))
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (r *Repository) ListAttachments(ctx context.Context, transactionID, userID int64) ([]*Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE transaction_id = $1 AND user_id = $2
+		ORDER BY created_at, id
+	`
+
+	rows, err := r.db.Query(ctx, query, transactionID, userID)
+	if err != nil {
+		r.logger.Error("failed to list attachments", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attachments []*Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// DeleteAttachment удаляет запись о вложении и возвращает ее, чтобы
+// вызывающий удалил содержимое из хранилища. nil — вложения нет.
+func (r *Repository) DeleteAttachment(ctx context.Context, attachmentID, userID int64) (*Attachment, error) {
+	query := `DELETE FROM attachments WHERE id = $1 AND user_id = $2 RETURNING ` + attachmentColumns
+
+	attachment, err := scanAttachment(r.db.QueryRow(ctx, query, attachmentID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to delete attachment", zap.Error(err))
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+// AttachmentUsage возвращает суммарный размер вложений пользователя в байтах
+// и число вложений у операции.
+func (r *Repository) AttachmentUsage(ctx context.Context, userID, transactionID int64) (int64, int, error) {
+	query := `
+		SELECT COALESCE(SUM(size_bytes), 0), COUNT(*) FILTER (WHERE transaction_id = $2)
+		FROM attachments
+		WHERE user_id = $1
+	`
+
+	var total int64
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID, transactionID).Scan(&total, &count); err != nil {
+		r.logger.Error("failed to get attachment usage", zap.Error(err))
+		return 0, 0, err
+	}
+
+	return total, count, nil
+}
+
+// LockUserAttachments сериализует загрузки одного пользователя до конца
+// транзакции, чтобы параллельные загрузки не превысили квоту.
+func (r *Repository) LockUserAttachments(ctx context.Context, userID int64) error {
+	_, err := r.db.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext('attachments'), $1::int)`, userID)
+	if err != nil {
+		r.logger.Error("failed to lock user attachments", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// TransactionAttachmentKeys возвращает ключи хранилища для вложений
+// операций: указанной операции или всех операций, затрагивающих счет.
+// Записи удаляются каскадно вместе с операциями, а содержимое нужно
+// удалить из хранилища отдельно.
+func (r *Repository) TransactionAttachmentKeys(ctx context.Context, userID, transactionID, accountID int64) ([]string, error) {
+	query := `
+		SELECT a.storage_key
+		FROM attachments a
+		JOIN transactions t ON t.id = a.transaction_id
+		WHERE a.user_id = $1
+			AND (t.id = $2 OR t.account_id = $3 OR t.related_account_id = $3)
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, transactionID, accountID)
+	if err != nil {
+		r.logger.Error("failed to list attachment keys", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// MissingAttachmentKeys возвращает ключи, для которых больше нет записи
+// о вложении.
+func (r *Repository) MissingAttachmentKeys(ctx context.Context, keys []string) ([]string, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	rows, err := r.db.Query(ctx, `
+		SELECT k FROM unnest($1::text[]) AS k
+		WHERE NOT EXISTS (SELECT 1 FROM attachments a WHERE a.storage_key = k)
+	`, keys)
+	if err != nil {
+		r.logger.Error("failed to check attachment keys", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var missing []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		missing = append(missing, key)
+	}
+
+	return missing, rows.Err()
+}
+
+// Receipt — фискальные реквизиты чека, по которому создана операция
+type Receipt struct {
+	TransactionID int64
+	UserID        int64
+	FN            string
+	FD            string
+	FP            string
+	Operation     int32
+}
+
+func (r *Repository) CreateTransactionReceipt(ctx context.Context, receipt *Receipt) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO transaction_receipts (transaction_id, user_id, fn, fd, fp, operation)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, receipt.TransactionID, receipt.UserID, receipt.FN, receipt.FD, receipt.FP, receipt.Operation)
+	if err != nil {
+		r.logger.Error("failed to save transaction receipt", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// FindReceiptTransaction возвращает ID операции, уже созданной по чеку
+// с такими реквизитами, или 0.
+func (r *Repository) FindReceiptTransaction(ctx context.Context, userID int64, fn, fd, fp string) (int64, error) {
+	var transactionID int64
+	err := r.db.QueryRow(ctx, `
+		SELECT transaction_id FROM transaction_receipts
+		WHERE user_id = $1 AND fn = $2 AND fd = $3 AND fp = $4
+	`, userID, fn, fd, fp).Scan(&transactionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to find receipt transaction", zap.Error(err))
+		return 0, err
+	}
+	return transactionID, nil
+}
+
+// Goal — цель накопления. Saved — накоплено: ручные взносы плюс переводы
+// на привязанный счет в валюте цели с момента создания цели.
+type Goal struct {
+	ID           int64
+	UserID       int64
+	Name         string
+	TargetAmount string
+	Currency     string
+	Deadline     time.Time
+	AccountID    sql.NullInt64
+	AccountName  sql.NullString
+	Saved        string
+	CreatedAt    time.Time
+}
+
+const goalView = `
+	SELECT g.id, g.user_id, g.name, g.target_amount, g.currency, g.deadline, g.account_id, a.name,
+		(COALESCE((SELECT SUM(c.amount) FROM goal_contributions c WHERE c.goal_id = g.id), 0) +
+		 COALESCE((SELECT SUM(t.amount) FROM transactions t
+		           WHERE t.user_id = g.user_id AND t.type = 'transfer' AND t.related_account_id = g.account_id
+		             AND t.currency = g.currency AND t.operation_date >= g.created_at), 0))::text,
+		g.created_at
+	FROM goals g
+	LEFT JOIN accounts a ON a.id = g.account_id`
+
+func scanGoal(row pgx.Row) (*Goal, error) {
+	var goal Goal
+	err := row.Scan(
+		&goal.ID,
+		&goal.UserID,
+		&goal.Name,
+		&goal.TargetAmount,
+		&goal.Currency,
+		&goal.Deadline,
+		&goal.AccountID,
+		&goal.AccountName,
+		&goal.Saved,
+		&goal.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &goal, nil
+}
+
+func (r *Repository) CreateGoal(ctx context.Context, goal *Goal) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO goals (user_id, name, target_amount, currency, deadline, account_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, goal.UserID, goal.Name, goal.TargetAmount, goal.Currency, goal.Deadline, goal.AccountID).Scan(&id)
+	if err != nil {
+		r.logger.Error("failed to create goal", zap.Error(err))
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *Repository) UpdateGoal(ctx context.Context, goal *Goal) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE goals
+		SET name = $3, target_amount = $4, currency = $5, deadline = $6, account_id = $7, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, goal.ID, goal.UserID, goal.Name, goal.TargetAmount, goal.Currency, goal.Deadline, goal.AccountID)
+	if err != nil {
+		r.logger.Error("failed to update goal", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetGoal возвращает цель пользователя с накопленной суммой или nil.
+func (r *Repository) GetGoal(ctx context.Context, goalID, userID int64) (*Goal, error) {
+	goal, err := scanGoal(r.db.QueryRow(ctx, goalView+` WHERE g.id = $1 AND g.user_id = $2`, goalID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get goal", zap.Error(err))
+		return nil, err
+	}
+	return goal, nil
+}
+
+func (r *Repository) ListGoals(ctx context.Context, userID int64) ([]*Goal, error) {
+	rows, err := r.db.Query(ctx, goalView+` WHERE g.user_id = $1 ORDER BY g.deadline, g.id`, userID)
+	if err != nil {
+		r.logger.Error("failed to list goals", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var goals []*Goal
+	for rows.Next() {
+		goal, err := scanGoal(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+
+	return goals, rows.Err()
+}
+
+func (r *Repository) DeleteGoal(ctx context.Context, goalID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM goals WHERE id = $1 AND user_id = $2`, goalID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete goal", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GoalContribution — взнос в цель. У перевода на привязанный счет ID равен
+// нулю, а TransactionID указывает на перевод.
+type GoalContribution struct {
+	ID            int64
+	GoalID        int64
+	Amount        string
+	Date          time.Time
+	Note          sql.NullString
+	TransactionID sql.NullInt64
+}
+
+func (r *Repository) CreateGoalContribution(ctx context.Context, userID int64, contribution *GoalContribution) (*GoalContribution, error) {
+	created := *contribution
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO goal_contributions (goal_id, user_id, amount, contribution_date, note)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, contribution.GoalID, userID, contribution.Amount, contribution.Date, contribution.Note).Scan(&created.ID)
+	if err != nil {
+		r.logger.Error("failed to create goal contribution", zap.Error(err))
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *Repository) DeleteGoalContribution(ctx context.Context, contributionID, goalID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM goal_contributions WHERE id = $1 AND goal_id = $2 AND user_id = $3
+	`, contributionID, goalID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete goal contribution", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListGoalContributions возвращает ручные взносы и переводы на привязанный
+// счет, новые первыми.
+func (r *Repository) ListGoalContributions(ctx context.Context, goal *Goal) ([]*GoalContribution, error) {
+	query := `
+		SELECT c.id, c.goal_id, c.amount, c.contribution_date, c.note, NULL::bigint
+		FROM goal_contributions c
+		WHERE c.goal_id = $1
+		UNION ALL
+		SELECT 0, $1, t.amount, t.operation_date, t.description, t.id
+		FROM transactions t
+		WHERE t.user_id = $2 AND t.type = 'transfer' AND t.related_account_id = $3
+			AND t.currency = $4 AND t.operation_date >= $5
+		ORDER BY 4 DESC, 6 DESC NULLS LAST, 1 DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, goal.ID, goal.UserID, goal.AccountID, goal.Currency, goal.CreatedAt)
+	if err != nil {
+		r.logger.Error("failed to list goal contributions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var contributions []*GoalContribution
+	for rows.Next() {
+		var c GoalContribution
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.Amount, &c.Date, &c.Note, &c.TransactionID); err != nil {
+			return nil, err
+		}
+		contributions = append(contributions, &c)
+	}
+
+	return contributions, rows.Err()
+}
+
+// Counterparty — человек, с которым у пользователя есть долги
+type Counterparty struct {
+	ID             int64
+	UserID         int64
+	Name           string
+	NormalizedName string
+	Note           sql.NullString
+	CreatedAt      time.Time
+}
+
+func scanCounterparty(row pgx.Row) (*Counterparty, error) {
+	var counterparty Counterparty
+	err := row.Scan(
+		&counterparty.ID,
+		&counterparty.UserID,
+		&counterparty.Name,
+		&counterparty.NormalizedName,
+		&counterparty.Note,
+		&counterparty.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &counterparty, nil
+}
+
+const counterpartyColumns = `id, user_id, name, normalized_name, note, created_at`
+
+func (r *Repository) CreateCounterparty(ctx context.Context, counterparty *Counterparty) (*Counterparty, error) {
+	result, err := scanCounterparty(r.db.QueryRow(ctx, `
+		INSERT INTO counterparties (user_id, name, normalized_name, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+counterpartyColumns,
+		counterparty.UserID, counterparty.Name, counterparty.NormalizedName, counterparty.Note,
+	))
+	if err != nil {
+		r.logger.Error("failed to create counterparty", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// UpdateCounterparty изменяет имя и заметку. Возвращает nil, если
+// контрагент не найден.
+func (r *Repository) UpdateCounterparty(ctx context.Context, counterparty *Counterparty) (*Counterparty, error) {
+	result, err := scanCounterparty(r.db.QueryRow(ctx, `
+		UPDATE counterparties
+		SET name = $3, normalized_name = $4, note = $5, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		RETURNING `+counterpartyColumns,
+		counterparty.ID, counterparty.UserID, counterparty.Name, counterparty.NormalizedName, counterparty.Note,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to update counterparty", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *Repository) GetCounterparty(ctx context.Context, counterpartyID, userID int64) (*Counterparty, error) {
+	counterparty, err := scanCounterparty(r.db.QueryRow(ctx,
+		`SELECT `+counterpartyColumns+` FROM counterparties WHERE id = $1 AND user_id = $2`,
+		counterpartyID, userID,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get counterparty", zap.Error(err))
+		return nil, err
+	}
+	return counterparty, nil
+}
+
+// FindCounterparty ищет контрагента по нормализованному имени
+func (r *Repository) FindCounterparty(ctx context.Context, userID int64, normalizedName string) (*Counterparty, error) {
+	counterparty, err := scanCounterparty(r.db.QueryRow(ctx,
+		`SELECT `+counterpartyColumns+` FROM counterparties WHERE user_id = $1 AND normalized_name = $2`,
+		userID, normalizedName,
+	))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to find counterparty", zap.Error(err))
+		return nil, err
+	}
+	return counterparty, nil
+}
+
+func (r *Repository) ListCounterparties(ctx context.Context, userID int64) ([]*Counterparty, error) {
+	rows, err := r.db.Query(ctx,
+		`SELECT `+counterpartyColumns+` FROM counterparties WHERE user_id = $1 ORDER BY name, id`,
+		userID,
+	)
+	if err != nil {
+		r.logger.Error("failed to list counterparties", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counterparties []*Counterparty
+	for rows.Next() {
+		counterparty, err := scanCounterparty(rows)
+		if err != nil {
+			return nil, err
+		}
+		counterparties = append(counterparties, counterparty)
+	}
+
+	return counterparties, rows.Err()
+}
+
+func (r *Repository) CounterpartyHasDebts(ctx context.Context, counterpartyID int64) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM debts WHERE counterparty_id = $1)`, counterpartyID).Scan(&exists)
+	if err != nil {
+		r.logger.Error("failed to check counterparty debts", zap.Error(err))
+		return false, err
+	}
+	return exists, nil
+}
+
+func (r *Repository) DeleteCounterparty(ctx context.Context, counterpartyID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM counterparties WHERE id = $1 AND user_id = $2`, counterpartyID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete counterparty", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Debt — долг: lent — пользователь дал в долг, borrowed — взял.
+// Repaid — сумма погашений.
+type Debt struct {
+	ID               int64
+	UserID           int64
+	CounterpartyID   int64
+	CounterpartyName string
+	Direction        string
+	Amount           string
+	Currency         string
+	Description      sql.NullString
+	DueDate          sql.NullTime
+	TransactionID    sql.NullInt64
+	Repaid           string
+	CreatedAt        time.Time
+}
+
+const debtView = `
+	SELECT d.id, d.user_id, d.counterparty_id, c.name, d.direction, d.amount, d.currency, d.description,
+		d.due_date, d.transaction_id,
+		COALESCE((SELECT SUM(r.amount) FROM debt_repayments r WHERE r.debt_id = d.id), 0)::text,
+		d.created_at
+	FROM debts d
+	JOIN counterparties c ON c.id = d.counterparty_id`
+
+// debtOutstanding — условие "долг не погашен" для запросов поверх debtView
+const debtOutstanding = `d.amount > COALESCE((SELECT SUM(r.amount) FROM debt_repayments r WHERE r.debt_id = d.id), 0)`
+
+func scanDebt(row pgx.Row) (*Debt, error) {
+	var debt Debt
+	err := row.Scan(
+		&debt.ID,
+		&debt.UserID,
+		&debt.CounterpartyID,
+		&debt.CounterpartyName,
+		&debt.Direction,
+		&debt.Amount,
+		&debt.Currency,
+		&debt.Description,
+		&debt.DueDate,
+		&debt.TransactionID,
+		&debt.Repaid,
+		&debt.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &debt, nil
+}
+
+func scanDebts(rows pgx.Rows) ([]*Debt, error) {
+	defer rows.Close()
+
+	var debts []*Debt
+	for rows.Next() {
+		debt, err := scanDebt(rows)
+		if err != nil {
+			return nil, err
+		}
+		debts = append(debts, debt)
+	}
+
+	return debts, rows.Err()
+}
+
+func (r *Repository) CreateDebt(ctx context.Context, debt *Debt) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO debts (user_id, counterparty_id, direction, amount, currency, description, due_date, transaction_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`, debt.UserID, debt.CounterpartyID, debt.Direction, debt.Amount, debt.Currency,
+		debt.Description, debt.DueDate, debt.TransactionID).Scan(&id)
+	if err != nil {
+		r.logger.Error("failed to create debt", zap.Error(err))
+		return 0, err
+	}
+	return id, nil
+}
+
+// UpdateDebt изменяет контрагента, сумму, описание и срок. При смене срока
+// напоминание отправляется заново.
+func (r *Repository) UpdateDebt(ctx context.Context, debt *Debt) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE debts
+		SET counterparty_id = $3, amount = $4, description = $5,
+		    reminded_at = CASE WHEN due_date IS DISTINCT FROM $6 THEN NULL ELSE reminded_at END,
+		    due_date = $6, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, debt.ID, debt.UserID, debt.CounterpartyID, debt.Amount, debt.Description, debt.DueDate)
+	if err != nil {
+		r.logger.Error("failed to update debt", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetDebt возвращает долг пользователя с суммой погашений или nil
+func (r *Repository) GetDebt(ctx context.Context, debtID, userID int64) (*Debt, error) {
+	debt, err := scanDebt(r.db.QueryRow(ctx, debtView+` WHERE d.id = $1 AND d.user_id = $2`, debtID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get debt", zap.Error(err))
+		return nil, err
+	}
+	return debt, nil
+}
+
+// ListDebts возвращает долги пользователя: по контрагенту, если он задан,
+// и только непогашенные, если не запрошены закрытые.
+func (r *Repository) ListDebts(ctx context.Context, userID, counterpartyID int64, includeClosed bool) ([]*Debt, error) {
+	query := debtView + `
+		WHERE d.user_id = $1 AND ($2 = 0 OR d.counterparty_id = $2)`
+	if !includeClosed {
+		query += ` AND ` + debtOutstanding
+	}
+	query += ` ORDER BY d.due_date NULLS LAST, d.created_at, d.id`
+
+	rows, err := r.db.Query(ctx, query, userID, counterpartyID)
+	if err != nil {
+		r.logger.Error("failed to list debts", zap.Error(err))
+		return nil, err
+	}
+	return scanDebts(rows)
+}
+
+func (r *Repository) DeleteDebt(ctx context.Context, debtID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM debts WHERE id = $1 AND user_id = $2`, debtID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete debt", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// LockDebt блокирует строку долга до конца транзакции, чтобы параллельные
+// погашения не превысили остаток
+func (r *Repository) LockDebt(ctx context.Context, debtID, userID int64) error {
+	_, err := r.db.Exec(ctx, `SELECT id FROM debts WHERE id = $1 AND user_id = $2 FOR UPDATE`, debtID, userID)
+	if err != nil {
+		r.logger.Error("failed to lock debt", zap.Error(err))
+	}
+	return err
+}
+
+// TransactionLinkedToDebt проверяет, привязана ли операция к долгу или
+// погашению
+func (r *Repository) TransactionLinkedToDebt(ctx context.Context, transactionID int64) (bool, error) {
+	var linked bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM debts WHERE transaction_id = $1
+			UNION ALL
+			SELECT 1 FROM debt_repayments WHERE transaction_id = $1
+		)
+	`, transactionID).Scan(&linked)
+	if err != nil {
+		r.logger.Error("failed to check transaction debt link", zap.Error(err))
+		return false, err
+	}
+	return linked, nil
+}
+
+// ClaimDebtReminders отмечает и возвращает непогашенные долги всех
+// пользователей, по которым пора напомнить: срок наступает в ближайшие
+// withinDays дней и напоминания еще не было, либо срок наступил, а
+// напоминали до него. Так по каждому долгу приходит не больше двух
+// напоминаний.
+func (r *Repository) ClaimDebtReminders(ctx context.Context, withinDays int) ([]*Debt, error) {
+	query := `
+		WITH claimed AS (
+			UPDATE debts d
+			SET reminded_at = NOW()
+			WHERE d.due_date IS NOT NULL AND d.due_date <= CURRENT_DATE + $1::int
+			  AND (d.reminded_at IS NULL OR (d.reminded_at::date < d.due_date AND CURRENT_DATE >= d.due_date))
+			  AND ` + debtOutstanding + `
+			RETURNING d.id
+		)` + debtView + `
+		WHERE d.id IN (SELECT id FROM claimed)
+		ORDER BY d.user_id, d.due_date, d.id`
+
+	rows, err := r.db.Query(ctx, query, withinDays)
+	if err != nil {
+		r.logger.Error("failed to claim debt reminders", zap.Error(err))
+		return nil, err
+	}
+	return scanDebts(rows)
+}
+
+// DebtRepayment — частичное или полное погашение долга
+type DebtRepayment struct {
+	ID            int64
+	DebtID        int64
+	Amount        string
+	Date          time.Time
+	TransactionID sql.NullInt64
+	Note          sql.NullString
+}
+
+func (r *Repository) CreateDebtRepayment(ctx context.Context, userID int64, repayment *DebtRepayment) (*DebtRepayment, error) {
+	created := *repayment
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO debt_repayments (debt_id, user_id, amount, repayment_date, transaction_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, repayment.DebtID, userID, repayment.Amount, repayment.Date, repayment.TransactionID, repayment.Note).Scan(&created.ID)
+	if err != nil {
+		r.logger.Error("failed to create debt repayment", zap.Error(err))
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *Repository) ListDebtRepayments(ctx context.Context, debtID int64) ([]*DebtRepayment, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT id, debt_id, amount, repayment_date, transaction_id, note
+		FROM debt_repayments
+		WHERE debt_id = $1
+		ORDER BY repayment_date DESC, id DESC
+	`, debtID)
+	if err != nil {
+		r.logger.Error("failed to list debt repayments", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var repayments []*DebtRepayment
+	for rows.Next() {
+		var p DebtRepayment
+		if err := rows.Scan(&p.ID, &p.DebtID, &p.Amount, &p.Date, &p.TransactionID, &p.Note); err != nil {
+			return nil, err
+		}
+		repayments = append(repayments, &p)
+	}
+
+	return repayments, rows.Err()
+}
+
+func (r *Repository) DeleteDebtRepayment(ctx context.Context, repaymentID, debtID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		DELETE FROM debt_repayments WHERE id = $1 AND debt_id = $2 AND user_id = $3
+	`, repaymentID, debtID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete debt repayment", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Loan — условия кредита, привязанные к счету типа loan
+type Loan struct {
+	AccountID          int64
+	UserID             int64
+	AccountName        string
+	Currency           string
+	Principal          string
+	AnnualRate         string
+	TermMonths         int32
+	PaymentType        string
+	FirstPaymentDate   time.Time
+	PaymentAccountID   sql.NullInt64
+	InterestCategoryID sql.NullInt64
+	CreatedAt          time.Time
+}
+
+// SaveLoan создает или заменяет условия кредита
+func (r *Repository) SaveLoan(ctx context.Context, loan *Loan) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO loans (account_id, user_id, principal, annual_rate, term_months, payment_type,
+		                   first_payment_date, payment_account_id, interest_category_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (account_id) DO UPDATE
+		SET principal = EXCLUDED.principal, annual_rate = EXCLUDED.annual_rate,
+		    term_months = EXCLUDED.term_months, payment_type = EXCLUDED.payment_type,
+		    first_payment_date = EXCLUDED.first_payment_date, payment_account_id = EXCLUDED.payment_account_id,
+		    interest_category_id = EXCLUDED.interest_category_id, updated_at = NOW()
+	`, loan.AccountID, loan.UserID, loan.Principal, loan.AnnualRate, loan.TermMonths, loan.PaymentType,
+		loan.FirstPaymentDate, loan.PaymentAccountID, loan.InterestCategoryID)
+	if err != nil {
+		r.logger.Error("failed to save loan", zap.Error(err))
+	}
+	return err
+}
+
+// GetLoan возвращает условия кредита по счету или nil. С forUpdate строка
+// блокируется до конца транзакции.
+func (r *Repository) GetLoan(ctx context.Context, accountID, userID int64, forUpdate bool) (*Loan, error) {
+	query := `
+		SELECT l.account_id, l.user_id, a.name, a.currency, l.principal, l.annual_rate, l.term_months, l.payment_type,
+		       l.first_payment_date, l.payment_account_id, l.interest_category_id, l.created_at
+		FROM loans l
+		JOIN accounts a ON a.id = l.account_id
+		WHERE l.account_id = $1 AND l.user_id = $2`
+	if forUpdate {
+		query += ` FOR UPDATE OF l`
+	}
+
+	var loan Loan
+	err := r.db.QueryRow(ctx, query, accountID, userID).Scan(
+		&loan.AccountID,
+		&loan.UserID,
+		&loan.AccountName,
+		&loan.Currency,
+		&loan.Principal,
+		&loan.AnnualRate,
+		&loan.TermMonths,
+		&loan.PaymentType,
+		&loan.FirstPaymentDate,
+		&loan.PaymentAccountID,
+		&loan.InterestCategoryID,
+		&loan.CreatedAt,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get loan", zap.Error(err))
+		return nil, err
+	}
+	return &loan, nil
+}
+
+func (r *Repository) DeleteLoan(ctx context.Context, accountID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM loans WHERE account_id = $1 AND user_id = $2`, accountID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete loan", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// PostedLoanPayment — проведенный платеж графика. Платеж, обе операции
+// которого удалены, считается непроведенным.
+type PostedLoanPayment struct {
+	Number                 int32
+	PrincipalTransactionID sql.NullInt64
+	InterestTransactionID  sql.NullInt64
+	PostedAt               time.Time
+}
+
+// ListPostedLoanPayments возвращает проведенные платежи кредита по номерам
+func (r *Repository) ListPostedLoanPayments(ctx context.Context, accountID int64) (map[int32]*PostedLoanPayment, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT number, principal_transaction_id, interest_transaction_id, posted_at
+		FROM loan_payments
+		WHERE loan_account_id = $1
+		  AND (principal_transaction_id IS NOT NULL OR interest_transaction_id IS NOT NULL)
+	`, accountID)
+	if err != nil {
+		r.logger.Error("failed to list loan payments", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	payments := map[int32]*PostedLoanPayment{}
+	for rows.Next() {
+		var p PostedLoanPayment
+		if err := rows.Scan(&p.Number, &p.PrincipalTransactionID, &p.InterestTransactionID, &p.PostedAt); err != nil {
+			return nil, err
+		}
+		payments[p.Number] = &p
+	}
+
+	return payments, rows.Err()
+}
+
+// SavePostedLoanPayment отмечает платеж проведенным. Запись о платеже,
+// операции которого были удалены, перезаписывается.
+func (r *Repository) SavePostedLoanPayment(ctx context.Context, accountID, userID int64, payment *PostedLoanPayment) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO loan_payments (loan_account_id, number, user_id, principal_transaction_id, interest_transaction_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (loan_account_id, number) DO UPDATE
+		SET principal_transaction_id = EXCLUDED.principal_transaction_id,
+		    interest_transaction_id = EXCLUDED.interest_transaction_id,
+		    posted_at = NOW()
+	`, accountID, payment.Number, userID, payment.PrincipalTransactionID, payment.InterestTransactionID)
+	if err != nil {
+		r.logger.Error("failed to save loan payment", zap.Error(err))
+	}
+	return err
+}
+
+// ExpenseSample — расход для расчета базовой линии аномалий
+type ExpenseSample struct {
+	TransactionID int64
+	UserID        int64
+	CategoryID    int64
+	Amount        string
+	Currency      string
+	OperationDate time.Time
+}
+
+// ListAnomalyCandidates возвращает расходы с категорией, созданные или
+// измененные после since и еще не отмеченные как крупные, по всем
+// пользователям.
+func (r *Repository) ListAnomalyCandidates(ctx context.Context, since time.Time) ([]*ExpenseSample, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT t.id, t.user_id, t.category_id, t.amount, t.currency, t.operation_date
+		FROM transactions t
+		WHERE t.type = 'expense' AND t.category_id IS NOT NULL AND t.updated_at >= $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM insights i WHERE i.transaction_id = t.id AND i.kind = 'large_transaction'
+		  )
+		ORDER BY t.user_id, t.category_id, t.currency, t.operation_date
+	`, since)
+	if err != nil {
+		r.logger.Error("failed to list anomaly candidates", zap.Error(err))
+		return nil, err
+	}
+	return scanExpenseSamples(rows)
+}
+
+// ListCategoryExpenses возвращает расходы пользователя по категории в одной
+// валюте за [from, to).
+func (r *Repository) ListCategoryExpenses(ctx context.Context, userID, categoryID int64, currency string, from, to time.Time) ([]*ExpenseSample, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT t.id, t.user_id, t.category_id, t.amount, t.currency, t.operation_date
+		FROM transactions t
+		WHERE t.user_id = $1 AND t.category_id = $2 AND t.currency = $3 AND t.type = 'expense'
+		  AND t.operation_date >= $4 AND t.operation_date < $5
+		ORDER BY t.operation_date
+	`, userID, categoryID, currency, from, to)
+	if err != nil {
+		r.logger.Error("failed to list category expenses", zap.Error(err))
+		return nil, err
+	}
+	return scanExpenseSamples(rows)
+}
+
+func scanExpenseSamples(rows pgx.Rows) ([]*ExpenseSample, error) {
+	defer rows.Close()
+
+	var samples []*ExpenseSample
+	for rows.Next() {
+		var sample ExpenseSample
+		if err := rows.Scan(
+			&sample.TransactionID,
+			&sample.UserID,
+			&sample.CategoryID,
+			&sample.Amount,
+			&sample.Currency,
+			&sample.OperationDate,
+		); err != nil {
+			return nil, err
+		}
+		samples = append(samples, &sample)
+	}
+	return samples, rows.Err()
+}
+
+// Insight — отметка об аномальных тратах
+type Insight struct {
+	ID            int64
+	UserID        int64
+	Kind          string
+	CategoryID    int64
+	CategoryName  string
+	TransactionID sql.NullInt64
+	Description   sql.NullString // Описание операции для large_transaction
+	OperationDate sql.NullTime   // Дата операции для large_transaction
+	PeriodStart   sql.NullTime
+	Weekday       sql.NullInt16 // NULL, если базовая линия посчитана без учета дня недели
+	Amount        string
+	Baseline      string
+	Threshold     string
+	Currency      string
+	CreatedAt     time.Time
+}
+
+const insightView = `
+	SELECT i.id, i.user_id, i.kind, i.category_id, COALESCE(o.name, c.name), i.transaction_id, t.description,
+		t.operation_date, i.period_start, i.weekday, i.amount, i.baseline, i.threshold, i.currency, i.created_at
+	FROM insights i
+	JOIN categories c ON c.id = i.category_id
+	LEFT JOIN category_overrides o ON o.category_id = c.id AND o.user_id = i.user_id
+	LEFT JOIN transactions t ON t.id = i.transaction_id`
+
+func scanInsights(rows pgx.Rows) ([]*Insight, error) {
+	defer rows.Close()
+
+	var insights []*Insight
+	for rows.Next() {
+		var insight Insight
+		if err := rows.Scan(
+			&insight.ID,
+			&insight.UserID,
+			&insight.Kind,
+			&insight.CategoryID,
+			&insight.CategoryName,
+			&insight.TransactionID,
+			&insight.Description,
+			&insight.OperationDate,
+			&insight.PeriodStart,
+			&insight.Weekday,
+			&insight.Amount,
+			&insight.Baseline,
+			&insight.Threshold,
+			&insight.Currency,
+			&insight.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		insights = append(insights, &insight)
+	}
+	return insights, rows.Err()
+}
+
+// SaveInsight сохраняет отметку и возвращает ее ID. Повторная отметка той
+// же операции или той же категории за тот же месяц не создается; в этом
+// случае возвращается 0.
+func (r *Repository) SaveInsight(ctx context.Context, insight *Insight) (int64, error) {
+	var conflict string
+	if insight.Kind == "large_transaction" {
+		conflict = "(transaction_id) WHERE kind = 'large_transaction'"
+	} else {
+		conflict = "(user_id, category_id, currency, period_start) WHERE kind = 'category_trend'"
+	}
+
+	var id int64
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO insights (user_id, kind, category_id, transaction_id, period_start, weekday, amount, baseline, threshold, currency)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT `+conflict+` DO NOTHING
+		RETURNING id
+	`, insight.UserID, insight.Kind, insight.CategoryID, insight.TransactionID, insight.PeriodStart, insight.Weekday,
+		insight.Amount, insight.Baseline, insight.Threshold, insight.Currency).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to save insight", zap.Error(err))
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *Repository) GetInsight(ctx context.Context, insightID int64) (*Insight, error) {
+	rows, err := r.db.Query(ctx, insightView+`
+		WHERE i.id = $1`, insightID)
+	if err != nil {
+		r.logger.Error("failed to get insight", zap.Error(err))
+		return nil, err
+	}
+	insights, err := scanInsights(rows)
+	if err != nil || len(insights) == 0 {
+		return nil, err
+	}
+	return insights[0], nil
+}
+
+func (r *Repository) ListInsights(ctx context.Context, userID int64, limit int32) ([]*Insight, error) {
+	rows, err := r.db.Query(ctx, insightView+`
+		WHERE i.user_id = $1
+		ORDER BY i.created_at DESC, i.id DESC
+		LIMIT $2`, userID, limit)
+	if err != nil {
+		r.logger.Error("failed to list insights", zap.Error(err))
+		return nil, err
+	}
+	return scanInsights(rows)
+}
+
+// Notification — уведомление в очереди на отправку ботом
+type Notification struct {
+	ID            int64
+	UserID        int64
+	Type          string
+	Payload       map[string]string // Значения для шаблона сообщения
+	DedupKey      sql.NullString
+	Status        string
+	Attempts      int32
+	NextAttemptAt time.Time
+	LastError     sql.NullString
+	CreatedAt     time.Time
+	SentAt        sql.NullTime
+}
+
+const notificationColumns = `id, user_id, type, payload, dedup_key, status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+func scanNotification(row pgx.Row) (*Notification, error) {
+	var n Notification
+	err := row.Scan(
+		&n.ID,
+		&n.UserID,
+		&n.Type,
+		&n.Payload,
+		&n.DedupKey,
+		&n.Status,
+		&n.Attempts,
+		&n.NextAttemptAt,
+		&n.LastError,
+		&n.CreatedAt,
+		&n.SentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// EnqueueNotification ставит уведомление в очередь. Уведомление с уже
+// известным ключом дедупликации не создается; в этом случае возвращается
+// false.
+func (r *Repository) EnqueueNotification(ctx context.Context, n *Notification) (bool, error) {
+	payload := n.Payload
+	if payload == nil {
+		payload = map[string]string{}
+	}
+	tag, err := r.db.Exec(ctx, `
+		INSERT INTO notifications (user_id, type, payload, dedup_key)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, dedup_key) WHERE dedup_key IS NOT NULL DO NOTHING
+	`, n.UserID, n.Type, payload, n.DedupKey)
+	if err != nil {
+		r.logger.Error("failed to enqueue notification", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// ListDueNotifications возвращает ожидающие уведомления, время которых
+// пришло, и блокирует их до конца транзакции. Уведомления, заблокированные
+// другим обработчиком, пропускаются.
+func (r *Repository) ListDueNotifications(ctx context.Context, limit int32) ([]*Notification, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+notificationColumns+`
+		FROM notifications
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at, id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`, limit)
+	if err != nil {
+		r.logger.Error("failed to list due notifications", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// GetNotification возвращает уведомление по ID или nil. С forUpdate строка
+// блокируется до конца транзакции.
+func (r *Repository) GetNotification(ctx context.Context, notificationID int64, forUpdate bool) (*Notification, error) {
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE id = $1`
+	if forUpdate {
+		query += ` FOR UPDATE`
+	}
+	n, err := scanNotification(r.db.QueryRow(ctx, query, notificationID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get notification", zap.Error(err))
+		return nil, err
+	}
+	return n, nil
+}
+
+// ScheduleNotification переносит следующую попытку отправки на at. С
+// countAttempt попытка засчитывается.
+func (r *Repository) ScheduleNotification(ctx context.Context, notificationID int64, at time.Time, countAttempt bool, lastError string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE notifications
+		SET next_attempt_at = $2,
+		    attempts = attempts + CASE WHEN $3 THEN 1 ELSE 0 END,
+		    last_error = COALESCE(NULLIF($4, ''), last_error)
+		WHERE id = $1
+	`, notificationID, at, countAttempt, lastError)
+	if err != nil {
+		r.logger.Error("failed to schedule notification", zap.Error(err))
+	}
+	return err
+}
+
+// FinishNotification переводит уведомление в конечный статус: sent, failed
+// или skipped.
+func (r *Repository) FinishNotification(ctx context.Context, notificationID int64, status, lastError string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE notifications
+		SET status = $2,
+		    sent_at = CASE WHEN $2 = 'sent' THEN NOW() ELSE sent_at END,
+		    last_error = COALESCE(NULLIF($3, ''), last_error)
+		WHERE id = $1
+	`, notificationID, status, lastError)
+	if err != nil {
+		r.logger.Error("failed to finish notification", zap.Error(err))
+	}
+	return err
+}
+
+// NotificationPreferences — настройки уведомлений пользователя. Тихие часы и
+// время сводки — локальное время "HH:MM" в часовом поясе Timezone.
+type NotificationPreferences struct {
+	UserID           int64
+	MutedTypes       []string
+	QuietFrom        sql.NullString
+	QuietTo          sql.NullString
+	Timezone         string
+	DigestEnabled    bool
+	DigestFrequency  string
+	DigestWeekday    int16
+	DigestTime       string
+	DigestSentPeriod sql.NullTime
+}
+
+const notificationPreferencesColumns = `user_id, muted_types, to_char(quiet_from, 'HH24:MI'), to_char(quiet_to, 'HH24:MI'), timezone,
+	digest_enabled, digest_frequency, digest_weekday, to_char(digest_time, 'HH24:MI'), digest_sent_period`
+
+func scanNotificationPreferences(row pgx.Row) (*NotificationPreferences, error) {
+	var prefs NotificationPreferences
+	err := row.Scan(
+		&prefs.UserID,
+		&prefs.MutedTypes,
+		&prefs.QuietFrom,
+		&prefs.QuietTo,
+		&prefs.Timezone,
+		&prefs.DigestEnabled,
+		&prefs.DigestFrequency,
+		&prefs.DigestWeekday,
+		&prefs.DigestTime,
+		&prefs.DigestSentPeriod,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// GetNotificationPreferences возвращает настройки пользователя или
+// настройки по умолчанию, если пользователь их не менял.
+func (r *Repository) GetNotificationPreferences(ctx context.Context, userID int64) (*NotificationPreferences, error) {
+	prefs, err := scanNotificationPreferences(r.db.QueryRow(ctx, `
+		SELECT `+notificationPreferencesColumns+`
+		FROM notification_preferences
+		WHERE user_id = $1
+	`, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &NotificationPreferences{
+			UserID:          userID,
+			MutedTypes:      []string{},
+			Timezone:        "UTC",
+			DigestFrequency: "weekly",
+			DigestWeekday:   1,
+			DigestTime:      "09:00",
+		}, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get notification preferences", zap.Error(err))
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (r *Repository) SaveNotificationPreferences(ctx context.Context, prefs *NotificationPreferences) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO notification_preferences (user_id, muted_types, quiet_from, quiet_to, timezone)
+		VALUES ($1, $2, $3::time, $4::time, $5)
+		ON CONFLICT (user_id) DO UPDATE
+		SET muted_types = EXCLUDED.muted_types,
+		    quiet_from = EXCLUDED.quiet_from,
+		    quiet_to = EXCLUDED.quiet_to,
+		    timezone = EXCLUDED.timezone,
+		    updated_at = NOW()
+	`, prefs.UserID, prefs.MutedTypes, prefs.QuietFrom, prefs.QuietTo, prefs.Timezone)
+	if err != nil {
+		r.logger.Error("failed to save notification preferences", zap.Error(err))
+	}
+	return err
+}
+
+// SaveDigestSettings сохраняет расписание сводки, не трогая остальные
+// настройки уведомлений
+func (r *Repository) SaveDigestSettings(ctx context.Context, prefs *NotificationPreferences) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO notification_preferences (user_id, digest_enabled, digest_frequency, digest_weekday, digest_time)
+		VALUES ($1, $2, $3, $4, $5::time)
+		ON CONFLICT (user_id) DO UPDATE
+		SET digest_enabled = EXCLUDED.digest_enabled,
+		    digest_frequency = EXCLUDED.digest_frequency,
+		    digest_weekday = EXCLUDED.digest_weekday,
+		    digest_time = EXCLUDED.digest_time,
+		    updated_at = NOW()
+	`, prefs.UserID, prefs.DigestEnabled, prefs.DigestFrequency, prefs.DigestWeekday, prefs.DigestTime)
+	if err != nil {
+		r.logger.Error("failed to save digest settings", zap.Error(err))
+	}
+	return err
+}
+
+// ListDigestSubscribers возвращает настройки пользователей с включенной
+// сводкой
+func (r *Repository) ListDigestSubscribers(ctx context.Context) ([]*NotificationPreferences, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+notificationPreferencesColumns+`
+		FROM notification_preferences
+		WHERE digest_enabled
+		ORDER BY user_id`)
+	if err != nil {
+		r.logger.Error("failed to list digest subscribers", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var subscribers []*NotificationPreferences
+	for rows.Next() {
+		prefs, err := scanNotificationPreferences(rows)
+		if err != nil {
+			return nil, err
+		}
+		subscribers = append(subscribers, prefs)
+	}
+	return subscribers, rows.Err()
+}
+
+// MarkDigestSent запоминает начало периода, за который сводка поставлена
+// в очередь
+func (r *Repository) MarkDigestSent(ctx context.Context, userID int64, periodStart time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE notification_preferences SET digest_sent_period = $2 WHERE user_id = $1
+	`, userID, periodStart)
+	if err != nil {
+		r.logger.Error("failed to mark digest sent", zap.Error(err))
+	}
+	return err
+}
+
+// GetDataVersion возвращает отпечаток данных, из которых строится
+// статистика пользователя: операций и названий категорий. Он меняется при
+// любом добавлении, изменении или удалении операции и при переименовании
+// или слиянии категорий, поэтому по нему можно кешировать отчеты.
+func (r *Repository) GetDataVersion(ctx context.Context, userID int64) (string, error) {
+	var version string
+	err := r.db.QueryRow(ctx, `
+		SELECT md5(concat_ws('|',
+			(SELECT concat_ws(':', COUNT(*), MAX(updated_at),
+				SUM(hashtext(concat_ws(':', id, type, account_id, category_id, amount, operation_date))))
+			FROM transactions WHERE user_id = $1),
+			(SELECT string_agg(concat_ws(':', c.id, c.parent_id, COALESCE(o.name, c.name)), ',' ORDER BY c.id)
+			FROM categories c
+			LEFT JOIN category_overrides o ON o.category_id = c.id AND o.user_id = $1
+			WHERE c.user_id = $1 OR c.user_id IS NULL)
+		))
+	`, userID).Scan(&version)
+	if err != nil {
+		r.logger.Error("failed to get data version", zap.Error(err))
+		return "", err
+	}
+	return version, nil
+}
+
+// expenseLinesView — строки расходов: операция с разбивкой дает строку на
+// каждую строку разбивки с ее категорией и суммой, без разбивки — одну
+// строку.
+const expenseLinesView = `
+	SELECT t.id AS transaction_id, t.user_id, t.payee_id, t.currency, t.operation_date,
+		CASE WHEN s.id IS NULL THEN t.category_id ELSE s.category_id END AS category_id,
+		CASE WHEN s.id IS NULL THEN t.amount ELSE s.amount END AS amount
+	FROM transactions t
+	LEFT JOIN transaction_splits s ON s.transaction_id = t.id
+	WHERE t.type = 'expense'`
+
+// Budget — лимит расходов за период по категории (с подкатегориями),
+// получателю или их сочетанию
+type Budget struct {
+	ID           int64
+	UserID       int64
+	Name         string
+	CategoryID   sql.NullInt64
+	CategoryName sql.NullString
+	PayeeID      sql.NullInt64
+	PayeeName    sql.NullString
+	Amount       string
+	Currency     string
+	Period       string
+	CreatedAt    time.Time
+}
+
+const budgetView = `
+	SELECT b.id, b.user_id, b.name, b.category_id, COALESCE(o.name, c.name), b.payee_id, p.name,
+		b.amount::text, b.currency, b.period, b.created_at
+	FROM budgets b
+	LEFT JOIN categories c ON c.id = b.category_id
+	LEFT JOIN category_overrides o ON o.category_id = b.category_id AND o.user_id = b.user_id
+	LEFT JOIN payees p ON p.id = b.payee_id`
+
+func scanBudget(row pgx.Row) (*Budget, error) {
+	var budget Budget
+	err := row.Scan(
+		&budget.ID,
+		&budget.UserID,
+		&budget.Name,
+		&budget.CategoryID,
+		&budget.CategoryName,
+		&budget.PayeeID,
+		&budget.PayeeName,
+		&budget.Amount,
+		&budget.Currency,
+		&budget.Period,
+		&budget.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &budget, nil
+}
+
+func (r *Repository) CreateBudget(ctx context.Context, budget *Budget) (int64, error) {
+	var id int64
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO budgets (user_id, name, category_id, payee_id, amount, currency, period)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`, budget.UserID, budget.Name, budget.CategoryID, budget.PayeeID, budget.Amount, budget.Currency, budget.Period).Scan(&id)
+	if err != nil {
+		r.logger.Error("failed to create budget", zap.Error(err))
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *Repository) UpdateBudget(ctx context.Context, budget *Budget) (bool, error) {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE budgets
+		SET name = $3, category_id = $4, payee_id = $5, amount = $6, currency = $7, period = $8, updated_at = NOW()
+		WHERE id = $1 AND user_id = $2
+	`, budget.ID, budget.UserID, budget.Name, budget.CategoryID, budget.PayeeID, budget.Amount, budget.Currency, budget.Period)
+	if err != nil {
+		r.logger.Error("failed to update budget", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// GetBudget возвращает бюджет пользователя или nil.
+func (r *Repository) GetBudget(ctx context.Context, budgetID, userID int64) (*Budget, error) {
+	budget, err := scanBudget(r.db.QueryRow(ctx, budgetView+` WHERE b.id = $1 AND b.user_id = $2`, budgetID, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("failed to get budget", zap.Error(err))
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (r *Repository) ListBudgets(ctx context.Context, userID int64) ([]*Budget, error) {
+	rows, err := r.db.Query(ctx, budgetView+` WHERE b.user_id = $1 ORDER BY b.name, b.id`, userID)
+	if err != nil {
+		r.logger.Error("failed to list budgets", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []*Budget
+	for rows.Next() {
+		budget, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+func (r *Repository) DeleteBudget(ctx context.Context, budgetID, userID int64) (bool, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM budgets WHERE id = $1 AND user_id = $2`, budgetID, userID)
+	if err != nil {
+		r.logger.Error("failed to delete budget", zap.Error(err))
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// BudgetSpent возвращает расходы по бюджету за [from, to): строки расходов
+// в валюте бюджета по его категории с подкатегориями и/или получателю.
+func (r *Repository) BudgetSpent(ctx context.Context, budget *Budget, from, to time.Time) (string, error) {
+	var spent string
+	err := r.db.QueryRow(ctx, `
+		WITH RECURSIVE scope AS (
+			SELECT id FROM categories WHERE id = $2
+			UNION ALL
+			SELECT c.id FROM categories c JOIN scope ON c.parent_id = scope.id
+		)
+		SELECT COALESCE(SUM(e.amount), 0)::text
+		FROM (`+expenseLinesView+`) e
+		WHERE e.user_id = $1 AND e.currency = $4 AND e.operation_date >= $5 AND e.operation_date < $6
+		  AND ($2::BIGINT IS NULL OR e.category_id IN (SELECT id FROM scope))
+		  AND ($3::BIGINT IS NULL OR e.payee_id = $3)
+	`, budget.UserID, budget.CategoryID, budget.PayeeID, budget.Currency, from, to).Scan(&spent)
+	if err != nil {
+		r.logger.Error("failed to get budget spending", zap.Error(err))
+		return "", err
+	}
+	return spent, nil
+}
+
+// PeriodTotal — сумма операций одного типа в одной валюте за период
+type PeriodTotal struct {
+	Type     string
+	Currency string
+	Total    string
+}
+
+// GetPeriodTotals суммирует доходы и расходы за [from, to) по валютам
+func (r *Repository) GetPeriodTotals(ctx context.Context, userID int64, from, to time.Time) ([]*PeriodTotal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT type, currency, SUM(amount)::text
+		FROM transactions
+		WHERE user_id = $1 AND type IN ('expense', 'income') AND operation_date >= $2 AND operation_date < $3
+		GROUP BY type, currency
+		ORDER BY type, currency
+	`, userID, from, to)
+	if err != nil {
+		r.logger.Error("failed to get period totals", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*PeriodTotal
+	for rows.Next() {
+		var total PeriodTotal
+		if err := rows.Scan(&total.Type, &total.Currency, &total.Total); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &total)
+	}
+	return totals, rows.Err()
+}
+
+// CategoryTotal — расходы по категории в одной валюте
+type CategoryTotal struct {
+	CategoryName sql.NullString // Пусто для расходов без категории
+	Currency     string
+	Total        string
+}
+
+// GetTopExpenseCategories возвращает limit категорий с наибольшими
+// расходами за [from, to). Расход с разбивкой учитывается по категориям
+// строк разбивки.
+func (r *Repository) GetTopExpenseCategories(ctx context.Context, userID int64, from, to time.Time, limit int) ([]*CategoryTotal, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT COALESCE(o.name, c.name) AS name, e.currency, SUM(e.amount)::text
+		FROM (`+expenseLinesView+`) e
+		LEFT JOIN categories c ON c.id = e.category_id
+		LEFT JOIN category_overrides o ON o.category_id = c.id AND o.user_id = $1
+		WHERE e.user_id = $1 AND e.operation_date >= $2 AND e.operation_date < $3
+		GROUP BY COALESCE(o.name, c.name), e.currency
+		ORDER BY SUM(e.amount) DESC, name
+		LIMIT $4
+	`, userID, from, to, limit)
+	if err != nil {
+		r.logger.Error("failed to get top expense categories", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var totals []*CategoryTotal
+	for rows.Next() {
+		var total CategoryTotal
+		if err := rows.Scan(&total.CategoryName, &total.Currency, &total.Total); err != nil {
+			return nil, err
+		}
+		totals = append(totals, &total)
+	}
+	return totals, rows.Err()
+}
+
+// GetTopExpenses возвращает limit крупнейших расходов за [from, to)
+func (r *Repository) GetTopExpenses(ctx context.Context, userID int64, from, to time.Time, limit int) ([]*TransactionWithDetails, error) {
+	rows, err := r.db.Query(ctx, transactionDetailsView+`
+		WHERE t.user_id = $1 AND t.type = 'expense' AND t.operation_date >= $2 AND t.operation_date < $3
+		ORDER BY t.amount DESC, t.operation_date DESC, t.id DESC
+		LIMIT $4
+	`, userID, from, to, limit)
+	if err != nil {
+		r.logger.Error("failed to get top expenses", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transactions []*TransactionWithDetails
+	for rows.Next() {
+		transaction, err := scanTransactionDetails(rows)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, rows.Err()
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// benchmarkTransactions — сколько операций создается для бенчмарка
+const benchmarkTransactions = 1000
+
+// newBenchmarkRepository подключается к базе из LEDGER_TEST_DSN (с
+// примененными миграциями) и создает пользователя со счетом и
+// benchmarkTransactions операциями. Все созданное удаляется после
+// бенчмарка. Без LEDGER_TEST_DSN бенчмарк пропускается.
+func newBenchmarkRepository(b *testing.B) (*Repository, int64) {
+	dsn := os.Getenv("LEDGER_TEST_DSN")
+	if dsn == "" {
+		b.Skip("LEDGER_TEST_DSN is not set")
+	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		b.Fatalf("failed to connect: %v", err)
+	}
+	b.Cleanup(pool.Close)
+
+	var userID, accountID int64
+	err = pool.QueryRow(ctx, `
+		INSERT INTO users (telegram_id, first_name)
+		VALUES (-(extract(epoch FROM clock_timestamp()) * 1000000)::BIGINT, 'benchmark')
+		RETURNING id
+	`).Scan(&userID)
+	if err != nil {
+		b.Fatalf("failed to create user: %v", err)
+	}
+	b.Cleanup(func() {
+		pool.Exec(ctx, `DELETE FROM transactions WHERE user_id = $1`, userID)
+		pool.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	err = pool.QueryRow(ctx, `
+		INSERT INTO accounts (user_id, name, currency, is_default)
+		VALUES ($1, 'benchmark', 'RUB', true)
+		RETURNING id
+	`, userID).Scan(&accountID)
+	if err != nil {
+		b.Fatalf("failed to create account: %v", err)
+	}
+
+	_, err = pool.Exec(ctx, `
+		INSERT INTO transactions (user_id, account_id, category_id, type, amount, currency, description, operation_date)
+		SELECT $1, $2,
+			(SELECT id FROM categories WHERE user_id IS NULL AND type = 'expense' ORDER BY id LIMIT 1),
+			'expense', 100 + n % 900, 'RUB', 'операция ' || n, NOW() - n * INTERVAL '1 hour'
+		FROM generate_series(1, $3::INT) AS n
+	`, userID, accountID, benchmarkTransactions)
+	if err != nil {
+		b.Fatalf("failed to seed transactions: %v", err)
+	}
+
+	return NewRepository(pool, zap.NewNop()), userID
+}
+
+// BenchmarkListTransactions сравнивает список операций одним запросом
+// с прежним способом: id операций, затем GetTransactionWithDetails на
+// каждую.
+func BenchmarkListTransactions(b *testing.B) {
+	repo, userID := newBenchmarkRepository(b)
+	ctx := context.Background()
+
+	b.Run("joined", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			transactions, err := repo.ListTransactions(ctx, userID, TransactionFilter{
+				Period: "all",
+				Limit:  benchmarkTransactions,
+			})
+			if err != nil {
+				b.Fatal(err)
+			}
+			if len(transactions) != benchmarkTransactions {
+				b.Fatalf("got %d transactions, want %d", len(transactions), benchmarkTransactions)
+			}
+		}
+	})
+
+	b.Run("per_row", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			rows, err := repo.db.Query(ctx, `
+				SELECT id FROM transactions
+				WHERE user_id = $1
+				ORDER BY operation_date DESC, id DESC
+				LIMIT $2
+			`, userID, benchmarkTransactions)
+			if err != nil {
+				b.Fatal(err)
+			}
+			var ids []int64
+			for rows.Next() {
+				var id int64
+				if err := rows.Scan(&id); err != nil {
+					b.Fatal(err)
+				}
+				ids = append(ids, id)
+			}
+			rows.Close()
+
+			for _, id := range ids {
+				transaction, err := repo.GetTransactionWithDetails(ctx, id, userID)
+				if err != nil || transaction == nil {
+					b.Fatalf("failed to get transaction %d: %v", id, err)
+				}
+			}
+		}
+	})
+}
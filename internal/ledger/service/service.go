@@ -2,181 +2,373 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/kiribu/financial-tracker/internal/ledger/repository"
+	"github.com/kiribu/financial-tracker/internal/pkg/blobstore"
 	"go.uber.org/zap"
 )
 
+// Типы счетов
+const (
+	AccountTypeCash       = "cash"
+	AccountTypeDebitCard  = "debit_card"
+	AccountTypeCreditCard = "credit_card"
+	AccountTypeSavings    = "savings"
+	AccountTypeDeposit    = "deposit"
+	AccountTypeLoan       = "loan"
+	AccountTypeInvestment = "investment"
+)
+
+// Политики овердрафта для некредитных счетов
+const (
+	OverdraftAllow  = "allow"
+	OverdraftWarn   = "warn"
+	OverdraftForbid = "forbid"
+)
+
+// MaxCategoryDepth — максимальная глубина вложенности категорий,
+// включая категорию верхнего уровня.
+const MaxCategoryDepth = 3
+
+var ErrCreditLimitExceeded = errors.New("credit limit exceeded")
+
+// InsufficientFundsError возвращается, когда списание уводит в минус счет
+// с политикой овердрафта "forbid".
+type InsufficientFundsError struct {
+	AccountID   int64
+	AccountName string
+	Shortfall   string
+	Currency    string
+}
+
+func (e *InsufficientFundsError) Error() string {
+	return fmt.Sprintf("недостаточно средств на счете «%s»: не хватает %s %s", e.AccountName, e.Shortfall, e.Currency)
+}
+
 type Service struct {
 	repo   *repository.Repository
+	blobs  blobstore.Store
+	limits AttachmentLimits
 	logger *zap.Logger
 }
 
-func NewService(repo *repository.Repository, logger *zap.Logger) *Service {
+// AttachmentLimits — ограничения на вложения в байтах
+type AttachmentLimits struct {
+	MaxFileSize int64
+	UserQuota   int64
+}
+
+func NewService(repo *repository.Repository, blobs blobstore.Store, limits AttachmentLimits, logger *zap.Logger) *Service {
 	return &Service{
 		repo:   repo,
+		blobs:  blobs,
+		limits: limits,
 		logger: logger,
 	}
 }
 
-func (s *Service) CreateExpense(ctx context.Context, userID, accountID int64, amount string, categoryID int64, description string, operationDate time.Time) (*repository.Transaction, string, error) {
-	// Verify account belongs to user
-	account, err := s.repo.GetAccount(ctx, accountID, userID)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to get account: %w", err)
-	}
-	if account == nil {
-		return nil, "", fmt.Errorf("account not found or doesn't belong to user")
-	}
+// TransactionInput — поля операции при создании и изменении. При изменении
+// теги и строки разбивки заменяются, только если выставлены ReplaceTags и
+// ReplaceSplits.
+type TransactionInput struct {
+	AccountID        int64
+	RelatedAccountID int64
+	Amount           string
+	CategoryID       int64
+	Description      string
+	OperationDate    time.Time
+	Tags             []string
+	ReplaceTags      bool
+	Splits           []*repository.Split
+	ReplaceSplits    bool
+	PayeeID          int64
+	Payee            string // Имя или псевдоним получателя, если PayeeID не задан
+	ApplyRules       bool   // Применить правила пользователя при создании
+	ReceiptQR        string // Текст QR-кода кассового чека: сумма и дата берутся из него, если не заданы
+	receipt          *FiscalReceipt
+}
 
-	// Create transaction
-	tx := &repository.Transaction{
-		UserID:        userID,
-		AccountID:     accountID,
-		CategoryID:    sql.NullInt64{Int64: categoryID, Valid: true},
-		Type:          "expense",
-		Amount:        amount,
-		Currency:      account.Currency,
-		Description:   sql.NullString{String: description, Valid: description != ""},
-		OperationDate: operationDate,
+func (s *Service) CreateExpense(ctx context.Context, userID int64, input TransactionInput) (*repository.Transaction, string, string, error) {
+	if err := prepareTransactionInput(ctx, s.repo, userID, "expense", &input); err != nil {
+		return nil, "", "", err
 	}
-
-	transaction, err := s.repo.CreateTransaction(ctx, tx)
+	accountID, amount := input.AccountID, input.Amount
+	tags, err := normalizeTags(input.Tags)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to create transaction: %w", err)
+		return nil, "", "", err
 	}
 
-	// Update account balance (decrease)
-	negativeAmount := "-" + amount
-	if err := s.repo.UpdateAccountBalance(ctx, accountID, negativeAmount); err != nil {
-		s.logger.Error("failed to update account balance", zap.Error(err))
-		return nil, "", fmt.Errorf("failed to update account balance: %w", err)
-	}
+	var transaction *repository.Transaction
+	var balance, warning string
+
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		// Verify account belongs to user and lock it until commit
+		account, err := repo.GetAccountForUpdate(ctx, accountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get account: %w", err)
+		}
+		if account == nil {
+			return fmt.Errorf("account not found or doesn't belong to user")
+		}
+
+		warning, err = checkDebit(account, amount)
+		if err != nil {
+			return err
+		}
+
+		if err := prepareSplits(ctx, repo, userID, "expense", amount, input.Splits); err != nil {
+			return err
+		}
+
+		// Create transaction
+		tx := &repository.Transaction{
+			UserID:        userID,
+			AccountID:     accountID,
+			CategoryID:    sql.NullInt64{Int64: splitCategoryID(input.CategoryID, input.Splits), Valid: true},
+			Type:          "expense",
+			Amount:        amount,
+			Currency:      account.Currency,
+			Description:   sql.NullString{String: input.Description, Valid: input.Description != ""},
+			OperationDate: input.OperationDate,
+			PayeeID:       sql.NullInt64{Int64: input.PayeeID, Valid: input.PayeeID > 0},
+		}
+
+		transaction, err = repo.CreateTransaction(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		if err := repo.SetTransactionTags(ctx, userID, transaction.ID, tags); err != nil {
+			return fmt.Errorf("failed to save transaction tags: %w", err)
+		}
+		if err := repo.SetTransactionSplits(ctx, transaction.ID, input.Splits); err != nil {
+			return fmt.Errorf("failed to save transaction splits: %w", err)
+		}
+		if err := saveReceipt(ctx, repo, userID, transaction.ID, input.receipt); err != nil {
+			return err
+		}
+
+		// Update account balance (decrease)
+		negativeAmount := "-" + amount
+		if err := repo.UpdateAccountBalance(ctx, accountID, negativeAmount); err != nil {
+			s.logger.Error("failed to update account balance", zap.Error(err))
+			return fmt.Errorf("failed to update account balance: %w", err)
+		}
+
+		// Get updated balance
+		updatedAccount, err := repo.GetAccount(ctx, accountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated account: %w", err)
+		}
+		balance = updatedAccount.Balance
 
-	// Get updated balance
-	updatedAccount, err := s.repo.GetAccount(ctx, accountID, userID)
+		return nil
+	})
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get updated account: %w", err)
+		return nil, "", "", err
 	}
+	s.notifyExceededBudgets(ctx, userID)
 
-	return transaction, updatedAccount.Balance, nil
+	return transaction, balance, warning, nil
 }
 
-func (s *Service) CreateIncome(ctx context.Context, userID, accountID int64, amount string, categoryID int64, description string, operationDate time.Time) (*repository.Transaction, string, error) {
-	// Verify account belongs to user
-	account, err := s.repo.GetAccount(ctx, accountID, userID)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to get account: %w", err)
+func (s *Service) CreateIncome(ctx context.Context, userID int64, input TransactionInput) (*repository.Transaction, string, error) {
+	if err := prepareTransactionInput(ctx, s.repo, userID, "income", &input); err != nil {
+		return nil, "", err
 	}
-	if account == nil {
-		return nil, "", fmt.Errorf("account not found or doesn't belong to user")
+	accountID, amount := input.AccountID, input.Amount
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, "", err
 	}
 
-	// Create transaction
-	tx := &repository.Transaction{
-		UserID:        userID,
-		AccountID:     accountID,
-		CategoryID:    sql.NullInt64{Int64: categoryID, Valid: true},
-		Type:          "income",
-		Amount:        amount,
-		Currency:      account.Currency,
-		Description:   sql.NullString{String: description, Valid: description != ""},
-		OperationDate: operationDate,
-	}
+	var transaction *repository.Transaction
+	var balance string
 
-	transaction, err := s.repo.CreateTransaction(ctx, tx)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to create transaction: %w", err)
-	}
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		// Verify account belongs to user and lock it until commit
+		account, err := repo.GetAccountForUpdate(ctx, accountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get account: %w", err)
+		}
+		if account == nil {
+			return fmt.Errorf("account not found or doesn't belong to user")
+		}
 
-	// Update account balance (increase)
-	if err := s.repo.UpdateAccountBalance(ctx, accountID, amount); err != nil {
-		s.logger.Error("failed to update account balance", zap.Error(err))
-		return nil, "", fmt.Errorf("failed to update account balance: %w", err)
-	}
+		if err := prepareSplits(ctx, repo, userID, "income", amount, input.Splits); err != nil {
+			return err
+		}
+
+		// Create transaction
+		tx := &repository.Transaction{
+			UserID:        userID,
+			AccountID:     accountID,
+			CategoryID:    sql.NullInt64{Int64: splitCategoryID(input.CategoryID, input.Splits), Valid: true},
+			Type:          "income",
+			Amount:        amount,
+			Currency:      account.Currency,
+			Description:   sql.NullString{String: input.Description, Valid: input.Description != ""},
+			OperationDate: input.OperationDate,
+			PayeeID:       sql.NullInt64{Int64: input.PayeeID, Valid: input.PayeeID > 0},
+		}
+
+		transaction, err = repo.CreateTransaction(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		if err := repo.SetTransactionTags(ctx, userID, transaction.ID, tags); err != nil {
+			return fmt.Errorf("failed to save transaction tags: %w", err)
+		}
+		if err := repo.SetTransactionSplits(ctx, transaction.ID, input.Splits); err != nil {
+			return fmt.Errorf("failed to save transaction splits: %w", err)
+		}
+		if err := saveReceipt(ctx, repo, userID, transaction.ID, input.receipt); err != nil {
+			return err
+		}
+
+		// Update account balance (increase)
+		if err := repo.UpdateAccountBalance(ctx, accountID, amount); err != nil {
+			s.logger.Error("failed to update account balance", zap.Error(err))
+			return fmt.Errorf("failed to update account balance: %w", err)
+		}
+
+		// Get updated balance
+		updatedAccount, err := repo.GetAccount(ctx, accountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated account: %w", err)
+		}
+		balance = updatedAccount.Balance
 
-	// Get updated balance
-	updatedAccount, err := s.repo.GetAccount(ctx, accountID, userID)
+		return nil
+	})
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get updated account: %w", err)
+		return nil, "", err
 	}
 
-	return transaction, updatedAccount.Balance, nil
+	return transaction, balance, nil
 }
 
-func (s *Service) CreateTransfer(ctx context.Context, userID, fromAccountID, toAccountID int64, amount string, description string, operationDate time.Time) (*repository.Transaction, string, string, error) {
-	// Verify both accounts belong to user
-	fromAccount, err := s.repo.GetAccount(ctx, fromAccountID, userID)
-	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to get from account: %w", err)
-	}
-	if fromAccount == nil {
-		return nil, "", "", fmt.Errorf("from account not found or doesn't belong to user")
+func (s *Service) CreateTransfer(ctx context.Context, userID, fromAccountID, toAccountID int64, amount string, description string, operationDate time.Time, tags []string) (*repository.Transaction, string, string, string, error) {
+	if fromAccountID == toAccountID {
+		return nil, "", "", "", fmt.Errorf("нельзя переводить с одного и того же счета на этот же")
 	}
-
-	toAccount, err := s.repo.GetAccount(ctx, toAccountID, userID)
+	tags, err := normalizeTags(tags)
 	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to get to account: %w", err)
-	}
-	if toAccount == nil {
-		return nil, "", "", fmt.Errorf("to account not found or doesn't belong to user")
+		return nil, "", "", "", err
 	}
 
-	if fromAccountID == toAccountID {
-		return nil, "", "", fmt.Errorf("нельзя переводить с одного и того же счета на этот же")
-	}
+	var transaction *repository.Transaction
+	var fromBalance, toBalance, warning string
 
-	// Create transaction
-	tx := &repository.Transaction{
-		UserID:          userID,
-		AccountID:       fromAccountID,
-		RelatedAccountID: sql.NullInt64{Int64: toAccountID, Valid: true},
-		Type:            "transfer",
-		Amount:          amount,
-		Currency:        fromAccount.Currency,
-		Description:     sql.NullString{String: description, Valid: description != ""},
-		OperationDate:   operationDate,
-	}
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		// Verify both accounts belong to user and lock them until commit
+		accounts, err := lockAccounts(ctx, repo, userID, fromAccountID, toAccountID)
+		if err != nil {
+			return err
+		}
+		fromAccount := accounts[fromAccountID]
+		if fromAccount == nil {
+			return fmt.Errorf("from account not found or doesn't belong to user")
+		}
+		if accounts[toAccountID] == nil {
+			return fmt.Errorf("to account not found or doesn't belong to user")
+		}
 
-	transaction, err := s.repo.CreateTransaction(ctx, tx)
-	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to create transaction: %w", err)
-	}
+		warning, err = checkDebit(fromAccount, amount)
+		if err != nil {
+			return err
+		}
 
-	// Update balances
-	negativeAmount := "-" + amount
-	if err := s.repo.UpdateAccountBalance(ctx, fromAccountID, negativeAmount); err != nil {
-		s.logger.Error("failed to update from account balance", zap.Error(err))
-		return nil, "", "", fmt.Errorf("failed to update from account balance: %w", err)
-	}
+		// Create transaction
+		tx := &repository.Transaction{
+			UserID:          userID,
+			AccountID:       fromAccountID,
+			RelatedAccountID: sql.NullInt64{Int64: toAccountID, Valid: true},
+			Type:            "transfer",
+			Amount:          amount,
+			Currency:        fromAccount.Currency,
+			Description:     sql.NullString{String: description, Valid: description != ""},
+			OperationDate:   operationDate,
+		}
 
-	if err := s.repo.UpdateAccountBalance(ctx, toAccountID, amount); err != nil {
-		s.logger.Error("failed to update to account balance", zap.Error(err))
-		return nil, "", "", fmt.Errorf("failed to update to account balance: %w", err)
-	}
+		transaction, err = repo.CreateTransaction(ctx, tx)
+		if err != nil {
+			return fmt.Errorf("failed to create transaction: %w", err)
+		}
+		if err := repo.SetTransactionTags(ctx, userID, transaction.ID, tags); err != nil {
+			return fmt.Errorf("failed to save transaction tags: %w", err)
+		}
 
-	// Get updated balances
-	updatedFromAccount, err := s.repo.GetAccount(ctx, fromAccountID, userID)
-	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to get updated from account: %w", err)
-	}
+		// Update balances
+		negativeAmount := "-" + amount
+		if err := repo.UpdateAccountBalance(ctx, fromAccountID, negativeAmount); err != nil {
+			s.logger.Error("failed to update from account balance", zap.Error(err))
+			return fmt.Errorf("failed to update from account balance: %w", err)
+		}
+
+		if err := repo.UpdateAccountBalance(ctx, toAccountID, amount); err != nil {
+			s.logger.Error("failed to update to account balance", zap.Error(err))
+			return fmt.Errorf("failed to update to account balance: %w", err)
+		}
+
+		// Get updated balances
+		updatedFromAccount, err := repo.GetAccount(ctx, fromAccountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated from account: %w", err)
+		}
 
-	updatedToAccount, err := s.repo.GetAccount(ctx, toAccountID, userID)
+		updatedToAccount, err := repo.GetAccount(ctx, toAccountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated to account: %w", err)
+		}
+
+		fromBalance = updatedFromAccount.Balance
+		toBalance = updatedToAccount.Balance
+		return nil
+	})
 	if err != nil {
-		return nil, "", "", fmt.Errorf("failed to get updated to account: %w", err)
+		return nil, "", "", "", err
 	}
 
-	return transaction, updatedFromAccount.Balance, updatedToAccount.Balance, nil
+	return transaction, fromBalance, toBalance, warning, nil
+}
+
+func (s *Service) ListAccounts(ctx context.Context, userID int64, includeArchived bool) ([]*repository.Account, error) {
+	return s.repo.ListAccounts(ctx, userID, includeArchived)
 }
 
-func (s *Service) ListAccounts(ctx context.Context, userID int64) ([]*repository.Account, error) {
-	return s.repo.ListAccounts(ctx, userID)
+// AccountUpdate — изменяемые поля счета. Пустые строки и нулевые дни оставляют
+// кредитные параметры без изменений, nil — оформление без изменений.
+type AccountUpdate struct {
+	Name              string
+	Balance           string
+	CreditLimit       string
+	StatementDay      int32
+	DueDay            int32
+	OverdraftPolicy   string
+	Group             *string
+	Icon              *string
+	Color             *string
+	ExcludeFromTotals *bool
 }
 
-func (s *Service) UpdateAccount(ctx context.Context, userID, accountID int64, name, balance string) (*repository.Account, error) {
+func (s *Service) UpdateAccount(ctx context.Context, userID, accountID int64, update AccountUpdate) (*repository.Account, error) {
 	// Verify account belongs to user
 	account, err := s.repo.GetAccount(ctx, accountID, userID)
 	if err != nil {
@@ -187,21 +379,51 @@ func (s *Service) UpdateAccount(ctx context.Context, userID, accountID int64, na
 	}
 
 	// Check if account with same name already exists for this user (excluding current account)
-	accounts, err := s.repo.ListAccounts(ctx, userID)
+	accounts, err := s.repo.ListAccounts(ctx, userID, false)
 	if err != nil {
 		return nil, fmt.Errorf("failed to check existing accounts: %w", err)
 	}
 	
 	for _, acc := range accounts {
-		if acc.ID != accountID && acc.Name == name && !acc.IsArchived {
+		if acc.ID != accountID && acc.Name == update.Name && !acc.IsArchived {
 			return nil, fmt.Errorf("счет с таким названием уже существует")
 		}
 	}
 
-	return s.repo.UpdateAccount(ctx, accountID, userID, name, balance)
+	account.Name = update.Name
+	account.Balance = update.Balance
+
+	// Кредитные параметры и политика овердрафта меняются только если переданы
+	if err := applyCreditParams(account, update.CreditLimit, update.StatementDay, update.DueDay); err != nil {
+		return nil, err
+	}
+	if update.OverdraftPolicy != "" {
+		if !IsValidOverdraftPolicy(update.OverdraftPolicy) {
+			return nil, fmt.Errorf("invalid overdraft policy")
+		}
+		account.OverdraftPolicy = update.OverdraftPolicy
+	}
+
+	if update.Group != nil {
+		account.Group = strings.TrimSpace(*update.Group)
+	}
+	if update.Icon != nil {
+		account.Icon = *update.Icon
+	}
+	if update.Color != nil {
+		account.Color = *update.Color
+	}
+	if update.ExcludeFromTotals != nil {
+		account.ExcludeFromTotals = *update.ExcludeFromTotals
+	}
+	if err := validateAccountAppearance(account); err != nil {
+		return nil, err
+	}
+
+	return s.repo.UpdateAccount(ctx, account)
 }
 
-func (s *Service) CreateCategory(ctx context.Context, userID int64, name, categoryType string) (*repository.Category, error) {
+func (s *Service) CreateCategory(ctx context.Context, userID int64, name, categoryType string, parentID int64) (*repository.Category, error) {
 	if name == "" {
 		return nil, fmt.Errorf("category name cannot be empty")
 	}
@@ -210,230 +432,5882 @@ func (s *Service) CreateCategory(ctx context.Context, userID int64, name, catego
 	}
 	
 	// Check if category with same name and type already exists for this user
-	categories, err := s.repo.ListCategories(ctx, userID, categoryType)
-	if err != nil {
-		return nil, fmt.Errorf("failed to check existing categories: %w", err)
+	if err := s.checkCategoryName(ctx, userID, categoryType, name, 0); err != nil {
+		return nil, err
+	}
+
+	if parentID > 0 {
+		parent, err := s.repo.GetCategory(ctx, parentID, userID)
+		if err != nil {
+			if err.Error() == "category not found" {
+				return nil, fmt.Errorf("parent category not found")
+			}
+			return nil, err
+		}
+		if parent.Type != categoryType {
+			return nil, fmt.Errorf("parent category type mismatch")
+		}
+		if parent.IsArchived {
+			return nil, fmt.Errorf("parent category is archived")
+		}
+		depth, err := categoryDepth(ctx, s.repo, userID, parent)
+		if err != nil {
+			return nil, err
+		}
+		if depth >= MaxCategoryDepth {
+			return nil, fmt.Errorf("category nesting is too deep")
+		}
 	}
 	
+	return s.repo.CreateCategory(ctx, userID, name, categoryType, parentID)
+}
+
+// checkCategoryName проверяет, что у пользователя нет другой категории
+// того же типа с таким названием, включая архивные.
+func (s *Service) checkCategoryName(ctx context.Context, userID int64, categoryType, name string, exceptID int64) error {
+	categories, err := s.repo.ListCategories(ctx, userID, categoryType, true)
+	if err != nil {
+		return fmt.Errorf("failed to check existing categories: %w", err)
+	}
+
 	for _, cat := range categories {
-		if cat.UserID.Valid && cat.UserID.Int64 == userID && cat.Name == name {
-			return nil, fmt.Errorf("категория с таким названием уже существует")
+		if cat.UserID.Valid && cat.UserID.Int64 == userID && cat.Name == name && cat.ID != exceptID {
+			return fmt.Errorf("категория с таким названием уже существует")
 		}
 	}
-	
-	return s.repo.CreateCategory(ctx, userID, name, categoryType)
+	return nil
+}
+
+// categoryDepth возвращает уровень категории в дереве: 1 для категории
+// верхнего уровня.
+func categoryDepth(ctx context.Context, repo *repository.Repository, userID int64, category *repository.Category) (int, error) {
+	depth := 1
+	for category.ParentID.Valid && depth <= MaxCategoryDepth {
+		parent, err := repo.GetCategory(ctx, category.ParentID.Int64, userID)
+		if err != nil {
+			return 0, err
+		}
+		category = parent
+		depth++
+	}
+	return depth, nil
+}
+
+func (s *Service) ListCategories(ctx context.Context, userID int64, categoryType string, includeArchived bool) ([]*repository.Category, error) {
+	return s.repo.ListCategories(ctx, userID, categoryType, includeArchived)
 }
 
-func (s *Service) ListCategories(ctx context.Context, userID int64, categoryType string) ([]*repository.Category, error) {
-	return s.repo.ListCategories(ctx, userID, categoryType)
+// CategoryNode — категория вместе с подкатегориями.
+type CategoryNode struct {
+	*repository.Category
+	Children []*CategoryNode
 }
 
-func (s *Service) ListTransactions(ctx context.Context, userID int64, period string, limit int32, startDate, endDate string) ([]*repository.TransactionWithDetails, error) {
-	transactions, err := s.repo.ListTransactions(ctx, userID, period, limit, startDate, endDate)
+// ListCategoryTree возвращает категории в виде дерева. Порядок внутри
+// каждого уровня совпадает с порядком ListCategories.
+func (s *Service) ListCategoryTree(ctx context.Context, userID int64, categoryType string, includeArchived bool) ([]*CategoryNode, error) {
+	categories, err := s.repo.ListCategories(ctx, userID, categoryType, includeArchived)
 	if err != nil {
 		return nil, err
 	}
 
-	// Enrich with details
-	var result []*repository.TransactionWithDetails
-	for _, tx := range transactions {
-		details, err := s.repo.GetTransactionWithDetails(ctx, tx.ID)
-		if err != nil {
-			s.logger.Warn("failed to get transaction details", zap.Int64("transaction_id", tx.ID), zap.Error(err))
+	nodes := make(map[int64]*CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &CategoryNode{Category: category}
+	}
+
+	var roots []*CategoryNode
+	for _, category := range categories {
+		node := nodes[category.ID]
+		parent, ok := nodes[category.ParentID.Int64]
+		if !category.ParentID.Valid || !ok {
+			roots = append(roots, node)
 			continue
 		}
-		if details != nil {
-			result = append(result, details)
-		}
+		parent.Children = append(parent.Children, node)
 	}
 
-	return result, nil
+	return roots, nil
 }
 
-func (s *Service) GetBalance(ctx context.Context, userID int64) ([]*repository.Account, error) {
-	return s.repo.ListAccounts(ctx, userID)
+// CategoryUpdate — изменяемые поля категории. Пустое название и nil
+// означают "без изменений".
+type CategoryUpdate struct {
+	Name  string
+	Icon  *string
+	Color *string
 }
 
-func (s *Service) CreateAccount(ctx context.Context, userID int64, name, currency, balance string) (*repository.Account, error) {
-	if name == "" {
-		return nil, fmt.Errorf("account name cannot be empty")
-	}
-	if currency == "" {
-		currency = "RUB"
-	}
-	
-	// Check if account with same name already exists for this user
-	accounts, err := s.repo.ListAccounts(ctx, userID)
+func (s *Service) UpdateCategory(ctx context.Context, userID, categoryID int64, update CategoryUpdate) (*repository.Category, error) {
+	category, err := s.repo.GetCategory(ctx, categoryID, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to check existing accounts: %w", err)
+		return nil, err
 	}
-	
-	for _, acc := range accounts {
-		if acc.Name == name && !acc.IsArchived {
-			return nil, fmt.Errorf("счет с таким названием уже существует")
+
+	if update.Name != "" && update.Name != category.Name {
+		if err := s.checkCategoryName(ctx, userID, category.Type, update.Name, category.ID); err != nil {
+			return nil, err
 		}
+		category.Name = update.Name
 	}
-	
-	// Set default balance if not provided
-	if balance == "" {
-		balance = "0"
+	if update.Icon != nil {
+		category.Icon = *update.Icon
+	}
+	if update.Color != nil {
+		category.Color = *update.Color
+	}
+	if err := validateCategoryAppearance(category); err != nil {
+		return nil, err
 	}
-	
-	return s.repo.CreateAccount(ctx, userID, name, currency, balance)
-}
-
-func (s *Service) DeleteAccount(ctx context.Context, userID, accountID int64) error {
-	return s.repo.DeleteAccount(ctx, accountID, userID)
-}
 
-func (s *Service) DeleteCategory(ctx context.Context, userID, categoryID int64) error {
-	return s.repo.DeleteCategory(ctx, categoryID, userID)
-}
+	if category.UserID.Valid {
+		return s.repo.UpdateCategory(ctx, category)
+	}
 
-func (s *Service) UpdateTransaction(ctx context.Context, userID, transactionID, accountID int64, amount string, categoryID int64, description string, operationDate time.Time, relatedAccountID int64) (*repository.Transaction, string, error) {
-	// Get old transaction
-	oldTx, err := s.repo.GetTransaction(ctx, transactionID, userID)
+	// Глобальные категории общие для всех: изменения сохраняются только
+	// в настройках пользователя
+	override, err := s.repo.GetCategoryOverride(ctx, userID, category.ID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get transaction: %w", err)
+		return nil, err
+	}
+	if update.Name != "" {
+		override.Name = sql.NullString{String: update.Name, Valid: true}
+	}
+	if update.Icon != nil {
+		override.Icon = sql.NullString{String: *update.Icon, Valid: true}
+	}
+	if update.Color != nil {
+		override.Color = sql.NullString{String: *update.Color, Valid: true}
 	}
-	if oldTx == nil {
-		return nil, "", fmt.Errorf("transaction not found")
+	if err := s.repo.SaveCategoryOverride(ctx, override); err != nil {
+		return nil, err
 	}
 
-	// Verify account belongs to user
-	account, err := s.repo.GetAccount(ctx, accountID, userID)
+	return s.repo.GetCategory(ctx, category.ID, userID)
+}
+
+// setSystemCategoryHidden скрывает глобальную категорию для пользователя
+// или возвращает ее.
+func setSystemCategoryHidden(ctx context.Context, repo *repository.Repository, userID, categoryID int64, hidden bool) error {
+	override, err := repo.GetCategoryOverride(ctx, userID, categoryID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get account: %w", err)
+		return err
 	}
-	if account == nil {
-		return nil, "", fmt.Errorf("account not found or doesn't belong to user")
+	override.IsHidden = hidden
+	return repo.SaveCategoryOverride(ctx, override)
+}
+
+// MergeCategories переносит операции из категорий-источников в целевую
+// категорию. Категории пользователя удаляются, глобальные — скрываются.
+// Подкатегории источников переходят к целевой категории. Все изменения
+// выполняются в одной транзакции.
+func (s *Service) MergeCategories(ctx context.Context, userID int64, sourceIDs []int64, targetID int64) (int64, error) {
+	if len(sourceIDs) == 0 {
+		return 0, fmt.Errorf("no source categories")
 	}
 
-	// Rollback old transaction balance
-	oldAmount := oldTx.Amount
-	if oldTx.Type == "expense" {
-		// Rollback expense: add back to account
-		if err := s.repo.UpdateAccountBalance(ctx, oldTx.AccountID, oldAmount); err != nil {
-			return nil, "", fmt.Errorf("failed to rollback old balance: %w", err)
+	var moved int64
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		target, err := repo.GetCategory(ctx, targetID, userID)
+		if err != nil {
+			if err.Error() == "category not found" {
+				return fmt.Errorf("target category not found")
+			}
+			return err
 		}
-	} else if oldTx.Type == "income" {
-		// Rollback income: subtract from account
-		negativeAmount := "-" + oldAmount
-		if err := s.repo.UpdateAccountBalance(ctx, oldTx.AccountID, negativeAmount); err != nil {
-			return nil, "", fmt.Errorf("failed to rollback old balance: %w", err)
+		if target.IsArchived {
+			return fmt.Errorf("target category is archived")
 		}
-	} else if oldTx.Type == "transfer" {
-		// Rollback transfer: restore both accounts
-		if oldTx.RelatedAccountID.Valid {
-			if err := s.repo.UpdateAccountBalance(ctx, oldTx.AccountID, oldAmount); err != nil {
-				return nil, "", fmt.Errorf("failed to rollback from account: %w", err)
+
+		seen := make(map[int64]bool, len(sourceIDs))
+		for _, sourceID := range sourceIDs {
+			if seen[sourceID] {
+				return fmt.Errorf("duplicate category id")
 			}
-			negativeAmount := "-" + oldAmount
-			if err := s.repo.UpdateAccountBalance(ctx, oldTx.RelatedAccountID.Int64, negativeAmount); err != nil {
-				return nil, "", fmt.Errorf("failed to rollback to account: %w", err)
+			seen[sourceID] = true
+			if sourceID == targetID {
+				return fmt.Errorf("cannot merge category into itself")
 			}
-		}
-	}
 
-	// Update transaction
-	updatedTx := &repository.Transaction{
-		ID:            transactionID,
-		UserID:        userID,
-		AccountID:     accountID,
-		CategoryID:    sql.NullInt64{Int64: categoryID, Valid: categoryID > 0},
-		Type:          oldTx.Type, // Keep original type
-		Amount:        amount,
-		Currency:      account.Currency,
-		Description:   sql.NullString{String: description, Valid: description != ""},
-		OperationDate: operationDate,
-	}
-	if relatedAccountID > 0 {
-		updatedTx.RelatedAccountID = sql.NullInt64{Int64: relatedAccountID, Valid: true}
-	}
+			source, err := repo.GetCategory(ctx, sourceID, userID)
+			if err != nil {
+				return err
+			}
+			if source.Type != target.Type {
+				return fmt.Errorf("category type mismatch")
+			}
+			if err := checkCategoryMove(ctx, repo, userID, source, target); err != nil {
+				return err
+			}
 
-	if err := s.repo.UpdateTransaction(ctx, updatedTx); err != nil {
-		return nil, "", fmt.Errorf("failed to update transaction: %w", err)
-	}
+			targetParent := sql.NullInt64{Int64: target.ID, Valid: true}
+			if err := repo.MoveChildCategories(ctx, source.ID, targetParent, userID); err != nil {
+				return err
+			}
+			count, err := repo.MoveCategoryTransactions(ctx, source.ID, target.ID, userID)
+			if err != nil {
+				return err
+			}
+			moved += count
+			if err := repo.ReplaceFallbackCategory(ctx, userID, source.ID, target.ID); err != nil {
+				return err
+			}
 
-	// Apply new transaction balance
-	if oldTx.Type == "expense" {
-		negativeAmount := "-" + amount
-		if err := s.repo.UpdateAccountBalance(ctx, accountID, negativeAmount); err != nil {
-			return nil, "", fmt.Errorf("failed to update account balance: %w", err)
-		}
-	} else if oldTx.Type == "income" {
-		if err := s.repo.UpdateAccountBalance(ctx, accountID, amount); err != nil {
-			return nil, "", fmt.Errorf("failed to update account balance: %w", err)
+			if source.UserID.Valid {
+				err = repo.RemoveCategory(ctx, source.ID, userID)
+			} else {
+				err = setSystemCategoryHidden(ctx, repo, userID, source.ID, true)
+			}
+			if err != nil {
+				return err
+			}
 		}
-	} else if oldTx.Type == "transfer" && relatedAccountID > 0 {
-		// Apply transfer: decrease from account, increase to account
-		negativeAmount := "-" + amount
-		if err := s.repo.UpdateAccountBalance(ctx, accountID, negativeAmount); err != nil {
-			return nil, "", fmt.Errorf("failed to update from account balance: %w", err)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return moved, nil
+}
+
+// checkCategoryMove проверяет, что подкатегории source можно перенести под
+// target: target не лежит внутри source и глубина дерева не превысит лимит.
+func checkCategoryMove(ctx context.Context, repo *repository.Repository, userID int64, source, target *repository.Category) error {
+	depth := 1
+	for ancestor := target; ancestor.ParentID.Valid; depth++ {
+		if ancestor.ParentID.Int64 == source.ID {
+			return fmt.Errorf("cannot merge category into its subcategory")
 		}
-		// Verify to account belongs to user
-		toAccount, err := s.repo.GetAccount(ctx, relatedAccountID, userID)
+		parent, err := repo.GetCategory(ctx, ancestor.ParentID.Int64, userID)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to get to account: %w", err)
-		}
-		if toAccount == nil {
-			return nil, "", fmt.Errorf("to account not found or doesn't belong to user")
-		}
-		if err := s.repo.UpdateAccountBalance(ctx, relatedAccountID, amount); err != nil {
-			return nil, "", fmt.Errorf("failed to update to account balance: %w", err)
+			return err
 		}
+		ancestor = parent
 	}
 
-	// Get updated balance
-	updatedAccount, err := s.repo.GetAccount(ctx, accountID, userID)
+	height, err := repo.CategorySubtreeDepth(ctx, source.ID)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get updated account: %w", err)
+		return err
 	}
-
-	return updatedTx, updatedAccount.Balance, nil
+	// Подкатегории источника встают на уровень ниже target
+	if depth+height-1 > MaxCategoryDepth {
+		return fmt.Errorf("category nesting is too deep")
+	}
+	return nil
 }
 
-func (s *Service) DeleteTransaction(ctx context.Context, userID, transactionID int64) error {
-	// Get transaction
-	tx, err := s.repo.GetTransaction(ctx, transactionID, userID)
+// ArchiveCategory скрывает категорию и ее подкатегории из выбора при вводе
+// операций. Операции остаются в категории и учитываются в статистике.
+// Глобальная категория скрывается только для этого пользователя.
+func (s *Service) ArchiveCategory(ctx context.Context, userID, categoryID int64) (*repository.Category, error) {
+	category, err := s.repo.GetCategory(ctx, categoryID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to get transaction: %w", err)
+		return nil, err
 	}
-	if tx == nil {
-		return fmt.Errorf("transaction not found")
+
+	if category.UserID.Valid {
+		err = s.repo.SetCategoryArchived(ctx, category.ID, userID, true)
+	} else {
+		err = setSystemCategoryHidden(ctx, s.repo, userID, category.ID, true)
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	// Rollback balance
-	if tx.Type == "expense" {
-		// Rollback expense: add back to account
-		if err := s.repo.UpdateAccountBalance(ctx, tx.AccountID, tx.Amount); err != nil {
-			return fmt.Errorf("failed to rollback balance: %w", err)
+	return s.repo.GetCategory(ctx, category.ID, userID)
+}
+
+func (s *Service) UnarchiveCategory(ctx context.Context, userID, categoryID int64) (*repository.Category, error) {
+	category, err := s.repo.GetCategory(ctx, categoryID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !category.UserID.Valid {
+		if err := setSystemCategoryHidden(ctx, s.repo, userID, category.ID, false); err != nil {
+			return nil, err
 		}
-	} else if tx.Type == "income" {
-		// Rollback income: subtract from account
-		negativeAmount := "-" + tx.Amount
-		if err := s.repo.UpdateAccountBalance(ctx, tx.AccountID, negativeAmount); err != nil {
-			return fmt.Errorf("failed to rollback balance: %w", err)
+		return s.repo.GetCategory(ctx, category.ID, userID)
+	}
+
+	if category.ParentID.Valid {
+		parent, err := s.repo.GetCategory(ctx, category.ParentID.Int64, userID)
+		if err != nil {
+			return nil, err
 		}
-	} else if tx.Type == "transfer" {
-		// Rollback transfer: restore both accounts
-		if tx.RelatedAccountID.Valid {
-			if err := s.repo.UpdateAccountBalance(ctx, tx.AccountID, tx.Amount); err != nil {
-				return fmt.Errorf("failed to rollback from account: %w", err)
-			}
-			negativeAmount := "-" + tx.Amount
-			if err := s.repo.UpdateAccountBalance(ctx, tx.RelatedAccountID.Int64, negativeAmount); err != nil {
-				return fmt.Errorf("failed to rollback to account: %w", err)
-			}
+		if parent.IsArchived {
+			return nil, fmt.Errorf("parent category is archived")
 		}
 	}
 
-	// Delete transaction
-	_, err = s.repo.DeleteTransaction(ctx, transactionID, userID)
-	if err != nil {
-		return fmt.Errorf("failed to delete transaction: %w", err)
+	if err := s.repo.SetCategoryArchived(ctx, category.ID, userID, false); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return s.repo.GetCategory(ctx, category.ID, userID)
+}
+
+// ReorderCategories задает порядок категорий в списках пользователя.
+// Порядок хранится в настройках пользователя, поэтому подходит и для
+// глобальных категорий. Категории, не попавшие в список, идут после.
+func (s *Service) ReorderCategories(ctx context.Context, userID int64, categoryIDs []int64) error {
+	seen := make(map[int64]bool, len(categoryIDs))
+	for _, id := range categoryIDs {
+		if seen[id] {
+			return fmt.Errorf("duplicate category id")
+		}
+		seen[id] = true
+	}
+
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		for i, id := range categoryIDs {
+			if _, err := repo.GetCategory(ctx, id, userID); err != nil {
+				return err
+			}
+			if err := repo.SetCategorySortOrder(ctx, userID, id, int32(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
+// GetFallbackCategoryID возвращает категорию по умолчанию пользователя
+// для операций указанного типа.
+func (s *Service) GetFallbackCategoryID(ctx context.Context, userID int64, categoryType string) (int64, error) {
+	return s.repo.GetFallbackCategoryID(ctx, userID, categoryType)
+}
+
+// SetFallbackCategory выбирает категорию по умолчанию вместо "Прочее": в нее
+// попадают быстрые расходы и операции удаленных категорий верхнего уровня.
+func (s *Service) SetFallbackCategory(ctx context.Context, userID int64, categoryType string, categoryID int64) (*repository.Category, error) {
+	if categoryType != "expense" && categoryType != "income" {
+		return nil, fmt.Errorf("invalid category type")
+	}
+
+	category, err := s.repo.GetCategory(ctx, categoryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if category.Type != categoryType {
+		return nil, fmt.Errorf("category type mismatch")
+	}
+	if category.IsArchived {
+		return nil, fmt.Errorf("fallback category is archived")
+	}
+
+	if err := s.repo.SetFallbackCategory(ctx, userID, categoryType, category.ID); err != nil {
+		return nil, err
+	}
+
+	return category, nil
+}
+
+// ListTransactions возвращает страницу операций по фильтру и токен следующей
+// страницы. Пустой токен означает, что операций больше нет. Фильтр по
+// категориям включает их подкатегории.
+func (s *Service) ListTransactions(ctx context.Context, userID int64, filter repository.TransactionFilter, pageToken string) ([]*repository.TransactionWithDetails, string, error) {
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, "", err
+	}
+	filter.Tags = tags
+
+	if err := normalizeTransactionFilter(&filter); err != nil {
+		return nil, "", err
+	}
+	if pageToken != "" {
+		filter.After, err = decodePageToken(pageToken, filter.Sort)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	if len(filter.CategoryIDs) > 0 {
+		filter.CategoryIDs, err = s.repo.CategorySubtreeIDs(ctx, filter.CategoryIDs)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	// Лишняя операция показывает, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	transactions, err := s.repo.ListTransactions(ctx, userID, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	var nextPageToken string
+	if limit > 0 && int32(len(transactions)) > limit {
+		transactions = transactions[:limit]
+		nextPageToken = encodePageToken(filter.Sort, &transactions[len(transactions)-1].Transaction)
+	}
+
+	if err := s.attachTagsAndSplits(ctx, userID, transactions); err != nil {
+		return nil, "", err
+	}
+
+	return transactions, nextPageToken, nil
+}
+
+// GetTransaction возвращает операцию пользователя со всеми подробностями.
+func (s *Service) GetTransaction(ctx context.Context, userID, transactionID int64) (*repository.TransactionWithDetails, error) {
+	tx, err := s.repo.GetTransactionWithDetails(ctx, transactionID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction not found")
+	}
+
+	if err := s.attachTagsAndSplits(ctx, userID, []*repository.TransactionWithDetails{tx}); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+// attachTagsAndSplits загружает теги и строки разбивки операций.
+func (s *Service) attachTagsAndSplits(ctx context.Context, userID int64, transactions []*repository.TransactionWithDetails) error {
+	ids := make([]int64, 0, len(transactions))
+	for _, tx := range transactions {
+		ids = append(ids, tx.ID)
+	}
+
+	transactionTags, err := s.repo.ListTransactionTags(ctx, ids)
+	if err != nil {
+		return err
+	}
+	transactionSplits, err := s.repo.ListTransactionSplits(ctx, userID, ids)
+	if err != nil {
+		return err
+	}
+	for _, tx := range transactions {
+		tx.Tags = transactionTags[tx.ID]
+		tx.Splits = transactionSplits[tx.ID]
+	}
+	return nil
+}
+
+// normalizeTransactionFilter проверяет условия фильтра операций и убирает
+// лишние пробелы из строк поиска.
+func normalizeTransactionFilter(filter *repository.TransactionFilter) error {
+	for _, txType := range filter.Types {
+		if txType != "expense" && txType != "income" && txType != "transfer" {
+			return fmt.Errorf("invalid transaction type")
+		}
+	}
+
+	var minAmount, maxAmount *big.Rat
+	var err error
+	if filter.MinAmount != "" {
+		if minAmount, err = parseAmount(filter.MinAmount); err != nil {
+			return fmt.Errorf("invalid amount range")
+		}
+	}
+	if filter.MaxAmount != "" {
+		if maxAmount, err = parseAmount(filter.MaxAmount); err != nil {
+			return fmt.Errorf("invalid amount range")
+		}
+	}
+	if minAmount != nil && maxAmount != nil && minAmount.Cmp(maxAmount) > 0 {
+		return fmt.Errorf("invalid amount range")
+	}
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = repository.SortDateDesc
+	case repository.SortDateDesc, repository.SortDateAsc, repository.SortAmountDesc, repository.SortAmountAsc:
+	default:
+		return fmt.Errorf("invalid sort order")
+	}
+
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Counterparty = normalizePayeeName(filter.Counterparty)
+	return nil
+}
+
+// encodePageToken кодирует позицию последней операции страницы вместе
+// с порядком сортировки, для которого она получена.
+func encodePageToken(sort string, tx *repository.Transaction) string {
+	value := tx.OperationDate.Format(time.RFC3339Nano)
+	if sort == repository.SortAmountDesc || sort == repository.SortAmountAsc {
+		value = tx.Amount
+	}
+	token := fmt.Sprintf("%s|%s|%d", sort, value, tx.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(token))
+}
+
+func decodePageToken(pageToken, sort string) (*repository.TransactionCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	parts := strings.SplitN(string(data), "|", 3)
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	id, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	if sort == repository.SortAmountDesc || sort == repository.SortAmountAsc {
+		if _, err := parseAmount(parts[1]); err != nil {
+			return nil, fmt.Errorf("invalid page token")
+		}
+	} else if _, err := time.Parse(time.RFC3339Nano, parts[1]); err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	return &repository.TransactionCursor{Value: parts[1], ID: id}, nil
+}
+
+// GetTagBreakdown возвращает суммы операций по тегам за период.
+func (s *Service) GetTagBreakdown(ctx context.Context, userID int64, txType, period, startDate, endDate string) ([]*repository.TagTotal, error) {
+	if txType == "" {
+		txType = "expense"
+	}
+	if txType != "expense" && txType != "income" {
+		return nil, fmt.Errorf("invalid transaction type")
+	}
+	return s.repo.GetTagBreakdown(ctx, userID, txType, period, startDate, endDate)
+}
+
+// BalanceSubtotal — сумма балансов счетов одной группы в одной валюте
+type BalanceSubtotal struct {
+	Group    string
+	Currency string
+	Total    string
+}
+
+type Balance struct {
+	Accounts []*repository.Account
+	Groups   []*BalanceSubtotal
+	Totals   []*BalanceSubtotal
+}
+
+// GetBalance возвращает активные счета и их итоги по группам и валютам.
+// Счета с ExcludeFromTotals в итоги не входят.
+func (s *Service) GetBalance(ctx context.Context, userID int64) (*Balance, error) {
+	accounts, err := s.repo.ListAccounts(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := newSubtotals()
+	totals := newSubtotals()
+	for _, acc := range accounts {
+		if acc.ExcludeFromTotals {
+			continue
+		}
+		amount, err := parseAmount(acc.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse balance of account %d: %w", acc.ID, err)
+		}
+		groups.add(acc.Group, acc.Currency, amount)
+		totals.add("", acc.Currency, amount)
+	}
+
+	return &Balance{
+		Accounts: accounts,
+		Groups:   groups.list(),
+		Totals:   totals.list(),
+	}, nil
+}
+
+// subtotals накапливает суммы по группе и валюте в порядке первого появления
+type subtotals struct {
+	order []*BalanceSubtotal
+	sums  map[[2]string]*big.Rat
+}
+
+func newSubtotals() *subtotals {
+	return &subtotals{sums: make(map[[2]string]*big.Rat)}
+}
+
+func (t *subtotals) add(group, currency string, amount *big.Rat) {
+	key := [2]string{group, currency}
+	sum, ok := t.sums[key]
+	if !ok {
+		sum = new(big.Rat)
+		t.sums[key] = sum
+		t.order = append(t.order, &BalanceSubtotal{Group: group, Currency: currency})
+	}
+	sum.Add(sum, amount)
+}
+
+func (t *subtotals) list() []*BalanceSubtotal {
+	for _, subtotal := range t.order {
+		subtotal.Total = formatAmount(t.sums[[2]string{subtotal.Group, subtotal.Currency}])
+	}
+	return t.order
+}
+
+// ReorderAccounts задает порядок счетов одной транзакцией: позиция счета
+// равна его индексу в accountIDs.
+func (s *Service) ReorderAccounts(ctx context.Context, userID int64, accountIDs []int64) error {
+	seen := make(map[int64]bool, len(accountIDs))
+	for _, id := range accountIDs {
+		if seen[id] {
+			return fmt.Errorf("duplicate account id")
+		}
+		seen[id] = true
+	}
+
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		for position, id := range accountIDs {
+			ok, err := repo.SetAccountSortOrder(ctx, id, userID, int32(position))
+			if err != nil {
+				return fmt.Errorf("failed to reorder accounts: %w", err)
+			}
+			if !ok {
+				return fmt.Errorf("account not found")
+			}
+		}
+		return nil
+	})
+}
+
+// AccountInput — параметры нового счета
+type AccountInput struct {
+	Name              string
+	Currency          string
+	Balance           string
+	Type              string
+	CreditLimit       string
+	StatementDay      int32
+	DueDay            int32
+	OverdraftPolicy   string
+	Group             string
+	Icon              string
+	Color             string
+	ExcludeFromTotals bool
+}
+
+func (s *Service) CreateAccount(ctx context.Context, userID int64, input AccountInput) (*repository.Account, error) {
+	if input.Name == "" {
+		return nil, fmt.Errorf("account name cannot be empty")
+	}
+	if input.Currency == "" {
+		input.Currency = "RUB"
+	}
+	if input.Type == "" {
+		input.Type = AccountTypeCash
+	}
+	if !IsValidAccountType(input.Type) {
+		return nil, fmt.Errorf("invalid account type")
+	}
+	if input.OverdraftPolicy == "" {
+		input.OverdraftPolicy = OverdraftForbid
+	}
+	if !IsValidOverdraftPolicy(input.OverdraftPolicy) {
+		return nil, fmt.Errorf("invalid overdraft policy")
+	}
+	
+	// Check if account with same name already exists for this user
+	accounts, err := s.repo.ListAccounts(ctx, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing accounts: %w", err)
+	}
+	
+	for _, acc := range accounts {
+		if acc.Name == input.Name && !acc.IsArchived {
+			return nil, fmt.Errorf("счет с таким названием уже существует")
+		}
+	}
+	
+	// Set default balance if not provided
+	if input.Balance == "" {
+		input.Balance = "0"
+	}
+
+	account := &repository.Account{
+		UserID:            userID,
+		Name:              input.Name,
+		Currency:          input.Currency,
+		Balance:           input.Balance,
+		Type:              input.Type,
+		OverdraftPolicy:   input.OverdraftPolicy,
+		Group:             strings.TrimSpace(input.Group),
+		Icon:              input.Icon,
+		Color:             input.Color,
+		ExcludeFromTotals: input.ExcludeFromTotals,
+	}
+
+	if err := applyCreditParams(account, input.CreditLimit, input.StatementDay, input.DueDay); err != nil {
+		return nil, err
+	}
+	if err := validateAccountAppearance(account); err != nil {
+		return nil, err
+	}
+	
+	return s.repo.CreateAccount(ctx, account)
+}
+
+func (s *Service) DeleteAccount(ctx context.Context, userID, accountID int64) error {
+	return s.repo.DeleteAccount(ctx, accountID, userID)
+}
+
+func (s *Service) UnarchiveAccount(ctx context.Context, userID, accountID int64) (*repository.Account, error) {
+	account, err := s.repo.GetAccount(ctx, accountID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("account not found")
+	}
+	if !account.IsArchived {
+		return account, nil
+	}
+
+	// Названия активных счетов должны оставаться уникальными
+	accounts, err := s.repo.ListAccounts(ctx, userID, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check existing accounts: %w", err)
+	}
+	for _, acc := range accounts {
+		if acc.Name == account.Name {
+			return nil, fmt.Errorf("счет с таким названием уже существует")
+		}
+	}
+
+	return s.repo.UnarchiveAccount(ctx, accountID, userID)
+}
+
+// PurgeAccount безвозвратно удаляет архивный счет. История операций либо
+// удаляется, либо переносится на счет reassignToAccountID той же валюты.
+// Возвращает количество удаленных и перенесенных операций.
+func (s *Service) PurgeAccount(ctx context.Context, userID, accountID, reassignToAccountID int64, confirmName string) (int64, int64, error) {
+	var deleted, reassigned int64
+	var orphanedKeys []string
+
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		accounts, err := lockAccounts(ctx, repo, userID, accountID, reassignToAccountID)
+		if err != nil {
+			return err
+		}
+
+		account := accounts[accountID]
+		if account == nil {
+			return fmt.Errorf("account not found")
+		}
+		if account.IsDefault {
+			return fmt.Errorf("cannot delete default account")
+		}
+		if !account.IsArchived {
+			return fmt.Errorf("only archived accounts can be purged")
+		}
+		if confirmName != account.Name {
+			return fmt.Errorf("confirmation does not match account name")
+		}
+
+		attachmentKeys, err := repo.TransactionAttachmentKeys(ctx, userID, 0, accountID)
+		if err != nil {
+			return fmt.Errorf("failed to list attachments: %w", err)
+		}
+
+		if reassignToAccountID == 0 {
+			deleted, err = repo.DeleteAccountTransactions(ctx, accountID, userID)
+			if err != nil {
+				return fmt.Errorf("failed to delete account transactions: %w", err)
+			}
+		} else {
+			target := accounts[reassignToAccountID]
+			if target == nil || reassignToAccountID == accountID {
+				return fmt.Errorf("target account not found")
+			}
+			if target.IsArchived {
+				return fmt.Errorf("target account is archived")
+			}
+			if target.Currency != account.Currency {
+				return fmt.Errorf("target account currency does not match")
+			}
+
+			// Вместе с историей к целевому счету переходит и остаток, иначе
+			// его баланс разойдется с суммой операций. Переводы между двумя
+			// счетами на общий остаток не влияют.
+			balance, err := parseAmount(account.Balance)
+			if err != nil {
+				return fmt.Errorf("failed to parse account balance: %w", err)
+			}
+			if _, err := checkDebits(accounts, map[int64]*big.Rat{reassignToAccountID: balance}); err != nil {
+				return err
+			}
+
+			reassigned, err = repo.ReassignAccountTransactions(ctx, accountID, reassignToAccountID, userID)
+			if err != nil {
+				return fmt.Errorf("failed to reassign account transactions: %w", err)
+			}
+			if balance.Sign() != 0 {
+				if err := repo.UpdateAccountBalance(ctx, reassignToAccountID, account.Balance); err != nil {
+					return fmt.Errorf("failed to update target account balance: %w", err)
+				}
+			}
+		}
+
+		if err := repo.DeleteArchivedAccount(ctx, accountID, userID); err != nil {
+			return fmt.Errorf("failed to purge account: %w", err)
+		}
+
+		// Вложения удаленных операций удалились каскадно, их файлы нужно
+		// убрать из хранилища
+		orphanedKeys, err = repo.MissingAttachmentKeys(ctx, attachmentKeys)
+		if err != nil {
+			return fmt.Errorf("failed to check attachments: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	s.removeBlobs(ctx, orphanedKeys)
+	return deleted, reassigned, nil
+}
+
+// DeleteCategory удаляет категорию пользователя. Глобальная категория
+// удаляется только из списков этого пользователя — она скрывается.
+func (s *Service) DeleteCategory(ctx context.Context, userID, categoryID int64) error {
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		category, err := repo.GetCategory(ctx, categoryID, userID)
+		if err != nil {
+			return err
+		}
+		if !category.UserID.Valid {
+			return setSystemCategoryHidden(ctx, repo, userID, category.ID, true)
+		}
+		return repo.DeleteCategory(ctx, categoryID, userID)
+	})
+}
+
+// UpdateTransaction изменяет операцию. Тип операции не меняется.
+func (s *Service) UpdateTransaction(ctx context.Context, userID, transactionID int64, input TransactionInput) (*repository.Transaction, string, string, error) {
+	accountID, amount, relatedAccountID := input.AccountID, input.Amount, input.RelatedAccountID
+	tags, err := normalizeTags(input.Tags)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var updatedTx *repository.Transaction
+	var balance, warning string
+
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		// Get old transaction
+		oldTx, err := repo.GetTransaction(ctx, transactionID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+		if oldTx == nil {
+			return fmt.Errorf("transaction not found")
+		}
+
+		// Lock every account touched by the old and the new version of the transaction
+		accountIDs := []int64{oldTx.AccountID, accountID}
+		if oldTx.RelatedAccountID.Valid {
+			accountIDs = append(accountIDs, oldTx.RelatedAccountID.Int64)
+		}
+		if relatedAccountID > 0 {
+			accountIDs = append(accountIDs, relatedAccountID)
+		}
+		accounts, err := lockAccounts(ctx, repo, userID, accountIDs...)
+		if err != nil {
+			return err
+		}
+
+		// Verify account belongs to user
+		account := accounts[accountID]
+		if account == nil {
+			return fmt.Errorf("account not found or doesn't belong to user")
+		}
+
+		// Rollback old transaction balance
+		oldAmount := oldTx.Amount
+		if oldTx.Type == "expense" {
+			// Rollback expense: add back to account
+			if err := repo.UpdateAccountBalance(ctx, oldTx.AccountID, oldAmount); err != nil {
+				return fmt.Errorf("failed to rollback old balance: %w", err)
+			}
+		} else if oldTx.Type == "income" {
+			// Rollback income: subtract from account
+			negativeAmount := "-" + oldAmount
+			if err := repo.UpdateAccountBalance(ctx, oldTx.AccountID, negativeAmount); err != nil {
+				return fmt.Errorf("failed to rollback old balance: %w", err)
+			}
+		} else if oldTx.Type == "transfer" {
+			// Rollback transfer: restore both accounts
+			if oldTx.RelatedAccountID.Valid {
+				if err := repo.UpdateAccountBalance(ctx, oldTx.AccountID, oldAmount); err != nil {
+					return fmt.Errorf("failed to rollback from account: %w", err)
+				}
+				negativeAmount := "-" + oldAmount
+				if err := repo.UpdateAccountBalance(ctx, oldTx.RelatedAccountID.Int64, negativeAmount); err != nil {
+					return fmt.Errorf("failed to rollback to account: %w", err)
+				}
+			}
+		}
+
+		// Проверяются все счета, остаток которых уменьшится после отмены
+		// старой версии и проведения новой — в том числе при уменьшении
+		// дохода или его переносе на другой счет
+		changes := make(map[int64]*big.Rat)
+		if err := addTransactionEffect(changes, oldTx.Type, oldTx.AccountID, oldTx.RelatedAccountID.Int64, oldAmount, -1); err != nil {
+			return err
+		}
+		if err := addTransactionEffect(changes, oldTx.Type, accountID, relatedAccountID, amount, 1); err != nil {
+			return err
+		}
+		warning, err = checkDebits(accounts, changes)
+		if err != nil {
+			return err
+		}
+
+		// Split lines must keep matching the amount, whether they are replaced or kept
+		categoryID := input.CategoryID
+		if input.ReplaceSplits {
+			if err := prepareSplits(ctx, repo, userID, oldTx.Type, amount, input.Splits); err != nil {
+				return err
+			}
+			categoryID = splitCategoryID(categoryID, input.Splits)
+		} else {
+			splits, err := repo.ListTransactionSplits(ctx, userID, []int64{transactionID})
+			if err != nil {
+				return fmt.Errorf("failed to get transaction splits: %w", err)
+			}
+			if err := checkSplitsTotal(amount, splits[transactionID]); err != nil {
+				return err
+			}
+		}
+
+		// Получатель определяется заново, но счет и категория остаются как заданы
+		var payeeID int64
+		if oldTx.Type != "transfer" {
+			payee, err := resolvePayee(ctx, repo, userID, input)
+			if err != nil {
+				return err
+			}
+			if payee != nil {
+				payeeID = payee.ID
+			}
+		}
+
+		// Update transaction
+		updatedTx = &repository.Transaction{
+			ID:            transactionID,
+			UserID:        userID,
+			AccountID:     accountID,
+			CategoryID:    sql.NullInt64{Int64: categoryID, Valid: categoryID > 0},
+			Type:          oldTx.Type, // Keep original type
+			Amount:        amount,
+			Currency:      account.Currency,
+			Description:   sql.NullString{String: input.Description, Valid: input.Description != ""},
+			OperationDate: input.OperationDate,
+			PayeeID:       sql.NullInt64{Int64: payeeID, Valid: payeeID > 0},
+		}
+		if relatedAccountID > 0 {
+			updatedTx.RelatedAccountID = sql.NullInt64{Int64: relatedAccountID, Valid: true}
+		}
+
+		if err := repo.UpdateTransaction(ctx, updatedTx); err != nil {
+			return fmt.Errorf("failed to update transaction: %w", err)
+		}
+		if input.ReplaceTags {
+			if err := repo.SetTransactionTags(ctx, userID, transactionID, tags); err != nil {
+				return fmt.Errorf("failed to save transaction tags: %w", err)
+			}
+		}
+		if input.ReplaceSplits {
+			if err := repo.SetTransactionSplits(ctx, transactionID, input.Splits); err != nil {
+				return fmt.Errorf("failed to save transaction splits: %w", err)
+			}
+		}
+
+		// Apply new transaction balance
+		if oldTx.Type == "expense" {
+			negativeAmount := "-" + amount
+			if err := repo.UpdateAccountBalance(ctx, accountID, negativeAmount); err != nil {
+				return fmt.Errorf("failed to update account balance: %w", err)
+			}
+		} else if oldTx.Type == "income" {
+			if err := repo.UpdateAccountBalance(ctx, accountID, amount); err != nil {
+				return fmt.Errorf("failed to update account balance: %w", err)
+			}
+		} else if oldTx.Type == "transfer" && relatedAccountID > 0 {
+			// Verify to account belongs to user
+			if accounts[relatedAccountID] == nil {
+				return fmt.Errorf("to account not found or doesn't belong to user")
+			}
+			// Apply transfer: decrease from account, increase to account
+			negativeAmount := "-" + amount
+			if err := repo.UpdateAccountBalance(ctx, accountID, negativeAmount); err != nil {
+				return fmt.Errorf("failed to update from account balance: %w", err)
+			}
+			if err := repo.UpdateAccountBalance(ctx, relatedAccountID, amount); err != nil {
+				return fmt.Errorf("failed to update to account balance: %w", err)
+			}
+		}
+
+		// Get updated balance
+		updatedAccount, err := repo.GetAccount(ctx, accountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated account: %w", err)
+		}
+		balance = updatedAccount.Balance
+
+		return nil
+	})
+	if err != nil {
+		return nil, "", "", err
+	}
+	if updatedTx.Type == "expense" {
+		s.notifyExceededBudgets(ctx, userID)
+	}
+
+	return updatedTx, balance, warning, nil
+}
+
+func (s *Service) DeleteTransaction(ctx context.Context, userID, transactionID int64) error {
+	var attachmentKeys []string
+
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		// Get transaction
+		tx, err := repo.GetTransaction(ctx, transactionID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+		if tx == nil {
+			return fmt.Errorf("transaction not found")
+		}
+
+		attachmentKeys, err = repo.TransactionAttachmentKeys(ctx, userID, transactionID, 0)
+		if err != nil {
+			return fmt.Errorf("failed to list attachments: %w", err)
+		}
+
+		// Удаление дохода или входящего перевода уменьшает остаток счета
+		accounts, err := lockAccounts(ctx, repo, userID, tx.AccountID, tx.RelatedAccountID.Int64)
+		if err != nil {
+			return err
+		}
+		changes := make(map[int64]*big.Rat)
+		if err := addTransactionEffect(changes, tx.Type, tx.AccountID, tx.RelatedAccountID.Int64, tx.Amount, -1); err != nil {
+			return err
+		}
+		if _, err := checkDebits(accounts, changes); err != nil {
+			return err
+		}
+
+		// Rollback balance
+		if tx.Type == "expense" {
+			// Rollback expense: add back to account
+			if err := repo.UpdateAccountBalance(ctx, tx.AccountID, tx.Amount); err != nil {
+				return fmt.Errorf("failed to rollback balance: %w", err)
+			}
+		} else if tx.Type == "income" {
+			// Rollback income: subtract from account
+			negativeAmount := "-" + tx.Amount
+			if err := repo.UpdateAccountBalance(ctx, tx.AccountID, negativeAmount); err != nil {
+				return fmt.Errorf("failed to rollback balance: %w", err)
+			}
+		} else if tx.Type == "transfer" {
+			// Rollback transfer: restore both accounts
+			if tx.RelatedAccountID.Valid {
+				if err := repo.UpdateAccountBalance(ctx, tx.AccountID, tx.Amount); err != nil {
+					return fmt.Errorf("failed to rollback from account: %w", err)
+				}
+				negativeAmount := "-" + tx.Amount
+				if err := repo.UpdateAccountBalance(ctx, tx.RelatedAccountID.Int64, negativeAmount); err != nil {
+					return fmt.Errorf("failed to rollback to account: %w", err)
+				}
+			}
+		}
+
+		// Delete transaction
+		_, err = repo.DeleteTransaction(ctx, transactionID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to delete transaction: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.removeBlobs(ctx, attachmentKeys)
+	return nil
+}
+
+func IsValidAccountType(accountType string) bool {
+	switch accountType {
+	case AccountTypeCash, AccountTypeDebitCard, AccountTypeCreditCard, AccountTypeSavings,
+		AccountTypeDeposit, AccountTypeLoan, AccountTypeInvestment:
+		return true
+	}
+	return false
+}
+
+// IsCreditAccountType сообщает, может ли счет уходить в минус в пределах кредитного лимита
+func IsCreditAccountType(accountType string) bool {
+	return accountType == AccountTypeCreditCard || accountType == AccountTypeLoan
+}
+
+// AccountCredit возвращает доступный остаток лимита и сумму задолженности.
+// Для некредитных счетов обе строки пустые.
+func AccountCredit(account *repository.Account) (availableCredit, amountOwed string) {
+	if !IsCreditAccountType(account.Type) {
+		return "", ""
+	}
+
+	balance, err := parseAmount(account.Balance)
+	if err != nil {
+		return "", ""
+	}
+
+	owed := new(big.Rat)
+	if balance.Sign() < 0 {
+		owed.Neg(balance)
+	}
+	amountOwed = formatAmount(owed)
+
+	if account.CreditLimit.Valid {
+		limit, err := parseAmount(account.CreditLimit.String)
+		if err == nil {
+			available := new(big.Rat).Add(limit, balance)
+			if available.Sign() < 0 {
+				available.SetInt64(0)
+			}
+			availableCredit = formatAmount(available)
+		}
+	}
+
+	return availableCredit, amountOwed
+}
+
+// checkDebit проверяет, можно ли списать amount со счета. Для кредитных счетов
+// действует кредитный лимит, для остальных — политика овердрафта.
+// Счет должен быть заблокирован в текущей транзакции. Для политики "warn"
+// возвращает текст предупреждения.
+func checkDebit(account *repository.Account, amount string) (string, error) {
+	if IsCreditAccountType(account.Type) {
+		return "", checkCreditLimit(account, amount)
+	}
+
+	value, err := parseAmount(amount)
+	if err != nil {
+		return "", fmt.Errorf("invalid amount")
+	}
+	balance, err := parseAmount(account.Balance)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse account balance: %w", err)
+	}
+
+	remaining := new(big.Rat).Sub(balance, value)
+	if remaining.Sign() >= 0 {
+		return "", nil
+	}
+
+	switch account.OverdraftPolicy {
+	case OverdraftAllow:
+		return "", nil
+	case OverdraftWarn:
+		return fmt.Sprintf("баланс счета «%s» стал отрицательным: %s %s", account.Name, formatAmount(remaining), account.Currency), nil
+	default:
+		return "", &InsufficientFundsError{
+			AccountID:   account.ID,
+			AccountName: account.Name,
+			Shortfall:   formatAmount(new(big.Rat).Neg(remaining)),
+			Currency:    account.Currency,
+		}
+	}
+}
+
+// addTransactionEffect добавляет в changes изменение остатков счетов от
+// проведения операции (sign = 1) или ее отмены (sign = -1).
+func addTransactionEffect(changes map[int64]*big.Rat, txType string, accountID, relatedAccountID int64, amount string, sign int64) error {
+	value, err := parseAmount(amount)
+	if err != nil {
+		return fmt.Errorf("invalid amount")
+	}
+	value.Mul(value, big.NewRat(sign, 1))
+
+	add := func(id int64, delta *big.Rat) {
+		if changes[id] == nil {
+			changes[id] = new(big.Rat)
+		}
+		changes[id].Add(changes[id], delta)
+	}
+	switch txType {
+	case "expense":
+		add(accountID, new(big.Rat).Neg(value))
+	case "income":
+		add(accountID, value)
+	case "transfer":
+		if relatedAccountID > 0 {
+			add(accountID, new(big.Rat).Neg(value))
+			add(relatedAccountID, value)
+		}
+	}
+	return nil
+}
+
+// checkDebits проверяет checkDebit каждый счет, остаток которого
+// уменьшается на changes. accounts — счета, заблокированные до изменения
+// остатков. Предупреждения политики "warn" объединяются через "; ".
+func checkDebits(accounts map[int64]*repository.Account, changes map[int64]*big.Rat) (string, error) {
+	ids := make([]int64, 0, len(changes))
+	for id := range changes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var warnings []string
+	for _, id := range ids {
+		account := accounts[id]
+		if account == nil || changes[id].Sign() >= 0 {
+			continue
+		}
+		warning, err := checkDebit(account, formatAmount(new(big.Rat).Neg(changes[id])))
+		if err != nil {
+			return "", err
+		}
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return strings.Join(warnings, "; "), nil
+}
+
+// lockAccounts блокирует счета пользователя в порядке возрастания id,
+// чтобы параллельные операции не взаимоблокировались. Нулевые id пропускаются,
+// для чужих и несуществующих счетов в результате nil.
+func lockAccounts(ctx context.Context, repo *repository.Repository, userID int64, accountIDs ...int64) (map[int64]*repository.Account, error) {
+	ids := append([]int64(nil), accountIDs...)
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	accounts := make(map[int64]*repository.Account, len(ids))
+	for _, id := range ids {
+		if _, ok := accounts[id]; ok || id <= 0 {
+			continue
+		}
+		account, err := repo.GetAccountForUpdate(ctx, id, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account: %w", err)
+		}
+		accounts[id] = account
+	}
+
+	return accounts, nil
+}
+
+func IsValidOverdraftPolicy(policy string) bool {
+	return policy == OverdraftAllow || policy == OverdraftWarn || policy == OverdraftForbid
+}
+
+// checkCreditLimit проверяет, что списание не превысит доступный кредитный лимит
+func checkCreditLimit(account *repository.Account, amount string) error {
+	if !IsCreditAccountType(account.Type) || !account.CreditLimit.Valid {
+		return nil
+	}
+
+	value, err := parseAmount(amount)
+	if err != nil {
+		return fmt.Errorf("invalid amount")
+	}
+
+	availableCredit, _ := AccountCredit(account)
+	available, err := parseAmount(availableCredit)
+	if err != nil {
+		return fmt.Errorf("failed to calculate available credit: %w", err)
+	}
+
+	if value.Cmp(available) > 0 {
+		return fmt.Errorf("%w: доступно %s %s", ErrCreditLimitExceeded, availableCredit, account.Currency)
+	}
+
+	return nil
+}
+
+// applyCreditParams проверяет и переносит на счет переданные кредитные параметры.
+// Пустой лимит и нулевые дни оставляют текущие значения.
+func applyCreditParams(account *repository.Account, creditLimit string, statementDay, dueDay int32) error {
+	if creditLimit == "" && statementDay == 0 && dueDay == 0 {
+		return nil
+	}
+	if !IsCreditAccountType(account.Type) {
+		return fmt.Errorf("credit parameters are only allowed for credit accounts")
+	}
+
+	if creditLimit != "" {
+		limit, err := parseAmount(creditLimit)
+		if err != nil || limit.Sign() < 0 {
+			return fmt.Errorf("invalid credit limit")
+		}
+		account.CreditLimit = sql.NullString{String: formatAmount(limit), Valid: true}
+	}
+	if statementDay != 0 {
+		if !isValidDayOfMonth(statementDay) {
+			return fmt.Errorf("invalid statement day")
+		}
+		account.StatementDay = sql.NullInt32{Int32: statementDay, Valid: true}
+	}
+	if dueDay != 0 {
+		if !isValidDayOfMonth(dueDay) {
+			return fmt.Errorf("invalid due day")
+		}
+		account.DueDay = sql.NullInt32{Int32: dueDay, Valid: true}
+	}
+
+	return nil
+}
+
+// Теги — одно слово из букв, цифр, "-" и "_", например "vacation-2026"
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}_-]+$`)
+
+const (
+	maxTagLength          = 32
+	maxTagsPerTransaction = 10
+)
+
+// normalizeTags приводит теги к нижнему регистру, убирает "#" в начале,
+// пустые значения и повторы.
+func normalizeTags(tags []string) ([]string, error) {
+	var result []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return nil, fmt.Errorf("tag is too long")
+		}
+		if !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag")
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	if len(result) > maxTagsPerTransaction {
+		return nil, fmt.Errorf("too many tags")
+	}
+	return result, nil
+}
+
+const (
+	maxSplitLines      = 50
+	maxSplitNoteLength = 255
+)
+
+// prepareSplits проверяет строки разбивки операции: их не меньше двух,
+// суммы положительны и в сумме дают сумму операции, а категории доступны
+// пользователю и совпадают с операцией по типу. Суммы приводятся к двум
+// знакам после запятой.
+func prepareSplits(ctx context.Context, repo *repository.Repository, userID int64, txType, amount string, splits []*repository.Split) error {
+	if len(splits) == 0 {
+		return nil
+	}
+	if txType != "expense" && txType != "income" {
+		return fmt.Errorf("splits are allowed only for expenses and income")
+	}
+	if len(splits) < 2 {
+		return fmt.Errorf("split must have at least two lines")
+	}
+	if len(splits) > maxSplitLines {
+		return fmt.Errorf("too many split lines")
+	}
+
+	for _, split := range splits {
+		value, err := parseAmount(split.Amount)
+		if err != nil || value.Sign() <= 0 {
+			return fmt.Errorf("invalid split amount")
+		}
+		split.Amount = formatAmount(value)
+
+		if !split.CategoryID.Valid || split.CategoryID.Int64 <= 0 {
+			return fmt.Errorf("split category is required")
+		}
+		category, err := repo.GetCategory(ctx, split.CategoryID.Int64, userID)
+		if err != nil {
+			if err.Error() == "category not found" {
+				return fmt.Errorf("split category not found")
+			}
+			return err
+		}
+		if category.Type != txType {
+			return fmt.Errorf("split category type mismatch")
+		}
+
+		note := strings.TrimSpace(split.Note.String)
+		if utf8.RuneCountInString(note) > maxSplitNoteLength {
+			return fmt.Errorf("split note is too long")
+		}
+		split.Note = sql.NullString{String: note, Valid: note != ""}
+	}
+
+	return checkSplitsTotal(amount, splits)
+}
+
+// checkSplitsTotal проверяет, что строки разбивки в сумме дают сумму
+// операции. Операция без разбивки проверку проходит.
+func checkSplitsTotal(amount string, splits []*repository.Split) error {
+	if len(splits) == 0 {
+		return nil
+	}
+	total, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+	sum := new(big.Rat)
+	for _, split := range splits {
+		value, err := parseAmount(split.Amount)
+		if err != nil {
+			return fmt.Errorf("invalid split amount")
+		}
+		sum.Add(sum, value)
+	}
+	if formatAmount(sum) != formatAmount(total) {
+		return fmt.Errorf("split lines must sum to transaction amount")
+	}
+	return nil
+}
+
+// splitCategoryID возвращает категорию операции: указанную явно, а для
+// разбитой операции без категории — категорию первой строки.
+func splitCategoryID(categoryID int64, splits []*repository.Split) int64 {
+	if categoryID == 0 && len(splits) > 0 {
+		return splits[0].CategoryID.Int64
+	}
+	return categoryID
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+func validateAccountAppearance(account *repository.Account) error {
+	if utf8.RuneCountInString(account.Group) > 64 {
+		return fmt.Errorf("account group is too long")
+	}
+	if utf8.RuneCountInString(account.Icon) > 16 {
+		return fmt.Errorf("account icon is too long")
+	}
+	if account.Color != "" && !colorPattern.MatchString(account.Color) {
+		return fmt.Errorf("invalid account color")
+	}
+	return nil
+}
+
+func validateCategoryAppearance(category *repository.Category) error {
+	if utf8.RuneCountInString(category.Icon) > 16 {
+		return fmt.Errorf("category icon is too long")
+	}
+	if category.Color != "" && !colorPattern.MatchString(category.Color) {
+		return fmt.Errorf("invalid category color")
+	}
+	return nil
+}
+
+func isValidDayOfMonth(day int32) bool {
+	return day >= 1 && day <= 31
+}
+
+func parseAmount(value string) (*big.Rat, error) {
+	amount, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount: %q", value)
+	}
+	return amount, nil
+}
+
+func formatAmount(amount *big.Rat) string {
+	return amount.FloatString(2)
+}
+
+const (
+	maxPayeeNameLength = 100
+	maxPayeeAliases    = 20
+)
+
+// normalizePayeeName приводит имя получателя к виду для поиска: нижний
+// регистр, "ё" как "е" и одиночные пробелы между словами.
+func normalizePayeeName(name string) string {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	return strings.ReplaceAll(name, "ё", "е")
+}
+
+// PayeeInput — поля получателя при создании и изменении. Псевдонимы
+// заменяются целиком.
+type PayeeInput struct {
+	Name              string
+	Aliases           []string
+	DefaultCategoryID int64
+	DefaultAccountID  int64
+}
+
+func (s *Service) ListPayees(ctx context.Context, userID int64) ([]*repository.Payee, error) {
+	return s.repo.ListPayees(ctx, userID)
+}
+
+func (s *Service) CreatePayee(ctx context.Context, userID int64, input PayeeInput) (*repository.Payee, error) {
+	return s.savePayee(ctx, userID, 0, input)
+}
+
+func (s *Service) UpdatePayee(ctx context.Context, userID, payeeID int64, input PayeeInput) (*repository.Payee, error) {
+	return s.savePayee(ctx, userID, payeeID, input)
+}
+
+// savePayee создает получателя или, если payeeID задан, изменяет его.
+// Имя и псевдонимы не должны совпадать с именами и псевдонимами других
+// получателей пользователя.
+func (s *Service) savePayee(ctx context.Context, userID, payeeID int64, input PayeeInput) (*repository.Payee, error) {
+	name := strings.Join(strings.Fields(input.Name), " ")
+	if name == "" {
+		return nil, fmt.Errorf("payee name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxPayeeNameLength {
+		return nil, fmt.Errorf("payee name is too long")
+	}
+	normalizedName := normalizePayeeName(name)
+
+	var aliases, normalizedAliases []string
+	seen := map[string]bool{normalizedName: true}
+	for _, alias := range input.Aliases {
+		alias = strings.Join(strings.Fields(alias), " ")
+		normalized := normalizePayeeName(alias)
+		if normalized == "" || seen[normalized] {
+			continue
+		}
+		if utf8.RuneCountInString(alias) > maxPayeeNameLength {
+			return nil, fmt.Errorf("payee name is too long")
+		}
+		seen[normalized] = true
+		aliases = append(aliases, alias)
+		normalizedAliases = append(normalizedAliases, normalized)
+	}
+	if len(aliases) > maxPayeeAliases {
+		return nil, fmt.Errorf("too many payee aliases")
+	}
+
+	var result *repository.Payee
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		payee := &repository.Payee{ID: payeeID, UserID: userID}
+		if payeeID > 0 {
+			existing, err := repo.GetPayee(ctx, payeeID, userID)
+			if err != nil {
+				return err
+			}
+			if existing == nil {
+				return fmt.Errorf("payee not found")
+			}
+		}
+
+		for _, normalized := range append([]string{normalizedName}, normalizedAliases...) {
+			taken, err := repo.PayeeNameTaken(ctx, userID, normalized, payeeID)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return fmt.Errorf("payee already exists")
+			}
+		}
+
+		if input.DefaultCategoryID > 0 {
+			category, err := repo.GetCategory(ctx, input.DefaultCategoryID, userID)
+			if err != nil {
+				if err.Error() == "category not found" {
+					return fmt.Errorf("default category not found")
+				}
+				return err
+			}
+			if category.IsArchived {
+				return fmt.Errorf("default category is archived")
+			}
+			payee.DefaultCategoryID = sql.NullInt64{Int64: category.ID, Valid: true}
+		}
+		if input.DefaultAccountID > 0 {
+			account, err := repo.GetAccount(ctx, input.DefaultAccountID, userID)
+			if err != nil {
+				return err
+			}
+			if account == nil {
+				return fmt.Errorf("default account not found")
+			}
+			if account.IsArchived {
+				return fmt.Errorf("default account is archived")
+			}
+			payee.DefaultAccountID = sql.NullInt64{Int64: account.ID, Valid: true}
+		}
+
+		payee.Name = name
+		payee.NormalizedName = normalizedName
+		var err error
+		if payeeID > 0 {
+			result, err = repo.UpdatePayee(ctx, payee)
+		} else {
+			result, err = repo.CreatePayee(ctx, payee)
+		}
+		if err != nil {
+			return err
+		}
+		if err := repo.SetPayeeAliases(ctx, result.ID, userID, aliases, normalizedAliases); err != nil {
+			return err
+		}
+		result.Aliases = aliases
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeletePayee удаляет получателя. Его операции остаются без получателя.
+func (s *Service) DeletePayee(ctx context.Context, userID, payeeID int64) error {
+	deleted, err := s.repo.DeletePayee(ctx, payeeID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("payee not found")
+	}
+	return nil
+}
+
+// GetPayeeBreakdown возвращает крупнейших получателей за период.
+func (s *Service) GetPayeeBreakdown(ctx context.Context, userID int64, txType, period, startDate, endDate string, limit int32) ([]*repository.PayeeTotal, error) {
+	if txType == "" {
+		txType = "expense"
+	}
+	if txType != "expense" && txType != "income" {
+		return nil, fmt.Errorf("invalid transaction type")
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	return s.repo.GetPayeeBreakdown(ctx, userID, txType, period, startDate, endDate, limit)
+}
+
+// resolvePayee находит получателя операции по ID, по имени или, если ни то
+// ни другое не задано, по описанию. Неизвестное имя оставляет операцию без
+// получателя.
+func resolvePayee(ctx context.Context, repo *repository.Repository, userID int64, input TransactionInput) (*repository.Payee, error) {
+	if input.PayeeID > 0 {
+		payee, err := repo.GetPayee(ctx, input.PayeeID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if payee == nil {
+			return nil, fmt.Errorf("payee not found")
+		}
+		return payee, nil
+	}
+
+	name := input.Payee
+	if name == "" {
+		name = input.Description
+	}
+	normalized := normalizePayeeName(name)
+	if normalized == "" {
+		return nil, nil
+	}
+	return repo.FindPayee(ctx, userID, normalized)
+}
+
+// prepareTransactionInput находит получателя новой операции и подставляет
+// его счет и категорию, если они не заданы. Операция без категории и без
+// разбивки попадает в категорию по умолчанию.
+func prepareTransactionInput(ctx context.Context, repo *repository.Repository, userID int64, txType string, input *TransactionInput) error {
+	if input.ReceiptQR != "" {
+		receipt, err := ParseFiscalReceipt(input.ReceiptQR)
+		if err != nil {
+			return err
+		}
+		if receipt.Type != txType {
+			return fmt.Errorf("receipt type does not match transaction")
+		}
+		if input.Amount == "" {
+			input.Amount = receipt.Amount
+		}
+		if input.OperationDate.IsZero() {
+			input.OperationDate = receipt.OperationDate
+		}
+		input.receipt = receipt
+	}
+
+	payee, err := resolvePayee(ctx, repo, userID, *input)
+	if err != nil {
+		return err
+	}
+	if input.ApplyRules {
+		if err := applyRulesToInput(ctx, repo, userID, txType, input, &payee); err != nil {
+			return fmt.Errorf("failed to apply rules: %w", err)
+		}
+	}
+
+	input.PayeeID = 0
+	if payee != nil {
+		input.PayeeID = payee.ID
+		if input.AccountID == 0 && payee.DefaultAccountID.Valid {
+			input.AccountID = payee.DefaultAccountID.Int64
+		}
+		if input.CategoryID == 0 && len(input.Splits) == 0 && payee.DefaultCategoryID.Valid {
+			category, err := repo.GetCategory(ctx, payee.DefaultCategoryID.Int64, userID)
+			if err != nil && err.Error() != "category not found" {
+				return err
+			}
+			if err == nil && category.Type == txType && !category.IsArchived {
+				input.CategoryID = category.ID
+			}
+		}
+	}
+
+	if input.CategoryID == 0 && len(input.Splits) == 0 {
+		input.CategoryID, err = repo.GetFallbackCategoryID(ctx, userID, txType)
+		if err != nil {
+			return fmt.Errorf("failed to find fallback category: %w", err)
+		}
+	}
+
+	return nil
+}
+
+const (
+	maxRuleNameLength    = 100
+	maxRulePatternLength = 500
+	maxRuleScan          = 5000
+)
+
+// RuleInput — условия и действия правила. Пустые поля не используются.
+type RuleInput struct {
+	Name                string
+	IsActive            bool
+	StopProcessing      bool
+	TransactionType     string
+	DescriptionContains string
+	DescriptionRegex    string
+	MinAmount           string
+	MaxAmount           string
+	AccountID           int64
+	PayeeID             int64
+	SetCategoryID       int64
+	AddTags             []string
+	SetPayeeID          int64
+	SetDescription      string
+}
+
+func (s *Service) ListRules(ctx context.Context, userID int64) ([]*repository.Rule, error) {
+	return s.repo.ListRules(ctx, userID, false)
+}
+
+func (s *Service) CreateRule(ctx context.Context, userID int64, input RuleInput) (*repository.Rule, error) {
+	rule, err := buildRule(ctx, s.repo, userID, input)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.CreateRule(ctx, rule)
+}
+
+func (s *Service) UpdateRule(ctx context.Context, userID, ruleID int64, input RuleInput) (*repository.Rule, error) {
+	rule, err := buildRule(ctx, s.repo, userID, input)
+	if err != nil {
+		return nil, err
+	}
+	rule.ID = ruleID
+
+	updated, err := s.repo.UpdateRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, fmt.Errorf("rule not found")
+	}
+	return updated, nil
+}
+
+func (s *Service) DeleteRule(ctx context.Context, userID, ruleID int64) error {
+	deleted, err := s.repo.DeleteRule(ctx, ruleID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("rule not found")
+	}
+	return nil
+}
+
+// ReorderRules задает порядок применения правил: первым идет ruleIDs[0].
+func (s *Service) ReorderRules(ctx context.Context, userID int64, ruleIDs []int64) error {
+	seen := make(map[int64]bool, len(ruleIDs))
+	for _, id := range ruleIDs {
+		if seen[id] {
+			return fmt.Errorf("duplicate rule id")
+		}
+		seen[id] = true
+	}
+
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		for position, id := range ruleIDs {
+			ok, err := repo.SetRulePosition(ctx, id, userID, int32(position))
+			if err != nil {
+				return fmt.Errorf("failed to reorder rules: %w", err)
+			}
+			if !ok {
+				return fmt.Errorf("rule not found")
+			}
+		}
+		return nil
+	})
+}
+
+// RuleMatch — операция, подходящая под правило, и ее поля после
+// применения правила.
+type RuleMatch struct {
+	Transaction *repository.TransactionWithDetails
+	CategoryID  int64
+	PayeeID     int64
+	Description string
+	Tags        []string
+}
+
+// TestRule проверяет несохраненное правило на операциях за период, ничего
+// не меняя. Возвращает число подходящих операций и первые limit из них.
+func (s *Service) TestRule(ctx context.Context, userID int64, input RuleInput, period, startDate, endDate string, limit int32) (int64, []*RuleMatch, error) {
+	rule, err := buildRule(ctx, s.repo, userID, input)
+	if err != nil {
+		return 0, nil, err
+	}
+	rule.IsActive = true
+	rules, err := compileRules(ctx, s.repo, userID, []*repository.Rule{rule})
+	if err != nil {
+		return 0, nil, err
+	}
+	if limit <= 0 {
+		limit = 50
+	}
+
+	transactions, err := s.ruleHistory(ctx, s.repo, userID, period, startDate, endDate)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var matched int64
+	var matches []*RuleMatch
+	for _, tx := range transactions {
+		target := newRuleTarget(tx)
+		if len(applyRules(rules, target)) == 0 {
+			continue
+		}
+		matched++
+		if int32(len(matches)) < limit {
+			matches = append(matches, &RuleMatch{
+				Transaction: tx,
+				CategoryID:  target.CategoryID,
+				PayeeID:     target.PayeeID,
+				Description: target.Description,
+				Tags:        target.Tags,
+			})
+		}
+	}
+
+	return matched, matches, nil
+}
+
+// ApplyRules заново применяет активные правила к операциям за период.
+// Категория разбитых операций не меняется. Возвращает число подходящих
+// и число измененных операций.
+func (s *Service) ApplyRules(ctx context.Context, userID int64, period, startDate, endDate string) (int64, int64, error) {
+	var matched, updated int64
+
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		stored, err := repo.ListRules(ctx, userID, true)
+		if err != nil {
+			return err
+		}
+		rules, err := compileRules(ctx, repo, userID, stored)
+		if err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+
+		transactions, err := s.ruleHistory(ctx, repo, userID, period, startDate, endDate)
+		if err != nil {
+			return err
+		}
+
+		for _, tx := range transactions {
+			target := newRuleTarget(tx)
+			if len(applyRules(rules, target)) == 0 {
+				continue
+			}
+			matched++
+
+			changed := tx.PayeeID.Int64 != target.PayeeID || tx.Description.String != target.Description
+			if len(tx.Splits) == 0 && tx.CategoryID.Int64 != target.CategoryID {
+				changed = true
+				tx.CategoryID = sql.NullInt64{Int64: target.CategoryID, Valid: target.CategoryID > 0}
+			}
+			tagsChanged := len(target.Tags) != len(tx.Tags)
+			if !changed && !tagsChanged {
+				continue
+			}
+
+			if changed {
+				tx.PayeeID = sql.NullInt64{Int64: target.PayeeID, Valid: target.PayeeID > 0}
+				tx.Description = sql.NullString{String: target.Description, Valid: target.Description != ""}
+				if err := repo.UpdateTransactionLabels(ctx, &tx.Transaction); err != nil {
+					return fmt.Errorf("failed to update transaction: %w", err)
+				}
+			}
+			if tagsChanged {
+				if err := repo.SetTransactionTags(ctx, userID, tx.ID, target.Tags); err != nil {
+					return fmt.Errorf("failed to save transaction tags: %w", err)
+				}
+			}
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return matched, updated, nil
+}
+
+// ruleHistory возвращает расходы и доходы пользователя за период вместе
+// с тегами и строками разбивки.
+func (s *Service) ruleHistory(ctx context.Context, repo *repository.Repository, userID int64, period, startDate, endDate string) ([]*repository.TransactionWithDetails, error) {
+	transactions, err := repo.ListTransactions(ctx, userID, repository.TransactionFilter{
+		Period:    period,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Types:     []string{"expense", "income"},
+		Sort:      repository.SortDateDesc,
+		Limit:     maxRuleScan,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := repo.ListTransactionTags(ctx, transactionIDs(transactions))
+	if err != nil {
+		return nil, err
+	}
+	splits, err := repo.ListTransactionSplits(ctx, userID, transactionIDs(transactions))
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range transactions {
+		tx.Tags = tags[tx.ID]
+		tx.Splits = splits[tx.ID]
+	}
+
+	return transactions, nil
+}
+
+func transactionIDs(transactions []*repository.TransactionWithDetails) []int64 {
+	ids := make([]int64, 0, len(transactions))
+	for _, tx := range transactions {
+		ids = append(ids, tx.ID)
+	}
+	return ids
+}
+
+// buildRule проверяет условия и действия правила и приводит их к виду
+// для хранения.
+func buildRule(ctx context.Context, repo *repository.Repository, userID int64, input RuleInput) (*repository.Rule, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("rule name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxRuleNameLength {
+		return nil, fmt.Errorf("rule name is too long")
+	}
+
+	rule := &repository.Rule{
+		UserID:         userID,
+		Name:           name,
+		IsActive:       input.IsActive,
+		StopProcessing: input.StopProcessing,
+	}
+
+	switch input.TransactionType {
+	case "":
+	case "expense", "income":
+		rule.TransactionType = sql.NullString{String: input.TransactionType, Valid: true}
+	default:
+		return nil, fmt.Errorf("invalid transaction type")
+	}
+
+	if contains := strings.TrimSpace(input.DescriptionContains); contains != "" {
+		if utf8.RuneCountInString(contains) > maxRulePatternLength {
+			return nil, fmt.Errorf("rule pattern is too long")
+		}
+		rule.DescriptionContains = sql.NullString{String: contains, Valid: true}
+	}
+	if input.DescriptionRegex != "" {
+		if utf8.RuneCountInString(input.DescriptionRegex) > maxRulePatternLength {
+			return nil, fmt.Errorf("rule pattern is too long")
+		}
+		if _, err := regexp.Compile("(?i)" + input.DescriptionRegex); err != nil {
+			return nil, fmt.Errorf("invalid rule regex")
+		}
+		rule.DescriptionRegex = sql.NullString{String: input.DescriptionRegex, Valid: true}
+	}
+
+	var minAmount, maxAmount *big.Rat
+	var err error
+	if input.MinAmount != "" {
+		if minAmount, err = parseAmount(input.MinAmount); err != nil {
+			return nil, fmt.Errorf("invalid amount range")
+		}
+		rule.MinAmount = sql.NullString{String: formatAmount(minAmount), Valid: true}
+	}
+	if input.MaxAmount != "" {
+		if maxAmount, err = parseAmount(input.MaxAmount); err != nil {
+			return nil, fmt.Errorf("invalid amount range")
+		}
+		rule.MaxAmount = sql.NullString{String: formatAmount(maxAmount), Valid: true}
+	}
+	if minAmount != nil && maxAmount != nil && minAmount.Cmp(maxAmount) > 0 {
+		return nil, fmt.Errorf("invalid amount range")
+	}
+
+	if input.AccountID > 0 {
+		account, err := repo.GetAccount(ctx, input.AccountID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			return nil, fmt.Errorf("rule account not found")
+		}
+		rule.AccountID = sql.NullInt64{Int64: account.ID, Valid: true}
+	}
+	for _, payeeID := range []int64{input.PayeeID, input.SetPayeeID} {
+		if payeeID <= 0 {
+			continue
+		}
+		payee, err := repo.GetPayee(ctx, payeeID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if payee == nil {
+			return nil, fmt.Errorf("rule payee not found")
+		}
+	}
+	rule.PayeeID = sql.NullInt64{Int64: input.PayeeID, Valid: input.PayeeID > 0}
+	rule.SetPayeeID = sql.NullInt64{Int64: input.SetPayeeID, Valid: input.SetPayeeID > 0}
+
+	if input.SetCategoryID > 0 {
+		category, err := repo.GetCategory(ctx, input.SetCategoryID, userID)
+		if err != nil {
+			if err.Error() == "category not found" {
+				return nil, fmt.Errorf("rule category not found")
+			}
+			return nil, err
+		}
+		if rule.TransactionType.Valid && category.Type != rule.TransactionType.String {
+			return nil, fmt.Errorf("rule category type mismatch")
+		}
+		rule.SetCategoryID = sql.NullInt64{Int64: category.ID, Valid: true}
+	}
+
+	rule.AddTags, err = normalizeTags(input.AddTags)
+	if err != nil {
+		return nil, err
+	}
+	if rule.AddTags == nil {
+		rule.AddTags = []string{}
+	}
+
+	if description := strings.TrimSpace(input.SetDescription); description != "" {
+		if utf8.RuneCountInString(description) > maxSplitNoteLength {
+			return nil, fmt.Errorf("rule description is too long")
+		}
+		rule.SetDescription = sql.NullString{String: description, Valid: true}
+	}
+
+	if !rule.TransactionType.Valid && !rule.DescriptionContains.Valid && !rule.DescriptionRegex.Valid &&
+		!rule.MinAmount.Valid && !rule.MaxAmount.Valid && !rule.AccountID.Valid && !rule.PayeeID.Valid {
+		return nil, fmt.Errorf("rule has no conditions")
+	}
+	if !rule.SetCategoryID.Valid && len(rule.AddTags) == 0 && !rule.SetPayeeID.Valid && !rule.SetDescription.Valid {
+		return nil, fmt.Errorf("rule has no actions")
+	}
+
+	return rule, nil
+}
+
+// compiledRule — правило с разобранными условиями. categoryType — тип
+// категории из действия; категория ставится только операциям того же типа.
+type compiledRule struct {
+	*repository.Rule
+	contains     string
+	pattern      *regexp.Regexp
+	minAmount    *big.Rat
+	maxAmount    *big.Rat
+	categoryType string
+}
+
+// compileRules готовит правила к применению. Действие с удаленной или
+// архивной категорией пропускается.
+func compileRules(ctx context.Context, repo *repository.Repository, userID int64, rules []*repository.Rule) ([]*compiledRule, error) {
+	var result []*compiledRule
+	for _, rule := range rules {
+		compiled := &compiledRule{
+			Rule:     rule,
+			contains: normalizePayeeName(rule.DescriptionContains.String),
+		}
+		if rule.DescriptionRegex.Valid {
+			pattern, err := regexp.Compile("(?i)" + rule.DescriptionRegex.String)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule regex")
+			}
+			compiled.pattern = pattern
+		}
+		if rule.MinAmount.Valid {
+			amount, err := parseAmount(rule.MinAmount.String)
+			if err != nil {
+				return nil, err
+			}
+			compiled.minAmount = amount
+		}
+		if rule.MaxAmount.Valid {
+			amount, err := parseAmount(rule.MaxAmount.String)
+			if err != nil {
+				return nil, err
+			}
+			compiled.maxAmount = amount
+		}
+		if rule.SetCategoryID.Valid {
+			category, err := repo.GetCategory(ctx, rule.SetCategoryID.Int64, userID)
+			if err != nil && err.Error() != "category not found" {
+				return nil, err
+			}
+			if err == nil && !category.IsArchived {
+				compiled.categoryType = category.Type
+			}
+		}
+		result = append(result, compiled)
+	}
+	return result, nil
+}
+
+// ruleTarget — поля операции, которые проверяют и меняют правила
+type ruleTarget struct {
+	Type        string
+	Description string
+	Amount      string
+	AccountID   int64
+	PayeeID     int64
+	CategoryID  int64
+	Tags        []string
+}
+
+func newRuleTarget(tx *repository.TransactionWithDetails) *ruleTarget {
+	return &ruleTarget{
+		Type:        tx.Type,
+		Description: tx.Description.String,
+		Amount:      tx.Amount,
+		AccountID:   tx.AccountID,
+		PayeeID:     tx.PayeeID.Int64,
+		CategoryID:  tx.CategoryID.Int64,
+		Tags:        append([]string(nil), tx.Tags...),
+	}
+}
+
+func (r *compiledRule) matches(target *ruleTarget) bool {
+	if r.TransactionType.Valid && r.TransactionType.String != target.Type {
+		return false
+	}
+	if r.contains != "" && !strings.Contains(normalizePayeeName(target.Description), r.contains) {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(target.Description) {
+		return false
+	}
+	if r.minAmount != nil || r.maxAmount != nil {
+		amount, err := parseAmount(target.Amount)
+		if err != nil {
+			return false
+		}
+		if r.minAmount != nil && amount.Cmp(r.minAmount) < 0 {
+			return false
+		}
+		if r.maxAmount != nil && amount.Cmp(r.maxAmount) > 0 {
+			return false
+		}
+	}
+	if r.AccountID.Valid && r.AccountID.Int64 != target.AccountID {
+		return false
+	}
+	if r.PayeeID.Valid && r.PayeeID.Int64 != target.PayeeID {
+		return false
+	}
+	return true
+}
+
+// applyRules применяет подходящие правила по порядку: следующие правила
+// видят изменения предыдущих. Возвращает ID сработавших правил.
+func applyRules(rules []*compiledRule, target *ruleTarget) []int64 {
+	var applied []int64
+	for _, rule := range rules {
+		if !rule.IsActive || !rule.matches(target) {
+			continue
+		}
+		applied = append(applied, rule.ID)
+
+		if rule.SetCategoryID.Valid && rule.categoryType == target.Type {
+			target.CategoryID = rule.SetCategoryID.Int64
+		}
+		for _, tag := range rule.AddTags {
+			if !containsString(target.Tags, tag) {
+				target.Tags = append(target.Tags, tag)
+			}
+		}
+		if rule.SetPayeeID.Valid {
+			target.PayeeID = rule.SetPayeeID.Int64
+		}
+		if rule.SetDescription.Valid {
+			target.Description = rule.SetDescription.String
+		}
+
+		if rule.StopProcessing {
+			break
+		}
+	}
+	return applied
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRulesToInput применяет активные правила пользователя к новой
+// операции. Категория из правила ставится, только если она не задана
+// явно и у операции нет разбивки.
+func applyRulesToInput(ctx context.Context, repo *repository.Repository, userID int64, txType string, input *TransactionInput, payee **repository.Payee) error {
+	stored, err := repo.ListRules(ctx, userID, true)
+	if err != nil {
+		return err
+	}
+	rules, err := compileRules(ctx, repo, userID, stored)
+	if err != nil {
+		return err
+	}
+
+	target := &ruleTarget{
+		Type:        txType,
+		Description: input.Description,
+		Amount:      input.Amount,
+		AccountID:   input.AccountID,
+		CategoryID:  input.CategoryID,
+		Tags:        input.Tags,
+	}
+	if *payee != nil {
+		target.PayeeID = (*payee).ID
+	}
+	if len(applyRules(rules, target)) == 0 {
+		return nil
+	}
+
+	if input.CategoryID == 0 && len(input.Splits) == 0 {
+		input.CategoryID = target.CategoryID
+	}
+	input.Tags = target.Tags
+	input.Description = target.Description
+	if target.PayeeID > 0 && (*payee == nil || (*payee).ID != target.PayeeID) {
+		*payee, err = repo.GetPayee(ctx, target.PayeeID, userID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const (
+	maxAttachmentsPerTransaction = 10
+	maxAttachmentNameLength      = 255
+)
+
+// attachmentExtensions — допустимые типы вложений и расширения файлов в
+// хранилище. Тип определяется по содержимому, а не по имени файла.
+var attachmentExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"image/gif":       ".gif",
+	"application/pdf": ".pdf",
+}
+
+// UploadAttachment сохраняет файл и прикрепляет его к операции пользователя.
+func (s *Service) UploadAttachment(ctx context.Context, userID, transactionID int64, fileName string, data []byte) (*repository.Attachment, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("attachment is empty")
+	}
+	if int64(len(data)) > s.limits.MaxFileSize {
+		return nil, fmt.Errorf("attachment is too large")
+	}
+
+	contentType := http.DetectContentType(data)
+	ext, ok := attachmentExtensions[contentType]
+	if !ok {
+		return nil, fmt.Errorf("unsupported attachment type")
+	}
+	fileName = cleanAttachmentName(fileName, ext)
+
+	key, err := newAttachmentKey(userID, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	var attachment *repository.Attachment
+	stored := false
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		if err := repo.LockUserAttachments(ctx, userID); err != nil {
+			return err
+		}
+
+		tx, err := repo.GetTransaction(ctx, transactionID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get transaction: %w", err)
+		}
+		if tx == nil {
+			return fmt.Errorf("transaction not found")
+		}
+
+		used, count, err := repo.AttachmentUsage(ctx, userID, transactionID)
+		if err != nil {
+			return err
+		}
+		if count >= maxAttachmentsPerTransaction {
+			return fmt.Errorf("too many attachments")
+		}
+		if used+int64(len(data)) > s.limits.UserQuota {
+			return fmt.Errorf("attachment quota exceeded")
+		}
+
+		if err := s.blobs.Put(ctx, key, data, contentType); err != nil {
+			return fmt.Errorf("failed to store attachment: %w", err)
+		}
+		stored = true
+
+		attachment, err = repo.CreateAttachment(ctx, &repository.Attachment{
+			UserID:        userID,
+			TransactionID: transactionID,
+			StorageKey:    key,
+			FileName:      fileName,
+			ContentType:   contentType,
+			SizeBytes:     int64(len(data)),
+		})
+		return err
+	})
+	if err != nil {
+		if stored {
+			s.removeBlobs(ctx, []string{key})
+		}
+		return nil, err
+	}
+
+	return attachment, nil
+}
+
+func (s *Service) ListAttachments(ctx context.Context, userID, transactionID int64) ([]*repository.Attachment, error) {
+	tx, err := s.repo.GetTransaction(ctx, transactionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction not found")
+	}
+
+	return s.repo.ListAttachments(ctx, transactionID, userID)
+}
+
+// GetAttachment возвращает вложение пользователя вместе с содержимым.
+func (s *Service) GetAttachment(ctx context.Context, userID, attachmentID int64) (*repository.Attachment, []byte, error) {
+	attachment, err := s.repo.GetAttachment(ctx, attachmentID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment == nil {
+		return nil, nil, fmt.Errorf("attachment not found")
+	}
+
+	data, err := s.blobs.Get(ctx, attachment.StorageKey)
+	if errors.Is(err, blobstore.ErrNotFound) {
+		s.logger.Error("attachment content is missing", zap.Int64("attachment_id", attachment.ID))
+		return nil, nil, fmt.Errorf("attachment not found")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	return attachment, data, nil
+}
+
+func (s *Service) DeleteAttachment(ctx context.Context, userID, attachmentID int64) error {
+	attachment, err := s.repo.DeleteAttachment(ctx, attachmentID, userID)
+	if err != nil {
+		return err
+	}
+	if attachment == nil {
+		return fmt.Errorf("attachment not found")
+	}
+
+	s.removeBlobs(ctx, []string{attachment.StorageKey})
+	return nil
+}
+
+// removeBlobs удаляет файлы из хранилища. Ошибки только логируются: запись
+// о вложении уже удалена, а лишний файл не мешает работе.
+func (s *Service) removeBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			s.logger.Warn("failed to delete attachment content", zap.String("key", key), zap.Error(err))
+		}
+	}
+}
+
+// cleanAttachmentName оставляет от имени файла только базовое имя без
+// управляющих символов. Пустое имя заменяется на "attachment".
+func cleanAttachmentName(name, ext string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == "/" {
+		name = "attachment" + ext
+	}
+	if utf8.RuneCountInString(name) > maxAttachmentNameLength {
+		name = string([]rune(name)[:maxAttachmentNameLength])
+	}
+	return name
+}
+
+func newAttachmentKey(userID int64, ext string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate attachment key: %w", err)
+	}
+	return fmt.Sprintf("attachments/%d/%s%s", userID, hex.EncodeToString(buf), ext), nil
+}
+
+// FiscalReceipt — реквизиты кассового чека из его QR-кода
+type FiscalReceipt struct {
+	Type          string // expense или income
+	Operation     int32  // Признак расчета n: 1 — приход, 2 — возврат прихода, 3 — расход, 4 — возврат расхода
+	Amount        string
+	OperationDate time.Time
+	FN            string // Номер фискального накопителя
+	FD            string // Номер фискального документа (i в QR-коде)
+	FP            string // Фискальный признак документа
+}
+
+// receiptTimeZone — время в QR-коде указано без часового пояса, считаем
+// его московским
+var receiptTimeZone = time.FixedZone("MSK", 3*60*60)
+
+var (
+	receiptFNPattern     = regexp.MustCompile(`^\d{16}$`)
+	receiptNumberPattern = regexp.MustCompile(`^\d{1,10}$`)
+	receiptAmountPattern = regexp.MustCompile(`^\d+(\.\d{1,2})?$`)
+)
+
+// ParseFiscalReceipt разбирает QR-код чека вида
+// t=20261016T1230&s=1234.50&fn=...&i=...&fp=...&n=1. Приход и возврат
+// расхода — расход пользователя, возврат прихода и расход — доход.
+func ParseFiscalReceipt(payload string) (*FiscalReceipt, error) {
+	payload = strings.TrimSpace(payload)
+	if i := strings.IndexByte(payload, '?'); i >= 0 {
+		payload = payload[i+1:]
+	}
+	values, err := url.ParseQuery(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt code")
+	}
+
+	receipt := &FiscalReceipt{
+		FN: values.Get("fn"),
+		FD: values.Get("i"),
+		FP: values.Get("fp"),
+	}
+	if !receiptFNPattern.MatchString(receipt.FN) || !receiptNumberPattern.MatchString(receipt.FD) ||
+		!receiptNumberPattern.MatchString(receipt.FP) {
+		return nil, fmt.Errorf("invalid receipt code")
+	}
+
+	var operationDate time.Time
+	for _, layout := range []string{"20060102T150405", "20060102T1504"} {
+		if operationDate, err = time.ParseInLocation(layout, values.Get("t"), receiptTimeZone); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid receipt code")
+	}
+	receipt.OperationDate = operationDate.UTC()
+
+	if !receiptAmountPattern.MatchString(values.Get("s")) {
+		return nil, fmt.Errorf("invalid receipt code")
+	}
+	amount, err := parseAmount(values.Get("s"))
+	if err != nil || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid receipt code")
+	}
+	receipt.Amount = formatAmount(amount)
+
+	switch values.Get("n") {
+	case "", "1":
+		receipt.Operation, receipt.Type = 1, "expense"
+	case "2":
+		receipt.Operation, receipt.Type = 2, "income"
+	case "3":
+		receipt.Operation, receipt.Type = 3, "income"
+	case "4":
+		receipt.Operation, receipt.Type = 4, "expense"
+	default:
+		return nil, fmt.Errorf("invalid receipt code")
+	}
+
+	return receipt, nil
+}
+
+// ParseReceipt разбирает QR-код чека в черновик операции. Если операция по
+// этому чеку уже записана, возвращается и ее ID.
+func (s *Service) ParseReceipt(ctx context.Context, userID int64, payload string) (*FiscalReceipt, int64, error) {
+	receipt, err := ParseFiscalReceipt(payload)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	duplicateID, err := s.repo.FindReceiptTransaction(ctx, userID, receipt.FN, receipt.FD, receipt.FP)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return receipt, duplicateID, nil
+}
+
+// saveReceipt привязывает реквизиты чека к новой операции. Один чек
+// записывается только один раз.
+func saveReceipt(ctx context.Context, repo *repository.Repository, userID, transactionID int64, receipt *FiscalReceipt) error {
+	if receipt == nil {
+		return nil
+	}
+
+	duplicateID, err := repo.FindReceiptTransaction(ctx, userID, receipt.FN, receipt.FD, receipt.FP)
+	if err != nil {
+		return err
+	}
+	if duplicateID != 0 {
+		return fmt.Errorf("receipt already recorded")
+	}
+
+	if err := repo.CreateTransactionReceipt(ctx, &repository.Receipt{
+		TransactionID: transactionID,
+		UserID:        userID,
+		FN:            receipt.FN,
+		FD:            receipt.FD,
+		FP:            receipt.FP,
+		Operation:     receipt.Operation,
+	}); err != nil {
+		return fmt.Errorf("failed to save receipt: %w", err)
+	}
+	return nil
+}
+
+const maxGoalNameLength = 100
+
+// daysPerMonth — средняя длина месяца для расчета ежемесячного взноса
+const daysPerMonth = 365.25 / 12
+
+// GoalInput — параметры цели накопления. Deadline — дата в формате
+// YYYY-MM-DD. Без валюты берется валюта привязанного счета или рубли.
+type GoalInput struct {
+	Name         string
+	TargetAmount string
+	Currency     string
+	Deadline     string
+	AccountID    int64
+}
+
+// GoalStatus — прогресс цели на текущую дату
+type GoalStatus struct {
+	Goal            *repository.Goal
+	Saved           string
+	Remaining       string
+	ProgressPercent string
+	RequiredMonthly string // Сколько откладывать в месяц, чтобы успеть к сроку
+	MonthsLeft      int32
+	OnTrack         bool // Накоплено не меньше, чем при равномерных взносах с момента создания
+}
+
+func (s *Service) ListGoals(ctx context.Context, userID int64) ([]*GoalStatus, error) {
+	goals, err := s.repo.ListGoals(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	statuses := make([]*GoalStatus, 0, len(goals))
+	for _, goal := range goals {
+		status, err := goalStatus(goal, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *Service) GetGoalStatus(ctx context.Context, userID, goalID int64) (*GoalStatus, error) {
+	goal, err := s.repo.GetGoal(ctx, goalID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if goal == nil {
+		return nil, fmt.Errorf("goal not found")
+	}
+	return goalStatus(goal, time.Now())
+}
+
+func (s *Service) CreateGoal(ctx context.Context, userID int64, input GoalInput) (*GoalStatus, error) {
+	goal, err := buildGoal(ctx, s.repo, userID, input)
+	if err != nil {
+		return nil, err
+	}
+	if goal.Deadline.Before(truncateToDay(time.Now())) {
+		return nil, fmt.Errorf("goal deadline is in the past")
+	}
+
+	id, err := s.repo.CreateGoal(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetGoalStatus(ctx, userID, id)
+}
+
+func (s *Service) UpdateGoal(ctx context.Context, userID, goalID int64, input GoalInput) (*GoalStatus, error) {
+	goal, err := buildGoal(ctx, s.repo, userID, input)
+	if err != nil {
+		return nil, err
+	}
+	goal.ID = goalID
+
+	updated, err := s.repo.UpdateGoal(ctx, goal)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("goal not found")
+	}
+	return s.GetGoalStatus(ctx, userID, goalID)
+}
+
+func (s *Service) DeleteGoal(ctx context.Context, userID, goalID int64) error {
+	deleted, err := s.repo.DeleteGoal(ctx, goalID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("goal not found")
+	}
+	return nil
+}
+
+// AddGoalContribution записывает ручной взнос в цель. Отрицательная сумма —
+// изъятие из накоплений.
+func (s *Service) AddGoalContribution(ctx context.Context, userID, goalID int64, amount string, date time.Time, note string) (*repository.GoalContribution, *GoalStatus, error) {
+	goal, err := s.repo.GetGoal(ctx, goalID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if goal == nil {
+		return nil, nil, fmt.Errorf("goal not found")
+	}
+
+	value, err := parseAmount(amount)
+	if err != nil || value.Sign() == 0 {
+		return nil, nil, fmt.Errorf("invalid contribution amount")
+	}
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxSplitNoteLength {
+		return nil, nil, fmt.Errorf("contribution note is too long")
+	}
+	if date.IsZero() {
+		date = time.Now()
+	}
+
+	contribution, err := s.repo.CreateGoalContribution(ctx, userID, &repository.GoalContribution{
+		GoalID: goalID,
+		Amount: formatAmount(value),
+		Date:   date,
+		Note:   sql.NullString{String: note, Valid: note != ""},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, err := s.GetGoalStatus(ctx, userID, goalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return contribution, status, nil
+}
+
+func (s *Service) ListGoalContributions(ctx context.Context, userID, goalID int64) ([]*repository.GoalContribution, error) {
+	goal, err := s.repo.GetGoal(ctx, goalID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if goal == nil {
+		return nil, fmt.Errorf("goal not found")
+	}
+	return s.repo.ListGoalContributions(ctx, goal)
+}
+
+// DeleteGoalContribution удаляет ручной взнос. Переводы на привязанный счет
+// удаляются вместе с самой операцией.
+func (s *Service) DeleteGoalContribution(ctx context.Context, userID, goalID, contributionID int64) error {
+	deleted, err := s.repo.DeleteGoalContribution(ctx, contributionID, goalID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("contribution not found")
+	}
+	return nil
+}
+
+func buildGoal(ctx context.Context, repo *repository.Repository, userID int64, input GoalInput) (*repository.Goal, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("goal name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxGoalNameLength {
+		return nil, fmt.Errorf("goal name is too long")
+	}
+
+	target, err := parseAmount(input.TargetAmount)
+	if err != nil || target.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid target amount")
+	}
+
+	deadline, err := time.Parse("2006-01-02", input.Deadline)
+	if err != nil {
+		return nil, fmt.Errorf("invalid goal deadline")
+	}
+
+	goal := &repository.Goal{
+		UserID:       userID,
+		Name:         name,
+		TargetAmount: formatAmount(target),
+		Currency:     strings.ToUpper(strings.TrimSpace(input.Currency)),
+		Deadline:     deadline,
+	}
+
+	if input.AccountID > 0 {
+		account, err := repo.GetAccount(ctx, input.AccountID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			return nil, fmt.Errorf("goal account not found")
+		}
+		if goal.Currency == "" {
+			goal.Currency = account.Currency
+		}
+		if goal.Currency != account.Currency {
+			return nil, fmt.Errorf("goal currency does not match account")
+		}
+		goal.AccountID = sql.NullInt64{Int64: account.ID, Valid: true}
+	}
+	if goal.Currency == "" {
+		goal.Currency = "RUB"
+	}
+
+	return goal, nil
+}
+
+// goalStatus считает прогресс цели на момент now. Ежемесячный взнос —
+// остаток, деленный на число оставшихся месяцев (неполный месяц
+// считается целым).
+func goalStatus(goal *repository.Goal, now time.Time) (*GoalStatus, error) {
+	target, err := parseAmount(goal.TargetAmount)
+	if err != nil {
+		return nil, err
+	}
+	saved, err := parseAmount(goal.Saved)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := new(big.Rat).Sub(target, saved)
+	if remaining.Sign() < 0 {
+		remaining.SetInt64(0)
+	}
+
+	progress := new(big.Rat).Quo(new(big.Rat).Mul(saved, big.NewRat(100, 1)), target)
+	if progress.Sign() < 0 {
+		progress.SetInt64(0)
+	}
+
+	today := truncateToDay(now)
+	deadline := truncateToDay(goal.Deadline)
+	daysLeft := deadline.Sub(today).Hours() / 24
+
+	var monthsLeft int32
+	if daysLeft > 0 {
+		monthsLeft = int32(math.Ceil(daysLeft / daysPerMonth))
+	}
+
+	required := new(big.Rat).Set(remaining)
+	if monthsLeft > 1 {
+		required.Quo(required, big.NewRat(int64(monthsLeft), 1))
+	}
+
+	onTrack := remaining.Sign() == 0
+	if !onTrack && daysLeft > 0 {
+		start := truncateToDay(goal.CreatedAt)
+		total := deadline.Sub(start).Hours() / 24
+		elapsed := today.Sub(start).Hours() / 24
+		expected := new(big.Rat).Set(target)
+		if total > 0 && elapsed < total {
+			share := new(big.Rat)
+			share.SetFloat64(math.Max(elapsed, 0) / total)
+			expected.Mul(expected, share)
+		}
+		onTrack = saved.Cmp(expected) >= 0
+	}
+
+	return &GoalStatus{
+		Goal:            goal,
+		Saved:           formatAmount(saved),
+		Remaining:       formatAmount(remaining),
+		ProgressPercent: progress.FloatString(1),
+		RequiredMonthly: formatAmount(required),
+		MonthsLeft:      monthsLeft,
+		OnTrack:         onTrack,
+	}, nil
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+const (
+	maxCounterpartyNameLength = 100
+	// maxDebtReminderDays — за сколько дней до срока можно напоминать о долге
+	maxDebtReminderDays = 30
+)
+
+// Направления долга
+const (
+	DebtLent     = "lent"     // Пользователь дал в долг
+	DebtBorrowed = "borrowed" // Пользователь взял в долг
+)
+
+// CounterpartyInput — поля контрагента при создании и изменении
+type CounterpartyInput struct {
+	Name string
+	Note string
+}
+
+func (s *Service) ListCounterparties(ctx context.Context, userID int64) ([]*repository.Counterparty, error) {
+	return s.repo.ListCounterparties(ctx, userID)
+}
+
+func (s *Service) CreateCounterparty(ctx context.Context, userID int64, input CounterpartyInput) (*repository.Counterparty, error) {
+	return s.saveCounterparty(ctx, userID, 0, input)
+}
+
+func (s *Service) UpdateCounterparty(ctx context.Context, userID, counterpartyID int64, input CounterpartyInput) (*repository.Counterparty, error) {
+	return s.saveCounterparty(ctx, userID, counterpartyID, input)
+}
+
+// saveCounterparty создает контрагента или, если counterpartyID задан,
+// изменяет его. Имена контрагентов пользователя не повторяются без учета
+// регистра.
+func (s *Service) saveCounterparty(ctx context.Context, userID, counterpartyID int64, input CounterpartyInput) (*repository.Counterparty, error) {
+	name, normalized, err := counterpartyName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+	note := strings.TrimSpace(input.Note)
+	if utf8.RuneCountInString(note) > maxSplitNoteLength {
+		return nil, fmt.Errorf("counterparty note is too long")
+	}
+
+	var result *repository.Counterparty
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		existing, err := repo.FindCounterparty(ctx, userID, normalized)
+		if err != nil {
+			return err
+		}
+		if existing != nil && existing.ID != counterpartyID {
+			return fmt.Errorf("counterparty already exists")
+		}
+
+		counterparty := &repository.Counterparty{
+			ID:             counterpartyID,
+			UserID:         userID,
+			Name:           name,
+			NormalizedName: normalized,
+			Note:           sql.NullString{String: note, Valid: note != ""},
+		}
+		if counterpartyID == 0 {
+			result, err = repo.CreateCounterparty(ctx, counterparty)
+			return err
+		}
+
+		result, err = repo.UpdateCounterparty(ctx, counterparty)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			return fmt.Errorf("counterparty not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DeleteCounterparty удаляет контрагента без долгов. Долги нужно удалить
+// заранее, чтобы история не пропала незаметно.
+func (s *Service) DeleteCounterparty(ctx context.Context, userID, counterpartyID int64) error {
+	counterparty, err := s.repo.GetCounterparty(ctx, counterpartyID, userID)
+	if err != nil {
+		return err
+	}
+	if counterparty == nil {
+		return fmt.Errorf("counterparty not found")
+	}
+
+	hasDebts, err := s.repo.CounterpartyHasDebts(ctx, counterpartyID)
+	if err != nil {
+		return err
+	}
+	if hasDebts {
+		return fmt.Errorf("counterparty has debts")
+	}
+
+	if _, err := s.repo.DeleteCounterparty(ctx, counterpartyID, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+func counterpartyName(name string) (string, string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	if name == "" {
+		return "", "", fmt.Errorf("counterparty name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxCounterpartyNameLength {
+		return "", "", fmt.Errorf("counterparty name is too long")
+	}
+	return name, normalizePayeeName(name), nil
+}
+
+// DebtInput — параметры долга. Контрагент задается идентификатором или
+// именем (новый создается автоматически). TransactionID связывает долг с
+// операцией, которой деньги были выданы или получены: без суммы и валюты
+// они берутся из операции. DueDate — дата в формате YYYY-MM-DD или пусто.
+type DebtInput struct {
+	CounterpartyID int64
+	Counterparty   string
+	Direction      string
+	Amount         string
+	Currency       string
+	Description    string
+	DueDate        string
+	TransactionID  int64
+}
+
+// RepaymentInput — погашение долга. С операцией без суммы и даты они
+// берутся из операции.
+type RepaymentInput struct {
+	Amount        string
+	Date          time.Time
+	Note          string
+	TransactionID int64
+}
+
+// DebtStatus — долг с остатком на текущую дату
+type DebtStatus struct {
+	Debt        *repository.Debt
+	Outstanding string
+	Closed      bool
+	Overdue     bool
+}
+
+// DebtSummary — итог по контрагенту в одной валюте: сколько должны
+// пользователю и сколько должен он
+type DebtSummary struct {
+	CounterpartyID   int64
+	CounterpartyName string
+	Currency         string
+	Lent             string // Остаток выданных долгов
+	Borrowed         string // Остаток полученных долгов
+	Net              string // Lent - Borrowed: больше нуля — должны пользователю
+	OpenDebts        int32
+	NextDueDate      time.Time // Ближайший срок или нулевое время
+}
+
+// ListDebts возвращает итоги по контрагентам с непогашенными долгами и сами
+// долги: по контрагенту, если он задан, и только открытые, если не
+// запрошены закрытые.
+func (s *Service) ListDebts(ctx context.Context, userID, counterpartyID int64, includeClosed bool) ([]*DebtSummary, []*DebtStatus, error) {
+	debts, err := s.repo.ListDebts(ctx, userID, counterpartyID, includeClosed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	statuses := make([]*DebtStatus, 0, len(debts))
+	type summaryKey struct {
+		counterpartyID int64
+		currency       string
+	}
+	summaries := map[summaryKey]*DebtSummary{}
+	lent := map[summaryKey]*big.Rat{}
+	borrowed := map[summaryKey]*big.Rat{}
+	var order []summaryKey
+
+	for _, debt := range debts {
+		status, outstanding, err := debtStatus(debt, now)
+		if err != nil {
+			return nil, nil, err
+		}
+		statuses = append(statuses, status)
+		if status.Closed {
+			continue
+		}
+
+		key := summaryKey{debt.CounterpartyID, debt.Currency}
+		summary, ok := summaries[key]
+		if !ok {
+			summary = &DebtSummary{
+				CounterpartyID:   debt.CounterpartyID,
+				CounterpartyName: debt.CounterpartyName,
+				Currency:         debt.Currency,
+			}
+			summaries[key] = summary
+			lent[key] = new(big.Rat)
+			borrowed[key] = new(big.Rat)
+			order = append(order, key)
+		}
+		summary.OpenDebts++
+		if debt.Direction == DebtLent {
+			lent[key].Add(lent[key], outstanding)
+		} else {
+			borrowed[key].Add(borrowed[key], outstanding)
+		}
+		if debt.DueDate.Valid && (summary.NextDueDate.IsZero() || debt.DueDate.Time.Before(summary.NextDueDate)) {
+			summary.NextDueDate = debt.DueDate.Time
+		}
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := summaries[order[i]], summaries[order[j]]
+		if a.CounterpartyName != b.CounterpartyName {
+			return a.CounterpartyName < b.CounterpartyName
+		}
+		return a.Currency < b.Currency
+	})
+
+	result := make([]*DebtSummary, 0, len(order))
+	for _, key := range order {
+		summary := summaries[key]
+		summary.Lent = formatAmount(lent[key])
+		summary.Borrowed = formatAmount(borrowed[key])
+		summary.Net = formatAmount(new(big.Rat).Sub(lent[key], borrowed[key]))
+		result = append(result, summary)
+	}
+
+	return result, statuses, nil
+}
+
+// GetDebt возвращает долг с остатком и историей погашений
+func (s *Service) GetDebt(ctx context.Context, userID, debtID int64) (*DebtStatus, []*repository.DebtRepayment, error) {
+	debt, err := s.repo.GetDebt(ctx, debtID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if debt == nil {
+		return nil, nil, fmt.Errorf("debt not found")
+	}
+
+	status, _, err := debtStatus(debt, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	repayments, err := s.repo.ListDebtRepayments(ctx, debtID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return status, repayments, nil
+}
+
+func (s *Service) CreateDebt(ctx context.Context, userID int64, input DebtInput) (*DebtStatus, error) {
+	if input.Direction != DebtLent && input.Direction != DebtBorrowed {
+		return nil, fmt.Errorf("invalid debt direction")
+	}
+	dueDate, description, err := debtDetails(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var debtID int64
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		counterpartyID, err := resolveCounterparty(ctx, repo, userID, input)
+		if err != nil {
+			return err
+		}
+
+		debt := &repository.Debt{
+			UserID:         userID,
+			CounterpartyID: counterpartyID,
+			Direction:      input.Direction,
+			Currency:       strings.ToUpper(strings.TrimSpace(input.Currency)),
+			Description:    description,
+			DueDate:        dueDate,
+		}
+
+		amount := input.Amount
+		if input.TransactionID > 0 {
+			// Дать в долг — расход или перевод, взять — доход или перевод
+			allowed := "expense"
+			if input.Direction == DebtBorrowed {
+				allowed = "income"
+			}
+			tx, err := debtTransaction(ctx, repo, userID, input.TransactionID, allowed)
+			if err != nil {
+				return err
+			}
+			if debt.Currency == "" {
+				debt.Currency = tx.Currency
+			}
+			if debt.Currency != tx.Currency {
+				return fmt.Errorf("transaction currency does not match debt")
+			}
+			if amount == "" {
+				amount = tx.Amount
+			}
+			debt.TransactionID = sql.NullInt64{Int64: tx.ID, Valid: true}
+		}
+		if debt.Currency == "" {
+			debt.Currency = "RUB"
+		}
+
+		value, err := parseAmount(amount)
+		if err != nil || value.Sign() <= 0 {
+			return fmt.Errorf("invalid debt amount")
+		}
+		debt.Amount = formatAmount(value)
+
+		debtID, err = repo.CreateDebt(ctx, debt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status, _, err := s.GetDebt(ctx, userID, debtID)
+	return status, err
+}
+
+// UpdateDebt изменяет контрагента, сумму, описание и срок долга.
+// Направление, валюта и операция задаются только при создании.
+func (s *Service) UpdateDebt(ctx context.Context, userID, debtID int64, input DebtInput) (*DebtStatus, error) {
+	dueDate, description, err := debtDetails(input)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		if err := repo.LockDebt(ctx, debtID, userID); err != nil {
+			return err
+		}
+		debt, err := repo.GetDebt(ctx, debtID, userID)
+		if err != nil {
+			return err
+		}
+		if debt == nil {
+			return fmt.Errorf("debt not found")
+		}
+
+		if input.CounterpartyID > 0 || strings.TrimSpace(input.Counterparty) != "" {
+			debt.CounterpartyID, err = resolveCounterparty(ctx, repo, userID, input)
+			if err != nil {
+				return err
+			}
+		}
+
+		if input.Amount != "" {
+			value, err := parseAmount(input.Amount)
+			if err != nil || value.Sign() <= 0 {
+				return fmt.Errorf("invalid debt amount")
+			}
+			repaid, err := parseAmount(debt.Repaid)
+			if err != nil {
+				return err
+			}
+			if value.Cmp(repaid) < 0 {
+				return fmt.Errorf("debt amount is less than repaid")
+			}
+			debt.Amount = formatAmount(value)
+		}
+		debt.Description = description
+		debt.DueDate = dueDate
+
+		_, err = repo.UpdateDebt(ctx, debt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	status, _, err := s.GetDebt(ctx, userID, debtID)
+	return status, err
+}
+
+// DeleteDebt удаляет долг с погашениями. Связанные операции остаются.
+func (s *Service) DeleteDebt(ctx context.Context, userID, debtID int64) error {
+	deleted, err := s.repo.DeleteDebt(ctx, debtID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("debt not found")
+	}
+	return nil
+}
+
+// AddDebtRepayment записывает погашение долга. Погашения не могут
+// превышать остаток.
+func (s *Service) AddDebtRepayment(ctx context.Context, userID, debtID int64, input RepaymentInput) (*repository.DebtRepayment, *DebtStatus, error) {
+	note := strings.TrimSpace(input.Note)
+	if utf8.RuneCountInString(note) > maxSplitNoteLength {
+		return nil, nil, fmt.Errorf("repayment note is too long")
+	}
+
+	var repayment *repository.DebtRepayment
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		if err := repo.LockDebt(ctx, debtID, userID); err != nil {
+			return err
+		}
+		debt, err := repo.GetDebt(ctx, debtID, userID)
+		if err != nil {
+			return err
+		}
+		if debt == nil {
+			return fmt.Errorf("debt not found")
+		}
+
+		amount := input.Amount
+		date := input.Date
+		var transactionID sql.NullInt64
+		if input.TransactionID > 0 {
+			// Выданный долг возвращают доходом, полученный — расходом
+			allowed := "income"
+			if debt.Direction == DebtBorrowed {
+				allowed = "expense"
+			}
+			tx, err := debtTransaction(ctx, repo, userID, input.TransactionID, allowed)
+			if err != nil {
+				return err
+			}
+			if tx.Currency != debt.Currency {
+				return fmt.Errorf("transaction currency does not match debt")
+			}
+			if amount == "" {
+				amount = tx.Amount
+			}
+			if date.IsZero() {
+				date = tx.OperationDate
+			}
+			transactionID = sql.NullInt64{Int64: tx.ID, Valid: true}
+		}
+		if date.IsZero() {
+			date = time.Now()
+		}
+
+		value, err := parseAmount(amount)
+		if err != nil || value.Sign() <= 0 {
+			return fmt.Errorf("invalid repayment amount")
+		}
+		_, outstanding, err := debtStatus(debt, time.Now())
+		if err != nil {
+			return err
+		}
+		if value.Cmp(outstanding) > 0 {
+			return fmt.Errorf("repayment exceeds outstanding amount")
+		}
+
+		repayment, err = repo.CreateDebtRepayment(ctx, userID, &repository.DebtRepayment{
+			DebtID:        debtID,
+			Amount:        formatAmount(value),
+			Date:          date,
+			TransactionID: transactionID,
+			Note:          sql.NullString{String: note, Valid: note != ""},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	status, _, err := s.GetDebt(ctx, userID, debtID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return repayment, status, nil
+}
+
+func (s *Service) DeleteDebtRepayment(ctx context.Context, userID, debtID, repaymentID int64) error {
+	deleted, err := s.repo.DeleteDebtRepayment(ctx, repaymentID, debtID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("repayment not found")
+	}
+	return nil
+}
+
+// EnqueueDebtReminders ставит в очередь уведомлений напоминания о долгах
+// всех пользователей: за withinDays дней до срока и еще раз, когда срок
+// наступил. Долг отмечается напомненным в той же транзакции, в которой
+// ставится уведомление, а доставку с повторами, тихими часами и
+// отключенными типами берет на себя очередь. Возвращает число напоминаний.
+func (s *Service) EnqueueDebtReminders(ctx context.Context, withinDays int) (int, error) {
+	if withinDays < 0 || withinDays > maxDebtReminderDays {
+		return 0, fmt.Errorf("invalid reminder window")
+	}
+
+	queued := 0
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		debts, err := repo.ClaimDebtReminders(ctx, withinDays)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, debt := range debts {
+			status, _, err := debtStatus(debt, now)
+			if err != nil {
+				return err
+			}
+			if _, err := repo.EnqueueNotification(ctx, debtReminder(status, now)); err != nil {
+				return err
+			}
+		}
+		queued = len(debts)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return queued, nil
+}
+
+// debtReminder составляет уведомление о долге. Ключ повтора включает срок
+// и стадию, так что до срока и в день срока приходит по одному
+// напоминанию, а перенос срока позволяет напомнить снова.
+func debtReminder(status *DebtStatus, now time.Time) *repository.Notification {
+	debt := status.Debt
+	stage := "upcoming"
+	if !truncateToDay(now).Before(debt.DueDate.Time) {
+		stage = "due"
+	}
+
+	payload := map[string]string{
+		"counterparty": debt.CounterpartyName,
+		"direction":    debt.Direction,
+		"amount":       status.Outstanding,
+		"currency":     debt.Currency,
+		"due_date":     debt.DueDate.Time.Format("02.01.2006"),
+	}
+	if status.Overdue {
+		payload["overdue"] = "true"
+	}
+	if debt.Description.Valid {
+		payload["description"] = debt.Description.String
+	}
+
+	dedupKey := fmt.Sprintf("debt:%d:%s:%s", debt.ID, debt.DueDate.Time.Format("2006-01-02"), stage)
+	return &repository.Notification{
+		UserID:   debt.UserID,
+		Type:     NotificationDebtReminder,
+		Payload:  payload,
+		DedupKey: sql.NullString{String: dedupKey, Valid: true},
+	}
+}
+
+// RunDebtReminders ставит в очередь напоминания о долгах сразу и затем
+// каждые interval, пока не закрыт stop.
+func (s *Service) RunDebtReminders(stop <-chan struct{}, interval time.Duration, withinDays int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		queued, err := s.EnqueueDebtReminders(context.Background(), withinDays)
+		if err != nil {
+			s.logger.Error("failed to enqueue debt reminders", zap.Error(err))
+		} else if queued > 0 {
+			s.logger.Info("debt reminders queued", zap.Int("count", queued))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// debtDetails проверяет срок и описание долга
+func debtDetails(input DebtInput) (sql.NullTime, sql.NullString, error) {
+	var dueDate sql.NullTime
+	if input.DueDate != "" {
+		date, err := time.Parse("2006-01-02", input.DueDate)
+		if err != nil {
+			return dueDate, sql.NullString{}, fmt.Errorf("invalid debt due date")
+		}
+		dueDate = sql.NullTime{Time: date, Valid: true}
+	}
+
+	description := strings.TrimSpace(input.Description)
+	if utf8.RuneCountInString(description) > maxSplitNoteLength {
+		return dueDate, sql.NullString{}, fmt.Errorf("debt description is too long")
+	}
+	return dueDate, sql.NullString{String: description, Valid: description != ""}, nil
+}
+
+// resolveCounterparty находит контрагента по идентификатору или имени.
+// Контрагент с новым именем создается.
+func resolveCounterparty(ctx context.Context, repo *repository.Repository, userID int64, input DebtInput) (int64, error) {
+	if input.CounterpartyID > 0 {
+		counterparty, err := repo.GetCounterparty(ctx, input.CounterpartyID, userID)
+		if err != nil {
+			return 0, err
+		}
+		if counterparty == nil {
+			return 0, fmt.Errorf("counterparty not found")
+		}
+		return counterparty.ID, nil
+	}
+
+	if strings.TrimSpace(input.Counterparty) == "" {
+		return 0, fmt.Errorf("counterparty is required")
+	}
+	name, normalized, err := counterpartyName(input.Counterparty)
+	if err != nil {
+		return 0, err
+	}
+	counterparty, err := repo.FindCounterparty(ctx, userID, normalized)
+	if err != nil {
+		return 0, err
+	}
+	if counterparty == nil {
+		counterparty, err = repo.CreateCounterparty(ctx, &repository.Counterparty{
+			UserID:         userID,
+			Name:           name,
+			NormalizedName: normalized,
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return counterparty.ID, nil
+}
+
+// debtTransaction проверяет операцию, с которой связывается долг или
+// погашение: она принадлежит пользователю, имеет тип allowedType или
+// является переводом и еще не связана с другим долгом.
+func debtTransaction(ctx context.Context, repo *repository.Repository, userID, transactionID int64, allowedType string) (*repository.Transaction, error) {
+	tx, err := repo.GetTransaction(ctx, transactionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction: %w", err)
+	}
+	if tx == nil {
+		return nil, fmt.Errorf("transaction not found")
+	}
+	if tx.Type != allowedType && tx.Type != "transfer" {
+		return nil, fmt.Errorf("transaction type does not match debt")
+	}
+
+	linked, err := repo.TransactionLinkedToDebt(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if linked {
+		return nil, fmt.Errorf("transaction already linked to a debt")
+	}
+	return tx, nil
+}
+
+// debtStatus считает остаток долга на момент now
+func debtStatus(debt *repository.Debt, now time.Time) (*DebtStatus, *big.Rat, error) {
+	amount, err := parseAmount(debt.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+	repaid, err := parseAmount(debt.Repaid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	outstanding := new(big.Rat).Sub(amount, repaid)
+	if outstanding.Sign() < 0 {
+		outstanding.SetInt64(0)
+	}
+	closed := outstanding.Sign() == 0
+
+	return &DebtStatus{
+		Debt:        debt,
+		Outstanding: formatAmount(outstanding),
+		Closed:      closed,
+		Overdue:     !closed && debt.DueDate.Valid && debt.DueDate.Time.Before(truncateToDay(now)),
+	}, outstanding, nil
+}
+
+// Виды платежей по кредиту
+const (
+	LoanAnnuity        = "annuity"        // Равные ежемесячные платежи
+	LoanDifferentiated = "differentiated" // Равные доли основного долга и проценты на остаток
+)
+
+// Способы учета досрочного погашения
+const (
+	PrepayReduceTerm    = "reduce_term"    // Платеж прежний, срок короче
+	PrepayReducePayment = "reduce_payment" // Срок прежний, платеж меньше
+)
+
+const maxLoanTermMonths = 600
+
+// LoanInput — условия кредита. AnnualRate — годовая ставка в процентах,
+// FirstPaymentDate — дата первого платежа в формате YYYY-MM-DD, следующие
+// платежи приходятся на то же число месяца.
+type LoanInput struct {
+	Principal          string
+	AnnualRate         string
+	TermMonths         int32
+	PaymentType        string
+	FirstPaymentDate   string
+	PaymentAccountID   int64 // Счет, с которого вносятся платежи
+	InterestCategoryID int64 // Категория расходов на проценты, по умолчанию — резервная
+}
+
+// LoanPayment — строка графика платежей
+type LoanPayment struct {
+	Number                 int32
+	Date                   time.Time
+	Payment                string
+	Principal              string
+	Interest               string
+	Balance                string // Остаток долга после платежа
+	Posted                 bool
+	PrincipalTransactionID int64
+	InterestTransactionID  int64
+}
+
+// LoanSchedule — полный график платежей по кредиту
+type LoanSchedule struct {
+	Loan          *repository.Loan
+	Payments      []*LoanPayment
+	TotalInterest string
+	TotalPaid     string
+}
+
+// LoanPrepayment — результат расчета досрочного погашения
+type LoanPrepayment struct {
+	Payments      []*LoanPayment // График с учетом досрочного погашения
+	BalanceAfter  string         // Остаток долга сразу после погашения
+	NewPayment    string         // Первый плановый платеж после погашения
+	TotalInterest string
+	InterestSaved string
+	MonthsSaved   int32
+}
+
+// LoanPaymentResult — проведенный платеж и остатки счетов после него
+type LoanPaymentResult struct {
+	Payment               *LoanPayment
+	PaymentAccountBalance string
+	LoanAccountBalance    string
+	Warning               string
+}
+
+// SetLoan задает условия кредита для счета типа loan. Проведенные платежи
+// сохраняются, график пересчитывается по новым условиям.
+func (s *Service) SetLoan(ctx context.Context, userID, accountID int64, input LoanInput) (*LoanSchedule, error) {
+	account, err := s.repo.GetAccount(ctx, accountID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		return nil, fmt.Errorf("account not found")
+	}
+	if account.Type != AccountTypeLoan {
+		return nil, fmt.Errorf("account is not a loan")
+	}
+
+	principal, err := parseAmount(input.Principal)
+	if err != nil || principal.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid loan principal")
+	}
+	rate, err := parseAmount(input.AnnualRate)
+	if err != nil || rate.Sign() < 0 || rate.Cmp(big.NewRat(100, 1)) > 0 {
+		return nil, fmt.Errorf("invalid loan rate")
+	}
+	if input.TermMonths < 1 || input.TermMonths > maxLoanTermMonths {
+		return nil, fmt.Errorf("invalid loan term")
+	}
+	if input.PaymentType != LoanAnnuity && input.PaymentType != LoanDifferentiated {
+		return nil, fmt.Errorf("invalid loan payment type")
+	}
+	firstPaymentDate, err := time.Parse("2006-01-02", input.FirstPaymentDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid first payment date")
+	}
+
+	loan := &repository.Loan{
+		AccountID:        accountID,
+		UserID:           userID,
+		Principal:        formatAmount(principal),
+		AnnualRate:       rate.FloatString(4),
+		TermMonths:       input.TermMonths,
+		PaymentType:      input.PaymentType,
+		FirstPaymentDate: firstPaymentDate,
+	}
+
+	if input.PaymentAccountID > 0 {
+		if input.PaymentAccountID == accountID {
+			return nil, fmt.Errorf("payment account must differ from loan account")
+		}
+		paymentAccount, err := s.repo.GetAccount(ctx, input.PaymentAccountID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if paymentAccount == nil {
+			return nil, fmt.Errorf("payment account not found")
+		}
+		if paymentAccount.Currency != account.Currency {
+			return nil, fmt.Errorf("payment account currency does not match loan")
+		}
+		loan.PaymentAccountID = sql.NullInt64{Int64: paymentAccount.ID, Valid: true}
+	}
+
+	if input.InterestCategoryID > 0 {
+		category, err := s.repo.GetCategory(ctx, input.InterestCategoryID, userID)
+		if err != nil {
+			if err.Error() == "category not found" {
+				return nil, fmt.Errorf("interest category not found")
+			}
+			return nil, err
+		}
+		if category.Type != "expense" {
+			return nil, fmt.Errorf("interest category must be an expense category")
+		}
+		loan.InterestCategoryID = sql.NullInt64{Int64: category.ID, Valid: true}
+	}
+
+	if err := s.repo.SaveLoan(ctx, loan); err != nil {
+		return nil, err
+	}
+	return s.GetLoanSchedule(ctx, userID, accountID)
+}
+
+func (s *Service) GetLoanSchedule(ctx context.Context, userID, accountID int64) (*LoanSchedule, error) {
+	loan, err := s.repo.GetLoan(ctx, accountID, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if loan == nil {
+		return nil, fmt.Errorf("loan not found")
+	}
+
+	payments, err := loanSchedule(loan)
+	if err != nil {
+		return nil, err
+	}
+	posted, err := s.repo.ListPostedLoanPayments(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	markPostedPayments(payments, posted)
+
+	totalInterest, totalPaid := loanTotals(payments)
+	return &LoanSchedule{
+		Loan:          loan,
+		Payments:      payments,
+		TotalInterest: totalInterest,
+		TotalPaid:     totalPaid,
+	}, nil
+}
+
+// DeleteLoan удаляет условия кредита. Счет и проведенные операции остаются.
+func (s *Service) DeleteLoan(ctx context.Context, userID, accountID int64) error {
+	deleted, err := s.repo.DeleteLoan(ctx, accountID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("loan not found")
+	}
+	return nil
+}
+
+// PostLoanPayment проводит платеж графика с номером number (0 — ближайший
+// непроведенный): основной долг — переводом со счета платежей на счет
+// кредита, проценты — расходом. Без даты операции берется дата платежа.
+// О проведенном платеже ставится уведомление recurring_payment.
+func (s *Service) PostLoanPayment(ctx context.Context, userID, accountID int64, number int32, operationDate time.Time) (*LoanPaymentResult, error) {
+	result := &LoanPaymentResult{}
+	var postedLoan *repository.Loan
+
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		loan, err := repo.GetLoan(ctx, accountID, userID, true)
+		if err != nil {
+			return err
+		}
+		if loan == nil {
+			return fmt.Errorf("loan not found")
+		}
+		if !loan.PaymentAccountID.Valid {
+			return fmt.Errorf("loan payment account is not set")
+		}
+
+		payments, err := loanSchedule(loan)
+		if err != nil {
+			return err
+		}
+		posted, err := repo.ListPostedLoanPayments(ctx, accountID)
+		if err != nil {
+			return err
+		}
+
+		var payment *LoanPayment
+		for _, p := range payments {
+			if (number == 0 && posted[p.Number] == nil) || p.Number == number {
+				payment = p
+				break
+			}
+		}
+		if payment == nil {
+			return fmt.Errorf("loan payment not found")
+		}
+		if posted[payment.Number] != nil {
+			return fmt.Errorf("loan payment already posted")
+		}
+		if operationDate.IsZero() {
+			operationDate = payment.Date
+		}
+
+		paymentAccountID := loan.PaymentAccountID.Int64
+		accounts, err := lockAccounts(ctx, repo, userID, paymentAccountID, accountID)
+		if err != nil {
+			return err
+		}
+		paymentAccount := accounts[paymentAccountID]
+		if paymentAccount == nil {
+			return fmt.Errorf("payment account not found")
+		}
+		if accounts[accountID] == nil {
+			return fmt.Errorf("loan not found")
+		}
+
+		result.Warning, err = checkDebit(paymentAccount, payment.Payment)
+		if err != nil {
+			return err
+		}
+
+		record := &repository.PostedLoanPayment{Number: payment.Number}
+		if payment.Principal != "0.00" {
+			tx, err := repo.CreateTransaction(ctx, &repository.Transaction{
+				UserID:           userID,
+				AccountID:        paymentAccountID,
+				RelatedAccountID: sql.NullInt64{Int64: accountID, Valid: true},
+				Type:             "transfer",
+				Amount:           payment.Principal,
+				Currency:         paymentAccount.Currency,
+				Description:      sql.NullString{String: fmt.Sprintf("%s, платеж %d: основной долг", loan.AccountName, payment.Number), Valid: true},
+				OperationDate:    operationDate,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create transaction: %w", err)
+			}
+			if err := repo.UpdateAccountBalance(ctx, paymentAccountID, "-"+payment.Principal); err != nil {
+				return fmt.Errorf("failed to update from account balance: %w", err)
+			}
+			if err := repo.UpdateAccountBalance(ctx, accountID, payment.Principal); err != nil {
+				return fmt.Errorf("failed to update to account balance: %w", err)
+			}
+			record.PrincipalTransactionID = sql.NullInt64{Int64: tx.ID, Valid: true}
+		}
+
+		if payment.Interest != "0.00" {
+			categoryID := loan.InterestCategoryID.Int64
+			if !loan.InterestCategoryID.Valid {
+				categoryID, err = repo.GetFallbackCategoryID(ctx, userID, "expense")
+				if err != nil {
+					return fmt.Errorf("failed to find fallback category: %w", err)
+				}
+			}
+			tx, err := repo.CreateTransaction(ctx, &repository.Transaction{
+				UserID:        userID,
+				AccountID:     paymentAccountID,
+				CategoryID:    sql.NullInt64{Int64: categoryID, Valid: true},
+				Type:          "expense",
+				Amount:        payment.Interest,
+				Currency:      paymentAccount.Currency,
+				Description:   sql.NullString{String: fmt.Sprintf("%s, платеж %d: проценты", loan.AccountName, payment.Number), Valid: true},
+				OperationDate: operationDate,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create transaction: %w", err)
+			}
+			if err := repo.UpdateAccountBalance(ctx, paymentAccountID, "-"+payment.Interest); err != nil {
+				return fmt.Errorf("failed to update account balance: %w", err)
+			}
+			record.InterestTransactionID = sql.NullInt64{Int64: tx.ID, Valid: true}
+		}
+
+		if err := repo.SavePostedLoanPayment(ctx, accountID, userID, record); err != nil {
+			return err
+		}
+		payment.Posted = true
+		payment.PrincipalTransactionID = record.PrincipalTransactionID.Int64
+		payment.InterestTransactionID = record.InterestTransactionID.Int64
+		result.Payment = payment
+
+		updatedPaymentAccount, err := repo.GetAccount(ctx, paymentAccountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated from account: %w", err)
+		}
+		updatedLoanAccount, err := repo.GetAccount(ctx, accountID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get updated to account: %w", err)
+		}
+		result.PaymentAccountBalance = updatedPaymentAccount.Balance
+		result.LoanAccountBalance = updatedLoanAccount.Balance
+		postedLoan = loan
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.notifyLoanPayment(ctx, postedLoan, result.Payment)
+
+	return result, nil
+}
+
+// notifyLoanPayment ставит в очередь уведомление о проведенном платеже по
+// кредиту: о каждом платеже графика — один раз. Платеж уже проведен,
+// поэтому ошибки только пишутся в лог.
+func (s *Service) notifyLoanPayment(ctx context.Context, loan *repository.Loan, payment *LoanPayment) {
+	payload := map[string]string{
+		"name":     fmt.Sprintf("%s, платеж %d", loan.AccountName, payment.Number),
+		"amount":   payment.Payment,
+		"currency": loan.Currency,
+	}
+	dedupKey := fmt.Sprintf("loan:%d:%d", loan.AccountID, payment.Number)
+	if err := s.EnqueueNotification(ctx, loan.UserID, NotificationRecurringPayment, payload, dedupKey); err != nil {
+		s.logger.Error("failed to enqueue loan payment notification", zap.Int64("account_id", loan.AccountID), zap.Error(err))
+	}
+}
+
+// CalculateLoanPrepayment считает, как изменится график, если внести
+// amount досрочно в дату date (по умолчанию сегодня). Плановые платежи до
+// этой даты включительно остаются прежними, проценты следующего периода
+// начисляются на уменьшенный остаток. Ничего не сохраняет.
+func (s *Service) CalculateLoanPrepayment(ctx context.Context, userID, accountID int64, amount string, date time.Time, mode string) (*LoanPrepayment, error) {
+	if mode == "" {
+		mode = PrepayReduceTerm
+	}
+	if mode != PrepayReduceTerm && mode != PrepayReducePayment {
+		return nil, fmt.Errorf("invalid prepayment mode")
+	}
+	value, err := parseAmount(amount)
+	if err != nil || value.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid prepayment amount")
+	}
+	if date.IsZero() {
+		date = time.Now()
+	}
+	date = truncateToDay(date)
+
+	loan, err := s.repo.GetLoan(ctx, accountID, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	if loan == nil {
+		return nil, fmt.Errorf("loan not found")
+	}
+	principal, rate, err := loanTerms(loan)
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := loanSchedule(loan)
+	if err != nil {
+		return nil, err
+	}
+	posted, err := s.repo.ListPostedLoanPayments(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	paid := 0
+	for paid < len(schedule) && !schedule[paid].Date.After(date) {
+		paid++
+	}
+	if paid == len(schedule) {
+		return nil, fmt.Errorf("loan is repaid by this date")
+	}
+
+	balance := new(big.Rat).Set(principal)
+	if paid > 0 {
+		if balance, err = parseAmount(schedule[paid-1].Balance); err != nil {
+			return nil, err
+		}
+	}
+	if value.Cmp(balance) > 0 {
+		return nil, fmt.Errorf("prepayment exceeds loan balance")
+	}
+	balance.Sub(balance, value)
+
+	payments := make([]*LoanPayment, 0, len(schedule))
+	for _, p := range schedule[:paid] {
+		copied := *p
+		payments = append(payments, &copied)
+	}
+
+	result := &LoanPrepayment{
+		BalanceAfter: formatAmount(balance),
+		NewPayment:   formatAmount(new(big.Rat)),
+	}
+	if balance.Sign() > 0 {
+		remaining := loan.TermMonths - int32(paid)
+		var step *big.Rat
+		switch {
+		case mode == PrepayReducePayment:
+			step = loanStep(balance, rate, remaining, loan.PaymentType)
+		default:
+			// Срок сокращается при прежнем платеже или прежней доле основного долга
+			step = loanStep(principal, rate, loan.TermMonths, loan.PaymentType)
+		}
+		tail := amortize(balance, rate, step, loan.PaymentType, int32(paid)+1, remaining, loan.FirstPaymentDate)
+		payments = append(payments, tail...)
+		if len(tail) > 0 {
+			result.NewPayment = tail[0].Payment
+		}
+	}
+	markPostedPayments(payments, posted)
+
+	originalInterest, _ := loanTotals(schedule)
+	newInterest, _ := loanTotals(payments)
+	before, _ := parseAmount(originalInterest)
+	after, _ := parseAmount(newInterest)
+
+	result.Payments = payments
+	result.TotalInterest = newInterest
+	result.InterestSaved = formatAmount(new(big.Rat).Sub(before, after))
+	result.MonthsSaved = int32(len(schedule) - len(payments))
+	return result, nil
+}
+
+// loanTerms возвращает сумму кредита и месячную ставку
+func loanTerms(loan *repository.Loan) (principal, monthlyRate *big.Rat, err error) {
+	principal, err = parseAmount(loan.Principal)
+	if err != nil {
+		return nil, nil, err
+	}
+	annualRate, err := parseAmount(loan.AnnualRate)
+	if err != nil {
+		return nil, nil, err
+	}
+	return principal, new(big.Rat).Quo(annualRate, big.NewRat(1200, 1)), nil
+}
+
+// loanSchedule строит полный график платежей по условиям кредита
+func loanSchedule(loan *repository.Loan) ([]*LoanPayment, error) {
+	principal, rate, err := loanTerms(loan)
+	if err != nil {
+		return nil, err
+	}
+	step := loanStep(principal, rate, loan.TermMonths, loan.PaymentType)
+	return amortize(principal, rate, step, loan.PaymentType, 1, loan.TermMonths, loan.FirstPaymentDate), nil
+}
+
+// loanStep возвращает ежемесячный платеж для аннуитета или ежемесячную
+// долю основного долга для дифференцированных платежей
+func loanStep(balance, monthlyRate *big.Rat, months int32, paymentType string) *big.Rat {
+	if paymentType == LoanDifferentiated || monthlyRate.Sign() == 0 {
+		return roundAmount(new(big.Rat).Quo(balance, big.NewRat(int64(months), 1)))
+	}
+
+	// A = S * r / (1 - (1 + r)^-n); платеж округляется до копеек, разница
+	// закрывается последним платежом
+	r, _ := monthlyRate.Float64()
+	s, _ := balance.Float64()
+	payment := s * r / (1 - math.Pow(1+r, -float64(months)))
+	return roundAmount(new(big.Rat).SetFloat64(payment))
+}
+
+// amortize раскладывает остаток balance на платежи с номерами first,
+// first+1, ... (не больше count). Проценты начисляются на остаток по
+// месячной ставке. Для аннуитета step — весь платеж, для
+// дифференцированных платежей — доля основного долга. Последний платеж
+// закрывает остаток.
+func amortize(balance, monthlyRate, step *big.Rat, paymentType string, first, count int32, firstPaymentDate time.Time) []*LoanPayment {
+	balance = new(big.Rat).Set(balance)
+
+	var payments []*LoanPayment
+	for i := int32(0); i < count && balance.Sign() > 0; i++ {
+		interest := roundAmount(new(big.Rat).Mul(balance, monthlyRate))
+		principal := new(big.Rat).Set(step)
+		if paymentType == LoanAnnuity {
+			principal.Sub(step, interest)
+		}
+		if principal.Sign() < 0 {
+			principal.SetInt64(0)
+		}
+		if principal.Cmp(balance) > 0 || i == count-1 {
+			principal.Set(balance)
+		}
+		balance.Sub(balance, principal)
+
+		number := first + i
+		payments = append(payments, &LoanPayment{
+			Number:    number,
+			Date:      addMonths(firstPaymentDate, int(number-1)),
+			Payment:   formatAmount(new(big.Rat).Add(principal, interest)),
+			Principal: formatAmount(principal),
+			Interest:  formatAmount(interest),
+			Balance:   formatAmount(balance),
+		})
+	}
+	return payments
+}
+
+func markPostedPayments(payments []*LoanPayment, posted map[int32]*repository.PostedLoanPayment) {
+	for _, payment := range payments {
+		if record := posted[payment.Number]; record != nil {
+			payment.Posted = true
+			payment.PrincipalTransactionID = record.PrincipalTransactionID.Int64
+			payment.InterestTransactionID = record.InterestTransactionID.Int64
+		}
+	}
+}
+
+// loanTotals возвращает сумму процентов и сумму всех платежей графика
+func loanTotals(payments []*LoanPayment) (interest, paid string) {
+	totalInterest, totalPaid := new(big.Rat), new(big.Rat)
+	for _, payment := range payments {
+		if value, err := parseAmount(payment.Interest); err == nil {
+			totalInterest.Add(totalInterest, value)
+		}
+		if value, err := parseAmount(payment.Payment); err == nil {
+			totalPaid.Add(totalPaid, value)
+		}
+	}
+	return formatAmount(totalInterest), formatAmount(totalPaid)
+}
+
+// roundAmount округляет сумму до копеек
+func roundAmount(amount *big.Rat) *big.Rat {
+	rounded, _ := parseAmount(formatAmount(amount))
+	return rounded
+}
+
+// addMonths сдвигает дату на months месяцев. Если в месяце нет такого
+// числа, берется последний день месяца: 31 января + 1 месяц = 28 февраля.
+func addMonths(t time.Time, months int) time.Time {
+	year, month, day := t.Date()
+	first := time.Date(year, month+time.Month(months), 1, 0, 0, 0, 0, t.Location())
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, t.Location())
+}
+
+// Периодичность подписки
+const (
+	CadenceWeekly    = "weekly"
+	CadenceMonthly   = "monthly"
+	CadenceQuarterly = "quarterly"
+	CadenceYearly    = "yearly"
+)
+
+const (
+	defaultSubscriptionMonths = 24
+	maxSubscriptionMonths     = 60
+	maxSubscriptionScan       = 10000
+	subscriptionAmountSpread  = 0.2 // Допустимое отклонение суммы от медианы
+)
+
+// subscriptionCadence — интервал между списаниями для периодичности.
+// Интервал между соседними списаниями должен попадать в [minDays, maxDays],
+// подписка считается активной, пока следующее списание просрочено не больше
+// чем на graceDays.
+type subscriptionCadence struct {
+	name       string
+	months     int // Сдвиг следующей даты в месяцах, 0 — в днях
+	days       int
+	minDays    int
+	maxDays    int
+	graceDays  int
+	minCharges int
+}
+
+// shift возвращает дату списания через count периодов после date. Сдвиг
+// считается от date целиком, чтобы 31-е число не съезжало после февраля.
+func (c *subscriptionCadence) shift(date time.Time, count int) time.Time {
+	if c.months > 0 {
+		return addMonths(date, c.months*count)
+	}
+	return date.AddDate(0, 0, c.days*count)
+}
+
+// cadenceByName возвращает периодичность по названию или nil
+func cadenceByName(name string) *subscriptionCadence {
+	for i := range subscriptionCadences {
+		if subscriptionCadences[i].name == name {
+			return &subscriptionCadences[i]
+		}
+	}
+	return nil
+}
+
+var subscriptionCadences = []subscriptionCadence{
+	{name: CadenceWeekly, days: 7, minDays: 5, maxDays: 9, graceDays: 3, minCharges: 4},
+	{name: CadenceMonthly, months: 1, minDays: 26, maxDays: 35, graceDays: 7, minCharges: 3},
+	{name: CadenceQuarterly, months: 3, minDays: 84, maxDays: 98, graceDays: 14, minCharges: 3},
+	{name: CadenceYearly, months: 12, minDays: 350, maxDays: 380, graceDays: 30, minCharges: 2},
+}
+
+// Subscription — регулярное списание, найденное в истории расходов
+type Subscription struct {
+	Key           string // Ключ группы списаний, по нему подписка превращается в бюджет
+	PayeeID       int64
+	Name          string
+	Cadence       string
+	Currency      string
+	AverageAmount string
+	LastAmount    string
+	ChargeCount   int32
+	FirstCharge   time.Time
+	LastCharge    time.Time
+	NextExpected  time.Time
+	Active        bool
+	CategoryID    int64
+	CategoryName  string
+	AccountID     int64
+	AccountName   string
+}
+
+// subscriptionCharge — расход-кандидат в подписку
+type subscriptionCharge struct {
+	tx     *repository.TransactionWithDetails
+	amount float64
+	date   time.Time
+}
+
+// DetectSubscriptions ищет в расходах за последние months месяцев регулярные
+// списания: один получатель (или одно описание, если получатель не задан),
+// близкая сумма и постоянный интервал. Неактивные подписки — те, чье
+// очередное списание давно просрочено, — возвращаются только по запросу.
+func (s *Service) DetectSubscriptions(ctx context.Context, userID int64, months int32, includeInactive bool) ([]*Subscription, error) {
+	if months == 0 {
+		months = defaultSubscriptionMonths
+	}
+	if months < 1 || months > maxSubscriptionMonths {
+		return nil, fmt.Errorf("invalid analysis period")
+	}
+
+	transactions, err := s.subscriptionHistory(ctx, userID, months, "expense")
+	if err != nil {
+		return nil, err
+	}
+
+	var subscriptions []*Subscription
+	for _, subscription := range findSubscriptions(transactions, startOfDay(time.Now())) {
+		if !subscription.Active && !includeInactive {
+			continue
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if !subscriptions[i].NextExpected.Equal(subscriptions[j].NextExpected) {
+			return subscriptions[i].NextExpected.Before(subscriptions[j].NextExpected)
+		}
+		return subscriptions[i].Name < subscriptions[j].Name
+	})
+
+	return subscriptions, nil
+}
+
+// subscriptionHistory возвращает операции пользователя типа txType за
+// последние months месяцев.
+func (s *Service) subscriptionHistory(ctx context.Context, userID int64, months int32, txType string) ([]*repository.TransactionWithDetails, error) {
+	now := time.Now().UTC()
+	return s.repo.ListTransactions(ctx, userID, repository.TransactionFilter{
+		Period:    "period",
+		StartDate: addMonths(now, -int(months)).Format("2006-01-02"),
+		EndDate:   now.Format(time.RFC3339),
+		Types:     []string{txType},
+		Sort:      repository.SortDateDesc,
+		Limit:     maxSubscriptionScan,
+	})
+}
+
+// findSubscriptions группирует операции по subscriptionKey и возвращает
+// найденные регулярные платежи по ключу группы.
+func findSubscriptions(transactions []*repository.TransactionWithDetails, today time.Time) map[string]*Subscription {
+	groups := make(map[string][]*subscriptionCharge)
+	for _, tx := range transactions {
+		key := subscriptionKey(tx)
+		if key == "" {
+			continue
+		}
+		amount, err := parseAmount(tx.Amount)
+		if err != nil {
+			continue
+		}
+		value, _ := amount.Float64()
+		groups[key] = append(groups[key], &subscriptionCharge{
+			tx:     tx,
+			amount: value,
+			date:   startOfDay(tx.OperationDate),
+		})
+	}
+
+	subscriptions := make(map[string]*Subscription)
+	for key, charges := range groups {
+		if subscription := detectSubscription(charges, today); subscription != nil {
+			subscription.Key = key
+			subscriptions[key] = subscription
+		}
+	}
+	return subscriptions
+}
+
+// subscriptionKey группирует расходы по получателю, а без получателя — по
+// нормализованному описанию. Валюта входит в ключ: суммы в разных валютах
+// не сравниваются.
+func subscriptionKey(tx *repository.TransactionWithDetails) string {
+	if tx.PayeeID.Valid {
+		return fmt.Sprintf("payee:%d:%s", tx.PayeeID.Int64, tx.Currency)
+	}
+	description := normalizePayeeName(tx.Description.String)
+	if description == "" {
+		return ""
+	}
+	return "description:" + description + ":" + tx.Currency
+}
+
+// detectSubscription проверяет, похожи ли списания одной группы на подписку.
+// Списания с суммой дальше subscriptionAmountSpread от медианы отбрасываются,
+// периодичность определяется по медиане интервалов, а большинство интервалов
+// должно в нее укладываться.
+func detectSubscription(charges []*subscriptionCharge, today time.Time) *Subscription {
+	amounts := make([]float64, 0, len(charges))
+	for _, charge := range charges {
+		amounts = append(amounts, charge.amount)
+	}
+	medianAmount := median(amounts)
+	if medianAmount <= 0 {
+		return nil
+	}
+
+	var similar []*subscriptionCharge
+	for _, charge := range charges {
+		if math.Abs(charge.amount-medianAmount) <= medianAmount*subscriptionAmountSpread {
+			similar = append(similar, charge)
+		}
+	}
+	if len(similar) < 2 {
+		return nil
+	}
+	sort.Slice(similar, func(i, j int) bool {
+		if !similar[i].date.Equal(similar[j].date) {
+			return similar[i].date.Before(similar[j].date)
+		}
+		return similar[i].tx.ID < similar[j].tx.ID
+	})
+
+	intervals := make([]float64, 0, len(similar)-1)
+	for i := 1; i < len(similar); i++ {
+		intervals = append(intervals, math.Round(similar[i].date.Sub(similar[i-1].date).Hours()/24))
+	}
+	interval := median(intervals)
+
+	var cadence *subscriptionCadence
+	for i := range subscriptionCadences {
+		if c := &subscriptionCadences[i]; interval >= float64(c.minDays) && interval <= float64(c.maxDays) {
+			cadence = c
+			break
+		}
+	}
+	if cadence == nil || len(similar) < cadence.minCharges {
+		return nil
+	}
+	regular := 0
+	for _, days := range intervals {
+		if days >= float64(cadence.minDays) && days <= float64(cadence.maxDays) {
+			regular++
+		}
+	}
+	if regular*4 < len(intervals)*3 {
+		return nil
+	}
+
+	total := new(big.Rat)
+	for _, charge := range similar {
+		if amount, err := parseAmount(charge.tx.Amount); err == nil {
+			total.Add(total, amount)
+		}
+	}
+	average := total.Quo(total, new(big.Rat).SetInt64(int64(len(similar))))
+
+	first, last := similar[0], similar[len(similar)-1]
+	next := cadence.shift(last.date, 1)
+
+	name := last.tx.PayeeName
+	if !last.tx.PayeeID.Valid {
+		name = strings.TrimSpace(last.tx.Description.String)
+	}
+
+	return &Subscription{
+		PayeeID:       last.tx.PayeeID.Int64,
+		Name:          name,
+		Cadence:       cadence.name,
+		Currency:      last.tx.Currency,
+		AverageAmount: formatAmount(average),
+		LastAmount:    last.tx.Amount,
+		ChargeCount:   int32(len(similar)),
+		FirstCharge:   first.date,
+		LastCharge:    last.date,
+		NextExpected:  next,
+		Active:        !today.After(next.AddDate(0, 0, cadence.graceDays)),
+		CategoryID:    last.tx.CategoryID.Int64,
+		CategoryName:  last.tx.CategoryName,
+		AccountID:     last.tx.AccountID,
+		AccountName:   last.tx.AccountName,
+	}
+}
+
+// median возвращает медиану значений, не меняя исходный срез
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
+
+// Виды отметок об аномальных тратах
+const (
+	InsightLargeTransaction = "large_transaction"
+	InsightCategoryTrend    = "category_trend"
+)
+
+const (
+	anomalyLookback       = 2 * 24 * time.Hour // Какие изменения расходов проверяются при каждом запуске
+	anomalyBaselineDays   = 180                // Глубина истории для крупных расходов
+	anomalyBaselineMonths = 6                  // Глубина истории для трат по категории за месяц
+	anomalyMinSamples     = 5                  // Минимум расходов в базовой линии
+	anomalyMinMonths      = 3                  // Минимум месяцев с тратами в базовой линии
+	anomalyMADFactor      = 3.0                // Порог — медиана плюс столько масштабированных MAD
+	anomalyMinRatio       = 1.5                // и не меньше медианы, умноженной на это число
+	madScale              = 1.4826             // MAD * madScale оценивает стандартное отклонение
+	defaultInsightLimit   = 20
+	maxInsightLimit       = 100
+)
+
+// AnalyzeSpending проверяет расходы, созданные или измененные за последние
+// двое суток, по всем пользователям. Расход отмечается как крупный, если
+// он выше порога по медиане и MAD расходов той же категории в тот же день
+// недели за полгода (если таких меньше anomalyMinSamples — по всем дням).
+// Для категорий с новыми расходами траты с начала месяца сравниваются с
+// тратами за то же число дней в предыдущих месяцах. Возвращает число новых
+// отметок.
+func (s *Service) AnalyzeSpending(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	candidates, err := s.repo.ListAnomalyCandidates(ctx, now.Add(-anomalyLookback))
+	if err != nil {
+		return 0, err
+	}
+
+	type categoryKey struct {
+		userID     int64
+		categoryID int64
+		currency   string
+	}
+	groups := make(map[categoryKey][]*repository.ExpenseSample)
+	var keys []categoryKey
+	for _, candidate := range candidates {
+		key := categoryKey{candidate.UserID, candidate.CategoryID, candidate.Currency}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], candidate)
+	}
+
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	created := 0
+	for _, key := range keys {
+		group := groups[key]
+		from := addMonths(monthStart, -anomalyBaselineMonths)
+		if earliest := startOfDay(group[0].OperationDate).AddDate(0, 0, -anomalyBaselineDays); earliest.Before(from) {
+			from = earliest
+		}
+		history, err := s.repo.ListCategoryExpenses(ctx, key.userID, key.categoryID, key.currency, from, now.Add(time.Second))
+		if err != nil {
+			return created, err
+		}
+
+		var insights []*repository.Insight
+		for _, candidate := range group {
+			if insight := largeTransactionInsight(candidate, history); insight != nil {
+				insights = append(insights, insight)
+			}
+		}
+		if insight := categoryTrendInsight(key.userID, key.categoryID, key.currency, history, now); insight != nil {
+			insights = append(insights, insight)
+		}
+
+		for _, insight := range insights {
+			id, err := s.repo.SaveInsight(ctx, insight)
+			if err != nil {
+				return created, err
+			}
+			if id == 0 {
+				continue
+			}
+			created++
+			if err := s.notifyInsight(ctx, id); err != nil {
+				s.logger.Error("failed to enqueue insight notification", zap.Int64("insight_id", id), zap.Error(err))
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// RunSpendingAnalysis запускает AnalyzeSpending сразу и затем каждые
+// interval, пока не закрыт stop.
+func (s *Service) RunSpendingAnalysis(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		created, err := s.AnalyzeSpending(context.Background())
+		if err != nil {
+			s.logger.Error("failed to analyze spending", zap.Error(err))
+		} else if created > 0 {
+			s.logger.Info("spending anomalies found", zap.Int("count", created))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Service) ListInsights(ctx context.Context, userID int64, limit int32) ([]*repository.Insight, error) {
+	if limit <= 0 {
+		limit = defaultInsightLimit
+	}
+	if limit > maxInsightLimit {
+		limit = maxInsightLimit
+	}
+	return s.repo.ListInsights(ctx, userID, limit)
+}
+
+// GetDataVersion возвращает версию данных статистики пользователя для
+// кеширования отчетов
+func (s *Service) GetDataVersion(ctx context.Context, userID int64) (string, error) {
+	return s.repo.GetDataVersion(ctx, userID)
+}
+
+// notifyInsight ставит в очередь уведомление о новой отметке
+func (s *Service) notifyInsight(ctx context.Context, insightID int64) error {
+	insight, err := s.repo.GetInsight(ctx, insightID)
+	if err != nil || insight == nil {
+		return err
+	}
+
+	notificationType := NotificationLargeExpense
+	payload := map[string]string{
+		"category": insight.CategoryName,
+		"amount":   insight.Amount,
+		"baseline": insight.Baseline,
+		"currency": insight.Currency,
+	}
+	if insight.Kind == InsightCategoryTrend {
+		notificationType = NotificationSpendingTrend
+	} else if insight.Description.Valid {
+		payload["description"] = insight.Description.String
+	}
+	return s.EnqueueNotification(ctx, insight.UserID, notificationType, payload, fmt.Sprintf("insight:%d", insight.ID))
+}
+
+// largeTransactionInsight сравнивает расход с расходами той же категории за
+// anomalyBaselineDays до дня операции.
+func largeTransactionInsight(candidate *repository.ExpenseSample, history []*repository.ExpenseSample) *repository.Insight {
+	day := startOfDay(candidate.OperationDate)
+	from := day.AddDate(0, 0, -anomalyBaselineDays)
+	weekday := day.Weekday()
+
+	var all, sameWeekday []float64
+	for _, sample := range history {
+		date := sample.OperationDate.UTC()
+		if date.Before(from) || !date.Before(day) {
+			continue
+		}
+		amount, err := parseAmount(sample.Amount)
+		if err != nil {
+			continue
+		}
+		value, _ := amount.Float64()
+		all = append(all, value)
+		if date.Weekday() == weekday {
+			sameWeekday = append(sameWeekday, value)
+		}
+	}
+
+	baseline, weekdayValue := all, sql.NullInt16{}
+	if len(sameWeekday) >= anomalyMinSamples {
+		baseline, weekdayValue = sameWeekday, sql.NullInt16{Int16: int16(weekday), Valid: true}
+	}
+	if len(baseline) < anomalyMinSamples {
+		return nil
+	}
+
+	amount, err := parseAmount(candidate.Amount)
+	if err != nil {
+		return nil
+	}
+	value, _ := amount.Float64()
+	center, threshold, ok := anomalyThreshold(baseline)
+	if !ok || value <= threshold {
+		return nil
+	}
+
+	return &repository.Insight{
+		UserID:        candidate.UserID,
+		Kind:          InsightLargeTransaction,
+		CategoryID:    candidate.CategoryID,
+		TransactionID: sql.NullInt64{Int64: candidate.TransactionID, Valid: true},
+		Weekday:       weekdayValue,
+		Amount:        candidate.Amount,
+		Baseline:      formatFloatAmount(center),
+		Threshold:     formatFloatAmount(threshold),
+		Currency:      candidate.Currency,
+	}
+}
+
+// categoryTrendInsight сравнивает траты по категории с начала текущего
+// месяца с тратами за то же число дней в каждом из anomalyBaselineMonths
+// предыдущих месяцев.
+func categoryTrendInsight(userID, categoryID int64, currency string, history []*repository.ExpenseSample, now time.Time) *repository.Insight {
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	elapsed := now.Sub(monthStart)
+
+	current := new(big.Rat)
+	totals := make([]*big.Rat, anomalyBaselineMonths)
+	for i := range totals {
+		totals[i] = new(big.Rat)
+	}
+	for _, sample := range history {
+		amount, err := parseAmount(sample.Amount)
+		if err != nil {
+			continue
+		}
+		date := sample.OperationDate.UTC()
+		if !date.Before(monthStart) {
+			current.Add(current, amount)
+			continue
+		}
+		for i := range totals {
+			start := addMonths(monthStart, -(i + 1))
+			end := start.Add(elapsed)
+			if next := addMonths(start, 1); end.After(next) {
+				end = next
+			}
+			if !date.Before(start) && date.Before(end) {
+				totals[i].Add(totals[i], amount)
+				break
+			}
+		}
+	}
+
+	var baseline []float64
+	active := 0
+	for _, total := range totals {
+		value, _ := total.Float64()
+		if value > 0 {
+			active++
+		}
+		baseline = append(baseline, value)
+	}
+	if active < anomalyMinMonths {
+		return nil
+	}
+
+	value, _ := current.Float64()
+	center, threshold, ok := anomalyThreshold(baseline)
+	if !ok || value <= threshold {
+		return nil
+	}
+
+	return &repository.Insight{
+		UserID:      userID,
+		Kind:        InsightCategoryTrend,
+		CategoryID:  categoryID,
+		PeriodStart: sql.NullTime{Time: monthStart, Valid: true},
+		Amount:      formatAmount(current),
+		Baseline:    formatFloatAmount(center),
+		Threshold:   formatFloatAmount(threshold),
+		Currency:    currency,
+	}
+}
+
+// anomalyThreshold возвращает медиану и порог аномалии: медиана плюс
+// anomalyMADFactor масштабированных MAD, но не меньше медианы, умноженной
+// на anomalyMinRatio. Без положительной медианы порог не определен.
+func anomalyThreshold(values []float64) (center, threshold float64, ok bool) {
+	center = median(values)
+	if center <= 0 {
+		return 0, 0, false
+	}
+	deviations := make([]float64, 0, len(values))
+	for _, value := range values {
+		deviations = append(deviations, math.Abs(value-center))
+	}
+	threshold = math.Max(center+anomalyMADFactor*madScale*median(deviations), center*anomalyMinRatio)
+	return center, threshold, true
+}
+
+// formatFloatAmount форматирует оценку суммы с точностью до копеек
+func formatFloatAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// Виды событий прогноза
+const (
+	ForecastSubscription = "subscription"
+	ForecastIncome       = "income"
+	ForecastLoanPayment  = "loan_payment"
+)
+
+const (
+	defaultForecastDays    = 30
+	forecastHistoryDays    = 90 // За сколько дней считаются средние траты
+	forecastMinHistoryDays = 14 // Меньший срок у новых счетов завышал бы средние
+)
+
+// CategorySpending — средние повседневные траты по категории в день
+type CategorySpending struct {
+	CategoryID   int64
+	CategoryName string
+	DailyAmount  string
+}
+
+// ForecastPoint — прогноз остатка счета на конец дня
+type ForecastPoint struct {
+	Date    time.Time
+	Balance string
+}
+
+// ForecastEvent — ожидаемое регулярное поступление или списание. Amount
+// отрицательный для списаний.
+type ForecastEvent struct {
+	Date      time.Time
+	AccountID int64
+	Kind      string
+	Name      string
+	Amount    string
+}
+
+// AccountForecast — прогноз остатка одного счета. Для кредитных счетов с
+// лимитом уходом в минус считается превышение лимита, для кредитных счетов
+// без лимита не проверяется.
+type AccountForecast struct {
+	Account           *repository.Account
+	DailySpending     string
+	Categories        []*CategorySpending
+	Points            []*ForecastPoint // От сегодняшнего дня включительно
+	MinBalance        string
+	FirstNegativeDate time.Time // Нулевая, если счет не уходит в минус
+}
+
+// Forecast — прогноз остатков всех счетов на days дней
+type Forecast struct {
+	Days          int32
+	Accounts      []*AccountForecast
+	Events        []*ForecastEvent
+	NegativeDates []time.Time // Дни, когда хотя бы один счет в минусе
+}
+
+// GetForecast прогнозирует остатки счетов на 30, 60 или 90 дней вперед.
+// В прогноз входят найденные регулярные расходы и доходы (см.
+// DetectSubscriptions), непроведенные платежи по кредитам и средние
+// повседневные траты по категориям за последние 90 дней, то есть расходы,
+// не попавшие в регулярные и не являющиеся процентами по кредиту.
+// Нерегулярные доходы и переводы не прогнозируются.
+func (s *Service) GetForecast(ctx context.Context, userID int64, days int32) (*Forecast, error) {
+	if days == 0 {
+		days = defaultForecastDays
+	}
+	if days != 30 && days != 60 && days != 90 {
+		return nil, fmt.Errorf("invalid forecast horizon")
+	}
+
+	accounts, err := s.repo.ListAccounts(ctx, userID, false)
+	if err != nil {
+		return nil, err
+	}
+	expenses, err := s.subscriptionHistory(ctx, userID, defaultSubscriptionMonths, "expense")
+	if err != nil {
+		return nil, err
+	}
+	incomes, err := s.subscriptionHistory(ctx, userID, defaultSubscriptionMonths, "income")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	today := startOfDay(now)
+	end := today.AddDate(0, 0, int(days))
+
+	forecasts := make(map[int64]*AccountForecast, len(accounts))
+	changes := make(map[int64]map[int]*big.Rat, len(accounts))
+	for _, account := range accounts {
+		forecasts[account.ID] = &AccountForecast{Account: account}
+		changes[account.ID] = make(map[int]*big.Rat)
+	}
+
+	var events []*ForecastEvent
+	addEvent := func(date time.Time, accountID int64, kind, name string, amount *big.Rat) {
+		byDay, ok := changes[accountID]
+		if !ok {
+			return
+		}
+		day := int(date.Sub(today).Hours() / 24)
+		if byDay[day] == nil {
+			byDay[day] = new(big.Rat)
+		}
+		byDay[day].Add(byDay[day], amount)
+		events = append(events, &ForecastEvent{
+			Date:      date,
+			AccountID: accountID,
+			Kind:      kind,
+			Name:      name,
+			Amount:    formatAmount(amount),
+		})
+	}
+
+	// Регулярные расходы и доходы. Просроченное, но еще ожидаемое списание
+	// ставится на завтра.
+	expenseSubscriptions := findSubscriptions(expenses, today)
+	recurring := []struct {
+		subscriptions map[string]*Subscription
+		kind          string
+		sign          int64
+	}{
+		{expenseSubscriptions, ForecastSubscription, -1},
+		{findSubscriptions(incomes, today), ForecastIncome, 1},
+	}
+	for _, group := range recurring {
+		for _, subscription := range group.subscriptions {
+			cadence := cadenceByName(subscription.Cadence)
+			amount, err := parseAmount(subscription.AverageAmount)
+			if !subscription.Active || cadence == nil || err != nil {
+				continue
+			}
+			amount.Mul(amount, new(big.Rat).SetInt64(group.sign))
+			for i := 0; ; i++ {
+				date := cadence.shift(subscription.NextExpected, i)
+				if date.After(end) {
+					break
+				}
+				if !date.After(today) {
+					date = today.AddDate(0, 0, 1)
+				}
+				addEvent(date, subscription.AccountID, group.kind, subscription.Name, amount)
+			}
+		}
+	}
+
+	// Платежи по кредитам: списание со счета платежей, основной долг
+	// уменьшает задолженность на счете кредита
+	loanInterest := make(map[int64]bool)
+	for _, account := range accounts {
+		if account.Type != AccountTypeLoan {
+			continue
+		}
+		schedule, err := s.GetLoanSchedule(ctx, userID, account.ID)
+		if err != nil {
+			if err.Error() == "loan not found" {
+				continue
+			}
+			return nil, err
+		}
+		for _, payment := range schedule.Payments {
+			if payment.InterestTransactionID > 0 {
+				loanInterest[payment.InterestTransactionID] = true
+			}
+			if payment.Posted || !payment.Date.After(today) || payment.Date.After(end) || !schedule.Loan.PaymentAccountID.Valid {
+				continue
+			}
+			name := fmt.Sprintf("%s, платеж %d", account.Name, payment.Number)
+			if amount, err := parseAmount(payment.Payment); err == nil {
+				addEvent(payment.Date, schedule.Loan.PaymentAccountID.Int64, ForecastLoanPayment, name, amount.Neg(amount))
+			}
+			if principal, err := parseAmount(payment.Principal); err == nil {
+				addEvent(payment.Date, account.ID, ForecastLoanPayment, name, principal)
+			}
+		}
+	}
+
+	// Повседневные траты: средние по категориям за forecastHistoryDays
+	historyStart := today.AddDate(0, 0, -forecastHistoryDays)
+	spending := make(map[int64]map[int64]*CategorySpending)
+	totals := make(map[int64]map[int64]*big.Rat)
+	for _, tx := range expenses {
+		if tx.OperationDate.Before(historyStart) || loanInterest[tx.ID] {
+			continue
+		}
+		if _, ok := expenseSubscriptions[subscriptionKey(tx)]; ok {
+			continue
+		}
+		amount, err := parseAmount(tx.Amount)
+		if err != nil || forecasts[tx.AccountID] == nil {
+			continue
+		}
+		if totals[tx.AccountID] == nil {
+			totals[tx.AccountID] = make(map[int64]*big.Rat)
+			spending[tx.AccountID] = make(map[int64]*CategorySpending)
+		}
+		categoryID := tx.CategoryID.Int64
+		if totals[tx.AccountID][categoryID] == nil {
+			totals[tx.AccountID][categoryID] = new(big.Rat)
+			spending[tx.AccountID][categoryID] = &CategorySpending{CategoryID: categoryID, CategoryName: tx.CategoryName}
+		}
+		totals[tx.AccountID][categoryID].Add(totals[tx.AccountID][categoryID], amount)
+	}
+
+	negativeDays := make(map[int]bool)
+	result := &Forecast{Days: days, Events: events}
+	for _, account := range accounts {
+		forecast := forecasts[account.ID]
+
+		historyDays := int64(forecastHistoryDays)
+		if age := int64(today.Sub(startOfDay(account.CreatedAt)).Hours() / 24); age < historyDays {
+			historyDays = age
+		}
+		if historyDays < forecastMinHistoryDays {
+			historyDays = forecastMinHistoryDays
+		}
+		daily := new(big.Rat)
+		for categoryID, total := range totals[account.ID] {
+			categoryDaily := new(big.Rat).Quo(total, new(big.Rat).SetInt64(historyDays))
+			daily.Add(daily, categoryDaily)
+			category := spending[account.ID][categoryID]
+			category.DailyAmount = formatAmount(categoryDaily)
+			forecast.Categories = append(forecast.Categories, category)
+		}
+		sort.Slice(forecast.Categories, func(i, j int) bool {
+			return forecast.Categories[i].CategoryName < forecast.Categories[j].CategoryName
+		})
+		forecast.DailySpending = formatAmount(daily)
+
+		var floor *big.Rat
+		if !IsCreditAccountType(account.Type) {
+			floor = new(big.Rat)
+		} else if account.CreditLimit.Valid {
+			if limit, err := parseAmount(account.CreditLimit.String); err == nil {
+				floor = limit.Neg(limit)
+			}
+		}
+
+		balance, err := parseAmount(account.Balance)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse account balance: %w", err)
+		}
+		minBalance := new(big.Rat).Set(balance)
+		for day := 0; day <= int(days); day++ {
+			if day > 0 {
+				balance.Sub(balance, daily)
+			}
+			if change := changes[account.ID][day]; change != nil {
+				balance.Add(balance, change)
+			}
+			date := today.AddDate(0, 0, day)
+			forecast.Points = append(forecast.Points, &ForecastPoint{Date: date, Balance: formatAmount(balance)})
+			if balance.Cmp(minBalance) < 0 {
+				minBalance.Set(balance)
+			}
+			if floor != nil && balance.Cmp(floor) < 0 {
+				negativeDays[day] = true
+				if forecast.FirstNegativeDate.IsZero() {
+					forecast.FirstNegativeDate = date
+				}
+			}
+		}
+		forecast.MinBalance = formatAmount(minBalance)
+		result.Accounts = append(result.Accounts, forecast)
+	}
+
+	for day := 0; day <= int(days); day++ {
+		if negativeDays[day] {
+			result.NegativeDates = append(result.NegativeDates, today.AddDate(0, 0, day))
+		}
+	}
+	sort.SliceStable(result.Events, func(i, j int) bool {
+		return result.Events[i].Date.Before(result.Events[j].Date)
+	})
+
+	return result, nil
+}
+
+// Типы уведомлений. Текст сообщения для каждого типа составляет бот по
+// шаблону из полей payload.
+const (
+	NotificationBudgetExceeded   = "budget_exceeded"
+	NotificationRecurringPayment = "recurring_payment"
+	NotificationLargeExpense     = "large_expense"
+	NotificationSpendingTrend    = "spending_trend"
+	NotificationWeeklyDigest     = "weekly_digest"
+	NotificationMonthlyDigest    = "monthly_digest"
+	NotificationDebtReminder     = "debt_reminder"
+)
+
+var notificationTypes = []string{
+	NotificationBudgetExceeded,
+	NotificationRecurringPayment,
+	NotificationLargeExpense,
+	NotificationSpendingTrend,
+	NotificationWeeklyDigest,
+	NotificationMonthlyDigest,
+	NotificationDebtReminder,
+}
+
+// Статусы уведомлений
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+	NotificationSkipped = "skipped"
+)
+
+const (
+	defaultNotificationClaim = 50
+	maxNotificationClaim     = 200
+	maxNotificationAttempts  = 8
+	notificationLease        = 5 * time.Minute  // Столько уведомление ждет ответа отправителя
+	notificationBackoff      = 30 * time.Second // Пауза после первой неудачи, дальше удваивается
+	maxNotificationBackoff   = time.Hour
+)
+
+var quietTimePattern = regexp.MustCompile(`^([01]\d|2[0-3]):[0-5]\d$`)
+
+// NotificationPreferences — настройки уведомлений. Пустые QuietFrom и
+// QuietTo отключают тихие часы.
+type NotificationPreferences struct {
+	MutedTypes []string
+	QuietFrom  string // "HH:MM" по местному времени
+	QuietTo    string
+	Timezone   string // Часовой пояс IANA, например Europe/Moscow
+}
+
+// EnqueueNotification ставит уведомление в очередь. Уведомление с тем же
+// dedupKey повторно не создается.
+func (s *Service) EnqueueNotification(ctx context.Context, userID int64, notificationType string, payload map[string]string, dedupKey string) error {
+	if !isNotificationType(notificationType) {
+		return fmt.Errorf("invalid notification type")
+	}
+	_, err := s.repo.EnqueueNotification(ctx, &repository.Notification{
+		UserID:   userID,
+		Type:     notificationType,
+		Payload:  payload,
+		DedupKey: sql.NullString{String: dedupKey, Valid: dedupKey != ""},
+	})
+	return err
+}
+
+// ClaimNotifications выдает отправителю уведомления, время которых пришло.
+// Уведомления отключенных пользователем типов помечаются пропущенными,
+// попавшие в тихие часы переносятся на их окончание. Выданное уведомление
+// не выдается повторно notificationLease; если отправитель не сообщит
+// результат, оно будет выдано снова.
+func (s *Service) ClaimNotifications(ctx context.Context, limit int32) ([]*repository.Notification, error) {
+	if limit <= 0 {
+		limit = defaultNotificationClaim
+	}
+	if limit > maxNotificationClaim {
+		limit = maxNotificationClaim
+	}
+
+	var claimed []*repository.Notification
+	err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		due, err := repo.ListDueNotifications(ctx, limit)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		preferences := make(map[int64]*repository.NotificationPreferences)
+		for _, n := range due {
+			prefs, ok := preferences[n.UserID]
+			if !ok {
+				if prefs, err = repo.GetNotificationPreferences(ctx, n.UserID); err != nil {
+					return err
+				}
+				preferences[n.UserID] = prefs
+			}
+
+			if containsString(prefs.MutedTypes, n.Type) {
+				if err := repo.FinishNotification(ctx, n.ID, NotificationSkipped, ""); err != nil {
+					return err
+				}
+				continue
+			}
+			if until, quiet := quietHoursEnd(prefs, now); quiet {
+				if err := repo.ScheduleNotification(ctx, n.ID, until, false, ""); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := repo.ScheduleNotification(ctx, n.ID, now.Add(notificationLease), true, ""); err != nil {
+				return err
+			}
+			n.Attempts++
+			claimed = append(claimed, n)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claimed, nil
+}
+
+// CompleteNotification записывает результат отправки. Неудачная отправка
+// повторяется с экспоненциальной паузой, но не раньше retryAfter (его
+// передает Telegram при превышении лимита запросов). После
+// maxNotificationAttempts попыток или при permanent уведомление помечается
+// неотправленным.
+func (s *Service) CompleteNotification(ctx context.Context, notificationID int64, delivered bool, errorText string, retryAfter time.Duration, permanent bool) error {
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		n, err := repo.GetNotification(ctx, notificationID, true)
+		if err != nil {
+			return err
+		}
+		if n == nil {
+			return fmt.Errorf("notification not found")
+		}
+		if n.Status != NotificationPending {
+			return fmt.Errorf("notification is already completed")
+		}
+
+		if delivered {
+			return repo.FinishNotification(ctx, n.ID, NotificationSent, "")
+		}
+		if errorText == "" {
+			errorText = "delivery failed"
+		}
+		// Ожидание из-за лимита Telegram не считается неудачей
+		if permanent || n.Attempts >= maxNotificationAttempts && retryAfter == 0 {
+			return repo.FinishNotification(ctx, n.ID, NotificationFailed, errorText)
+		}
+		return repo.ScheduleNotification(ctx, n.ID, time.Now().UTC().Add(notificationDelay(n.Attempts, retryAfter)), false, errorText)
+	})
+}
+
+func (s *Service) GetNotificationPreferences(ctx context.Context, userID int64) (*NotificationPreferences, error) {
+	prefs, err := s.repo.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &NotificationPreferences{
+		MutedTypes: prefs.MutedTypes,
+		QuietFrom:  prefs.QuietFrom.String,
+		QuietTo:    prefs.QuietTo.String,
+		Timezone:   prefs.Timezone,
+	}, nil
+}
+
+// UpdateNotificationPreferences заменяет настройки уведомлений целиком.
+// Пустой часовой пояс означает UTC.
+func (s *Service) UpdateNotificationPreferences(ctx context.Context, userID int64, input NotificationPreferences) (*NotificationPreferences, error) {
+	muted := []string{}
+	for _, notificationType := range input.MutedTypes {
+		if !isNotificationType(notificationType) {
+			return nil, fmt.Errorf("invalid notification type")
+		}
+		if !containsString(muted, notificationType) {
+			muted = append(muted, notificationType)
+		}
+	}
+
+	if (input.QuietFrom == "") != (input.QuietTo == "") {
+		return nil, fmt.Errorf("quiet hours require both start and end")
+	}
+	if input.QuietFrom != "" && (!quietTimePattern.MatchString(input.QuietFrom) || !quietTimePattern.MatchString(input.QuietTo)) {
+		return nil, fmt.Errorf("invalid quiet hours")
+	}
+	if input.QuietFrom != "" && input.QuietFrom == input.QuietTo {
+		return nil, fmt.Errorf("invalid quiet hours")
+	}
+
+	if input.Timezone == "" {
+		input.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(input.Timezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone")
+	}
+
+	err := s.repo.SaveNotificationPreferences(ctx, &repository.NotificationPreferences{
+		UserID:     userID,
+		MutedTypes: muted,
+		QuietFrom:  sql.NullString{String: input.QuietFrom, Valid: input.QuietFrom != ""},
+		QuietTo:    sql.NullString{String: input.QuietTo, Valid: input.QuietTo != ""},
+		Timezone:   input.Timezone,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetNotificationPreferences(ctx, userID)
+}
+
+// quietHoursEnd проверяет, попадает ли now в тихие часы пользователя, и
+// возвращает момент их окончания.
+func quietHoursEnd(prefs *repository.NotificationPreferences, now time.Time) (time.Time, bool) {
+	if !prefs.QuietFrom.Valid || !prefs.QuietTo.Valid {
+		return time.Time{}, false
+	}
+	location, err := time.LoadLocation(prefs.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	from, errFrom := time.Parse("15:04", prefs.QuietFrom.String)
+	to, errTo := time.Parse("15:04", prefs.QuietTo.String)
+	if errFrom != nil || errTo != nil {
+		return time.Time{}, false
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	start, end := from.Hour()*60+from.Minute(), to.Hour()*60+to.Minute()
+
+	var quiet bool
+	if start < end {
+		quiet = minute >= start && minute < end
+	} else {
+		quiet = minute >= start || minute < end
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	until := time.Date(local.Year(), local.Month(), local.Day(), to.Hour(), to.Minute(), 0, 0, location)
+	if !until.After(local) {
+		until = until.AddDate(0, 0, 1)
+	}
+	return until.UTC(), true
+}
+
+// notificationDelay возвращает паузу перед следующей попыткой: 30 секунд
+// после первой неудачи, дальше вдвое больше, но не больше часа и не меньше
+// retryAfter.
+func notificationDelay(attempts int32, retryAfter time.Duration) time.Duration {
+	delay := notificationBackoff
+	for i := int32(1); i < attempts && delay < maxNotificationBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxNotificationBackoff {
+		delay = maxNotificationBackoff
+	}
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+func isNotificationType(notificationType string) bool {
+	return containsString(notificationTypes, notificationType)
+}
+
+// Периодичность сводки
+const (
+	DigestWeekly  = "weekly"
+	DigestMonthly = "monthly"
+)
+
+const (
+	digestTopCategories   = 5
+	digestTopTransactions = 3
+	digestCatchUp         = 24 * time.Hour // Сводка, не отправленная за это время, пропускается
+)
+
+// DigestSettings — расписание сводки. Weekday — день недели для
+// еженедельной сводки (0 — воскресенье), ежемесячная приходит первого
+// числа. Time — местное время "HH:MM" в часовом поясе Timezone из настроек
+// уведомлений.
+type DigestSettings struct {
+	Enabled   bool
+	Frequency string
+	Weekday   int32
+	Time      string
+	Timezone  string
+}
+
+func (s *Service) GetDigestSettings(ctx context.Context, userID int64) (*DigestSettings, error) {
+	prefs, err := s.repo.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &DigestSettings{
+		Enabled:   prefs.DigestEnabled,
+		Frequency: prefs.DigestFrequency,
+		Weekday:   int32(prefs.DigestWeekday),
+		Time:      prefs.DigestTime,
+		Timezone:  prefs.Timezone,
+	}, nil
+}
+
+// UpdateDigestSettings заменяет расписание сводки. Часовой пояс меняется
+// через настройки уведомлений.
+func (s *Service) UpdateDigestSettings(ctx context.Context, userID int64, input DigestSettings) (*DigestSettings, error) {
+	if input.Frequency != DigestWeekly && input.Frequency != DigestMonthly {
+		return nil, fmt.Errorf("invalid digest frequency")
+	}
+	if input.Weekday < 0 || input.Weekday > 6 {
+		return nil, fmt.Errorf("invalid digest weekday")
+	}
+	if !quietTimePattern.MatchString(input.Time) {
+		return nil, fmt.Errorf("invalid digest time")
+	}
+
+	err := s.repo.SaveDigestSettings(ctx, &repository.NotificationPreferences{
+		UserID:          userID,
+		DigestEnabled:   input.Enabled,
+		DigestFrequency: input.Frequency,
+		DigestWeekday:   int16(input.Weekday),
+		DigestTime:      input.Time,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetDigestSettings(ctx, userID)
+}
+
+// RunDigests ставит в очередь сводки, время которых пришло, сразу и затем
+// каждые interval, пока не закрыт stop.
+func (s *Service) RunDigests(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		queued, err := s.EnqueueDueDigests(context.Background())
+		if err != nil {
+			s.logger.Error("failed to enqueue digests", zap.Error(err))
+		} else if queued > 0 {
+			s.logger.Info("digests queued", zap.Int("count", queued))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// EnqueueDueDigests составляет сводки пользователей, у которых наступило
+// время отправки, и ставит их в очередь уведомлений. Сводка за период
+// ставится один раз; если время отправки прошло больше суток назад
+// (например, сервис был остановлен), она пропускается. Возвращает число
+// поставленных сводок.
+func (s *Service) EnqueueDueDigests(ctx context.Context) (int, error) {
+	subscribers, err := s.repo.ListDigestSubscribers(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	queued := 0
+	for _, prefs := range subscribers {
+		location, err := time.LoadLocation(prefs.Timezone)
+		if err != nil {
+			location = time.UTC
+		}
+		start, end, ok := digestPeriod(prefs.DigestFrequency, int(prefs.DigestWeekday), prefs.DigestTime, now.In(location))
+		if !ok {
+			continue
+		}
+		periodDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		if prefs.DigestSentPeriod.Valid && !prefs.DigestSentPeriod.Time.Before(periodDate) {
+			continue
+		}
+
+		title, text, err := s.buildDigest(ctx, prefs.UserID, prefs.DigestFrequency, start, end)
+		if err != nil {
+			s.logger.Error("failed to build digest", zap.Int64("user_id", prefs.UserID), zap.Error(err))
+			continue
+		}
+		notificationType := NotificationWeeklyDigest
+		if prefs.DigestFrequency == DigestMonthly {
+			notificationType = NotificationMonthlyDigest
+		}
+		dedupKey := fmt.Sprintf("digest:%s:%s", prefs.DigestFrequency, periodDate.Format("2006-01-02"))
+		if err := s.EnqueueNotification(ctx, prefs.UserID, notificationType, map[string]string{
+			"title": title,
+			"text":  text,
+		}, dedupKey); err != nil {
+			return queued, err
+		}
+		if err := s.repo.MarkDigestSent(ctx, prefs.UserID, periodDate); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+
+	return queued, nil
+}
+
+// digestPeriod находит последний момент отправки сводки не позже now (now —
+// в часовом поясе пользователя) и возвращает период, за который она
+// составляется: прошедшие семь дней или прошедший календарный месяц.
+// ok = false, если момент отправки прошел больше digestCatchUp назад.
+func digestPeriod(frequency string, weekday int, sendTime string, now time.Time) (start, end time.Time, ok bool) {
+	clock, err := time.Parse("15:04", sendTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	location := now.Location()
+
+	var scheduled time.Time
+	if frequency == DigestMonthly {
+		scheduled = time.Date(now.Year(), now.Month(), 1, clock.Hour(), clock.Minute(), 0, 0, location)
+		if scheduled.After(now) {
+			scheduled = time.Date(now.Year(), now.Month()-1, 1, clock.Hour(), clock.Minute(), 0, 0, location)
+		}
+		end = time.Date(scheduled.Year(), scheduled.Month(), 1, 0, 0, 0, 0, location)
+		start = time.Date(end.Year(), end.Month()-1, 1, 0, 0, 0, 0, location)
+	} else {
+		back := (int(now.Weekday()) - weekday + 7) % 7
+		scheduled = time.Date(now.Year(), now.Month(), now.Day()-back, clock.Hour(), clock.Minute(), 0, 0, location)
+		if scheduled.After(now) {
+			scheduled = scheduled.AddDate(0, 0, -7)
+		}
+		end = time.Date(scheduled.Year(), scheduled.Month(), scheduled.Day(), 0, 0, 0, 0, location)
+		start = end.AddDate(0, 0, -7)
+	}
+
+	if now.Sub(scheduled) > digestCatchUp {
+		return time.Time{}, time.Time{}, false
+	}
+	return start, end, true
+}
+
+// digestTotals — доходы и расходы за период по валютам
+type digestTotals struct {
+	income  map[string]*big.Rat
+	expense map[string]*big.Rat
+}
+
+// buildDigest составляет заголовок и текст сводки за [start, end):
+// доходы, расходы и итог по валютам в сравнении с предыдущим таким же
+// периодом, крупнейшие категории, крупнейшие расходы и бюджеты.
+func (s *Service) buildDigest(ctx context.Context, userID int64, frequency string, start, end time.Time) (string, string, error) {
+	previousStart := start.AddDate(0, 0, -7)
+	periodName, previousName := "неделю", "прошлой неделе"
+	if frequency == DigestMonthly {
+		previousStart = time.Date(start.Year(), start.Month()-1, 1, 0, 0, 0, 0, start.Location())
+		periodName, previousName = "месяц", "прошлому месяцу"
+	}
+
+	currentTotals, err := s.digestTotals(ctx, userID, start, end)
+	if err != nil {
+		return "", "", err
+	}
+	previousTotals, err := s.digestTotals(ctx, userID, previousStart, start)
+	if err != nil {
+		return "", "", err
+	}
+
+	lastDay := end.AddDate(0, 0, -1)
+	title := fmt.Sprintf("Итоги недели %s–%s", start.Format("02.01"), lastDay.Format("02.01"))
+	if frequency == DigestMonthly {
+		title = fmt.Sprintf("Итоги месяца: %s %d", monthNames[start.Month()-1], start.Year())
+	}
+
+	currencies := digestCurrencies(currentTotals)
+	if len(currencies) == 0 {
+		return title, fmt.Sprintf("За %s операций не было", periodName), nil
+	}
+
+	var text strings.Builder
+	for _, currency := range currencies {
+		income := ratOrZero(currentTotals.income[currency])
+		expense := ratOrZero(currentTotals.expense[currency])
+		if len(currencies) > 1 {
+			fmt.Fprintf(&text, "%s\n", currency)
+		}
+		fmt.Fprintf(&text, "Доходы: %s %s%s\n", formatAmount(income), currency,
+			digestChange(income, previousTotals.income[currency], previousName))
+		fmt.Fprintf(&text, "Расходы: %s %s%s\n", formatAmount(expense), currency,
+			digestChange(expense, previousTotals.expense[currency], previousName))
+		net := new(big.Rat).Sub(income, expense)
+		sign := ""
+		if net.Sign() > 0 {
+			sign = "+"
+		}
+		fmt.Fprintf(&text, "Итого: %s%s %s\n\n", sign, formatAmount(net), currency)
+	}
+
+	categories, err := s.repo.GetTopExpenseCategories(ctx, userID, start.UTC(), end.UTC(), digestTopCategories)
+	if err != nil {
+		return "", "", err
+	}
+	if len(categories) > 0 {
+		text.WriteString("Больше всего потрачено:\n")
+		for _, category := range categories {
+			name := category.CategoryName.String
+			if name == "" {
+				name = "Без категории"
+			}
+			fmt.Fprintf(&text, "• %s — %s %s\n", name, category.Total, category.Currency)
+		}
+		text.WriteString("\n")
+	}
+
+	expenses, err := s.repo.GetTopExpenses(ctx, userID, start.UTC(), end.UTC(), digestTopTransactions)
+	if err != nil {
+		return "", "", err
+	}
+	if len(expenses) > 0 {
+		text.WriteString("Крупнейшие расходы:\n")
+		for _, tx := range expenses {
+			name := tx.PayeeName
+			if name == "" {
+				name = tx.Description.String
+			}
+			if name == "" {
+				name = tx.CategoryName
+			}
+			fmt.Fprintf(&text, "• %s %s — %s %s\n", tx.OperationDate.In(start.Location()).Format("02.01"), name, tx.Amount, tx.Currency)
+		}
+		text.WriteString("\n")
+	}
+
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		return "", "", err
+	}
+	if len(budgets) > 0 {
+		text.WriteString("Бюджеты:\n")
+		for _, budget := range budgets {
+			// Бюджет за период, в который попадает последний день сводки
+			status, err := s.budgetStatus(ctx, budget, end.Add(-time.Nanosecond))
+			if err != nil {
+				return "", "", err
+			}
+			fmt.Fprintf(&text, "• %s — %s из %s %s (%s%%)", budget.Name, status.Spent, budget.Amount, budget.Currency, status.ProgressPercent)
+			if status.Exceeded {
+				text.WriteString(" ⚠️ превышен")
+			}
+			text.WriteString("\n")
+		}
+	}
+
+	return title, strings.TrimSpace(text.String()), nil
+}
+
+// digestTotals суммирует доходы и расходы за [from, to) по валютам
+func (s *Service) digestTotals(ctx context.Context, userID int64, from, to time.Time) (digestTotals, error) {
+	totals := digestTotals{income: map[string]*big.Rat{}, expense: map[string]*big.Rat{}}
+	rows, err := s.repo.GetPeriodTotals(ctx, userID, from.UTC(), to.UTC())
+	if err != nil {
+		return totals, err
+	}
+	for _, row := range rows {
+		amount, err := parseAmount(row.Total)
+		if err != nil {
+			return totals, err
+		}
+		if row.Type == "expense" {
+			totals.expense[row.Currency] = amount
+		} else {
+			totals.income[row.Currency] = amount
+		}
+	}
+	return totals, nil
+}
+
+// digestCurrencies возвращает валюты, по которым были операции
+func digestCurrencies(totals digestTotals) []string {
+	seen := make(map[string]bool)
+	for currency := range totals.income {
+		seen[currency] = true
+	}
+	for currency := range totals.expense {
+		seen[currency] = true
+	}
+	currencies := make([]string, 0, len(seen))
+	for currency := range seen {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	return currencies
+}
+
+// digestChange описывает изменение суммы к предыдущему периоду в процентах
+func digestChange(current, previous *big.Rat, previousName string) string {
+	if previous == nil || previous.Sign() == 0 {
+		return ""
+	}
+	change := new(big.Rat).Sub(current, previous)
+	change.Quo(change, previous)
+	percent, _ := change.Float64()
+	percent = math.Round(percent * 100)
+	if percent == 0 {
+		return fmt.Sprintf(" (без изменений к %s)", previousName)
+	}
+	return fmt.Sprintf(" (%+.0f%% к %s)", percent, previousName)
+}
+
+func ratOrZero(value *big.Rat) *big.Rat {
+	if value == nil {
+		return new(big.Rat)
+	}
+	return value
+}
+
+var monthNames = []string{
+	"январь", "февраль", "март", "апрель", "май", "июнь",
+	"июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь",
+}
+
+// Периоды бюджетов
+const (
+	BudgetWeekly  = "weekly"
+	BudgetMonthly = "monthly"
+	BudgetYearly  = "yearly"
+)
+
+const maxBudgetNameLength = 100
+
+// BudgetInput — параметры бюджета. Нужна категория, получатель или оба.
+// Без валюты берутся рубли.
+type BudgetInput struct {
+	Name       string
+	CategoryID int64
+	PayeeID    int64
+	Amount     string
+	Currency   string
+	Period     string
+}
+
+// BudgetStatus — расходы по бюджету за текущий период [PeriodStart, PeriodEnd)
+type BudgetStatus struct {
+	Budget          *repository.Budget
+	PeriodStart     time.Time
+	PeriodEnd       time.Time
+	Spent           string
+	Remaining       string // Отрицательный, если бюджет превышен
+	ProgressPercent string
+	Exceeded        bool
+}
+
+func (s *Service) ListBudgets(ctx context.Context, userID int64) ([]*BudgetStatus, error) {
+	budgets, err := s.repo.ListBudgets(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	statuses := make([]*BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		status, err := s.budgetStatus(ctx, budget, now)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *Service) GetBudgetStatus(ctx context.Context, userID, budgetID int64) (*BudgetStatus, error) {
+	budget, err := s.repo.GetBudget(ctx, budgetID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if budget == nil {
+		return nil, fmt.Errorf("budget not found")
+	}
+	return s.budgetStatus(ctx, budget, time.Now())
+}
+
+func (s *Service) CreateBudget(ctx context.Context, userID int64, input BudgetInput) (*BudgetStatus, error) {
+	budget, err := buildBudget(ctx, s.repo, userID, input)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.repo.CreateBudget(ctx, budget)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetBudgetStatus(ctx, userID, id)
+}
+
+func (s *Service) UpdateBudget(ctx context.Context, userID, budgetID int64, input BudgetInput) (*BudgetStatus, error) {
+	budget, err := buildBudget(ctx, s.repo, userID, input)
+	if err != nil {
+		return nil, err
+	}
+	budget.ID = budgetID
+
+	updated, err := s.repo.UpdateBudget(ctx, budget)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, fmt.Errorf("budget not found")
+	}
+	return s.GetBudgetStatus(ctx, userID, budgetID)
+}
+
+func (s *Service) DeleteBudget(ctx context.Context, userID, budgetID int64) error {
+	deleted, err := s.repo.DeleteBudget(ctx, budgetID, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("budget not found")
+	}
+	return nil
+}
+
+// CreateBudgetFromSubscription создает бюджет по найденной подписке с
+// ключом key: ее получатель и категория, последняя сумма списания и период
+// по периодичности подписки. Квартальная подписка становится годовым
+// бюджетом на четыре списания. months — глубина поиска, как в
+// DetectSubscriptions.
+func (s *Service) CreateBudgetFromSubscription(ctx context.Context, userID int64, key string, months int32) (*BudgetStatus, error) {
+	subscriptions, err := s.DetectSubscriptions(ctx, userID, months, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscription *Subscription
+	for _, candidate := range subscriptions {
+		if candidate.Key == key {
+			subscription = candidate
+			break
+		}
+	}
+	if subscription == nil {
+		return nil, fmt.Errorf("subscription not found")
+	}
+
+	input, err := subscriptionBudget(subscription)
+	if err != nil {
+		return nil, err
+	}
+	return s.CreateBudget(ctx, userID, input)
+}
+
+// subscriptionBudget описывает бюджет на одну подписку
+func subscriptionBudget(subscription *Subscription) (BudgetInput, error) {
+	if subscription.PayeeID == 0 && subscription.CategoryID == 0 {
+		return BudgetInput{}, fmt.Errorf("subscription has no payee or category")
+	}
+
+	amount, err := parseAmount(subscription.LastAmount)
+	if err != nil {
+		return BudgetInput{}, err
+	}
+	var period string
+	switch subscription.Cadence {
+	case CadenceWeekly:
+		period = BudgetWeekly
+	case CadenceMonthly:
+		period = BudgetMonthly
+	case CadenceQuarterly:
+		period = BudgetYearly
+		amount.Mul(amount, big.NewRat(4, 1))
+	default:
+		period = BudgetYearly
+	}
+
+	return BudgetInput{
+		Name:       subscription.Name,
+		CategoryID: subscription.CategoryID,
+		PayeeID:    subscription.PayeeID,
+		Amount:     formatAmount(amount),
+		Currency:   subscription.Currency,
+		Period:     period,
+	}, nil
+}
+
+func buildBudget(ctx context.Context, repo *repository.Repository, userID int64, input BudgetInput) (*repository.Budget, error) {
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return nil, fmt.Errorf("budget name cannot be empty")
+	}
+	if utf8.RuneCountInString(name) > maxBudgetNameLength {
+		return nil, fmt.Errorf("budget name is too long")
+	}
+
+	amount, err := parseAmount(input.Amount)
+	if err != nil || amount.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid budget amount")
+	}
+	if input.Period != BudgetWeekly && input.Period != BudgetMonthly && input.Period != BudgetYearly {
+		return nil, fmt.Errorf("invalid budget period")
+	}
+	if input.CategoryID <= 0 && input.PayeeID <= 0 {
+		return nil, fmt.Errorf("budget needs a category or payee")
+	}
+
+	budget := &repository.Budget{
+		UserID:   userID,
+		Name:     name,
+		Amount:   formatAmount(amount),
+		Currency: strings.ToUpper(strings.TrimSpace(input.Currency)),
+		Period:   input.Period,
+	}
+	if budget.Currency == "" {
+		budget.Currency = "RUB"
+	}
+
+	if input.CategoryID > 0 {
+		category, err := repo.GetCategory(ctx, input.CategoryID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if category == nil || category.Type != "expense" {
+			return nil, fmt.Errorf("budget category not found")
+		}
+		budget.CategoryID = sql.NullInt64{Int64: category.ID, Valid: true}
+	}
+	if input.PayeeID > 0 {
+		payee, err := repo.GetPayee(ctx, input.PayeeID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if payee == nil {
+			return nil, fmt.Errorf("budget payee not found")
+		}
+		budget.PayeeID = sql.NullInt64{Int64: payee.ID, Valid: true}
+	}
+
+	return budget, nil
+}
+
+// budgetStatus считает расходы по бюджету за период, в который попадает now
+func (s *Service) budgetStatus(ctx context.Context, budget *repository.Budget, now time.Time) (*BudgetStatus, error) {
+	start, end := budgetPeriod(budget.Period, now)
+	spentText, err := s.repo.BudgetSpent(ctx, budget, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := parseAmount(budget.Amount)
+	if err != nil {
+		return nil, err
+	}
+	spent, err := parseAmount(spentText)
+	if err != nil {
+		return nil, err
+	}
+	progress := new(big.Rat).Quo(spent, limit)
+	progress.Mul(progress, big.NewRat(100, 1))
+
+	return &BudgetStatus{
+		Budget:          budget,
+		PeriodStart:     start,
+		PeriodEnd:       end,
+		Spent:           formatAmount(spent),
+		Remaining:       formatAmount(new(big.Rat).Sub(limit, spent)),
+		ProgressPercent: progress.FloatString(0),
+		Exceeded:        spent.Cmp(limit) > 0,
+	}, nil
+}
+
+// budgetPeriod возвращает календарную неделю (с понедельника), месяц или
+// год в UTC, в которые попадает now
+func budgetPeriod(period string, now time.Time) (start, end time.Time) {
+	day := startOfDay(now)
+	switch period {
+	case BudgetWeekly:
+		start = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	case BudgetYearly:
+		start = time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, 0)
+	}
+	start = time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// notifyExceededBudgets ставит в очередь уведомления о бюджетах
+// пользователя, превышенных в текущем периоде: о каждом бюджете — не чаще
+// раза за период. Вызывается после сохранения расхода, поэтому ошибки
+// только пишутся в лог.
+func (s *Service) notifyExceededBudgets(ctx context.Context, userID int64) {
+	statuses, err := s.ListBudgets(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to check budgets", zap.Int64("user_id", userID), zap.Error(err))
+		return
+	}
+
+	for _, st := range statuses {
+		if !st.Exceeded {
+			continue
+		}
+		payload := map[string]string{
+			"budget":   st.Budget.Name,
+			"spent":    st.Spent,
+			"limit":    st.Budget.Amount,
+			"currency": st.Budget.Currency,
+		}
+		dedupKey := fmt.Sprintf("budget:%d:%s", st.Budget.ID, st.PeriodStart.Format("2006-01-02"))
+		if err := s.EnqueueNotification(ctx, userID, NotificationBudgetExceeded, payload, dedupKey); err != nil {
+			s.logger.Error("failed to enqueue budget notification", zap.Int64("budget_id", st.Budget.ID), zap.Error(err))
+		}
+	}
+}
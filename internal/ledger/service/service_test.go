@@ -0,0 +1,844 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"math"
+	"math/big"
+	"testing"
+	"time"
+	_ "time/tzdata" // Часовые пояса для тестов без системной базы
+
+	"github.com/kiribu/financial-tracker/internal/ledger/repository"
+)
+
+// transactionEffect — проведение (sign = 1) или отмена (sign = -1) операции
+type transactionEffect struct {
+	txType    string
+	accountID int64
+	relatedID int64
+	amount    string
+	sign      int64
+}
+
+func TestCheckDebits(t *testing.T) {
+	account := func(id int64, accountType, balance, policy string) *repository.Account {
+		return &repository.Account{
+			ID:              id,
+			Name:            "Счет",
+			Currency:        "RUB",
+			Balance:         balance,
+			Type:            accountType,
+			OverdraftPolicy: policy,
+		}
+	}
+	creditCard := account(3, AccountTypeCreditCard, "-900", OverdraftForbid)
+	creditCard.CreditLimit = sql.NullString{String: "1000", Valid: true}
+
+	tests := []struct {
+		name     string
+		accounts []*repository.Account
+		// Отмена старой версии операции и проведение новой
+		effects []transactionEffect
+		// Непустой shortfall — ожидается InsufficientFundsError
+		shortfall   string
+		creditLimit bool
+		warning     string
+	}{
+		{
+			name:     "lowering an income",
+			accounts: []*repository.Account{account(1, AccountTypeCash, "500", OverdraftForbid)},
+			effects: []transactionEffect{
+				{"income", 1, 0, "1000", -1},
+				{"income", 1, 0, "300", 1},
+			},
+			shortfall: "200.00",
+		},
+		{
+			name:     "raising an income",
+			accounts: []*repository.Account{account(1, AccountTypeCash, "-50", OverdraftForbid)},
+			effects: []transactionEffect{
+				{"income", 1, 0, "300", -1},
+				{"income", 1, 0, "1000", 1},
+			},
+		},
+		{
+			name: "moving an income to another account",
+			accounts: []*repository.Account{
+				account(1, AccountTypeCash, "400", OverdraftForbid),
+				account(2, AccountTypeDebitCard, "0", OverdraftForbid),
+			},
+			effects: []transactionEffect{
+				{"income", 1, 0, "1000", -1},
+				{"income", 2, 0, "1000", 1},
+			},
+			shortfall: "600.00",
+		},
+		{
+			name: "deleting an incoming transfer",
+			accounts: []*repository.Account{
+				account(1, AccountTypeCash, "100", OverdraftForbid),
+				account(2, AccountTypeDebitCard, "0", OverdraftForbid),
+			},
+			effects: []transactionEffect{
+				{"transfer", 2, 1, "300", -1},
+			},
+			shortfall: "200.00",
+		},
+		{
+			name: "deleting an outgoing transfer",
+			accounts: []*repository.Account{
+				account(1, AccountTypeCash, "100", OverdraftForbid),
+				account(2, AccountTypeDebitCard, "500", OverdraftForbid),
+			},
+			effects: []transactionEffect{
+				{"transfer", 1, 2, "300", -1},
+			},
+		},
+		{
+			name:     "lowering an expense on a negative account",
+			accounts: []*repository.Account{account(1, AccountTypeCash, "-100", OverdraftForbid)},
+			effects: []transactionEffect{
+				{"expense", 1, 0, "500", -1},
+				{"expense", 1, 0, "200", 1},
+			},
+		},
+		{
+			name:     "warn policy",
+			accounts: []*repository.Account{account(1, AccountTypeCash, "500", OverdraftWarn)},
+			effects: []transactionEffect{
+				{"income", 1, 0, "1000", -1},
+				{"income", 1, 0, "300", 1},
+			},
+			warning: "баланс счета «Счет» стал отрицательным: -200.00 RUB",
+		},
+		{
+			name:     "allow policy",
+			accounts: []*repository.Account{account(1, AccountTypeCash, "0", OverdraftAllow)},
+			effects: []transactionEffect{
+				{"expense", 1, 0, "100", -1},
+				{"expense", 1, 0, "900", 1},
+			},
+		},
+		{
+			name:     "balance reaching exactly zero",
+			accounts: []*repository.Account{account(1, AccountTypeCash, "300", OverdraftForbid)},
+			effects: []transactionEffect{
+				{"expense", 1, 0, "100", -1},
+				{"expense", 1, 0, "400", 1},
+			},
+		},
+		{
+			name:     "credit limit exceeded",
+			accounts: []*repository.Account{creditCard},
+			effects: []transactionEffect{
+				{"expense", 3, 0, "100", -1},
+				{"expense", 3, 0, "300", 1},
+			},
+			creditLimit: true,
+		},
+		{
+			name:     "credit limit not exceeded",
+			accounts: []*repository.Account{creditCard},
+			effects: []transactionEffect{
+				{"expense", 3, 0, "100", -1},
+				{"expense", 3, 0, "200", 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accounts := make(map[int64]*repository.Account)
+			for _, account := range tt.accounts {
+				accounts[account.ID] = account
+			}
+			changes := make(map[int64]*big.Rat)
+			for _, effect := range tt.effects {
+				if err := addTransactionEffect(changes, effect.txType, effect.accountID, effect.relatedID, effect.amount, effect.sign); err != nil {
+					t.Fatalf("addTransactionEffect: %v", err)
+				}
+			}
+
+			warning, err := checkDebits(accounts, changes)
+			var insufficient *InsufficientFundsError
+			switch {
+			case tt.shortfall != "":
+				if !errors.As(err, &insufficient) {
+					t.Fatalf("err = %v, want InsufficientFundsError", err)
+				}
+				if insufficient.Shortfall != tt.shortfall || insufficient.AccountID != tt.accounts[0].ID {
+					t.Errorf("shortfall = %s on account %d, want %s on account %d",
+						insufficient.Shortfall, insufficient.AccountID, tt.shortfall, tt.accounts[0].ID)
+				}
+			case tt.creditLimit:
+				if !errors.Is(err, ErrCreditLimitExceeded) {
+					t.Fatalf("err = %v, want ErrCreditLimitExceeded", err)
+				}
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if warning != tt.warning {
+				t.Errorf("warning = %q, want %q", warning, tt.warning)
+			}
+		})
+	}
+}
+
+func TestParseFiscalReceipt(t *testing.T) {
+	const fiscal = "fn=9287440300090728&i=12345&fp=3522207165"
+
+	tests := []struct {
+		name      string
+		payload   string
+		wantErr   bool
+		operation int32
+		txType    string
+		amount    string
+		date      time.Time
+	}{
+		{
+			name:      "sale without n",
+			payload:   "t=20261016T1230&s=1234.5&" + fiscal,
+			operation: 1,
+			txType:    "expense",
+			amount:    "1234.50",
+			date:      time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "sale with seconds",
+			payload:   "t=20261016T123045&s=100&" + fiscal + "&n=1",
+			operation: 1,
+			txType:    "expense",
+			amount:    "100.00",
+			date:      time.Date(2026, 10, 16, 9, 30, 45, 0, time.UTC),
+		},
+		{
+			name:      "sale refund is income",
+			payload:   "t=20261016T1230&s=99.90&" + fiscal + "&n=2",
+			operation: 2,
+			txType:    "income",
+			amount:    "99.90",
+			date:      time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "purchase by the shop is income",
+			payload:   "t=20261016T1230&s=500.00&" + fiscal + "&n=3",
+			operation: 3,
+			txType:    "income",
+			amount:    "500.00",
+			date:      time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "purchase refund is expense",
+			payload:   "t=20261016T1230&s=500.00&" + fiscal + "&n=4",
+			operation: 4,
+			txType:    "expense",
+			amount:    "500.00",
+			date:      time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+		},
+		{
+			name:      "url with query",
+			payload:   "  https://check.example/?t=20260101T0030&s=10&" + fiscal + "  ",
+			operation: 1,
+			txType:    "expense",
+			amount:    "10.00",
+			date:      time.Date(2025, 12, 31, 21, 30, 0, 0, time.UTC),
+		},
+		{name: "unknown operation", payload: "t=20261016T1230&s=10&" + fiscal + "&n=5", wantErr: true},
+		{name: "zero amount", payload: "t=20261016T1230&s=0&" + fiscal, wantErr: true},
+		{name: "three decimals", payload: "t=20261016T1230&s=1.005&" + fiscal, wantErr: true},
+		{name: "negative amount", payload: "t=20261016T1230&s=-10&" + fiscal, wantErr: true},
+		{name: "bad date", payload: "t=2026-10-16&s=10&" + fiscal, wantErr: true},
+		{name: "short fn", payload: "t=20261016T1230&s=10&fn=123&i=1&fp=1", wantErr: true},
+		{name: "missing fp", payload: "t=20261016T1230&s=10&fn=9287440300090728&i=1", wantErr: true},
+		{name: "not a receipt", payload: "hello", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipt, err := ParseFiscalReceipt(tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", receipt)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if receipt.Operation != tt.operation || receipt.Type != tt.txType {
+				t.Errorf("operation = %d %s, want %d %s", receipt.Operation, receipt.Type, tt.operation, tt.txType)
+			}
+			if receipt.Amount != tt.amount {
+				t.Errorf("amount = %s, want %s", receipt.Amount, tt.amount)
+			}
+			if !receipt.OperationDate.Equal(tt.date) {
+				t.Errorf("date = %s, want %s", receipt.OperationDate, tt.date)
+			}
+			if receipt.FN != "9287440300090728" || receipt.FD != "12345" || receipt.FP != "3522207165" {
+				t.Errorf("fiscal data = %s/%s/%s", receipt.FN, receipt.FD, receipt.FP)
+			}
+		})
+	}
+}
+
+func TestLoanStep(t *testing.T) {
+	tests := []struct {
+		name        string
+		balance     string
+		annualRate  int64
+		months      int32
+		paymentType string
+		want        string
+	}{
+		{"annuity", "100000", 12, 12, LoanAnnuity, "8884.88"},
+		{"annuity zero rate", "1000", 0, 3, LoanAnnuity, "333.33"},
+		{"differentiated", "1200", 12, 12, LoanDifferentiated, "100.00"},
+		{"differentiated zero rate", "1000", 0, 3, LoanDifferentiated, "333.33"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, _ := parseAmount(tt.balance)
+			rate := big.NewRat(tt.annualRate, 1200)
+			if got := formatAmount(loanStep(balance, rate, tt.months, tt.paymentType)); got != tt.want {
+				t.Errorf("loanStep = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAmortize(t *testing.T) {
+	firstPayment := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		balance     string
+		annualRate  int64
+		months      int32
+		paymentType string
+		// Платежи: основной долг и проценты
+		principal []string
+		interest  []string
+	}{
+		{
+			name:        "annuity zero rate",
+			balance:     "1000",
+			months:      3,
+			paymentType: LoanAnnuity,
+			principal:   []string{"333.33", "333.33", "333.34"},
+			interest:    []string{"0.00", "0.00", "0.00"},
+		},
+		{
+			name:        "differentiated",
+			balance:     "1000",
+			annualRate:  12,
+			months:      3,
+			paymentType: LoanDifferentiated,
+			principal:   []string{"333.33", "333.33", "333.34"},
+			interest:    []string{"10.00", "6.67", "3.33"},
+		},
+		{
+			name:        "annuity",
+			balance:     "1000",
+			annualRate:  12,
+			months:      3,
+			paymentType: LoanAnnuity,
+			principal:   []string{"330.02", "333.32", "336.66"},
+			interest:    []string{"10.00", "6.70", "3.37"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			balance, _ := parseAmount(tt.balance)
+			rate := big.NewRat(tt.annualRate, 1200)
+			step := loanStep(balance, rate, tt.months, tt.paymentType)
+			payments := amortize(balance, rate, step, tt.paymentType, 1, tt.months, firstPayment)
+
+			if len(payments) != len(tt.principal) {
+				t.Fatalf("got %d payments, want %d", len(payments), len(tt.principal))
+			}
+			total := new(big.Rat)
+			for i, payment := range payments {
+				if payment.Principal != tt.principal[i] || payment.Interest != tt.interest[i] {
+					t.Errorf("payment %d = %s + %s, want %s + %s",
+						payment.Number, payment.Principal, payment.Interest, tt.principal[i], tt.interest[i])
+				}
+				principal, _ := parseAmount(payment.Principal)
+				total.Add(total, principal)
+			}
+			if last := payments[len(payments)-1]; last.Balance != "0.00" {
+				t.Errorf("balance after last payment = %s, want 0.00", last.Balance)
+			}
+			if total.Cmp(balance) != 0 {
+				t.Errorf("principal paid = %s, want %s", formatAmount(total), tt.balance)
+			}
+			if date := payments[1].Date; !date.Equal(time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("second payment date = %s, want 2026-02-28", date.Format("2006-01-02"))
+			}
+		})
+	}
+}
+
+func TestAmortizePartialTail(t *testing.T) {
+	// Досрочное погашение: остаток меньше, чем покрывают оставшиеся
+	// платежи, — график заканчивается раньше, последний платеж закрывает
+	// остаток
+	balance, _ := parseAmount("500")
+	step, _ := parseAmount("200")
+	payments := amortize(balance, new(big.Rat), step, LoanAnnuity, 4, 6, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	if len(payments) != 3 {
+		t.Fatalf("got %d payments, want 3", len(payments))
+	}
+	last := payments[2]
+	if last.Number != 6 || last.Principal != "100.00" || last.Balance != "0.00" {
+		t.Errorf("last payment = #%d %s, balance %s", last.Number, last.Principal, last.Balance)
+	}
+	if payments[0].Date.Month() != time.April {
+		t.Errorf("payment #4 date = %s, want April", payments[0].Date.Format("2006-01-02"))
+	}
+}
+
+// testCharges строит списания одного получателя: даты "YYYY-MM-DD" и
+// суммы по порядку
+func testCharges(dates []string, amounts []string) []*subscriptionCharge {
+	charges := make([]*subscriptionCharge, 0, len(dates))
+	for i, date := range dates {
+		operationDate, _ := time.Parse("2006-01-02", date)
+		amount, _ := parseAmount(amounts[i])
+		value, _ := amount.Float64()
+		tx := &repository.TransactionWithDetails{PayeeName: "Кинотеатр"}
+		tx.ID = int64(i + 1)
+		tx.PayeeID = sql.NullInt64{Int64: 7, Valid: true}
+		tx.Amount = amounts[i]
+		tx.Currency = "RUB"
+		tx.OperationDate = operationDate
+		charges = append(charges, &subscriptionCharge{tx: tx, amount: value, date: operationDate})
+	}
+	return charges
+}
+
+func TestDetectSubscription(t *testing.T) {
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		dates   []string
+		amounts []string
+		// Пустой cadence — подписка не найдена
+		cadence string
+		count   int32
+		average string
+		next    string
+		active  bool
+	}{
+		{
+			name:    "monthly",
+			dates:   []string{"2026-07-15", "2026-08-15", "2026-09-15"},
+			amounts: []string{"299", "299", "299"},
+			cadence: CadenceMonthly,
+			count:   3,
+			average: "299.00",
+			next:    "2026-10-15",
+			active:  true,
+		},
+		{
+			name:    "monthly with price change and outlier",
+			dates:   []string{"2026-06-12", "2026-07-12", "2026-07-20", "2026-08-12", "2026-09-12"},
+			amounts: []string{"300", "300", "5000", "330", "330"},
+			cadence: CadenceMonthly,
+			count:   4,
+			average: "315.00",
+			next:    "2026-10-12",
+			active:  true,
+		},
+		{
+			name:    "weekly",
+			dates:   []string{"2026-09-21", "2026-09-28", "2026-10-05", "2026-10-12"},
+			amounts: []string{"150", "150", "160", "150"},
+			cadence: CadenceWeekly,
+			count:   4,
+			average: "152.50",
+			next:    "2026-10-19",
+			active:  true,
+		},
+		{
+			name:    "quarterly",
+			dates:   []string{"2026-01-05", "2026-04-05", "2026-07-05"},
+			amounts: []string{"900", "900", "900"},
+			cadence: CadenceQuarterly,
+			count:   3,
+			average: "900.00",
+			next:    "2026-10-05",
+			active:  true,
+		},
+		{
+			name:    "yearly lapsed",
+			dates:   []string{"2023-01-10", "2024-01-10"},
+			amounts: []string{"1990", "1990"},
+			cadence: CadenceYearly,
+			count:   2,
+			average: "1990.00",
+			next:    "2025-01-10",
+			active:  false,
+		},
+		{
+			name:    "too few weekly charges",
+			dates:   []string{"2026-09-28", "2026-10-05", "2026-10-12"},
+			amounts: []string{"150", "150", "150"},
+		},
+		{
+			name:    "irregular intervals",
+			dates:   []string{"2026-05-01", "2026-05-11", "2026-06-30", "2026-07-02"},
+			amounts: []string{"100", "100", "100", "100"},
+		},
+		{
+			name:    "single charge",
+			dates:   []string{"2026-10-01"},
+			amounts: []string{"100"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			subscription := detectSubscription(testCharges(tt.dates, tt.amounts), today)
+			if tt.cadence == "" {
+				if subscription != nil {
+					t.Fatalf("expected no subscription, got %s", subscription.Cadence)
+				}
+				return
+			}
+			if subscription == nil {
+				t.Fatal("expected subscription, got nil")
+			}
+			if subscription.Cadence != tt.cadence {
+				t.Errorf("cadence = %s, want %s", subscription.Cadence, tt.cadence)
+			}
+			if subscription.ChargeCount != tt.count {
+				t.Errorf("charge count = %d, want %d", subscription.ChargeCount, tt.count)
+			}
+			if subscription.AverageAmount != tt.average {
+				t.Errorf("average = %s, want %s", subscription.AverageAmount, tt.average)
+			}
+			if next := subscription.NextExpected.Format("2006-01-02"); next != tt.next {
+				t.Errorf("next expected = %s, want %s", next, tt.next)
+			}
+			if subscription.Active != tt.active {
+				t.Errorf("active = %v, want %v", subscription.Active, tt.active)
+			}
+			if subscription.PayeeID != 7 || subscription.Name != "Кинотеатр" {
+				t.Errorf("payee = %d %q", subscription.PayeeID, subscription.Name)
+			}
+		})
+	}
+}
+
+func TestSubscriptionBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription Subscription
+		wantErr      bool
+		period       string
+		amount       string
+	}{
+		{
+			name:         "weekly",
+			subscription: Subscription{PayeeID: 1, Cadence: CadenceWeekly, LastAmount: "150.00"},
+			period:       BudgetWeekly,
+			amount:       "150.00",
+		},
+		{
+			name:         "monthly",
+			subscription: Subscription{CategoryID: 3, Cadence: CadenceMonthly, LastAmount: "299.00"},
+			period:       BudgetMonthly,
+			amount:       "299.00",
+		},
+		{
+			name:         "quarterly becomes yearly",
+			subscription: Subscription{PayeeID: 1, Cadence: CadenceQuarterly, LastAmount: "900.50"},
+			period:       BudgetYearly,
+			amount:       "3602.00",
+		},
+		{
+			name:         "yearly",
+			subscription: Subscription{PayeeID: 1, Cadence: CadenceYearly, LastAmount: "1990.00"},
+			period:       BudgetYearly,
+			amount:       "1990.00",
+		},
+		{
+			name:         "no payee or category",
+			subscription: Subscription{Cadence: CadenceMonthly, LastAmount: "100.00"},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.subscription.Name = "Подписка"
+			tt.subscription.Currency = "RUB"
+			input, err := subscriptionBudget(&tt.subscription)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", input)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if input.Period != tt.period || input.Amount != tt.amount {
+				t.Errorf("budget = %s %s, want %s %s", input.Period, input.Amount, tt.period, tt.amount)
+			}
+			if input.Name != "Подписка" || input.Currency != "RUB" ||
+				input.PayeeID != tt.subscription.PayeeID || input.CategoryID != tt.subscription.CategoryID {
+				t.Errorf("budget = %+v", input)
+			}
+		})
+	}
+}
+
+func TestAnomalyThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    []float64
+		ok        bool
+		center    float64
+		threshold float64
+	}{
+		{"identical values use min ratio", []float64{100, 100, 100}, true, 100, 150},
+		{"spread uses MAD", []float64{500, 100, 300, 200, 400}, true, 300, 300 + 3*1.4826*100},
+		{"even count", []float64{100, 200}, true, 150, 150 + 3*1.4826*50},
+		{"single outlier does not move threshold", []float64{100, 100, 10000, 100, 100}, true, 100, 150},
+		{"single value", []float64{80}, true, 80, 120},
+		{"empty", nil, false, 0, 0},
+		{"zero median", []float64{0, 0, 50}, false, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			center, threshold, ok := anomalyThreshold(tt.values)
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if math.Abs(center-tt.center) > 1e-9 || math.Abs(threshold-tt.threshold) > 1e-9 {
+				t.Errorf("got %v / %v, want %v / %v", center, threshold, tt.center, tt.threshold)
+			}
+		})
+	}
+}
+
+func TestQuietHoursEnd(t *testing.T) {
+	utc := func(value string) time.Time {
+		parsed, err := time.Parse("2006-01-02 15:04", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		timezone string
+		now      string // UTC
+		quiet    bool
+		until    string // UTC
+	}{
+		{"after midnight in Moscow", "23:00", "07:00", "Europe/Moscow", "2026-10-18 21:30", true, "2026-10-19 04:00"},
+		{"before midnight in Moscow", "23:00", "07:00", "Europe/Moscow", "2026-10-18 20:30", true, "2026-10-19 04:00"},
+		{"end is exclusive", "23:00", "07:00", "Europe/Moscow", "2026-10-19 04:00", false, ""},
+		{"just before start", "23:00", "07:00", "Europe/Moscow", "2026-10-18 19:59", false, ""},
+		{"daytime window", "13:00", "15:00", "America/New_York", "2026-10-18 18:00", true, "2026-10-18 19:00"},
+		{"outside daytime window", "13:00", "15:00", "America/New_York", "2026-10-18 16:00", false, ""},
+		{"night with DST end", "22:00", "06:00", "America/New_York", "2026-11-01 03:00", true, "2026-11-01 11:00"},
+		{"unknown timezone is UTC", "22:00", "06:00", "Mars/Olympus", "2026-10-18 23:00", true, "2026-10-19 06:00"},
+		{"disabled", "", "", "Europe/Moscow", "2026-10-18 21:30", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := &repository.NotificationPreferences{
+				QuietFrom: sql.NullString{String: tt.from, Valid: tt.from != ""},
+				QuietTo:   sql.NullString{String: tt.to, Valid: tt.to != ""},
+				Timezone:  tt.timezone,
+			}
+			until, quiet := quietHoursEnd(prefs, utc(tt.now))
+			if quiet != tt.quiet {
+				t.Fatalf("quiet = %v, want %v", quiet, tt.quiet)
+			}
+			if quiet && !until.Equal(utc(tt.until)) {
+				t.Errorf("until = %s, want %s UTC", until, tt.until)
+			}
+		})
+	}
+}
+
+func TestNotificationDelay(t *testing.T) {
+	tests := []struct {
+		attempts   int32
+		retryAfter time.Duration
+		want       time.Duration
+	}{
+		{0, 0, 30 * time.Second},
+		{1, 0, 30 * time.Second},
+		{2, 0, time.Minute},
+		{3, 0, 2 * time.Minute},
+		{7, 0, 32 * time.Minute},
+		{8, 0, time.Hour},
+		{50, 0, time.Hour},
+		{1, 90 * time.Second, 90 * time.Second},
+		{3, 10 * time.Second, 2 * time.Minute},
+		{8, 2 * time.Hour, 2 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := notificationDelay(tt.attempts, tt.retryAfter); got != tt.want {
+			t.Errorf("notificationDelay(%d, %s) = %s, want %s", tt.attempts, tt.retryAfter, got, tt.want)
+		}
+	}
+}
+
+func TestDigestPeriod(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(value string) time.Time {
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, berlin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name      string
+		frequency string
+		weekday   int
+		sendTime  string
+		now       string // Время Берлина
+		ok        bool
+		start     string
+		end       string
+		hours     float64 // Длина периода
+	}{
+		{"weekly across DST end", DigestWeekly, 1, "09:00", "2026-10-26 10:00", true, "2026-10-19 00:00", "2026-10-26 00:00", 7*24 + 1},
+		{"weekly across DST start", DigestWeekly, 1, "09:00", "2026-03-30 09:00", true, "2026-03-23 00:00", "2026-03-30 00:00", 7*24 - 1},
+		{"weekly across month end", DigestWeekly, 0, "20:00", "2026-11-01 20:30", true, "2026-10-25 00:00", "2026-11-01 00:00", 7*24 + 1},
+		{"weekly caught up next day", DigestWeekly, 1, "09:00", "2026-10-27 08:00", true, "2026-10-19 00:00", "2026-10-26 00:00", 7*24 + 1},
+		{"weekly before send time", DigestWeekly, 1, "09:00", "2026-10-26 08:59", false, "", "", 0},
+		{"monthly", DigestMonthly, 0, "09:00", "2026-11-01 09:30", true, "2026-10-01 00:00", "2026-11-01 00:00", 31*24 + 1},
+		{"monthly across year end", DigestMonthly, 0, "00:00", "2027-01-01 00:00", true, "2026-12-01 00:00", "2027-01-01 00:00", 31 * 24},
+		{"monthly February", DigestMonthly, 0, "09:00", "2026-03-01 12:00", true, "2026-02-01 00:00", "2026-03-01 00:00", 28 * 24},
+		{"monthly too late", DigestMonthly, 0, "09:00", "2026-11-02 09:01", false, "", "", 0},
+		{"invalid time", DigestWeekly, 1, "9am", "2026-10-26 10:00", false, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := digestPeriod(tt.frequency, tt.weekday, tt.sendTime, at(tt.now))
+			if ok != tt.ok {
+				t.Fatalf("ok = %v, want %v", ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if !start.Equal(at(tt.start)) || !end.Equal(at(tt.end)) {
+				t.Errorf("period = %s – %s, want %s – %s", start, end, tt.start, tt.end)
+			}
+			if hours := end.Sub(start).Hours(); hours != tt.hours {
+				t.Errorf("period length = %vh, want %vh", hours, tt.hours)
+			}
+		})
+	}
+}
+
+func TestBudgetPeriod(t *testing.T) {
+	tests := []struct {
+		period string
+		now    time.Time
+		start  string
+		end    string
+	}{
+		{BudgetWeekly, time.Date(2026, 10, 18, 23, 0, 0, 0, time.UTC), "2026-10-12", "2026-10-19"},
+		{BudgetWeekly, time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC), "2026-10-19", "2026-10-26"},
+		{BudgetWeekly, time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC), "2026-12-28", "2027-01-04"},
+		{BudgetMonthly, time.Date(2026, 10, 31, 23, 59, 0, 0, time.UTC), "2026-10-01", "2026-11-01"},
+		// Время переводится в UTC: в Москве уже 1 ноября, в UTC еще октябрь
+		{BudgetMonthly, time.Date(2026, 11, 1, 1, 0, 0, 0, time.FixedZone("MSK", 3*60*60)), "2026-10-01", "2026-11-01"},
+		{BudgetYearly, time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC), "2026-01-01", "2027-01-01"},
+	}
+
+	for _, tt := range tests {
+		start, end := budgetPeriod(tt.period, tt.now)
+		if got := start.Format("2006-01-02") + " " + end.Format("2006-01-02"); got != tt.start+" "+tt.end {
+			t.Errorf("budgetPeriod(%s, %s) = %s, want %s %s", tt.period, tt.now, got, tt.start, tt.end)
+		}
+	}
+}
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	tx := &repository.Transaction{
+		ID:            42,
+		Amount:        "1234.50",
+		OperationDate: time.Date(2026, 10, 16, 12, 30, 15, 123456000, time.UTC),
+	}
+
+	tests := []struct {
+		sort  string
+		value string
+	}{
+		{repository.SortDateDesc, "2026-10-16T12:30:15.123456Z"},
+		{repository.SortDateAsc, "2026-10-16T12:30:15.123456Z"},
+		{repository.SortAmountDesc, "1234.50"},
+		{repository.SortAmountAsc, "1234.50"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			cursor, err := decodePageToken(encodePageToken(tt.sort, tx), tt.sort)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cursor.Value != tt.value || cursor.ID != 42 {
+				t.Errorf("cursor = %+v, want %s/42", cursor, tt.value)
+			}
+		})
+	}
+}
+
+func TestDecodePageTokenRejectsTampering(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+	dateToken := encodePageToken(repository.SortDateDesc, &repository.Transaction{ID: 7, OperationDate: time.Now()})
+
+	tests := []struct {
+		name  string
+		token string
+		sort  string
+	}{
+		{"not base64", "%%%", repository.SortDateDesc},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte("date_desc|2026-10-16T12:30:15Z|1")), repository.SortDateDesc},
+		{"token for another sort", dateToken, repository.SortAmountDesc},
+		{"token for reversed sort", dateToken, repository.SortDateAsc},
+		{"missing id", encode("date_desc|2026-10-16T12:30:15Z"), repository.SortDateDesc},
+		{"non-numeric id", encode("date_desc|2026-10-16T12:30:15Z|1 OR 1=1"), repository.SortDateDesc},
+		{"amount instead of date", encode("date_desc|1500.00|7"), repository.SortDateDesc},
+		{"date instead of amount", encode("amount_desc|2026-10-16T12:30:15Z|7"), repository.SortAmountDesc},
+		{"injected amount", encode("amount_desc|1; DROP TABLE transactions|7"), repository.SortAmountDesc},
+		{"empty", "", repository.SortDateDesc},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if cursor, err := decodePageToken(tt.token, tt.sort); err == nil {
+				t.Errorf("expected error, got %+v", cursor)
+			}
+		})
+	}
+}
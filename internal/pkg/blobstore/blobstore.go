@@ -0,0 +1,38 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/kiribu/financial-tracker/internal/pkg/config"
+)
+
+// ErrNotFound — объекта с таким ключом нет в хранилище
+var ErrNotFound = errors.New("blob not found")
+
+// Store хранит содержимое файлов по ключу. Метаданные файлов хранятся
+// отдельно, в Postgres.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) ([]byte, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// New создает хранилище по настройкам: "local" — каталог на диске,
+// "s3" — S3-совместимое объектное хранилище.
+func New(cfg config.StorageConfig) (Store, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(S3Config{
+			Endpoint:  cfg.S3Endpoint,
+			Region:    cfg.S3Region,
+			Bucket:    cfg.S3Bucket,
+			AccessKey: cfg.S3AccessKey,
+			SecretKey: cfg.S3SecretKey,
+		})
+	}
+	return nil, fmt.Errorf("unknown storage backend: %s", cfg.Backend)
+}
@@ -0,0 +1,185 @@
+package chart
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+)
+
+// supersample — во сколько раз крупнее рисуется картинка перед
+// уменьшением; так края фигур и линий получаются сглаженными
+const supersample = 3
+
+var (
+	white = color.RGBA{0xFF, 0xFF, 0xFF, 0xFF}
+	grid  = color.RGBA{0xE0, 0xE0, 0xE0, 0xFF}
+	axis  = color.RGBA{0x75, 0x75, 0x75, 0xFF}
+	ink   = color.RGBA{0x42, 0x42, 0x42, 0xFF}
+)
+
+// canvas — картинка в supersample раз крупнее итоговой. Координаты всех
+// методов — в пикселях итоговой картинки.
+type canvas struct {
+	img *image.RGBA
+}
+
+func newCanvas() *canvas {
+	img := image.NewRGBA(image.Rect(0, 0, Width*supersample, Height*supersample))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	return &canvas{img: img}
+}
+
+// fillFunc закрашивает пиксели прямоугольника, для которых fn возвращает
+// цвет. fn получает координаты центра пикселя.
+func (c *canvas) fillFunc(x0, y0, x1, y1 float64, fn func(x, y float64) (color.RGBA, bool)) {
+	bounds := c.img.Bounds()
+	r := image.Rect(
+		int(math.Floor(x0*supersample)), int(math.Floor(y0*supersample)),
+		int(math.Ceil(x1*supersample)), int(math.Ceil(y1*supersample)),
+	).Intersect(bounds)
+	for py := r.Min.Y; py < r.Max.Y; py++ {
+		for px := r.Min.X; px < r.Max.X; px++ {
+			x := (float64(px) + 0.5) / supersample
+			y := (float64(py) + 0.5) / supersample
+			if col, ok := fn(x, y); ok {
+				c.img.SetRGBA(px, py, col)
+			}
+		}
+	}
+}
+
+func (c *canvas) fillRect(x0, y0, x1, y1 float64, col color.RGBA) {
+	c.fillFunc(x0, y0, x1, y1, func(x, y float64) (color.RGBA, bool) {
+		return col, true
+	})
+}
+
+// line рисует отрезок толщиной width со скругленными концами
+func (c *canvas) line(x0, y0, x1, y1, width float64, col color.RGBA) {
+	half := width / 2
+	dx, dy := x1-x0, y1-y0
+	length := dx*dx + dy*dy
+	c.fillFunc(math.Min(x0, x1)-half, math.Min(y0, y1)-half, math.Max(x0, x1)+half, math.Max(y0, y1)+half,
+		func(x, y float64) (color.RGBA, bool) {
+			t := 0.0
+			if length > 0 {
+				t = math.Max(0, math.Min(1, ((x-x0)*dx+(y-y0)*dy)/length))
+			}
+			px, py := x0+t*dx-x, y0+t*dy-y
+			return col, px*px+py*py <= half*half
+		})
+}
+
+func (c *canvas) dot(x, y, radius float64, col color.RGBA) {
+	c.line(x, y, x, y, radius*2, col)
+}
+
+// Выравнивание подписи относительно точки привязки
+const (
+	alignLeft = iota
+	alignCenter
+	alignRight
+)
+
+// Подписи рисуются растровым шрифтом 3×5, увеличенным в fontScale раз
+const (
+	fontScale  = 2
+	glyphWidth = 3
+	textHeight = 5 * fontScale
+)
+
+// glyphs — строки глифов сверху вниз, старший из трех битов — левый
+// столбец. Шрифт нужен только для чисел и дат на осях, поэтому других
+// символов в нем нет; они рисуются пробелом.
+var glyphs = map[rune][5]uint8{
+	'0': {7, 5, 5, 5, 7},
+	'1': {2, 6, 2, 2, 7},
+	'2': {7, 1, 7, 4, 7},
+	'3': {7, 1, 7, 1, 7},
+	'4': {5, 5, 7, 1, 1},
+	'5': {7, 4, 7, 1, 7},
+	'6': {7, 4, 7, 5, 7},
+	'7': {7, 1, 1, 1, 1},
+	'8': {7, 5, 7, 5, 7},
+	'9': {7, 5, 7, 1, 7},
+	'.': {0, 0, 0, 0, 2},
+	',': {0, 0, 0, 2, 4},
+	'-': {0, 0, 7, 0, 0},
+	'+': {0, 2, 7, 2, 0},
+	':': {0, 2, 0, 2, 0},
+	'/': {1, 1, 2, 4, 4},
+	'k': {4, 5, 6, 5, 5},
+	'M': {5, 7, 7, 5, 5},
+}
+
+// textWidth возвращает ширину подписи из n символов
+func textWidth(n int) int {
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * fontScale
+}
+
+// text рисует подпись; y — верхний край
+func (c *canvas) text(x, y float64, s string, align int) {
+	runes := []rune(s)
+	width := float64(textWidth(len(runes)))
+	switch align {
+	case alignCenter:
+		x -= width / 2
+	case alignRight:
+		x -= width
+	}
+
+	for _, r := range runes {
+		glyph := glyphs[r]
+		for row, bits := range glyph {
+			for col := 0; col < glyphWidth; col++ {
+				if bits&(1<<(glyphWidth-1-col)) == 0 {
+					continue
+				}
+				px := x + float64(col*fontScale)
+				py := y + float64(row*fontScale)
+				c.fillRect(px, py, px+fontScale, py+fontScale, ink)
+			}
+		}
+		x += float64((glyphWidth + 1) * fontScale)
+	}
+}
+
+// encode уменьшает картинку до итогового размера, усредняя цвета, и
+// кодирует ее в PNG
+func (c *canvas) encode() ([]byte, error) {
+	out := image.NewRGBA(image.Rect(0, 0, Width, Height))
+	const samples = supersample * supersample
+	for y := 0; y < Height; y++ {
+		for x := 0; x < Width; x++ {
+			var r, g, b int
+			for sy := 0; sy < supersample; sy++ {
+				i := c.img.PixOffset(x*supersample, y*supersample+sy)
+				for sx := 0; sx < supersample; sx++ {
+					r += int(c.img.Pix[i])
+					g += int(c.img.Pix[i+1])
+					b += int(c.img.Pix[i+2])
+					i += 4
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{uint8(r / samples), uint8(g / samples), uint8(b / samples), 0xFF})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func formatFloat(value float64, precision int) string {
+	return strconv.FormatFloat(value, 'f', precision, 64)
+}
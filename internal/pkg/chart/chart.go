@@ -0,0 +1,263 @@
+package chart
+
+import (
+	"errors"
+	"image/color"
+	"math"
+)
+
+// Размер картинки в пикселях
+const (
+	Width  = 800
+	Height = 480
+)
+
+// ErrNoData — нечего рисовать: нет значений или все они нулевые
+var ErrNoData = errors.New("no data to chart")
+
+var (
+	Red    = color.RGBA{0xE5, 0x39, 0x35, 0xFF}
+	Orange = color.RGBA{0xFB, 0x8C, 0x00, 0xFF}
+	Yellow = color.RGBA{0xFD, 0xD8, 0x35, 0xFF}
+	Green  = color.RGBA{0x43, 0xA0, 0x47, 0xFF}
+	Blue   = color.RGBA{0x1E, 0x88, 0xE5, 0xFF}
+	Purple = color.RGBA{0x8E, 0x24, 0xAA, 0xFF}
+	Brown  = color.RGBA{0x6D, 0x4C, 0x41, 0xFF}
+)
+
+// Palette — цвета секторов по порядку. Они совпадают с цветными
+// квадратами эмодзи 🟥🟧🟨🟩🟦🟪🟫, так что легенду можно написать
+// обычным текстом в подписи к картинке.
+var Palette = []color.RGBA{Red, Orange, Yellow, Green, Blue, Purple, Brown}
+
+// Series — ряд значений одного цвета для столбчатой или линейной диаграммы
+type Series struct {
+	Values []float64
+	Color  color.RGBA
+}
+
+const (
+	marginLeft   = 72
+	marginRight  = 24
+	marginTop    = 24
+	marginBottom = 40
+	yTicks       = 4
+)
+
+// Pie рисует круговую диаграмму: сектор i занимает долю values[i] от суммы
+// и окрашен в colors[i]. Отрицательные значения не рисуются.
+func Pie(values []float64, colors []color.RGBA) ([]byte, error) {
+	var total float64
+	for _, value := range values {
+		if value > 0 {
+			total += value
+		}
+	}
+	if total == 0 || len(colors) < len(values) {
+		return nil, ErrNoData
+	}
+
+	c := newCanvas()
+	cx, cy := float64(Width)/2, float64(Height)/2
+	radius := math.Min(cx, cy) - marginTop
+
+	// Границы секторов по часовой стрелке от "12 часов"
+	bounds := make([]float64, len(values))
+	var sum float64
+	for i, value := range values {
+		if value > 0 {
+			sum += value
+		}
+		bounds[i] = sum / total * 2 * math.Pi
+	}
+	c.fillFunc(cx-radius, cy-radius, cx+radius, cy+radius, func(x, y float64) (color.RGBA, bool) {
+		dx, dy := x-cx, y-cy
+		if dx*dx+dy*dy > radius*radius {
+			return color.RGBA{}, false
+		}
+		angle := math.Atan2(dx, -dy)
+		if angle < 0 {
+			angle += 2 * math.Pi
+		}
+		for i, bound := range bounds {
+			if angle <= bound && values[i] > 0 {
+				return colors[i], true
+			}
+		}
+		return colors[len(colors)-1], true
+	})
+
+	// Белые линии между секторами
+	drawn := 0
+	for _, value := range values {
+		if value > 0 {
+			drawn++
+		}
+	}
+	if drawn > 1 {
+		for i, bound := range bounds {
+			if values[i] <= 0 {
+				continue
+			}
+			c.line(cx, cy, cx+radius*math.Sin(bound), cy-radius*math.Cos(bound), 3, white)
+		}
+	}
+
+	return c.encode()
+}
+
+// Bar рисует столбчатую диаграмму: по группе столбцов на каждую подпись,
+// в группе — по столбцу на ряд.
+func Bar(labels []string, series []Series) ([]byte, error) {
+	min, max, ok := seriesRange(labels, series)
+	if !ok {
+		return nil, ErrNoData
+	}
+
+	c := newCanvas()
+	scale := c.axes(labels, min, max)
+
+	group := plotWidth() / float64(len(labels))
+	bar := group * 0.8 / float64(len(series))
+	zero := scale(0)
+	for i := range labels {
+		x := marginLeft + group*float64(i) + group*0.1
+		for _, s := range series {
+			if i < len(s.Values) && s.Values[i] != 0 {
+				y := scale(s.Values[i])
+				c.fillRect(x, math.Min(y, zero), x+bar, math.Max(y, zero), s.Color)
+			}
+			x += bar
+		}
+	}
+
+	return c.encode()
+}
+
+// Line рисует линейную диаграмму: по линии на ряд, точки — в центрах
+// интервалов подписей.
+func Line(labels []string, series []Series) ([]byte, error) {
+	min, max, ok := seriesRange(labels, series)
+	if !ok {
+		return nil, ErrNoData
+	}
+
+	c := newCanvas()
+	scale := c.axes(labels, min, max)
+
+	group := plotWidth() / float64(len(labels))
+	for _, s := range series {
+		for i := range labels {
+			if i >= len(s.Values) {
+				break
+			}
+			x := marginLeft + group*(float64(i)+0.5)
+			y := scale(s.Values[i])
+			if i > 0 {
+				c.line(x-group, scale(s.Values[i-1]), x, y, 3, s.Color)
+			}
+			if len(labels) <= 31 {
+				c.dot(x, y, 4, s.Color)
+			}
+		}
+	}
+
+	return c.encode()
+}
+
+// seriesRange возвращает границы значений рядов, всегда включающие ноль
+func seriesRange(labels []string, series []Series) (min, max float64, ok bool) {
+	if len(labels) == 0 {
+		return 0, 0, false
+	}
+	for _, s := range series {
+		for i, value := range s.Values {
+			if i >= len(labels) {
+				break
+			}
+			min = math.Min(min, value)
+			max = math.Max(max, value)
+		}
+	}
+	return min, max, min != 0 || max != 0
+}
+
+func plotWidth() float64 {
+	return Width - marginLeft - marginRight
+}
+
+// axes рисует сетку с подписями значений и подписи интервалов и
+// возвращает функцию перевода значения в координату y.
+func (c *canvas) axes(labels []string, min, max float64) func(float64) float64 {
+	step := niceStep((max - min) / yTicks)
+	low := math.Floor(min/step) * step
+	high := math.Ceil(max/step) * step
+
+	top, bottom := float64(marginTop), float64(Height-marginBottom)
+	scale := func(value float64) float64 {
+		return bottom - (value-low)/(high-low)*(bottom-top)
+	}
+
+	for i := 0; low+float64(i)*step <= high+step/2; i++ {
+		value := low + float64(i)*step
+		y := scale(value)
+		lineColor := grid
+		if math.Abs(value) < step/2 {
+			lineColor = axis
+		}
+		c.line(marginLeft, y, Width-marginRight, y, 1, lineColor)
+		c.text(marginLeft-8, y-textHeight/2, formatTick(value), alignRight)
+	}
+
+	// Подписи интервалов без наложения: каждая n-я
+	group := plotWidth() / float64(len(labels))
+	widest := 0
+	for _, label := range labels {
+		if len(label) > widest {
+			widest = len(label)
+		}
+	}
+	every := int(math.Ceil(float64(textWidth(widest)+12) / group))
+	if every < 1 {
+		every = 1
+	}
+	for i := 0; i < len(labels); i += every {
+		x := marginLeft + group*(float64(i)+0.5)
+		c.text(x, bottom+12, labels[i], alignCenter)
+	}
+
+	return scale
+}
+
+// niceStep округляет шаг сетки до 1, 2 или 5, умноженных на степень десяти
+func niceStep(raw float64) float64 {
+	if raw <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, factor := range []float64{1, 2, 5, 10} {
+		if raw <= factor*magnitude {
+			return factor * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+// formatTick сокращает значение для подписи оси: 1500 → "1.5k"
+func formatTick(value float64) string {
+	abs := math.Abs(value)
+	switch {
+	case abs >= 1e6:
+		return trimZero(value/1e6) + "M"
+	case abs >= 1e3:
+		return trimZero(value/1e3) + "k"
+	}
+	return trimZero(value)
+}
+
+func trimZero(value float64) string {
+	if value == math.Trunc(value) {
+		return formatFloat(value, 0)
+	}
+	return formatFloat(value, 1)
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -31,11 +32,19 @@ func (c PostgresConfig) DSN() string {
 type BotConfig struct {
 	Token      string `env:"BOT_TOKEN" env-required:"true"`
 	GatewayURL string `env:"GATEWAY_URL" env-default:"http://localhost:8080"`
+	// Общий с gateway секрет для служебных запросов бота
+	APISecret string `env:"BOT_API_SECRET"`
+
+	// Как часто проверять очередь уведомлений (0 — не отправлять)
+	NotificationInterval time.Duration `env:"NOTIFICATION_INTERVAL" env-default:"10s"`
 }
 
 type GatewayConfig struct {
 	HTTPPort string `env:"HTTP_PORT" env-default:"8080"`
 	Services ServicesConfig
+	// Секрет, которым бот подписывает служебные запросы (очередь
+	// уведомлений). Пустой секрет закрывает служебные эндпоинты.
+	BotSecret string `env:"BOT_API_SECRET"`
 }
 
 type ServicesConfig struct {
@@ -48,6 +57,36 @@ type ServiceConfig struct {
 	GRPCPort string `env:"GRPC_PORT" env-default:"50051"`
 }
 
+// LedgerConfig — настройки ledger-сервиса: к общим добавляется хранилище
+// вложений
+type LedgerConfig struct {
+	ServiceConfig
+	Storage StorageConfig
+
+	// Как часто искать аномальные траты (0 — не искать)
+	AnalysisInterval time.Duration `env:"ANALYSIS_INTERVAL" env-default:"1h"`
+	// Как часто проверять, не пора ли составить сводки (0 — не составлять)
+	DigestInterval time.Duration `env:"DIGEST_INTERVAL" env-default:"5m"`
+	// Напоминания о долгах: как часто проверять (0 — не напоминать) и за
+	// сколько дней до срока
+	ReminderInterval time.Duration `env:"REMINDER_INTERVAL" env-default:"1h"`
+	DebtReminderDays int           `env:"DEBT_REMINDER_DAYS" env-default:"3"`
+}
+
+// StorageConfig — хранилище вложений и лимиты на них
+type StorageConfig struct {
+	Backend     string `env:"STORAGE_BACKEND" env-default:"local"` // local или s3
+	LocalDir    string `env:"STORAGE_LOCAL_DIR" env-default:"./data/attachments"`
+	S3Endpoint  string `env:"S3_ENDPOINT"`
+	S3Region    string `env:"S3_REGION" env-default:"us-east-1"`
+	S3Bucket    string `env:"S3_BUCKET"`
+	S3AccessKey string `env:"S3_ACCESS_KEY"`
+	S3SecretKey string `env:"S3_SECRET_KEY"`
+
+	MaxFileSize int64 `env:"ATTACHMENT_MAX_SIZE" env-default:"10485760"`   // 10 МБ
+	UserQuota   int64 `env:"ATTACHMENT_USER_QUOTA" env-default:"104857600"` // 100 МБ
+}
+
 
 func LoadConfig(cfg interface{}) error {
 	if err := cleanenv.ReadEnv(cfg); err != nil {
@@ -61,6 +61,26 @@ func (h *Handler) GetUserByTelegramId(ctx context.Context, req *pb.GetUserByTele
 	}, nil
 }
 
+func (h *Handler) GetUserById(ctx context.Context, req *pb.GetUserByIdRequest) (*pb.UserResponse, error) {
+	user, err := h.service.GetUserByID(ctx, req.UserId)
+	if err != nil {
+		h.logger.Error("failed to get user by id", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get user: %v", err)
+	}
+	if user == nil {
+		return nil, status.Errorf(codes.NotFound, "user not found")
+	}
+
+	return &pb.UserResponse{
+		UserId:     user.ID,
+		TelegramId: user.TelegramID,
+		Username:   getStringValue(user.Username),
+		FirstName:  user.FirstName,
+		LastName:   getStringValue(user.LastName),
+		CreatedAt:  user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
 func getStringValue(ns sql.NullString) string {
 	if ns.Valid {
 		return ns.String
@@ -3,8 +3,10 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.uber.org/zap"
 )
@@ -118,3 +120,30 @@ func (r *Repository) GetUserByTelegramID(ctx context.Context, telegramID int64)
 	return &user, nil
 }
 
+func (r *Repository) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	var user User
+
+	query := `
+		SELECT id, telegram_id, username, first_name, last_name, created_at
+		FROM users
+		WHERE id = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, userID).Scan(
+		&user.ID,
+		&user.TelegramID,
+		&user.Username,
+		&user.FirstName,
+		&user.LastName,
+		&user.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("failed to get user by id", zap.Error(err))
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -37,3 +37,12 @@ func (s *Service) GetUserByTelegramID(ctx context.Context, telegramID int64) (*r
 	}
 	return user, nil
 }
+
+func (s *Service) GetUserByID(ctx context.Context, userID int64) (*repository.User, error) {
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		s.logger.Error("failed to get user by id", zap.Error(err))
+		return nil, fmt.Errorf("failed to get user by id: %w", err)
+	}
+	return user, nil
+}
@@ -0,0 +1,5 @@
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS due_day,
+    DROP COLUMN IF EXISTS statement_day,
+    DROP COLUMN IF EXISTS credit_limit,
+    DROP COLUMN IF EXISTS type;
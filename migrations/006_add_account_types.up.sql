@@ -0,0 +1,7 @@
+-- Ledger Service: account types and credit parameters
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT 'cash'
+        CHECK (type IN ('cash', 'debit_card', 'credit_card', 'savings', 'deposit', 'loan', 'investment')),
+    ADD COLUMN IF NOT EXISTS credit_limit NUMERIC(15, 2) CHECK (credit_limit >= 0),
+    ADD COLUMN IF NOT EXISTS statement_day SMALLINT CHECK (statement_day BETWEEN 1 AND 31),
+    ADD COLUMN IF NOT EXISTS due_day SMALLINT CHECK (due_day BETWEEN 1 AND 31);
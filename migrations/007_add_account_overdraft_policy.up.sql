@@ -0,0 +1,12 @@
+-- Ledger Service: overdraft policy for accounts
+-- allow  — баланс может уходить в минус
+-- warn   — операция проводится, но клиент получает предупреждение
+-- forbid — операция, уводящая баланс в минус, отклоняется
+-- Существующие счета могли уже уйти в минус, поэтому получают warn;
+-- новые счета по умолчанию создаются с forbid.
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS overdraft_policy TEXT NOT NULL DEFAULT 'warn'
+        CHECK (overdraft_policy IN ('allow', 'warn', 'forbid'));
+
+ALTER TABLE accounts
+    ALTER COLUMN overdraft_policy SET DEFAULT 'forbid';
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_accounts_user_id_sort_order;
+
+ALTER TABLE accounts
+    DROP COLUMN IF EXISTS exclude_from_totals,
+    DROP COLUMN IF EXISTS color,
+    DROP COLUMN IF EXISTS icon,
+    DROP COLUMN IF EXISTS group_name,
+    DROP COLUMN IF EXISTS sort_order;
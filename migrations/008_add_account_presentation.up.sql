@@ -0,0 +1,18 @@
+-- Ledger Service: account ordering, grouping and appearance
+ALTER TABLE accounts
+    ADD COLUMN IF NOT EXISTS sort_order INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS group_name TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS icon TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS color TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS exclude_from_totals BOOLEAN NOT NULL DEFAULT false;
+
+-- Сохраняем текущий порядок: основной счет первым, затем от новых к старым
+UPDATE accounts a
+SET sort_order = ordered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY is_default DESC, created_at DESC) - 1 AS position
+    FROM accounts
+) ordered
+WHERE a.id = ordered.id;
+
+CREATE INDEX IF NOT EXISTS idx_accounts_user_id_sort_order ON accounts(user_id, sort_order);
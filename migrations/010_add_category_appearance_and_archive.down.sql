@@ -0,0 +1,4 @@
+ALTER TABLE categories
+    DROP COLUMN IF EXISTS is_archived,
+    DROP COLUMN IF EXISTS color,
+    DROP COLUMN IF EXISTS icon;
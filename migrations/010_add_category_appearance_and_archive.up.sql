@@ -0,0 +1,5 @@
+-- Ledger Service: category appearance and archiving
+ALTER TABLE categories
+    ADD COLUMN IF NOT EXISTS icon TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS color TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS is_archived BOOLEAN NOT NULL DEFAULT false;
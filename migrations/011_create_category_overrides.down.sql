@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS category_fallbacks;
+DROP TABLE IF EXISTS category_overrides;
@@ -0,0 +1,20 @@
+-- Ledger Service: per-user category overrides and fallback categories
+CREATE TABLE IF NOT EXISTS category_overrides (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    name TEXT,
+    icon TEXT,
+    color TEXT,
+    is_hidden BOOLEAN NOT NULL DEFAULT false,
+    sort_order INTEGER,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, category_id)
+);
+
+-- Категория, в которую попадают операции без категории и операции удаленных категорий
+CREATE TABLE IF NOT EXISTS category_fallbacks (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL CHECK (type IN ('expense', 'income')),
+    category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, type)
+);
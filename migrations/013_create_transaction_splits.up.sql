@@ -0,0 +1,12 @@
+-- Ledger Service: transaction split lines
+CREATE TABLE IF NOT EXISTS transaction_splits (
+    id BIGSERIAL PRIMARY KEY,
+    transaction_id BIGINT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    category_id BIGINT REFERENCES categories(id) ON DELETE SET NULL,
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount > 0),
+    note TEXT,
+    position INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_transaction_id ON transaction_splits(transaction_id);
+CREATE INDEX IF NOT EXISTS idx_transaction_splits_category_id ON transaction_splits(category_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_transactions_user_id_amount;
+DROP INDEX IF EXISTS idx_transactions_search_vector;
+ALTER TABLE transactions DROP COLUMN IF EXISTS search_vector;
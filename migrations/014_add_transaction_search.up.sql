@@ -0,0 +1,7 @@
+-- Ledger Service: full-text search over transaction descriptions
+ALTER TABLE transactions
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+        GENERATED ALWAYS AS (to_tsvector('russian', COALESCE(description, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_search_vector ON transactions USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_transactions_user_id_amount ON transactions(user_id, amount);
@@ -0,0 +1,5 @@
+-- Ledger Service: track when a transaction was last edited
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP;
+UPDATE transactions SET updated_at = created_at WHERE updated_at IS NULL;
+ALTER TABLE transactions ALTER COLUMN updated_at SET DEFAULT NOW();
+ALTER TABLE transactions ALTER COLUMN updated_at SET NOT NULL;
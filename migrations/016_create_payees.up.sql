@@ -0,0 +1,25 @@
+-- Ledger Service: payees with aliases and default category/account
+CREATE TABLE IF NOT EXISTS payees (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    normalized_name TEXT NOT NULL,
+    default_category_id BIGINT REFERENCES categories(id) ON DELETE SET NULL,
+    default_account_id BIGINT REFERENCES accounts(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, normalized_name)
+);
+
+CREATE TABLE IF NOT EXISTS payee_aliases (
+    payee_id BIGINT NOT NULL REFERENCES payees(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    alias TEXT NOT NULL,
+    normalized_alias TEXT NOT NULL,
+    PRIMARY KEY (payee_id, normalized_alias),
+    UNIQUE (user_id, normalized_alias)
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS payee_id BIGINT REFERENCES payees(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_transactions_payee_id ON transactions(payee_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_rules_user_id_position;
+DROP TABLE IF EXISTS rules;
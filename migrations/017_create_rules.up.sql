@@ -0,0 +1,26 @@
+-- Ledger Service: per-user categorization rules
+CREATE TABLE IF NOT EXISTS rules (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    stop_processing BOOLEAN NOT NULL DEFAULT false,
+    -- Условия: NULL не проверяется
+    transaction_type TEXT CHECK (transaction_type IN ('expense', 'income')),
+    description_contains TEXT,
+    description_regex TEXT,
+    min_amount NUMERIC(15, 2),
+    max_amount NUMERIC(15, 2),
+    account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE,
+    payee_id BIGINT REFERENCES payees(id) ON DELETE CASCADE,
+    -- Действия
+    set_category_id BIGINT REFERENCES categories(id) ON DELETE SET NULL,
+    add_tags TEXT[] NOT NULL DEFAULT '{}',
+    set_payee_id BIGINT REFERENCES payees(id) ON DELETE SET NULL,
+    set_description TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rules_user_id_position ON rules(user_id, position);
@@ -0,0 +1,2 @@
+-- Ledger Service: files attached to transactions (receipts, warranties)
+DROP TABLE IF EXISTS attachments;
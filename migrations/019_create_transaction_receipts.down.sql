@@ -0,0 +1,2 @@
+-- Ledger Service: fiscal receipt identifiers for transactions created from receipt QR codes
+DROP TABLE IF EXISTS transaction_receipts;
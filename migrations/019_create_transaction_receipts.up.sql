@@ -0,0 +1,11 @@
+-- Ledger Service: fiscal receipt identifiers for transactions created from receipt QR codes
+CREATE TABLE IF NOT EXISTS transaction_receipts (
+    transaction_id BIGINT PRIMARY KEY REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    fn TEXT NOT NULL,
+    fd TEXT NOT NULL,
+    fp TEXT NOT NULL,
+    operation SMALLINT NOT NULL CHECK (operation BETWEEN 1 AND 4),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, fn, fd, fp)
+);
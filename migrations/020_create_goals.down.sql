@@ -0,0 +1,3 @@
+-- Ledger Service: savings goals and manual contributions
+DROP TABLE IF EXISTS goal_contributions;
+DROP TABLE IF EXISTS goals;
@@ -0,0 +1,27 @@
+-- Ledger Service: savings goals and manual contributions
+CREATE TABLE IF NOT EXISTS goals (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    target_amount NUMERIC(15, 2) NOT NULL CHECK (target_amount > 0),
+    currency TEXT NOT NULL,
+    deadline DATE NOT NULL,
+    account_id BIGINT REFERENCES accounts(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goals_user_id ON goals(user_id);
+
+-- Ручные взносы; переводы на привязанный счет считаются взносами без записи здесь
+CREATE TABLE IF NOT EXISTS goal_contributions (
+    id BIGSERIAL PRIMARY KEY,
+    goal_id BIGINT NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount <> 0),
+    contribution_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_contributions_goal_id ON goal_contributions(goal_id);
@@ -0,0 +1,4 @@
+-- Ledger Service: debts between the user and other people
+DROP TABLE IF EXISTS debt_repayments;
+DROP TABLE IF EXISTS debts;
+DROP TABLE IF EXISTS counterparties;
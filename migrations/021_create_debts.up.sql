@@ -0,0 +1,46 @@
+-- Ledger Service: debts between the user and other people
+CREATE TABLE IF NOT EXISTS counterparties (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    normalized_name TEXT NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, normalized_name)
+);
+
+-- lent — пользователь дал в долг, borrowed — взял в долг
+CREATE TABLE IF NOT EXISTS debts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    counterparty_id BIGINT NOT NULL REFERENCES counterparties(id) ON DELETE RESTRICT,
+    direction TEXT NOT NULL CHECK (direction IN ('lent', 'borrowed')),
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    description TEXT,
+    due_date DATE,
+    transaction_id BIGINT REFERENCES transactions(id) ON DELETE SET NULL,
+    reminded_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_debts_user_id ON debts(user_id);
+CREATE INDEX IF NOT EXISTS idx_debts_counterparty_id ON debts(counterparty_id);
+CREATE INDEX IF NOT EXISTS idx_debts_due_date ON debts(due_date) WHERE due_date IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_debts_transaction_id ON debts(transaction_id) WHERE transaction_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS debt_repayments (
+    id BIGSERIAL PRIMARY KEY,
+    debt_id BIGINT NOT NULL REFERENCES debts(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount > 0),
+    repayment_date TIMESTAMP NOT NULL DEFAULT NOW(),
+    transaction_id BIGINT REFERENCES transactions(id) ON DELETE SET NULL,
+    note TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_debt_repayments_debt_id ON debt_repayments(debt_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_debt_repayments_transaction_id ON debt_repayments(transaction_id) WHERE transaction_id IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Ledger Service: loan terms and posted scheduled payments
+DROP TABLE IF EXISTS loan_payments;
+DROP TABLE IF EXISTS loans;
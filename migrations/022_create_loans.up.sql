@@ -0,0 +1,28 @@
+-- Ledger Service: loan terms and posted scheduled payments
+CREATE TABLE IF NOT EXISTS loans (
+    account_id BIGINT PRIMARY KEY REFERENCES accounts(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    principal NUMERIC(15, 2) NOT NULL CHECK (principal > 0),
+    annual_rate NUMERIC(7, 4) NOT NULL CHECK (annual_rate >= 0),
+    term_months INT NOT NULL CHECK (term_months > 0),
+    payment_type TEXT NOT NULL CHECK (payment_type IN ('annuity', 'differentiated')),
+    first_payment_date DATE NOT NULL,
+    payment_account_id BIGINT REFERENCES accounts(id) ON DELETE SET NULL,
+    interest_category_id BIGINT REFERENCES categories(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_loans_user_id ON loans(user_id);
+
+-- Проведенные платежи графика: основной долг — перевод на счет кредита,
+-- проценты — расход
+CREATE TABLE IF NOT EXISTS loan_payments (
+    loan_account_id BIGINT NOT NULL REFERENCES loans(account_id) ON DELETE CASCADE,
+    number INT NOT NULL CHECK (number > 0),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    principal_transaction_id BIGINT REFERENCES transactions(id) ON DELETE SET NULL,
+    interest_transaction_id BIGINT REFERENCES transactions(id) ON DELETE SET NULL,
+    posted_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (loan_account_id, number)
+);
@@ -0,0 +1,2 @@
+-- Ledger Service: spending anomalies found by the analysis job
+DROP TABLE IF EXISTS insights;
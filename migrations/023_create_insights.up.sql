@@ -0,0 +1,26 @@
+-- Ledger Service: spending anomalies found by the analysis job
+-- large_transaction — расход намного больше обычного для категории и дня
+-- недели, category_trend — траты по категории с начала месяца намного
+-- больше обычного
+CREATE TABLE IF NOT EXISTS insights (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL CHECK (kind IN ('large_transaction', 'category_trend')),
+    category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    transaction_id BIGINT REFERENCES transactions(id) ON DELETE CASCADE,
+    period_start DATE,
+    weekday SMALLINT CHECK (weekday BETWEEN 0 AND 6),
+    amount NUMERIC(15, 2) NOT NULL,
+    baseline NUMERIC(15, 2) NOT NULL,
+    threshold NUMERIC(15, 2) NOT NULL,
+    currency TEXT NOT NULL,
+    notified_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (kind <> 'large_transaction' OR transaction_id IS NOT NULL),
+    CHECK (kind <> 'category_trend' OR period_start IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_insights_user_id ON insights(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_insights_not_notified ON insights(created_at) WHERE notified_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_insights_transaction_id ON insights(transaction_id) WHERE kind = 'large_transaction';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_insights_category_period ON insights(user_id, category_id, currency, period_start) WHERE kind = 'category_trend';
@@ -0,0 +1,6 @@
+-- Ledger Service: outbox of user notifications delivered by the bot
+ALTER TABLE insights ADD COLUMN IF NOT EXISTS notified_at TIMESTAMP;
+UPDATE insights SET notified_at = created_at WHERE notified_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_insights_not_notified ON insights(created_at) WHERE notified_at IS NULL;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
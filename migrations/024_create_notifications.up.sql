@@ -0,0 +1,36 @@
+-- Ledger Service: outbox of user notifications delivered by the bot
+-- pending — ждет отправки (next_attempt_at — не раньше), sent — отправлено,
+-- failed — попытки исчерпаны, skipped — тип отключен пользователем
+CREATE TABLE IF NOT EXISTS notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    dedup_key TEXT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed', 'skipped')),
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_error TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, created_at DESC);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_dedup_key ON notifications(user_id, dedup_key) WHERE dedup_key IS NOT NULL;
+
+-- Тихие часы задаются локальным временем в часовом поясе пользователя;
+-- если quiet_from > quiet_to, интервал переходит через полночь
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    muted_types TEXT[] NOT NULL DEFAULT '{}',
+    quiet_from TIME,
+    quiet_to TIME,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((quiet_from IS NULL) = (quiet_to IS NULL))
+);
+
+-- Отметки об аномалиях теперь рассылаются через notifications
+DROP INDEX IF EXISTS idx_insights_not_notified;
+ALTER TABLE insights DROP COLUMN IF EXISTS notified_at;
@@ -0,0 +1,7 @@
+-- Ledger Service: weekly and monthly digest schedule
+DROP INDEX IF EXISTS idx_notification_preferences_digest;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS digest_sent_period;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS digest_time;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS digest_weekday;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS digest_frequency;
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS digest_enabled;
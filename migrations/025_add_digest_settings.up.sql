@@ -0,0 +1,13 @@
+-- Ledger Service: weekly and monthly digest schedule
+-- Сводка отправляется в digest_weekday (0 — воскресенье, только для weekly)
+-- или первого числа (monthly) в digest_time по часовому поясу пользователя
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_frequency TEXT NOT NULL DEFAULT 'weekly'
+    CHECK (digest_frequency IN ('weekly', 'monthly'));
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_weekday SMALLINT NOT NULL DEFAULT 1
+    CHECK (digest_weekday BETWEEN 0 AND 6);
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_time TIME NOT NULL DEFAULT '09:00';
+-- Начало последнего периода, за который сводка поставлена в очередь
+ALTER TABLE notification_preferences ADD COLUMN IF NOT EXISTS digest_sent_period DATE;
+
+CREATE INDEX IF NOT EXISTS idx_notification_preferences_digest ON notification_preferences(user_id) WHERE digest_enabled;
@@ -0,0 +1,2 @@
+-- Ledger Service: spending budgets by category and/or payee
+DROP TABLE IF EXISTS budgets;
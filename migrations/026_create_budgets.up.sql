@@ -0,0 +1,19 @@
+-- Ledger Service: spending budgets by category and/or payee
+-- Бюджет ограничивает расходы за календарную неделю, месяц или год (UTC).
+-- Категория включает подкатегории; если задан и получатель, учитываются
+-- только его операции.
+CREATE TABLE IF NOT EXISTS budgets (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    category_id BIGINT REFERENCES categories(id) ON DELETE CASCADE,
+    payee_id BIGINT REFERENCES payees(id) ON DELETE CASCADE,
+    amount NUMERIC(15, 2) NOT NULL CHECK (amount > 0),
+    currency TEXT NOT NULL,
+    period TEXT NOT NULL CHECK (period IN ('weekly', 'monthly', 'yearly')),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (category_id IS NOT NULL OR payee_id IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_budgets_user_id ON budgets(user_id);
@@ -19,20 +19,87 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	LedgerService_CreateExpense_FullMethodName     = "/ledger.LedgerService/CreateExpense"
-	LedgerService_CreateIncome_FullMethodName      = "/ledger.LedgerService/CreateIncome"
-	LedgerService_CreateTransfer_FullMethodName    = "/ledger.LedgerService/CreateTransfer"
-	LedgerService_ListAccounts_FullMethodName      = "/ledger.LedgerService/ListAccounts"
-	LedgerService_CreateAccount_FullMethodName     = "/ledger.LedgerService/CreateAccount"
-	LedgerService_UpdateAccount_FullMethodName     = "/ledger.LedgerService/UpdateAccount"
-	LedgerService_DeleteAccount_FullMethodName     = "/ledger.LedgerService/DeleteAccount"
-	LedgerService_ListCategories_FullMethodName    = "/ledger.LedgerService/ListCategories"
-	LedgerService_CreateCategory_FullMethodName    = "/ledger.LedgerService/CreateCategory"
-	LedgerService_DeleteCategory_FullMethodName    = "/ledger.LedgerService/DeleteCategory"
-	LedgerService_ListTransactions_FullMethodName  = "/ledger.LedgerService/ListTransactions"
-	LedgerService_UpdateTransaction_FullMethodName = "/ledger.LedgerService/UpdateTransaction"
-	LedgerService_DeleteTransaction_FullMethodName = "/ledger.LedgerService/DeleteTransaction"
-	LedgerService_GetBalance_FullMethodName        = "/ledger.LedgerService/GetBalance"
+	LedgerService_CreateExpense_FullMethodName                 = "/ledger.LedgerService/CreateExpense"
+	LedgerService_CreateIncome_FullMethodName                  = "/ledger.LedgerService/CreateIncome"
+	LedgerService_CreateTransfer_FullMethodName                = "/ledger.LedgerService/CreateTransfer"
+	LedgerService_ListAccounts_FullMethodName                  = "/ledger.LedgerService/ListAccounts"
+	LedgerService_CreateAccount_FullMethodName                 = "/ledger.LedgerService/CreateAccount"
+	LedgerService_UpdateAccount_FullMethodName                 = "/ledger.LedgerService/UpdateAccount"
+	LedgerService_DeleteAccount_FullMethodName                 = "/ledger.LedgerService/DeleteAccount"
+	LedgerService_UnarchiveAccount_FullMethodName              = "/ledger.LedgerService/UnarchiveAccount"
+	LedgerService_PurgeAccount_FullMethodName                  = "/ledger.LedgerService/PurgeAccount"
+	LedgerService_ReorderAccounts_FullMethodName               = "/ledger.LedgerService/ReorderAccounts"
+	LedgerService_ListCategories_FullMethodName                = "/ledger.LedgerService/ListCategories"
+	LedgerService_CreateCategory_FullMethodName                = "/ledger.LedgerService/CreateCategory"
+	LedgerService_DeleteCategory_FullMethodName                = "/ledger.LedgerService/DeleteCategory"
+	LedgerService_UpdateCategory_FullMethodName                = "/ledger.LedgerService/UpdateCategory"
+	LedgerService_MergeCategories_FullMethodName               = "/ledger.LedgerService/MergeCategories"
+	LedgerService_ArchiveCategory_FullMethodName               = "/ledger.LedgerService/ArchiveCategory"
+	LedgerService_UnarchiveCategory_FullMethodName             = "/ledger.LedgerService/UnarchiveCategory"
+	LedgerService_ReorderCategories_FullMethodName             = "/ledger.LedgerService/ReorderCategories"
+	LedgerService_SetFallbackCategory_FullMethodName           = "/ledger.LedgerService/SetFallbackCategory"
+	LedgerService_ListTransactions_FullMethodName              = "/ledger.LedgerService/ListTransactions"
+	LedgerService_GetTransaction_FullMethodName                = "/ledger.LedgerService/GetTransaction"
+	LedgerService_UpdateTransaction_FullMethodName             = "/ledger.LedgerService/UpdateTransaction"
+	LedgerService_DeleteTransaction_FullMethodName             = "/ledger.LedgerService/DeleteTransaction"
+	LedgerService_GetBalance_FullMethodName                    = "/ledger.LedgerService/GetBalance"
+	LedgerService_GetTagBreakdown_FullMethodName               = "/ledger.LedgerService/GetTagBreakdown"
+	LedgerService_ListPayees_FullMethodName                    = "/ledger.LedgerService/ListPayees"
+	LedgerService_CreatePayee_FullMethodName                   = "/ledger.LedgerService/CreatePayee"
+	LedgerService_UpdatePayee_FullMethodName                   = "/ledger.LedgerService/UpdatePayee"
+	LedgerService_DeletePayee_FullMethodName                   = "/ledger.LedgerService/DeletePayee"
+	LedgerService_GetPayeeBreakdown_FullMethodName             = "/ledger.LedgerService/GetPayeeBreakdown"
+	LedgerService_ListRules_FullMethodName                     = "/ledger.LedgerService/ListRules"
+	LedgerService_CreateRule_FullMethodName                    = "/ledger.LedgerService/CreateRule"
+	LedgerService_UpdateRule_FullMethodName                    = "/ledger.LedgerService/UpdateRule"
+	LedgerService_DeleteRule_FullMethodName                    = "/ledger.LedgerService/DeleteRule"
+	LedgerService_ReorderRules_FullMethodName                  = "/ledger.LedgerService/ReorderRules"
+	LedgerService_TestRule_FullMethodName                      = "/ledger.LedgerService/TestRule"
+	LedgerService_ApplyRules_FullMethodName                    = "/ledger.LedgerService/ApplyRules"
+	LedgerService_UploadAttachment_FullMethodName              = "/ledger.LedgerService/UploadAttachment"
+	LedgerService_ListAttachments_FullMethodName               = "/ledger.LedgerService/ListAttachments"
+	LedgerService_GetAttachment_FullMethodName                 = "/ledger.LedgerService/GetAttachment"
+	LedgerService_DeleteAttachment_FullMethodName              = "/ledger.LedgerService/DeleteAttachment"
+	LedgerService_ParseReceipt_FullMethodName                  = "/ledger.LedgerService/ParseReceipt"
+	LedgerService_ListGoals_FullMethodName                     = "/ledger.LedgerService/ListGoals"
+	LedgerService_GetGoalStatus_FullMethodName                 = "/ledger.LedgerService/GetGoalStatus"
+	LedgerService_CreateGoal_FullMethodName                    = "/ledger.LedgerService/CreateGoal"
+	LedgerService_UpdateGoal_FullMethodName                    = "/ledger.LedgerService/UpdateGoal"
+	LedgerService_DeleteGoal_FullMethodName                    = "/ledger.LedgerService/DeleteGoal"
+	LedgerService_AddGoalContribution_FullMethodName           = "/ledger.LedgerService/AddGoalContribution"
+	LedgerService_ListGoalContributions_FullMethodName         = "/ledger.LedgerService/ListGoalContributions"
+	LedgerService_DeleteGoalContribution_FullMethodName        = "/ledger.LedgerService/DeleteGoalContribution"
+	LedgerService_ListCounterparties_FullMethodName            = "/ledger.LedgerService/ListCounterparties"
+	LedgerService_CreateCounterparty_FullMethodName            = "/ledger.LedgerService/CreateCounterparty"
+	LedgerService_UpdateCounterparty_FullMethodName            = "/ledger.LedgerService/UpdateCounterparty"
+	LedgerService_DeleteCounterparty_FullMethodName            = "/ledger.LedgerService/DeleteCounterparty"
+	LedgerService_ListDebts_FullMethodName                     = "/ledger.LedgerService/ListDebts"
+	LedgerService_GetDebt_FullMethodName                       = "/ledger.LedgerService/GetDebt"
+	LedgerService_CreateDebt_FullMethodName                    = "/ledger.LedgerService/CreateDebt"
+	LedgerService_UpdateDebt_FullMethodName                    = "/ledger.LedgerService/UpdateDebt"
+	LedgerService_DeleteDebt_FullMethodName                    = "/ledger.LedgerService/DeleteDebt"
+	LedgerService_AddDebtRepayment_FullMethodName              = "/ledger.LedgerService/AddDebtRepayment"
+	LedgerService_DeleteDebtRepayment_FullMethodName           = "/ledger.LedgerService/DeleteDebtRepayment"
+	LedgerService_SetLoan_FullMethodName                       = "/ledger.LedgerService/SetLoan"
+	LedgerService_GetLoanSchedule_FullMethodName               = "/ledger.LedgerService/GetLoanSchedule"
+	LedgerService_DeleteLoan_FullMethodName                    = "/ledger.LedgerService/DeleteLoan"
+	LedgerService_PostLoanPayment_FullMethodName               = "/ledger.LedgerService/PostLoanPayment"
+	LedgerService_CalculateLoanPrepayment_FullMethodName       = "/ledger.LedgerService/CalculateLoanPrepayment"
+	LedgerService_DetectSubscriptions_FullMethodName           = "/ledger.LedgerService/DetectSubscriptions"
+	LedgerService_ListBudgets_FullMethodName                   = "/ledger.LedgerService/ListBudgets"
+	LedgerService_CreateBudget_FullMethodName                  = "/ledger.LedgerService/CreateBudget"
+	LedgerService_UpdateBudget_FullMethodName                  = "/ledger.LedgerService/UpdateBudget"
+	LedgerService_DeleteBudget_FullMethodName                  = "/ledger.LedgerService/DeleteBudget"
+	LedgerService_CreateBudgetFromSubscription_FullMethodName  = "/ledger.LedgerService/CreateBudgetFromSubscription"
+	LedgerService_ListInsights_FullMethodName                  = "/ledger.LedgerService/ListInsights"
+	LedgerService_ClaimNotifications_FullMethodName            = "/ledger.LedgerService/ClaimNotifications"
+	LedgerService_CompleteNotification_FullMethodName          = "/ledger.LedgerService/CompleteNotification"
+	LedgerService_GetNotificationPreferences_FullMethodName    = "/ledger.LedgerService/GetNotificationPreferences"
+	LedgerService_UpdateNotificationPreferences_FullMethodName = "/ledger.LedgerService/UpdateNotificationPreferences"
+	LedgerService_GetDigestSettings_FullMethodName             = "/ledger.LedgerService/GetDigestSettings"
+	LedgerService_UpdateDigestSettings_FullMethodName          = "/ledger.LedgerService/UpdateDigestSettings"
+	LedgerService_GetForecast_FullMethodName                   = "/ledger.LedgerService/GetForecast"
+	LedgerService_GetDataVersion_FullMethodName                = "/ledger.LedgerService/GetDataVersion"
 )
 
 // LedgerServiceClient is the client API for LedgerService service.
@@ -48,13 +115,80 @@ type LedgerServiceClient interface {
 	CreateAccount(ctx context.Context, in *CreateAccountRequest, opts ...grpc.CallOption) (*AccountResponse, error)
 	UpdateAccount(ctx context.Context, in *UpdateAccountRequest, opts ...grpc.CallOption) (*AccountResponse, error)
 	DeleteAccount(ctx context.Context, in *DeleteAccountRequest, opts ...grpc.CallOption) (*DeleteAccountResponse, error)
+	UnarchiveAccount(ctx context.Context, in *UnarchiveAccountRequest, opts ...grpc.CallOption) (*AccountResponse, error)
+	PurgeAccount(ctx context.Context, in *PurgeAccountRequest, opts ...grpc.CallOption) (*PurgeAccountResponse, error)
+	ReorderAccounts(ctx context.Context, in *ReorderAccountsRequest, opts ...grpc.CallOption) (*ReorderAccountsResponse, error)
 	ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error)
 	CreateCategory(ctx context.Context, in *CreateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
 	DeleteCategory(ctx context.Context, in *DeleteCategoryRequest, opts ...grpc.CallOption) (*DeleteCategoryResponse, error)
+	UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	MergeCategories(ctx context.Context, in *MergeCategoriesRequest, opts ...grpc.CallOption) (*MergeCategoriesResponse, error)
+	ArchiveCategory(ctx context.Context, in *ArchiveCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	UnarchiveCategory(ctx context.Context, in *UnarchiveCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
+	ReorderCategories(ctx context.Context, in *ReorderCategoriesRequest, opts ...grpc.CallOption) (*ReorderCategoriesResponse, error)
+	SetFallbackCategory(ctx context.Context, in *SetFallbackCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error)
 	ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (*ListTransactionsResponse, error)
+	GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error)
 	UpdateTransaction(ctx context.Context, in *UpdateTransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error)
 	DeleteTransaction(ctx context.Context, in *DeleteTransactionRequest, opts ...grpc.CallOption) (*DeleteTransactionResponse, error)
 	GetBalance(ctx context.Context, in *GetBalanceRequest, opts ...grpc.CallOption) (*GetBalanceResponse, error)
+	GetTagBreakdown(ctx context.Context, in *GetTagBreakdownRequest, opts ...grpc.CallOption) (*GetTagBreakdownResponse, error)
+	ListPayees(ctx context.Context, in *ListPayeesRequest, opts ...grpc.CallOption) (*ListPayeesResponse, error)
+	CreatePayee(ctx context.Context, in *CreatePayeeRequest, opts ...grpc.CallOption) (*PayeeResponse, error)
+	UpdatePayee(ctx context.Context, in *UpdatePayeeRequest, opts ...grpc.CallOption) (*PayeeResponse, error)
+	DeletePayee(ctx context.Context, in *DeletePayeeRequest, opts ...grpc.CallOption) (*DeletePayeeResponse, error)
+	GetPayeeBreakdown(ctx context.Context, in *GetPayeeBreakdownRequest, opts ...grpc.CallOption) (*GetPayeeBreakdownResponse, error)
+	ListRules(ctx context.Context, in *ListRulesRequest, opts ...grpc.CallOption) (*ListRulesResponse, error)
+	CreateRule(ctx context.Context, in *CreateRuleRequest, opts ...grpc.CallOption) (*RuleResponse, error)
+	UpdateRule(ctx context.Context, in *UpdateRuleRequest, opts ...grpc.CallOption) (*RuleResponse, error)
+	DeleteRule(ctx context.Context, in *DeleteRuleRequest, opts ...grpc.CallOption) (*DeleteRuleResponse, error)
+	ReorderRules(ctx context.Context, in *ReorderRulesRequest, opts ...grpc.CallOption) (*ReorderRulesResponse, error)
+	TestRule(ctx context.Context, in *TestRuleRequest, opts ...grpc.CallOption) (*TestRuleResponse, error)
+	ApplyRules(ctx context.Context, in *ApplyRulesRequest, opts ...grpc.CallOption) (*ApplyRulesResponse, error)
+	UploadAttachment(ctx context.Context, in *UploadAttachmentRequest, opts ...grpc.CallOption) (*AttachmentResponse, error)
+	ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsResponse, error)
+	GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (*GetAttachmentResponse, error)
+	DeleteAttachment(ctx context.Context, in *DeleteAttachmentRequest, opts ...grpc.CallOption) (*DeleteAttachmentResponse, error)
+	ParseReceipt(ctx context.Context, in *ParseReceiptRequest, opts ...grpc.CallOption) (*ParseReceiptResponse, error)
+	ListGoals(ctx context.Context, in *ListGoalsRequest, opts ...grpc.CallOption) (*ListGoalsResponse, error)
+	GetGoalStatus(ctx context.Context, in *GetGoalStatusRequest, opts ...grpc.CallOption) (*GoalStatusResponse, error)
+	CreateGoal(ctx context.Context, in *CreateGoalRequest, opts ...grpc.CallOption) (*GoalStatusResponse, error)
+	UpdateGoal(ctx context.Context, in *UpdateGoalRequest, opts ...grpc.CallOption) (*GoalStatusResponse, error)
+	DeleteGoal(ctx context.Context, in *DeleteGoalRequest, opts ...grpc.CallOption) (*DeleteGoalResponse, error)
+	AddGoalContribution(ctx context.Context, in *AddGoalContributionRequest, opts ...grpc.CallOption) (*GoalContributionResponse, error)
+	ListGoalContributions(ctx context.Context, in *ListGoalContributionsRequest, opts ...grpc.CallOption) (*ListGoalContributionsResponse, error)
+	DeleteGoalContribution(ctx context.Context, in *DeleteGoalContributionRequest, opts ...grpc.CallOption) (*DeleteGoalContributionResponse, error)
+	ListCounterparties(ctx context.Context, in *ListCounterpartiesRequest, opts ...grpc.CallOption) (*ListCounterpartiesResponse, error)
+	CreateCounterparty(ctx context.Context, in *CreateCounterpartyRequest, opts ...grpc.CallOption) (*CounterpartyResponse, error)
+	UpdateCounterparty(ctx context.Context, in *UpdateCounterpartyRequest, opts ...grpc.CallOption) (*CounterpartyResponse, error)
+	DeleteCounterparty(ctx context.Context, in *DeleteCounterpartyRequest, opts ...grpc.CallOption) (*DeleteCounterpartyResponse, error)
+	ListDebts(ctx context.Context, in *ListDebtsRequest, opts ...grpc.CallOption) (*ListDebtsResponse, error)
+	GetDebt(ctx context.Context, in *GetDebtRequest, opts ...grpc.CallOption) (*GetDebtResponse, error)
+	CreateDebt(ctx context.Context, in *CreateDebtRequest, opts ...grpc.CallOption) (*DebtResponse, error)
+	UpdateDebt(ctx context.Context, in *UpdateDebtRequest, opts ...grpc.CallOption) (*DebtResponse, error)
+	DeleteDebt(ctx context.Context, in *DeleteDebtRequest, opts ...grpc.CallOption) (*DeleteDebtResponse, error)
+	AddDebtRepayment(ctx context.Context, in *AddDebtRepaymentRequest, opts ...grpc.CallOption) (*DebtRepaymentResponse, error)
+	DeleteDebtRepayment(ctx context.Context, in *DeleteDebtRepaymentRequest, opts ...grpc.CallOption) (*DeleteDebtRepaymentResponse, error)
+	SetLoan(ctx context.Context, in *SetLoanRequest, opts ...grpc.CallOption) (*LoanScheduleResponse, error)
+	GetLoanSchedule(ctx context.Context, in *GetLoanScheduleRequest, opts ...grpc.CallOption) (*LoanScheduleResponse, error)
+	DeleteLoan(ctx context.Context, in *DeleteLoanRequest, opts ...grpc.CallOption) (*DeleteLoanResponse, error)
+	PostLoanPayment(ctx context.Context, in *PostLoanPaymentRequest, opts ...grpc.CallOption) (*PostLoanPaymentResponse, error)
+	CalculateLoanPrepayment(ctx context.Context, in *CalculateLoanPrepaymentRequest, opts ...grpc.CallOption) (*CalculateLoanPrepaymentResponse, error)
+	DetectSubscriptions(ctx context.Context, in *DetectSubscriptionsRequest, opts ...grpc.CallOption) (*DetectSubscriptionsResponse, error)
+	ListBudgets(ctx context.Context, in *ListBudgetsRequest, opts ...grpc.CallOption) (*ListBudgetsResponse, error)
+	CreateBudget(ctx context.Context, in *CreateBudgetRequest, opts ...grpc.CallOption) (*BudgetStatusResponse, error)
+	UpdateBudget(ctx context.Context, in *UpdateBudgetRequest, opts ...grpc.CallOption) (*BudgetStatusResponse, error)
+	DeleteBudget(ctx context.Context, in *DeleteBudgetRequest, opts ...grpc.CallOption) (*DeleteBudgetResponse, error)
+	CreateBudgetFromSubscription(ctx context.Context, in *CreateBudgetFromSubscriptionRequest, opts ...grpc.CallOption) (*BudgetStatusResponse, error)
+	ListInsights(ctx context.Context, in *ListInsightsRequest, opts ...grpc.CallOption) (*ListInsightsResponse, error)
+	ClaimNotifications(ctx context.Context, in *ClaimNotificationsRequest, opts ...grpc.CallOption) (*ClaimNotificationsResponse, error)
+	CompleteNotification(ctx context.Context, in *CompleteNotificationRequest, opts ...grpc.CallOption) (*CompleteNotificationResponse, error)
+	GetNotificationPreferences(ctx context.Context, in *GetNotificationPreferencesRequest, opts ...grpc.CallOption) (*NotificationPreferences, error)
+	UpdateNotificationPreferences(ctx context.Context, in *UpdateNotificationPreferencesRequest, opts ...grpc.CallOption) (*NotificationPreferences, error)
+	GetDigestSettings(ctx context.Context, in *GetDigestSettingsRequest, opts ...grpc.CallOption) (*DigestSettings, error)
+	UpdateDigestSettings(ctx context.Context, in *UpdateDigestSettingsRequest, opts ...grpc.CallOption) (*DigestSettings, error)
+	GetForecast(ctx context.Context, in *GetForecastRequest, opts ...grpc.CallOption) (*GetForecastResponse, error)
+	GetDataVersion(ctx context.Context, in *GetDataVersionRequest, opts ...grpc.CallOption) (*DataVersion, error)
 }
 
 type ledgerServiceClient struct {
@@ -135,6 +269,36 @@ func (c *ledgerServiceClient) DeleteAccount(ctx context.Context, in *DeleteAccou
 	return out, nil
 }
 
+func (c *ledgerServiceClient) UnarchiveAccount(ctx context.Context, in *UnarchiveAccountRequest, opts ...grpc.CallOption) (*AccountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccountResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UnarchiveAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) PurgeAccount(ctx context.Context, in *PurgeAccountRequest, opts ...grpc.CallOption) (*PurgeAccountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PurgeAccountResponse)
+	err := c.cc.Invoke(ctx, LedgerService_PurgeAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ReorderAccounts(ctx context.Context, in *ReorderAccountsRequest, opts ...grpc.CallOption) (*ReorderAccountsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReorderAccountsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ReorderAccounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *ledgerServiceClient) ListCategories(ctx context.Context, in *ListCategoriesRequest, opts ...grpc.CallOption) (*ListCategoriesResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListCategoriesResponse)
@@ -165,6 +329,66 @@ func (c *ledgerServiceClient) DeleteCategory(ctx context.Context, in *DeleteCate
 	return out, nil
 }
 
+func (c *ledgerServiceClient) UpdateCategory(ctx context.Context, in *UpdateCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateCategory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) MergeCategories(ctx context.Context, in *MergeCategoriesRequest, opts ...grpc.CallOption) (*MergeCategoriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MergeCategoriesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_MergeCategories_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ArchiveCategory(ctx context.Context, in *ArchiveCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ArchiveCategory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UnarchiveCategory(ctx context.Context, in *UnarchiveCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UnarchiveCategory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ReorderCategories(ctx context.Context, in *ReorderCategoriesRequest, opts ...grpc.CallOption) (*ReorderCategoriesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReorderCategoriesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ReorderCategories_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) SetFallbackCategory(ctx context.Context, in *SetFallbackCategoryRequest, opts ...grpc.CallOption) (*CategoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CategoryResponse)
+	err := c.cc.Invoke(ctx, LedgerService_SetFallbackCategory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *ledgerServiceClient) ListTransactions(ctx context.Context, in *ListTransactionsRequest, opts ...grpc.CallOption) (*ListTransactionsResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(ListTransactionsResponse)
@@ -175,6 +399,16 @@ func (c *ledgerServiceClient) ListTransactions(ctx context.Context, in *ListTran
 	return out, nil
 }
 
+func (c *ledgerServiceClient) GetTransaction(ctx context.Context, in *GetTransactionRequest, opts ...grpc.CallOption) (*GetTransactionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTransactionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetTransaction_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *ledgerServiceClient) UpdateTransaction(ctx context.Context, in *UpdateTransactionRequest, opts ...grpc.CallOption) (*TransactionResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	out := new(TransactionResponse)
@@ -205,347 +439,2391 @@ func (c *ledgerServiceClient) GetBalance(ctx context.Context, in *GetBalanceRequ
 	return out, nil
 }
 
-// LedgerServiceServer is the server API for LedgerService service.
-// All implementations must embed UnimplementedLedgerServiceServer
-// for forward compatibility.
-//
-// Ledger Service
-type LedgerServiceServer interface {
-	CreateExpense(context.Context, *CreateExpenseRequest) (*TransactionResponse, error)
-	CreateIncome(context.Context, *CreateIncomeRequest) (*TransactionResponse, error)
-	CreateTransfer(context.Context, *CreateTransferRequest) (*TransferResponse, error)
-	ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error)
-	CreateAccount(context.Context, *CreateAccountRequest) (*AccountResponse, error)
-	UpdateAccount(context.Context, *UpdateAccountRequest) (*AccountResponse, error)
-	DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResponse, error)
-	ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error)
-	CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error)
-	DeleteCategory(context.Context, *DeleteCategoryRequest) (*DeleteCategoryResponse, error)
-	ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error)
-	UpdateTransaction(context.Context, *UpdateTransactionRequest) (*TransactionResponse, error)
-	DeleteTransaction(context.Context, *DeleteTransactionRequest) (*DeleteTransactionResponse, error)
-	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
-	mustEmbedUnimplementedLedgerServiceServer()
+func (c *ledgerServiceClient) GetTagBreakdown(ctx context.Context, in *GetTagBreakdownRequest, opts ...grpc.CallOption) (*GetTagBreakdownResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTagBreakdownResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetTagBreakdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
 
-// UnimplementedLedgerServiceServer must be embedded to have
-// forward compatible implementations.
-//
-// NOTE: this should be embedded by value instead of pointer to avoid a nil
-// pointer dereference when methods are called.
-type UnimplementedLedgerServiceServer struct{}
+func (c *ledgerServiceClient) ListPayees(ctx context.Context, in *ListPayeesRequest, opts ...grpc.CallOption) (*ListPayeesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListPayeesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListPayees_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
 
-func (UnimplementedLedgerServiceServer) CreateExpense(context.Context, *CreateExpenseRequest) (*TransactionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateExpense not implemented")
+func (c *ledgerServiceClient) CreatePayee(ctx context.Context, in *CreatePayeeRequest, opts ...grpc.CallOption) (*PayeeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PayeeResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreatePayee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) CreateIncome(context.Context, *CreateIncomeRequest) (*TransactionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateIncome not implemented")
+
+func (c *ledgerServiceClient) UpdatePayee(ctx context.Context, in *UpdatePayeeRequest, opts ...grpc.CallOption) (*PayeeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PayeeResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UpdatePayee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) CreateTransfer(context.Context, *CreateTransferRequest) (*TransferResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateTransfer not implemented")
+
+func (c *ledgerServiceClient) DeletePayee(ctx context.Context, in *DeletePayeeRequest, opts ...grpc.CallOption) (*DeletePayeeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeletePayeeResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeletePayee_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListAccounts not implemented")
+
+func (c *ledgerServiceClient) GetPayeeBreakdown(ctx context.Context, in *GetPayeeBreakdownRequest, opts ...grpc.CallOption) (*GetPayeeBreakdownResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPayeeBreakdownResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetPayeeBreakdown_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) CreateAccount(context.Context, *CreateAccountRequest) (*AccountResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateAccount not implemented")
+
+func (c *ledgerServiceClient) ListRules(ctx context.Context, in *ListRulesRequest, opts ...grpc.CallOption) (*ListRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListRulesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) UpdateAccount(context.Context, *UpdateAccountRequest) (*AccountResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateAccount not implemented")
+
+func (c *ledgerServiceClient) CreateRule(ctx context.Context, in *CreateRuleRequest, opts ...grpc.CallOption) (*RuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RuleResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreateRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
+
+func (c *ledgerServiceClient) UpdateRule(ctx context.Context, in *UpdateRuleRequest, opts ...grpc.CallOption) (*RuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RuleResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
+
+func (c *ledgerServiceClient) DeleteRule(ctx context.Context, in *DeleteRuleRequest, opts ...grpc.CallOption) (*DeleteRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteRuleResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method CreateCategory not implemented")
+
+func (c *ledgerServiceClient) ReorderRules(ctx context.Context, in *ReorderRulesRequest, opts ...grpc.CallOption) (*ReorderRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReorderRulesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ReorderRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) DeleteCategory(context.Context, *DeleteCategoryRequest) (*DeleteCategoryResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteCategory not implemented")
+
+func (c *ledgerServiceClient) TestRule(ctx context.Context, in *TestRuleRequest, opts ...grpc.CallOption) (*TestRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TestRuleResponse)
+	err := c.cc.Invoke(ctx, LedgerService_TestRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method ListTransactions not implemented")
+
+func (c *ledgerServiceClient) ApplyRules(ctx context.Context, in *ApplyRulesRequest, opts ...grpc.CallOption) (*ApplyRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ApplyRulesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ApplyRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) UpdateTransaction(context.Context, *UpdateTransactionRequest) (*TransactionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method UpdateTransaction not implemented")
+
+func (c *ledgerServiceClient) UploadAttachment(ctx context.Context, in *UploadAttachmentRequest, opts ...grpc.CallOption) (*AttachmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AttachmentResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UploadAttachment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
 }
-func (UnimplementedLedgerServiceServer) DeleteTransaction(context.Context, *DeleteTransactionRequest) (*DeleteTransactionResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method DeleteTransaction not implemented")
+
+func (c *ledgerServiceClient) ListAttachments(ctx context.Context, in *ListAttachmentsRequest, opts ...grpc.CallOption) (*ListAttachmentsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListAttachmentsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListAttachments_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetAttachment(ctx context.Context, in *GetAttachmentRequest, opts ...grpc.CallOption) (*GetAttachmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAttachmentResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetAttachment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteAttachment(ctx context.Context, in *DeleteAttachmentRequest, opts ...grpc.CallOption) (*DeleteAttachmentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteAttachmentResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteAttachment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ParseReceipt(ctx context.Context, in *ParseReceiptRequest, opts ...grpc.CallOption) (*ParseReceiptResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ParseReceiptResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ParseReceipt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListGoals(ctx context.Context, in *ListGoalsRequest, opts ...grpc.CallOption) (*ListGoalsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGoalsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListGoals_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetGoalStatus(ctx context.Context, in *GetGoalStatusRequest, opts ...grpc.CallOption) (*GoalStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GoalStatusResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetGoalStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CreateGoal(ctx context.Context, in *CreateGoalRequest, opts ...grpc.CallOption) (*GoalStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GoalStatusResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreateGoal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UpdateGoal(ctx context.Context, in *UpdateGoalRequest, opts ...grpc.CallOption) (*GoalStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GoalStatusResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateGoal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteGoal(ctx context.Context, in *DeleteGoalRequest, opts ...grpc.CallOption) (*DeleteGoalResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteGoalResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteGoal_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) AddGoalContribution(ctx context.Context, in *AddGoalContributionRequest, opts ...grpc.CallOption) (*GoalContributionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GoalContributionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_AddGoalContribution_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListGoalContributions(ctx context.Context, in *ListGoalContributionsRequest, opts ...grpc.CallOption) (*ListGoalContributionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListGoalContributionsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListGoalContributions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteGoalContribution(ctx context.Context, in *DeleteGoalContributionRequest, opts ...grpc.CallOption) (*DeleteGoalContributionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteGoalContributionResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteGoalContribution_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListCounterparties(ctx context.Context, in *ListCounterpartiesRequest, opts ...grpc.CallOption) (*ListCounterpartiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListCounterpartiesResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListCounterparties_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CreateCounterparty(ctx context.Context, in *CreateCounterpartyRequest, opts ...grpc.CallOption) (*CounterpartyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CounterpartyResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreateCounterparty_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UpdateCounterparty(ctx context.Context, in *UpdateCounterpartyRequest, opts ...grpc.CallOption) (*CounterpartyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CounterpartyResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateCounterparty_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteCounterparty(ctx context.Context, in *DeleteCounterpartyRequest, opts ...grpc.CallOption) (*DeleteCounterpartyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteCounterpartyResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteCounterparty_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListDebts(ctx context.Context, in *ListDebtsRequest, opts ...grpc.CallOption) (*ListDebtsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListDebtsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListDebts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetDebt(ctx context.Context, in *GetDebtRequest, opts ...grpc.CallOption) (*GetDebtResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetDebtResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetDebt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CreateDebt(ctx context.Context, in *CreateDebtRequest, opts ...grpc.CallOption) (*DebtResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DebtResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreateDebt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UpdateDebt(ctx context.Context, in *UpdateDebtRequest, opts ...grpc.CallOption) (*DebtResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DebtResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateDebt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteDebt(ctx context.Context, in *DeleteDebtRequest, opts ...grpc.CallOption) (*DeleteDebtResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteDebtResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteDebt_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) AddDebtRepayment(ctx context.Context, in *AddDebtRepaymentRequest, opts ...grpc.CallOption) (*DebtRepaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DebtRepaymentResponse)
+	err := c.cc.Invoke(ctx, LedgerService_AddDebtRepayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteDebtRepayment(ctx context.Context, in *DeleteDebtRepaymentRequest, opts ...grpc.CallOption) (*DeleteDebtRepaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteDebtRepaymentResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteDebtRepayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) SetLoan(ctx context.Context, in *SetLoanRequest, opts ...grpc.CallOption) (*LoanScheduleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoanScheduleResponse)
+	err := c.cc.Invoke(ctx, LedgerService_SetLoan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetLoanSchedule(ctx context.Context, in *GetLoanScheduleRequest, opts ...grpc.CallOption) (*LoanScheduleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoanScheduleResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetLoanSchedule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteLoan(ctx context.Context, in *DeleteLoanRequest, opts ...grpc.CallOption) (*DeleteLoanResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteLoanResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteLoan_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) PostLoanPayment(ctx context.Context, in *PostLoanPaymentRequest, opts ...grpc.CallOption) (*PostLoanPaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PostLoanPaymentResponse)
+	err := c.cc.Invoke(ctx, LedgerService_PostLoanPayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CalculateLoanPrepayment(ctx context.Context, in *CalculateLoanPrepaymentRequest, opts ...grpc.CallOption) (*CalculateLoanPrepaymentResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CalculateLoanPrepaymentResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CalculateLoanPrepayment_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DetectSubscriptions(ctx context.Context, in *DetectSubscriptionsRequest, opts ...grpc.CallOption) (*DetectSubscriptionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DetectSubscriptionsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DetectSubscriptions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListBudgets(ctx context.Context, in *ListBudgetsRequest, opts ...grpc.CallOption) (*ListBudgetsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListBudgetsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListBudgets_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CreateBudget(ctx context.Context, in *CreateBudgetRequest, opts ...grpc.CallOption) (*BudgetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BudgetStatusResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreateBudget_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UpdateBudget(ctx context.Context, in *UpdateBudgetRequest, opts ...grpc.CallOption) (*BudgetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BudgetStatusResponse)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateBudget_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) DeleteBudget(ctx context.Context, in *DeleteBudgetRequest, opts ...grpc.CallOption) (*DeleteBudgetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteBudgetResponse)
+	err := c.cc.Invoke(ctx, LedgerService_DeleteBudget_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CreateBudgetFromSubscription(ctx context.Context, in *CreateBudgetFromSubscriptionRequest, opts ...grpc.CallOption) (*BudgetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BudgetStatusResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CreateBudgetFromSubscription_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ListInsights(ctx context.Context, in *ListInsightsRequest, opts ...grpc.CallOption) (*ListInsightsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListInsightsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ListInsights_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) ClaimNotifications(ctx context.Context, in *ClaimNotificationsRequest, opts ...grpc.CallOption) (*ClaimNotificationsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClaimNotificationsResponse)
+	err := c.cc.Invoke(ctx, LedgerService_ClaimNotifications_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) CompleteNotification(ctx context.Context, in *CompleteNotificationRequest, opts ...grpc.CallOption) (*CompleteNotificationResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CompleteNotificationResponse)
+	err := c.cc.Invoke(ctx, LedgerService_CompleteNotification_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetNotificationPreferences(ctx context.Context, in *GetNotificationPreferencesRequest, opts ...grpc.CallOption) (*NotificationPreferences, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NotificationPreferences)
+	err := c.cc.Invoke(ctx, LedgerService_GetNotificationPreferences_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UpdateNotificationPreferences(ctx context.Context, in *UpdateNotificationPreferencesRequest, opts ...grpc.CallOption) (*NotificationPreferences, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(NotificationPreferences)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateNotificationPreferences_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetDigestSettings(ctx context.Context, in *GetDigestSettingsRequest, opts ...grpc.CallOption) (*DigestSettings, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DigestSettings)
+	err := c.cc.Invoke(ctx, LedgerService_GetDigestSettings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) UpdateDigestSettings(ctx context.Context, in *UpdateDigestSettingsRequest, opts ...grpc.CallOption) (*DigestSettings, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DigestSettings)
+	err := c.cc.Invoke(ctx, LedgerService_UpdateDigestSettings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetForecast(ctx context.Context, in *GetForecastRequest, opts ...grpc.CallOption) (*GetForecastResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetForecastResponse)
+	err := c.cc.Invoke(ctx, LedgerService_GetForecast_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ledgerServiceClient) GetDataVersion(ctx context.Context, in *GetDataVersionRequest, opts ...grpc.CallOption) (*DataVersion, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DataVersion)
+	err := c.cc.Invoke(ctx, LedgerService_GetDataVersion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LedgerServiceServer is the server API for LedgerService service.
+// All implementations must embed UnimplementedLedgerServiceServer
+// for forward compatibility.
+//
+// Ledger Service
+type LedgerServiceServer interface {
+	CreateExpense(context.Context, *CreateExpenseRequest) (*TransactionResponse, error)
+	CreateIncome(context.Context, *CreateIncomeRequest) (*TransactionResponse, error)
+	CreateTransfer(context.Context, *CreateTransferRequest) (*TransferResponse, error)
+	ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error)
+	CreateAccount(context.Context, *CreateAccountRequest) (*AccountResponse, error)
+	UpdateAccount(context.Context, *UpdateAccountRequest) (*AccountResponse, error)
+	DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResponse, error)
+	UnarchiveAccount(context.Context, *UnarchiveAccountRequest) (*AccountResponse, error)
+	PurgeAccount(context.Context, *PurgeAccountRequest) (*PurgeAccountResponse, error)
+	ReorderAccounts(context.Context, *ReorderAccountsRequest) (*ReorderAccountsResponse, error)
+	ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error)
+	CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error)
+	DeleteCategory(context.Context, *DeleteCategoryRequest) (*DeleteCategoryResponse, error)
+	UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error)
+	MergeCategories(context.Context, *MergeCategoriesRequest) (*MergeCategoriesResponse, error)
+	ArchiveCategory(context.Context, *ArchiveCategoryRequest) (*CategoryResponse, error)
+	UnarchiveCategory(context.Context, *UnarchiveCategoryRequest) (*CategoryResponse, error)
+	ReorderCategories(context.Context, *ReorderCategoriesRequest) (*ReorderCategoriesResponse, error)
+	SetFallbackCategory(context.Context, *SetFallbackCategoryRequest) (*CategoryResponse, error)
+	ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error)
+	GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error)
+	UpdateTransaction(context.Context, *UpdateTransactionRequest) (*TransactionResponse, error)
+	DeleteTransaction(context.Context, *DeleteTransactionRequest) (*DeleteTransactionResponse, error)
+	GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error)
+	GetTagBreakdown(context.Context, *GetTagBreakdownRequest) (*GetTagBreakdownResponse, error)
+	ListPayees(context.Context, *ListPayeesRequest) (*ListPayeesResponse, error)
+	CreatePayee(context.Context, *CreatePayeeRequest) (*PayeeResponse, error)
+	UpdatePayee(context.Context, *UpdatePayeeRequest) (*PayeeResponse, error)
+	DeletePayee(context.Context, *DeletePayeeRequest) (*DeletePayeeResponse, error)
+	GetPayeeBreakdown(context.Context, *GetPayeeBreakdownRequest) (*GetPayeeBreakdownResponse, error)
+	ListRules(context.Context, *ListRulesRequest) (*ListRulesResponse, error)
+	CreateRule(context.Context, *CreateRuleRequest) (*RuleResponse, error)
+	UpdateRule(context.Context, *UpdateRuleRequest) (*RuleResponse, error)
+	DeleteRule(context.Context, *DeleteRuleRequest) (*DeleteRuleResponse, error)
+	ReorderRules(context.Context, *ReorderRulesRequest) (*ReorderRulesResponse, error)
+	TestRule(context.Context, *TestRuleRequest) (*TestRuleResponse, error)
+	ApplyRules(context.Context, *ApplyRulesRequest) (*ApplyRulesResponse, error)
+	UploadAttachment(context.Context, *UploadAttachmentRequest) (*AttachmentResponse, error)
+	ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsResponse, error)
+	GetAttachment(context.Context, *GetAttachmentRequest) (*GetAttachmentResponse, error)
+	DeleteAttachment(context.Context, *DeleteAttachmentRequest) (*DeleteAttachmentResponse, error)
+	ParseReceipt(context.Context, *ParseReceiptRequest) (*ParseReceiptResponse, error)
+	ListGoals(context.Context, *ListGoalsRequest) (*ListGoalsResponse, error)
+	GetGoalStatus(context.Context, *GetGoalStatusRequest) (*GoalStatusResponse, error)
+	CreateGoal(context.Context, *CreateGoalRequest) (*GoalStatusResponse, error)
+	UpdateGoal(context.Context, *UpdateGoalRequest) (*GoalStatusResponse, error)
+	DeleteGoal(context.Context, *DeleteGoalRequest) (*DeleteGoalResponse, error)
+	AddGoalContribution(context.Context, *AddGoalContributionRequest) (*GoalContributionResponse, error)
+	ListGoalContributions(context.Context, *ListGoalContributionsRequest) (*ListGoalContributionsResponse, error)
+	DeleteGoalContribution(context.Context, *DeleteGoalContributionRequest) (*DeleteGoalContributionResponse, error)
+	ListCounterparties(context.Context, *ListCounterpartiesRequest) (*ListCounterpartiesResponse, error)
+	CreateCounterparty(context.Context, *CreateCounterpartyRequest) (*CounterpartyResponse, error)
+	UpdateCounterparty(context.Context, *UpdateCounterpartyRequest) (*CounterpartyResponse, error)
+	DeleteCounterparty(context.Context, *DeleteCounterpartyRequest) (*DeleteCounterpartyResponse, error)
+	ListDebts(context.Context, *ListDebtsRequest) (*ListDebtsResponse, error)
+	GetDebt(context.Context, *GetDebtRequest) (*GetDebtResponse, error)
+	CreateDebt(context.Context, *CreateDebtRequest) (*DebtResponse, error)
+	UpdateDebt(context.Context, *UpdateDebtRequest) (*DebtResponse, error)
+	DeleteDebt(context.Context, *DeleteDebtRequest) (*DeleteDebtResponse, error)
+	AddDebtRepayment(context.Context, *AddDebtRepaymentRequest) (*DebtRepaymentResponse, error)
+	DeleteDebtRepayment(context.Context, *DeleteDebtRepaymentRequest) (*DeleteDebtRepaymentResponse, error)
+	SetLoan(context.Context, *SetLoanRequest) (*LoanScheduleResponse, error)
+	GetLoanSchedule(context.Context, *GetLoanScheduleRequest) (*LoanScheduleResponse, error)
+	DeleteLoan(context.Context, *DeleteLoanRequest) (*DeleteLoanResponse, error)
+	PostLoanPayment(context.Context, *PostLoanPaymentRequest) (*PostLoanPaymentResponse, error)
+	CalculateLoanPrepayment(context.Context, *CalculateLoanPrepaymentRequest) (*CalculateLoanPrepaymentResponse, error)
+	DetectSubscriptions(context.Context, *DetectSubscriptionsRequest) (*DetectSubscriptionsResponse, error)
+	ListBudgets(context.Context, *ListBudgetsRequest) (*ListBudgetsResponse, error)
+	CreateBudget(context.Context, *CreateBudgetRequest) (*BudgetStatusResponse, error)
+	UpdateBudget(context.Context, *UpdateBudgetRequest) (*BudgetStatusResponse, error)
+	DeleteBudget(context.Context, *DeleteBudgetRequest) (*DeleteBudgetResponse, error)
+	CreateBudgetFromSubscription(context.Context, *CreateBudgetFromSubscriptionRequest) (*BudgetStatusResponse, error)
+	ListInsights(context.Context, *ListInsightsRequest) (*ListInsightsResponse, error)
+	ClaimNotifications(context.Context, *ClaimNotificationsRequest) (*ClaimNotificationsResponse, error)
+	CompleteNotification(context.Context, *CompleteNotificationRequest) (*CompleteNotificationResponse, error)
+	GetNotificationPreferences(context.Context, *GetNotificationPreferencesRequest) (*NotificationPreferences, error)
+	UpdateNotificationPreferences(context.Context, *UpdateNotificationPreferencesRequest) (*NotificationPreferences, error)
+	GetDigestSettings(context.Context, *GetDigestSettingsRequest) (*DigestSettings, error)
+	UpdateDigestSettings(context.Context, *UpdateDigestSettingsRequest) (*DigestSettings, error)
+	GetForecast(context.Context, *GetForecastRequest) (*GetForecastResponse, error)
+	GetDataVersion(context.Context, *GetDataVersionRequest) (*DataVersion, error)
+	mustEmbedUnimplementedLedgerServiceServer()
+}
+
+// UnimplementedLedgerServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLedgerServiceServer struct{}
+
+func (UnimplementedLedgerServiceServer) CreateExpense(context.Context, *CreateExpenseRequest) (*TransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateExpense not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateIncome(context.Context, *CreateIncomeRequest) (*TransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateIncome not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateTransfer(context.Context, *CreateTransferRequest) (*TransferResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTransfer not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListAccounts(context.Context, *ListAccountsRequest) (*ListAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAccounts not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateAccount(context.Context, *CreateAccountRequest) (*AccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateAccount(context.Context, *UpdateAccountRequest) (*AccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteAccount(context.Context, *DeleteAccountRequest) (*DeleteAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) UnarchiveAccount(context.Context, *UnarchiveAccountRequest) (*AccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnarchiveAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) PurgeAccount(context.Context, *PurgeAccountRequest) (*PurgeAccountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PurgeAccount not implemented")
+}
+func (UnimplementedLedgerServiceServer) ReorderAccounts(context.Context, *ReorderAccountsRequest) (*ReorderAccountsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReorderAccounts not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListCategories(context.Context, *ListCategoriesRequest) (*ListCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCategories not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateCategory(context.Context, *CreateCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCategory not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteCategory(context.Context, *DeleteCategoryRequest) (*DeleteCategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCategory not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateCategory(context.Context, *UpdateCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCategory not implemented")
+}
+func (UnimplementedLedgerServiceServer) MergeCategories(context.Context, *MergeCategoriesRequest) (*MergeCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MergeCategories not implemented")
+}
+func (UnimplementedLedgerServiceServer) ArchiveCategory(context.Context, *ArchiveCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ArchiveCategory not implemented")
+}
+func (UnimplementedLedgerServiceServer) UnarchiveCategory(context.Context, *UnarchiveCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnarchiveCategory not implemented")
+}
+func (UnimplementedLedgerServiceServer) ReorderCategories(context.Context, *ReorderCategoriesRequest) (*ReorderCategoriesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReorderCategories not implemented")
+}
+func (UnimplementedLedgerServiceServer) SetFallbackCategory(context.Context, *SetFallbackCategoryRequest) (*CategoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetFallbackCategory not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListTransactions(context.Context, *ListTransactionsRequest) (*ListTransactionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTransactions not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetTransaction(context.Context, *GetTransactionRequest) (*GetTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTransaction not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateTransaction(context.Context, *UpdateTransactionRequest) (*TransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateTransaction not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteTransaction(context.Context, *DeleteTransactionRequest) (*DeleteTransactionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteTransaction not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetTagBreakdown(context.Context, *GetTagBreakdownRequest) (*GetTagBreakdownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetTagBreakdown not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListPayees(context.Context, *ListPayeesRequest) (*ListPayeesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListPayees not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreatePayee(context.Context, *CreatePayeeRequest) (*PayeeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreatePayee not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdatePayee(context.Context, *UpdatePayeeRequest) (*PayeeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdatePayee not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeletePayee(context.Context, *DeletePayeeRequest) (*DeletePayeeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeletePayee not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetPayeeBreakdown(context.Context, *GetPayeeBreakdownRequest) (*GetPayeeBreakdownResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPayeeBreakdown not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListRules(context.Context, *ListRulesRequest) (*ListRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRules not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateRule(context.Context, *CreateRuleRequest) (*RuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRule not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateRule(context.Context, *UpdateRuleRequest) (*RuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRule not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteRule(context.Context, *DeleteRuleRequest) (*DeleteRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRule not implemented")
+}
+func (UnimplementedLedgerServiceServer) ReorderRules(context.Context, *ReorderRulesRequest) (*ReorderRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReorderRules not implemented")
+}
+func (UnimplementedLedgerServiceServer) TestRule(context.Context, *TestRuleRequest) (*TestRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method TestRule not implemented")
+}
+func (UnimplementedLedgerServiceServer) ApplyRules(context.Context, *ApplyRulesRequest) (*ApplyRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ApplyRules not implemented")
+}
+func (UnimplementedLedgerServiceServer) UploadAttachment(context.Context, *UploadAttachmentRequest) (*AttachmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UploadAttachment not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListAttachments(context.Context, *ListAttachmentsRequest) (*ListAttachmentsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAttachments not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetAttachment(context.Context, *GetAttachmentRequest) (*GetAttachmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAttachment not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteAttachment(context.Context, *DeleteAttachmentRequest) (*DeleteAttachmentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteAttachment not implemented")
+}
+func (UnimplementedLedgerServiceServer) ParseReceipt(context.Context, *ParseReceiptRequest) (*ParseReceiptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ParseReceipt not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListGoals(context.Context, *ListGoalsRequest) (*ListGoalsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGoals not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetGoalStatus(context.Context, *GetGoalStatusRequest) (*GoalStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetGoalStatus not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateGoal(context.Context, *CreateGoalRequest) (*GoalStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateGoal not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateGoal(context.Context, *UpdateGoalRequest) (*GoalStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateGoal not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteGoal(context.Context, *DeleteGoalRequest) (*DeleteGoalResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteGoal not implemented")
+}
+func (UnimplementedLedgerServiceServer) AddGoalContribution(context.Context, *AddGoalContributionRequest) (*GoalContributionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddGoalContribution not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListGoalContributions(context.Context, *ListGoalContributionsRequest) (*ListGoalContributionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListGoalContributions not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteGoalContribution(context.Context, *DeleteGoalContributionRequest) (*DeleteGoalContributionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteGoalContribution not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListCounterparties(context.Context, *ListCounterpartiesRequest) (*ListCounterpartiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListCounterparties not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateCounterparty(context.Context, *CreateCounterpartyRequest) (*CounterpartyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateCounterparty not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateCounterparty(context.Context, *UpdateCounterpartyRequest) (*CounterpartyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateCounterparty not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteCounterparty(context.Context, *DeleteCounterpartyRequest) (*DeleteCounterpartyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteCounterparty not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListDebts(context.Context, *ListDebtsRequest) (*ListDebtsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDebts not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetDebt(context.Context, *GetDebtRequest) (*GetDebtResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDebt not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateDebt(context.Context, *CreateDebtRequest) (*DebtResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateDebt not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateDebt(context.Context, *UpdateDebtRequest) (*DebtResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDebt not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteDebt(context.Context, *DeleteDebtRequest) (*DeleteDebtResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDebt not implemented")
+}
+func (UnimplementedLedgerServiceServer) AddDebtRepayment(context.Context, *AddDebtRepaymentRequest) (*DebtRepaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddDebtRepayment not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteDebtRepayment(context.Context, *DeleteDebtRepaymentRequest) (*DeleteDebtRepaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteDebtRepayment not implemented")
+}
+func (UnimplementedLedgerServiceServer) SetLoan(context.Context, *SetLoanRequest) (*LoanScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetLoan not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetLoanSchedule(context.Context, *GetLoanScheduleRequest) (*LoanScheduleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLoanSchedule not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteLoan(context.Context, *DeleteLoanRequest) (*DeleteLoanResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteLoan not implemented")
+}
+func (UnimplementedLedgerServiceServer) PostLoanPayment(context.Context, *PostLoanPaymentRequest) (*PostLoanPaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PostLoanPayment not implemented")
+}
+func (UnimplementedLedgerServiceServer) CalculateLoanPrepayment(context.Context, *CalculateLoanPrepaymentRequest) (*CalculateLoanPrepaymentResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CalculateLoanPrepayment not implemented")
+}
+func (UnimplementedLedgerServiceServer) DetectSubscriptions(context.Context, *DetectSubscriptionsRequest) (*DetectSubscriptionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DetectSubscriptions not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListBudgets(context.Context, *ListBudgetsRequest) (*ListBudgetsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListBudgets not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateBudget(context.Context, *CreateBudgetRequest) (*BudgetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBudget not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateBudget(context.Context, *UpdateBudgetRequest) (*BudgetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateBudget not implemented")
+}
+func (UnimplementedLedgerServiceServer) DeleteBudget(context.Context, *DeleteBudgetRequest) (*DeleteBudgetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteBudget not implemented")
+}
+func (UnimplementedLedgerServiceServer) CreateBudgetFromSubscription(context.Context, *CreateBudgetFromSubscriptionRequest) (*BudgetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateBudgetFromSubscription not implemented")
+}
+func (UnimplementedLedgerServiceServer) ListInsights(context.Context, *ListInsightsRequest) (*ListInsightsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListInsights not implemented")
+}
+func (UnimplementedLedgerServiceServer) ClaimNotifications(context.Context, *ClaimNotificationsRequest) (*ClaimNotificationsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClaimNotifications not implemented")
+}
+func (UnimplementedLedgerServiceServer) CompleteNotification(context.Context, *CompleteNotificationRequest) (*CompleteNotificationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompleteNotification not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetNotificationPreferences(context.Context, *GetNotificationPreferencesRequest) (*NotificationPreferences, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNotificationPreferences not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateNotificationPreferences(context.Context, *UpdateNotificationPreferencesRequest) (*NotificationPreferences, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateNotificationPreferences not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetDigestSettings(context.Context, *GetDigestSettingsRequest) (*DigestSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDigestSettings not implemented")
+}
+func (UnimplementedLedgerServiceServer) UpdateDigestSettings(context.Context, *UpdateDigestSettingsRequest) (*DigestSettings, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateDigestSettings not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetForecast(context.Context, *GetForecastRequest) (*GetForecastResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetForecast not implemented")
+}
+func (UnimplementedLedgerServiceServer) GetDataVersion(context.Context, *GetDataVersionRequest) (*DataVersion, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDataVersion not implemented")
+}
+func (UnimplementedLedgerServiceServer) mustEmbedUnimplementedLedgerServiceServer() {}
+func (UnimplementedLedgerServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeLedgerServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LedgerServiceServer will
+// result in compilation errors.
+type UnsafeLedgerServiceServer interface {
+	mustEmbedUnimplementedLedgerServiceServer()
+}
+
+func RegisterLedgerServiceServer(s grpc.ServiceRegistrar, srv LedgerServiceServer) {
+	// If the following call pancis, it indicates UnimplementedLedgerServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&LedgerService_ServiceDesc, srv)
+}
+
+func _LedgerService_CreateExpense_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateExpenseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateExpense(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateExpense_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateExpense(ctx, req.(*CreateExpenseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateIncome_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateIncomeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateIncome(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateIncome_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateIncome(ctx, req.(*CreateIncomeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTransferRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateTransfer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateTransfer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateTransfer(ctx, req.(*CreateTransferRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListAccounts(ctx, req.(*ListAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateAccount(ctx, req.(*CreateAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdateAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdateAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdateAccount(ctx, req.(*UpdateAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteAccount(ctx, req.(*DeleteAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UnarchiveAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnarchiveAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UnarchiveAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UnarchiveAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UnarchiveAccount(ctx, req.(*UnarchiveAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_PurgeAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PurgeAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).PurgeAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_PurgeAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).PurgeAccount(ctx, req.(*PurgeAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ReorderAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorderAccountsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ReorderAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ReorderAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ReorderAccounts(ctx, req.(*ReorderAccountsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListCategories(ctx, req.(*ListCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateCategory(ctx, req.(*CreateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteCategory(ctx, req.(*DeleteCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdateCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdateCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdateCategory(ctx, req.(*UpdateCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_MergeCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MergeCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).MergeCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_MergeCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).MergeCategories(ctx, req.(*MergeCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ArchiveCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ArchiveCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ArchiveCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ArchiveCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ArchiveCategory(ctx, req.(*ArchiveCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UnarchiveCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnarchiveCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UnarchiveCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UnarchiveCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UnarchiveCategory(ctx, req.(*UnarchiveCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ReorderCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorderCategoriesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ReorderCategories(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ReorderCategories_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ReorderCategories(ctx, req.(*ReorderCategoriesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_SetFallbackCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetFallbackCategoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).SetFallbackCategory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_SetFallbackCategory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).SetFallbackCategory(ctx, req.(*SetFallbackCategoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTransactionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListTransactions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListTransactions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListTransactions(ctx, req.(*ListTransactionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetTransaction(ctx, req.(*GetTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdateTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdateTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdateTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdateTransaction(ctx, req.(*UpdateTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteTransaction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteTransaction(ctx, req.(*DeleteTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetBalance_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetTagBreakdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTagBreakdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetTagBreakdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetTagBreakdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetTagBreakdown(ctx, req.(*GetTagBreakdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListPayees_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListPayeesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListPayees(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListPayees_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListPayees(ctx, req.(*ListPayeesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreatePayee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreatePayeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreatePayee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreatePayee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreatePayee(ctx, req.(*CreatePayeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdatePayee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdatePayeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdatePayee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdatePayee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdatePayee(ctx, req.(*UpdatePayeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeletePayee_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeletePayeeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeletePayee(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeletePayee_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeletePayee(ctx, req.(*DeletePayeeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetPayeeBreakdown_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPayeeBreakdownRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetPayeeBreakdown(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetPayeeBreakdown_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetPayeeBreakdown(ctx, req.(*GetPayeeBreakdownRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListRules(ctx, req.(*ListRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateRule(ctx, req.(*CreateRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdateRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdateRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdateRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdateRule(ctx, req.(*UpdateRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteRule(ctx, req.(*DeleteRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ReorderRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReorderRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ReorderRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ReorderRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ReorderRules(ctx, req.(*ReorderRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_TestRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TestRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).TestRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_TestRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).TestRule(ctx, req.(*TestRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ApplyRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ApplyRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ApplyRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ApplyRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ApplyRules(ctx, req.(*ApplyRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UploadAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UploadAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UploadAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UploadAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UploadAttachment(ctx, req.(*UploadAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListAttachments_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAttachmentsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListAttachments(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListAttachments_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListAttachments(ctx, req.(*ListAttachmentsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetAttachment(ctx, req.(*GetAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteAttachment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteAttachmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteAttachment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteAttachment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteAttachment(ctx, req.(*DeleteAttachmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ParseReceipt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ParseReceiptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ParseReceipt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ParseReceipt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ParseReceipt(ctx, req.(*ParseReceiptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListGoals_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGoalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListGoals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListGoals_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListGoals(ctx, req.(*ListGoalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetGoalStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetGoalStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetGoalStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetGoalStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetGoalStatus(ctx, req.(*GetGoalStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateGoal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateGoalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateGoal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateGoal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateGoal(ctx, req.(*CreateGoalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdateGoal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateGoalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdateGoal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdateGoal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdateGoal(ctx, req.(*UpdateGoalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteGoal_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteGoalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteGoal(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteGoal_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteGoal(ctx, req.(*DeleteGoalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_AddGoalContribution_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddGoalContributionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).AddGoalContribution(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_AddGoalContribution_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).AddGoalContribution(ctx, req.(*AddGoalContributionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListGoalContributions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListGoalContributionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListGoalContributions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListGoalContributions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListGoalContributions(ctx, req.(*ListGoalContributionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteGoalContribution_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteGoalContributionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteGoalContribution(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteGoalContribution_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteGoalContribution(ctx, req.(*DeleteGoalContributionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListCounterparties_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListCounterpartiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListCounterparties(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListCounterparties_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListCounterparties(ctx, req.(*ListCounterpartiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateCounterparty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateCounterpartyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateCounterparty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateCounterparty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateCounterparty(ctx, req.(*CreateCounterpartyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdateCounterparty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateCounterpartyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdateCounterparty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdateCounterparty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdateCounterparty(ctx, req.(*UpdateCounterpartyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteCounterparty_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteCounterpartyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteCounterparty(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteCounterparty_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteCounterparty(ctx, req.(*DeleteCounterpartyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_ListDebts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListDebtsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).ListDebts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_ListDebts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).ListDebts(ctx, req.(*ListDebtsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetDebt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDebtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetDebt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetDebt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetDebt(ctx, req.(*GetDebtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_CreateDebt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateDebtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CreateDebt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CreateDebt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CreateDebt(ctx, req.(*CreateDebtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_UpdateDebt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDebtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).UpdateDebt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_UpdateDebt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).UpdateDebt(ctx, req.(*UpdateDebtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteDebt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDebtRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteDebt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteDebt_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteDebt(ctx, req.(*DeleteDebtRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_AddDebtRepayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddDebtRepaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).AddDebtRepayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_AddDebtRepayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).AddDebtRepayment(ctx, req.(*AddDebtRepaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteDebtRepayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteDebtRepaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteDebtRepayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteDebtRepayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteDebtRepayment(ctx, req.(*DeleteDebtRepaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_SetLoan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetLoanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).SetLoan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_SetLoan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).SetLoan(ctx, req.(*SetLoanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_GetLoanSchedule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLoanScheduleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).GetLoanSchedule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_GetLoanSchedule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).GetLoanSchedule(ctx, req.(*GetLoanScheduleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LedgerService_DeleteLoan_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteLoanRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DeleteLoan(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DeleteLoan_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DeleteLoan(ctx, req.(*DeleteLoanRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedLedgerServiceServer) GetBalance(context.Context, *GetBalanceRequest) (*GetBalanceResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetBalance not implemented")
+
+func _LedgerService_PostLoanPayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PostLoanPaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).PostLoanPayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_PostLoanPayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).PostLoanPayment(ctx, req.(*PostLoanPaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
-func (UnimplementedLedgerServiceServer) mustEmbedUnimplementedLedgerServiceServer() {}
-func (UnimplementedLedgerServiceServer) testEmbeddedByValue()                       {}
 
-// UnsafeLedgerServiceServer may be embedded to opt out of forward compatibility for this service.
-// Use of this interface is not recommended, as added methods to LedgerServiceServer will
-// result in compilation errors.
-type UnsafeLedgerServiceServer interface {
-	mustEmbedUnimplementedLedgerServiceServer()
+func _LedgerService_CalculateLoanPrepayment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CalculateLoanPrepaymentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).CalculateLoanPrepayment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_CalculateLoanPrepayment_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).CalculateLoanPrepayment(ctx, req.(*CalculateLoanPrepaymentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterLedgerServiceServer(s grpc.ServiceRegistrar, srv LedgerServiceServer) {
-	// If the following call pancis, it indicates UnimplementedLedgerServiceServer was
-	// embedded by pointer and is nil.  This will cause panics if an
-	// unimplemented method is ever invoked, so we test this at initialization
-	// time to prevent it from happening at runtime later due to I/O.
-	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
-		t.testEmbeddedByValue()
+func _LedgerService_DetectSubscriptions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DetectSubscriptionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	s.RegisterService(&LedgerService_ServiceDesc, srv)
+	if interceptor == nil {
+		return srv.(LedgerServiceServer).DetectSubscriptions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LedgerService_DetectSubscriptions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LedgerServiceServer).DetectSubscriptions(ctx, req.(*DetectSubscriptionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_CreateExpense_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateExpenseRequest)
+func _LedgerService_ListBudgets_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListBudgetsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).CreateExpense(ctx, in)
+		return srv.(LedgerServiceServer).ListBudgets(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_CreateExpense_FullMethodName,
+		FullMethod: LedgerService_ListBudgets_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).CreateExpense(ctx, req.(*CreateExpenseRequest))
+		return srv.(LedgerServiceServer).ListBudgets(ctx, req.(*ListBudgetsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_CreateIncome_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateIncomeRequest)
+func _LedgerService_CreateBudget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBudgetRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).CreateIncome(ctx, in)
+		return srv.(LedgerServiceServer).CreateBudget(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_CreateIncome_FullMethodName,
+		FullMethod: LedgerService_CreateBudget_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).CreateIncome(ctx, req.(*CreateIncomeRequest))
+		return srv.(LedgerServiceServer).CreateBudget(ctx, req.(*CreateBudgetRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_CreateTransfer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateTransferRequest)
+func _LedgerService_UpdateBudget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateBudgetRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).CreateTransfer(ctx, in)
+		return srv.(LedgerServiceServer).UpdateBudget(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_CreateTransfer_FullMethodName,
+		FullMethod: LedgerService_UpdateBudget_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).CreateTransfer(ctx, req.(*CreateTransferRequest))
+		return srv.(LedgerServiceServer).UpdateBudget(ctx, req.(*UpdateBudgetRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_ListAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ListAccountsRequest)
+func _LedgerService_DeleteBudget_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteBudgetRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).ListAccounts(ctx, in)
+		return srv.(LedgerServiceServer).DeleteBudget(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_ListAccounts_FullMethodName,
+		FullMethod: LedgerService_DeleteBudget_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).ListAccounts(ctx, req.(*ListAccountsRequest))
+		return srv.(LedgerServiceServer).DeleteBudget(ctx, req.(*DeleteBudgetRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_CreateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateAccountRequest)
+func _LedgerService_CreateBudgetFromSubscription_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateBudgetFromSubscriptionRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).CreateAccount(ctx, in)
+		return srv.(LedgerServiceServer).CreateBudgetFromSubscription(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_CreateAccount_FullMethodName,
+		FullMethod: LedgerService_CreateBudgetFromSubscription_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).CreateAccount(ctx, req.(*CreateAccountRequest))
+		return srv.(LedgerServiceServer).CreateBudgetFromSubscription(ctx, req.(*CreateBudgetFromSubscriptionRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_UpdateAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateAccountRequest)
+func _LedgerService_ListInsights_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListInsightsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).UpdateAccount(ctx, in)
+		return srv.(LedgerServiceServer).ListInsights(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_UpdateAccount_FullMethodName,
+		FullMethod: LedgerService_ListInsights_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).UpdateAccount(ctx, req.(*UpdateAccountRequest))
+		return srv.(LedgerServiceServer).ListInsights(ctx, req.(*ListInsightsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_DeleteAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteAccountRequest)
+func _LedgerService_ClaimNotifications_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClaimNotificationsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).DeleteAccount(ctx, in)
+		return srv.(LedgerServiceServer).ClaimNotifications(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_DeleteAccount_FullMethodName,
+		FullMethod: LedgerService_ClaimNotifications_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).DeleteAccount(ctx, req.(*DeleteAccountRequest))
+		return srv.(LedgerServiceServer).ClaimNotifications(ctx, req.(*ClaimNotificationsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_ListCategories_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ListCategoriesRequest)
+func _LedgerService_CompleteNotification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompleteNotificationRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).ListCategories(ctx, in)
+		return srv.(LedgerServiceServer).CompleteNotification(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_ListCategories_FullMethodName,
+		FullMethod: LedgerService_CompleteNotification_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).ListCategories(ctx, req.(*ListCategoriesRequest))
+		return srv.(LedgerServiceServer).CompleteNotification(ctx, req.(*CompleteNotificationRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_CreateCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(CreateCategoryRequest)
+func _LedgerService_GetNotificationPreferences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNotificationPreferencesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).CreateCategory(ctx, in)
+		return srv.(LedgerServiceServer).GetNotificationPreferences(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_CreateCategory_FullMethodName,
+		FullMethod: LedgerService_GetNotificationPreferences_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).CreateCategory(ctx, req.(*CreateCategoryRequest))
+		return srv.(LedgerServiceServer).GetNotificationPreferences(ctx, req.(*GetNotificationPreferencesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_DeleteCategory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteCategoryRequest)
+func _LedgerService_UpdateNotificationPreferences_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateNotificationPreferencesRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).DeleteCategory(ctx, in)
+		return srv.(LedgerServiceServer).UpdateNotificationPreferences(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_DeleteCategory_FullMethodName,
+		FullMethod: LedgerService_UpdateNotificationPreferences_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).DeleteCategory(ctx, req.(*DeleteCategoryRequest))
+		return srv.(LedgerServiceServer).UpdateNotificationPreferences(ctx, req.(*UpdateNotificationPreferencesRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_ListTransactions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(ListTransactionsRequest)
+func _LedgerService_GetDigestSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDigestSettingsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).ListTransactions(ctx, in)
+		return srv.(LedgerServiceServer).GetDigestSettings(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_ListTransactions_FullMethodName,
+		FullMethod: LedgerService_GetDigestSettings_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).ListTransactions(ctx, req.(*ListTransactionsRequest))
+		return srv.(LedgerServiceServer).GetDigestSettings(ctx, req.(*GetDigestSettingsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_UpdateTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(UpdateTransactionRequest)
+func _LedgerService_UpdateDigestSettings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateDigestSettingsRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).UpdateTransaction(ctx, in)
+		return srv.(LedgerServiceServer).UpdateDigestSettings(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_UpdateTransaction_FullMethodName,
+		FullMethod: LedgerService_UpdateDigestSettings_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).UpdateTransaction(ctx, req.(*UpdateTransactionRequest))
+		return srv.(LedgerServiceServer).UpdateDigestSettings(ctx, req.(*UpdateDigestSettingsRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_DeleteTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(DeleteTransactionRequest)
+func _LedgerService_GetForecast_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetForecastRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).DeleteTransaction(ctx, in)
+		return srv.(LedgerServiceServer).GetForecast(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_DeleteTransaction_FullMethodName,
+		FullMethod: LedgerService_GetForecast_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).DeleteTransaction(ctx, req.(*DeleteTransactionRequest))
+		return srv.(LedgerServiceServer).GetForecast(ctx, req.(*GetForecastRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _LedgerService_GetBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetBalanceRequest)
+func _LedgerService_GetDataVersion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDataVersionRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(LedgerServiceServer).GetBalance(ctx, in)
+		return srv.(LedgerServiceServer).GetDataVersion(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: LedgerService_GetBalance_FullMethodName,
+		FullMethod: LedgerService_GetDataVersion_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(LedgerServiceServer).GetBalance(ctx, req.(*GetBalanceRequest))
+		return srv.(LedgerServiceServer).GetDataVersion(ctx, req.(*GetDataVersionRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -585,6 +2863,18 @@ var LedgerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteAccount",
 			Handler:    _LedgerService_DeleteAccount_Handler,
 		},
+		{
+			MethodName: "UnarchiveAccount",
+			Handler:    _LedgerService_UnarchiveAccount_Handler,
+		},
+		{
+			MethodName: "PurgeAccount",
+			Handler:    _LedgerService_PurgeAccount_Handler,
+		},
+		{
+			MethodName: "ReorderAccounts",
+			Handler:    _LedgerService_ReorderAccounts_Handler,
+		},
 		{
 			MethodName: "ListCategories",
 			Handler:    _LedgerService_ListCategories_Handler,
@@ -597,10 +2887,38 @@ var LedgerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteCategory",
 			Handler:    _LedgerService_DeleteCategory_Handler,
 		},
+		{
+			MethodName: "UpdateCategory",
+			Handler:    _LedgerService_UpdateCategory_Handler,
+		},
+		{
+			MethodName: "MergeCategories",
+			Handler:    _LedgerService_MergeCategories_Handler,
+		},
+		{
+			MethodName: "ArchiveCategory",
+			Handler:    _LedgerService_ArchiveCategory_Handler,
+		},
+		{
+			MethodName: "UnarchiveCategory",
+			Handler:    _LedgerService_UnarchiveCategory_Handler,
+		},
+		{
+			MethodName: "ReorderCategories",
+			Handler:    _LedgerService_ReorderCategories_Handler,
+		},
+		{
+			MethodName: "SetFallbackCategory",
+			Handler:    _LedgerService_SetFallbackCategory_Handler,
+		},
 		{
 			MethodName: "ListTransactions",
 			Handler:    _LedgerService_ListTransactions_Handler,
 		},
+		{
+			MethodName: "GetTransaction",
+			Handler:    _LedgerService_GetTransaction_Handler,
+		},
 		{
 			MethodName: "UpdateTransaction",
 			Handler:    _LedgerService_UpdateTransaction_Handler,
@@ -613,6 +2931,234 @@ var LedgerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetBalance",
 			Handler:    _LedgerService_GetBalance_Handler,
 		},
+		{
+			MethodName: "GetTagBreakdown",
+			Handler:    _LedgerService_GetTagBreakdown_Handler,
+		},
+		{
+			MethodName: "ListPayees",
+			Handler:    _LedgerService_ListPayees_Handler,
+		},
+		{
+			MethodName: "CreatePayee",
+			Handler:    _LedgerService_CreatePayee_Handler,
+		},
+		{
+			MethodName: "UpdatePayee",
+			Handler:    _LedgerService_UpdatePayee_Handler,
+		},
+		{
+			MethodName: "DeletePayee",
+			Handler:    _LedgerService_DeletePayee_Handler,
+		},
+		{
+			MethodName: "GetPayeeBreakdown",
+			Handler:    _LedgerService_GetPayeeBreakdown_Handler,
+		},
+		{
+			MethodName: "ListRules",
+			Handler:    _LedgerService_ListRules_Handler,
+		},
+		{
+			MethodName: "CreateRule",
+			Handler:    _LedgerService_CreateRule_Handler,
+		},
+		{
+			MethodName: "UpdateRule",
+			Handler:    _LedgerService_UpdateRule_Handler,
+		},
+		{
+			MethodName: "DeleteRule",
+			Handler:    _LedgerService_DeleteRule_Handler,
+		},
+		{
+			MethodName: "ReorderRules",
+			Handler:    _LedgerService_ReorderRules_Handler,
+		},
+		{
+			MethodName: "TestRule",
+			Handler:    _LedgerService_TestRule_Handler,
+		},
+		{
+			MethodName: "ApplyRules",
+			Handler:    _LedgerService_ApplyRules_Handler,
+		},
+		{
+			MethodName: "UploadAttachment",
+			Handler:    _LedgerService_UploadAttachment_Handler,
+		},
+		{
+			MethodName: "ListAttachments",
+			Handler:    _LedgerService_ListAttachments_Handler,
+		},
+		{
+			MethodName: "GetAttachment",
+			Handler:    _LedgerService_GetAttachment_Handler,
+		},
+		{
+			MethodName: "DeleteAttachment",
+			Handler:    _LedgerService_DeleteAttachment_Handler,
+		},
+		{
+			MethodName: "ParseReceipt",
+			Handler:    _LedgerService_ParseReceipt_Handler,
+		},
+		{
+			MethodName: "ListGoals",
+			Handler:    _LedgerService_ListGoals_Handler,
+		},
+		{
+			MethodName: "GetGoalStatus",
+			Handler:    _LedgerService_GetGoalStatus_Handler,
+		},
+		{
+			MethodName: "CreateGoal",
+			Handler:    _LedgerService_CreateGoal_Handler,
+		},
+		{
+			MethodName: "UpdateGoal",
+			Handler:    _LedgerService_UpdateGoal_Handler,
+		},
+		{
+			MethodName: "DeleteGoal",
+			Handler:    _LedgerService_DeleteGoal_Handler,
+		},
+		{
+			MethodName: "AddGoalContribution",
+			Handler:    _LedgerService_AddGoalContribution_Handler,
+		},
+		{
+			MethodName: "ListGoalContributions",
+			Handler:    _LedgerService_ListGoalContributions_Handler,
+		},
+		{
+			MethodName: "DeleteGoalContribution",
+			Handler:    _LedgerService_DeleteGoalContribution_Handler,
+		},
+		{
+			MethodName: "ListCounterparties",
+			Handler:    _LedgerService_ListCounterparties_Handler,
+		},
+		{
+			MethodName: "CreateCounterparty",
+			Handler:    _LedgerService_CreateCounterparty_Handler,
+		},
+		{
+			MethodName: "UpdateCounterparty",
+			Handler:    _LedgerService_UpdateCounterparty_Handler,
+		},
+		{
+			MethodName: "DeleteCounterparty",
+			Handler:    _LedgerService_DeleteCounterparty_Handler,
+		},
+		{
+			MethodName: "ListDebts",
+			Handler:    _LedgerService_ListDebts_Handler,
+		},
+		{
+			MethodName: "GetDebt",
+			Handler:    _LedgerService_GetDebt_Handler,
+		},
+		{
+			MethodName: "CreateDebt",
+			Handler:    _LedgerService_CreateDebt_Handler,
+		},
+		{
+			MethodName: "UpdateDebt",
+			Handler:    _LedgerService_UpdateDebt_Handler,
+		},
+		{
+			MethodName: "DeleteDebt",
+			Handler:    _LedgerService_DeleteDebt_Handler,
+		},
+		{
+			MethodName: "AddDebtRepayment",
+			Handler:    _LedgerService_AddDebtRepayment_Handler,
+		},
+		{
+			MethodName: "DeleteDebtRepayment",
+			Handler:    _LedgerService_DeleteDebtRepayment_Handler,
+		},
+		{
+			MethodName: "SetLoan",
+			Handler:    _LedgerService_SetLoan_Handler,
+		},
+		{
+			MethodName: "GetLoanSchedule",
+			Handler:    _LedgerService_GetLoanSchedule_Handler,
+		},
+		{
+			MethodName: "DeleteLoan",
+			Handler:    _LedgerService_DeleteLoan_Handler,
+		},
+		{
+			MethodName: "PostLoanPayment",
+			Handler:    _LedgerService_PostLoanPayment_Handler,
+		},
+		{
+			MethodName: "CalculateLoanPrepayment",
+			Handler:    _LedgerService_CalculateLoanPrepayment_Handler,
+		},
+		{
+			MethodName: "DetectSubscriptions",
+			Handler:    _LedgerService_DetectSubscriptions_Handler,
+		},
+		{
+			MethodName: "ListBudgets",
+			Handler:    _LedgerService_ListBudgets_Handler,
+		},
+		{
+			MethodName: "CreateBudget",
+			Handler:    _LedgerService_CreateBudget_Handler,
+		},
+		{
+			MethodName: "UpdateBudget",
+			Handler:    _LedgerService_UpdateBudget_Handler,
+		},
+		{
+			MethodName: "DeleteBudget",
+			Handler:    _LedgerService_DeleteBudget_Handler,
+		},
+		{
+			MethodName: "CreateBudgetFromSubscription",
+			Handler:    _LedgerService_CreateBudgetFromSubscription_Handler,
+		},
+		{
+			MethodName: "ListInsights",
+			Handler:    _LedgerService_ListInsights_Handler,
+		},
+		{
+			MethodName: "ClaimNotifications",
+			Handler:    _LedgerService_ClaimNotifications_Handler,
+		},
+		{
+			MethodName: "CompleteNotification",
+			Handler:    _LedgerService_CompleteNotification_Handler,
+		},
+		{
+			MethodName: "GetNotificationPreferences",
+			Handler:    _LedgerService_GetNotificationPreferences_Handler,
+		},
+		{
+			MethodName: "UpdateNotificationPreferences",
+			Handler:    _LedgerService_UpdateNotificationPreferences_Handler,
+		},
+		{
+			MethodName: "GetDigestSettings",
+			Handler:    _LedgerService_GetDigestSettings_Handler,
+		},
+		{
+			MethodName: "UpdateDigestSettings",
+			Handler:    _LedgerService_UpdateDigestSettings_Handler,
+		},
+		{
+			MethodName: "GetForecast",
+			Handler:    _LedgerService_GetForecast_Handler,
+		},
+		{
+			MethodName: "GetDataVersion",
+			Handler:    _LedgerService_GetDataVersion_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/ledger/ledger.proto",
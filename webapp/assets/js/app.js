@@ -23,6 +23,8 @@ let customStartDate = null;
 let customEndDate = null;
 let accounts = [];
 let categories = [];
+const MAX_CATEGORY_DEPTH = 3;
+let categoryRootNames = {}; // id категории -> имя категории верхнего уровня
 let editingTransactionId = null;
 let editingAccountId = null;
 let selectedCategoryId = null;
@@ -473,6 +475,11 @@ function setupEventListeners() {
         form.querySelectorAll('.form-input').forEach(field => {
             field.classList.remove('invalid');
         });
+        const parentSelect = document.getElementById('categoryParent');
+        parentSelect.innerHTML = '<option value="0">Без родителя</option>' + categories
+            .filter(cat => cat.depth < MAX_CATEGORY_DEPTH)
+            .map(cat => `<option value="${cat.id}">${cat.label}</option>`)
+            .join('');
         openModal('addCategoryModal');
     });
 
@@ -1105,7 +1112,9 @@ async function loadAccounts() {
         // Update total balance
         const totalBalanceEl = document.getElementById('totalBalance');
         if (totalBalanceEl) {
-            const total = accounts.reduce((sum, acc) => sum + parseFloat(acc.balance || 0), 0);
+            const total = accounts
+                .filter(acc => !acc.exclude_from_totals)
+                .reduce((sum, acc) => sum + parseFloat(acc.balance || 0), 0);
             totalBalanceEl.textContent = formatAmount(total) + ' ₽';
         }
 
@@ -1139,7 +1148,7 @@ async function loadCategories(type) {
     try {
         const response = await fetch(`${gatewayUrl}/api/categories?telegram_id=${telegramId}&type=${type}`);
         const data = await response.json();
-        categories = data.categories || [];
+        categories = flattenCategories(data.categories || []);
 
         // Показываем первые 3 категории + кнопка "Еще"
         const displayCategories = categories.slice(0, 3);
@@ -1152,8 +1161,8 @@ async function loadCategories(type) {
         
         grid.innerHTML = displayCategories.map(cat => `
             <button type="button" class="category-button" data-category-id="${cat.id}" onclick="selectCategory(${cat.id})">
-                <div class="icon">📁</div>
-                <div class="category-name">${cat.name}</div>
+                <div class="icon">${cat.icon || '📁'}</div>
+                <div class="category-name">${cat.label}</div>
             </button>
         `).join('') + `
             <button type="button" class="category-button" onclick="loadAllCategories(); openModal('moreCategoriesModal');">
@@ -1166,13 +1175,41 @@ async function loadCategories(type) {
     }
 }
 
+// Теги вводятся через запятую или пробел, "#" в начале необязателен
+function parseTags(value) {
+    return value.split(/[\s,]+/)
+        .map(tag => tag.replace(/^#/, '').trim())
+        .filter(tag => tag !== '');
+}
+
+function formatTags(tags) {
+    return tags.map(tag => `#${tag}`).join(' ');
+}
+
+// Разворачивает дерево категорий в плоский список. У каждой категории
+// появляются label с полным путем, depth и root_name — имя верхнего уровня.
+function flattenCategories(tree, parent = null) {
+    const result = [];
+    tree.forEach(cat => {
+        const item = {
+            ...cat,
+            label: parent ? `${parent.label} › ${cat.name}` : cat.name,
+            depth: parent ? parent.depth + 1 : 1,
+            root_name: parent ? parent.root_name : cat.name
+        };
+        result.push(item);
+        result.push(...flattenCategories(cat.children || [], item));
+    });
+    return result;
+}
+
 async function loadAllCategories() {
     try {
         const grid = document.getElementById('allCategoriesGrid');
         grid.innerHTML = categories.map(cat => `
             <button type="button" class="category-button" data-category-id="${cat.id}" onclick="selectCategoryFromAll(${cat.id})">
-                <div class="icon">📁</div>
-                <div class="category-name">${cat.name}</div>
+                <div class="icon">${cat.icon || '📁'}</div>
+                <div class="category-name">${cat.label}</div>
             </button>
         `).join('');
     } catch (error) {
@@ -1217,7 +1254,8 @@ async function loadTransactions(categoryName = null) {
         const params = {
             telegram_id: telegramId,
             limit: 1000,
-            period: periodParams.period
+            period: periodParams.period,
+            types: currentType
         };
         
         if (periodParams.startDate) {
@@ -1231,7 +1269,7 @@ async function loadTransactions(categoryName = null) {
 
         const response = await fetch(url);
         const data = await response.json();
-        let transactions = (data.transactions || []).filter(tx => tx.type === currentType);
+        let transactions = data.transactions || [];
         
         // Фильтруем по категории, если указана
         if (categoryName) {
@@ -1253,6 +1291,8 @@ async function loadTransactions(categoryName = null) {
                         <span>${formatDate(new Date(tx.operation_date))}</span>
                     </div>
                     ${tx.description ? `<div style="margin-top: 5px; font-size: 12px; opacity: 0.7;">${tx.description}</div>` : ''}
+                    ${tx.splits ? `<div style="margin-top: 5px; font-size: 12px; opacity: 0.7;">${formatSplits(tx.splits)}</div>` : ''}
+                    ${tx.tags ? `<div style="margin-top: 5px; font-size: 12px; opacity: 0.7;">${formatTags(tx.tags)}</div>` : ''}
                 </li>
             `).join('');
         }
@@ -1268,7 +1308,8 @@ async function loadCategoriesWithStats() {
         const params = {
             telegram_id: telegramId,
             limit: 1000,
-            period: periodParams.period
+            period: periodParams.period,
+            types: currentType
         };
         
         if (periodParams.startDate) {
@@ -1282,12 +1323,20 @@ async function loadCategoriesWithStats() {
 
         const response = await fetch(url);
         const data = await response.json();
-        const transactions = (data.transactions || []).filter(tx => tx.type === currentType);
+        const transactions = data.transactions || [];
+
+        // Подкатегории учитываются в сумме родительской категории
+        const catResponse = await fetch(`${gatewayUrl}/api/categories?telegram_id=${telegramId}&type=${currentType}&include_archived=true`);
+        const catData = await catResponse.json();
+        categoryRootNames = {};
+        flattenCategories(catData.categories || []).forEach(cat => {
+            categoryRootNames[cat.id] = cat.root_name;
+        });
         
         // Группируем транзакции по категориям и суммируем
         const categoryStats = {};
-        transactions.forEach(tx => {
-            const categoryName = tx.category_name || 'Без категории';
+        transactions.forEach(tx => transactionLines(tx).forEach(line => {
+            const categoryName = rootCategoryName(line) || 'Без категории';
             if (!categoryStats[categoryName]) {
                 categoryStats[categoryName] = {
                     name: categoryName,
@@ -1295,9 +1344,9 @@ async function loadCategoriesWithStats() {
                     count: 0
                 };
             }
-            categoryStats[categoryName].total += parseFloat(tx.amount || 0);
+            categoryStats[categoryName].total += parseFloat(line.amount || 0);
             categoryStats[categoryName].count += 1;
-        });
+        }));
         
         // Преобразуем в массив и сортируем по сумме (по убыванию)
         const categoriesArray = Object.values(categoryStats).sort((a, b) => b.total - a.total);
@@ -1328,6 +1377,20 @@ async function loadCategoriesWithStats() {
     }
 }
 
+function rootCategoryName(tx) {
+    return categoryRootNames[tx.category_id] || tx.category_name;
+}
+
+// Строки операции для статистики: у разбитой операции каждая строка
+// учитывается в своей категории.
+function transactionLines(tx) {
+    return tx.splits && tx.splits.length > 0 ? tx.splits : [tx];
+}
+
+function formatSplits(splits) {
+    return splits.map(split => `${split.category_name || 'Без категории'}: ${formatAmount(split.amount)}${split.note ? ` (${split.note})` : ''}`).join(', ');
+}
+
 function showCategoryTransactions(categoryName) {
     selectedCategoryName = categoryName;
     switchTab('categoryTransactions');
@@ -1345,7 +1408,8 @@ async function loadCategoryTransactions(categoryName) {
         const params = {
             telegram_id: telegramId,
             limit: 1000,
-            period: periodParams.period
+            period: periodParams.period,
+            types: currentType
         };
         
         if (periodParams.startDate) {
@@ -1359,10 +1423,10 @@ async function loadCategoryTransactions(categoryName) {
 
         const response = await fetch(url);
         const data = await response.json();
-        let transactions = (data.transactions || []).filter(tx => tx.type === currentType);
+        let transactions = data.transactions || [];
         
-        // Фильтруем по категории
-        transactions = transactions.filter(tx => tx.category_name === categoryName);
+        // Фильтруем по категории вместе с подкатегориями
+        transactions = transactions.filter(tx => transactionLines(tx).some(line => rootCategoryName(line) === categoryName));
 
         const list = document.getElementById('categoryTransactionsList');
         if (transactions.length === 0) {
@@ -1379,6 +1443,8 @@ async function loadCategoryTransactions(categoryName) {
                         <span>${formatDate(new Date(tx.operation_date))}</span>
                     </div>
                     ${tx.description ? `<div style="margin-top: 5px; font-size: 12px; opacity: 0.7;">${tx.description}</div>` : ''}
+                    ${tx.splits ? `<div style="margin-top: 5px; font-size: 12px; opacity: 0.7;">${formatSplits(tx.splits)}</div>` : ''}
+                    ${tx.tags ? `<div style="margin-top: 5px; font-size: 12px; opacity: 0.7;">${formatTags(tx.tags)}</div>` : ''}
                 </li>
             `).join('');
         }
@@ -1458,12 +1524,13 @@ async function handleTransactionSubmit(e) {
             amount: amountStr,
             category_id: selectedCategoryId,
             description: document.getElementById('transactionComment').value,
-            operation_date: operationDate
+            operation_date: operationDate,
+            tags: parseTags(document.getElementById('transactionTags').value)
         })
     });
 
     if (result.success) {
-        showAlert('Транзакция создана!');
+        showAlert(result.data.warning ? `Транзакция создана. ${result.data.warning}` : 'Транзакция создана!');
         closeModal('transactionModal');
         resetForm('transactionForm');
         selectedCategoryId = null;
@@ -1477,9 +1544,8 @@ async function handleTransactionSubmit(e) {
 async function openTransactionEdit(transactionId) {
     editingTransactionId = transactionId;
     try {
-        const response = await fetch(`${gatewayUrl}/api/transactions?telegram_id=${telegramId}&limit=1000`);
-        const data = await response.json();
-        const transaction = data.transactions.find(tx => tx.id === transactionId);
+        const response = await fetch(`${gatewayUrl}/api/transactions/${transactionId}?telegram_id=${telegramId}`);
+        const transaction = response.ok ? await response.json() : null;
 
         if (transaction) {
             document.getElementById('editTransactionAmount').value = transaction.amount;
@@ -1488,13 +1554,17 @@ async function openTransactionEdit(transactionId) {
             document.getElementById('editTransactionDate').value = transactionDate;
             document.getElementById('editTransactionDate').setAttribute('max', today);
             document.getElementById('editTransactionComment').value = transaction.description || '';
+            document.getElementById('editTransactionTags').value = (transaction.tags || []).join(', ');
 
             // Load categories for edit
-            const catResponse = await fetch(`${gatewayUrl}/api/categories?telegram_id=${telegramId}&type=${transaction.type}`);
+            // Архивная категория остается в списке, только если она уже выбрана
+            const catResponse = await fetch(`${gatewayUrl}/api/categories?telegram_id=${telegramId}&type=${transaction.type}&include_archived=true`);
             const catData = await catResponse.json();
             const select = document.getElementById('editTransactionCategory');
-            select.innerHTML = catData.categories.map(cat => 
-                `<option value="${cat.id}" ${cat.id === transaction.category_id ? 'selected' : ''}>${cat.name}</option>`
+            select.innerHTML = flattenCategories(catData.categories || [])
+                .filter(cat => !cat.is_archived || cat.id === transaction.category_id)
+                .map(cat => 
+                `<option value="${cat.id}" ${cat.id === transaction.category_id ? 'selected' : ''}>${cat.label}</option>`
             ).join('');
 
             openModal('transactionEditModal');
@@ -1528,7 +1598,8 @@ async function handleTransactionEditSubmit(e) {
             amount: amountStr,
             category_id: parseInt(document.getElementById('editTransactionCategory').value),
             description: document.getElementById('editTransactionComment').value,
-            operation_date: formatDateForApi(date)
+            operation_date: formatDateForApi(date),
+            tags: parseTags(document.getElementById('editTransactionTags').value)
         })
     });
 
@@ -1730,7 +1801,7 @@ async function handleTransferSubmit(e) {
     });
 
     if (result.success) {
-        showAlert('Перевод выполнен!');
+        showAlert(result.data.warning ? `Перевод выполнен. ${result.data.warning}` : 'Перевод выполнен!');
         closeModal('transferModal');
         resetForm('transferForm');
         loadAccounts();
@@ -1744,9 +1815,9 @@ let editingTransferId = null;
 async function openTransferEdit(transferId) {
     editingTransferId = transferId;
     try {
-        const response = await fetch(`${gatewayUrl}/api/transactions?telegram_id=${telegramId}&limit=1000`);
-        const data = await response.json();
-        const transfer = data.transactions.find(tx => tx.id === transferId && tx.type === 'transfer');
+        const response = await fetch(`${gatewayUrl}/api/transactions/${transferId}?telegram_id=${telegramId}`);
+        const data = response.ok ? await response.json() : null;
+        const transfer = data && data.type === 'transfer' ? data : null;
 
         if (transfer) {
             // Заполнить select счетами
@@ -1856,21 +1927,42 @@ async function handleDeleteTransfer() {
     }
 }
 
-async function loadTransferHistory() {
+const TRANSFER_HISTORY_PAGE_SIZE = 50;
+let transferHistory = [];
+let transferHistoryPageToken = null;
+
+// Загружает первую страницу истории переводов, а с more = true —
+// следующую страницу после уже показанных.
+async function loadTransferHistory(more = false) {
     try {
-        const response = await fetch(`${gatewayUrl}/api/transactions?telegram_id=${telegramId}&limit=1000`);
+        const params = {
+            telegram_id: telegramId,
+            types: 'transfer',
+            limit: TRANSFER_HISTORY_PAGE_SIZE
+        };
+        if (more && transferHistoryPageToken) {
+            params.page_token = transferHistoryPageToken;
+        }
+        const response = await fetch(buildApiUrl('/api/transactions', params));
         const data = await response.json();
-        const transfers = (data.transactions || []).filter(tx => tx.type === 'transfer');
+        transferHistory = more ? transferHistory.concat(data.transactions || []) : (data.transactions || []);
+        transferHistoryPageToken = data.next_page_token || null;
+        const transfers = transferHistory;
+
+        // Переводы могут ссылаться на архивные счета, поэтому берем полный список
+        const accountsResponse = await fetch(`${gatewayUrl}/api/accounts?telegram_id=${telegramId}&include_archived=true`);
+        const accountsData = await accountsResponse.json();
+        const allAccounts = accountsData.accounts || accounts;
 
         const list = document.getElementById('transferHistoryList');
         if (transfers.length === 0) {
             list.innerHTML = '<li class="empty-state"><div class="empty-state-icon">💸</div><div>Нет переводов</div></li>';
         } else {
             list.innerHTML = transfers.map(tx => {
-                const fromAccount = accounts.find(acc => acc.id === tx.account_id);
-                const toAccount = tx.related_account_id ? accounts.find(acc => acc.id === tx.related_account_id) : null;
+                const fromAccount = allAccounts.find(acc => acc.id === tx.account_id);
+                const toAccount = tx.related_account_id ? allAccounts.find(acc => acc.id === tx.related_account_id) : null;
                 const fromName = fromAccount ? fromAccount.name : tx.account_name || 'Неизвестный счет';
-                const toName = toAccount ? toAccount.name : 'Неизвестный счет';
+                const toName = toAccount ? toAccount.name : tx.related_account_name || 'Неизвестный счет';
                 
                 return `
                     <li class="transaction-item" onclick="openTransferEdit(${tx.id})">
@@ -1884,7 +1976,9 @@ async function loadTransferHistory() {
                         </div>
                     </li>
                 `;
-            }).join('');
+            }).join('') + (transferHistoryPageToken ? `
+                <li class="transaction-item" style="text-align: center;" onclick="loadTransferHistory(true)">Показать еще</li>
+            ` : '');
         }
     } catch (error) {
         console.error('Error loading transfer history:', error);
@@ -1903,7 +1997,8 @@ async function handleAddCategorySubmit(e) {
         body: JSON.stringify({
             telegram_id: telegramId,
             name: document.getElementById('categoryName').value,
-            type: currentType
+            type: currentType,
+            parent_id: parseInt(document.getElementById('categoryParent').value, 10) || 0
         })
     });
 